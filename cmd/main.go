@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 
+	"github.com/jrmarcco/synp/internal/admin"
 	"github.com/jrmarcco/synp/internal/app"
 	"github.com/jrmarcco/synp/internal/pkg/providers"
 	"github.com/jrmarcco/synp/internal/ws"
@@ -27,13 +28,18 @@ func main() {
 		// 初始化 zap.Logger。
 		providers.ZapLoggerFxModule,
 
-		// 初始化 redis.Cmdable。
+		// 初始化当前网关节点信息。
+		providers.NodeFxModule,
+
+		// 初始化 redis.Cmdable & redis.UniversalClient。
 		providers.RedisFxModule,
 
-		// 初始化 kafka。
-		providers.KafkaFxModule,
-		providers.KafkaConsumerFxModule,
-		providers.KafkaProducerFxModule,
+		// 初始化消息队列 ( kafka / redis / nats / memory ) 及网关事件消费者。
+		providers.MQFxModule,
+		providers.GatewayConsumerFxModule,
+
+		// 初始化消息去重器。
+		providers.DedupFxModule,
 
 		// 初始化 token validator。
 		providers.ValidatorFxModule,
@@ -44,12 +50,36 @@ func main() {
 		// 初始化 message push func。
 		providers.MessagePushFuncFxModule,
 
+		// 初始化大消息分片传输。
+		providers.ChunkFxModule,
+
+		// 初始化投递回执及已读回执。
+		providers.ReceiptFxModule,
+
 		// 初始化 retransmit manager。
 		providers.RetransmitFxModule,
 
+		// 初始化上行消息路由。
+		providers.RouteFxModule,
+
 		// 初始化 message handler。
 		providers.MessageHandlerFxModule,
 
+		// 初始化 presence tracker。
+		providers.PresenceFxModule,
+
+		// 初始化离线消息存储。
+		providers.OfflineFxModule,
+
+		// 初始化 rpc manager。
+		providers.RPCFxModule,
+
+		// 初始化节点间消息转发。
+		providers.ClusterFxModule,
+
+		// 初始化后端直接推送。
+		providers.PushFxModule,
+
 		// 初始化 upgrader。
 		ws.WsUpgraderFxModule,
 
@@ -59,6 +89,9 @@ func main() {
 		// 初始化 conn manager。
 		conn.ConnManagerFxModule,
 
+		// 初始化 admin server。
+		admin.AdminFxModule,
+
 		// 初始化 app。
 		app.AppFxModule,
 	).Run()
@@ -74,7 +107,7 @@ func loadConfig() error {
 		return fmt.Errorf("failed to read base config: %w", err)
 	}
 
-	subConfigNames := []string{"redis", "kafka"}
+	subConfigNames := []string{"redis", "kafka", "nats"}
 	for _, subConfigName := range subConfigNames {
 		viper.SetConfigName(subConfigName)
 		if err := viper.MergeInConfig(); err != nil {
@@ -0,0 +1,91 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jrmarcco/synp/internal/ws/gateway"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultRedriveLimit = 100
+	maxRedriveLimit     = 10000
+)
+
+var _ Route = (*DeadLetterRedriveRoute)(nil)
+
+// DeadLetterRedriveRequest 为重新发送死信消息的请求。
+type DeadLetterRedriveRequest struct {
+	Limit         int   `json:"limit"`         // 最多重新发送的消息数，默认 100
+	IdleTimeoutMs int64 `json:"idleTimeoutMs"` // 等待新的死信消息的时间 ( 毫秒 )，默认 5000
+}
+
+// DeadLetterRedriveResponse 为重新发送死信消息的响应。
+type DeadLetterRedriveResponse struct {
+	Redriven int    `json:"redriven"`
+	Error    string `json:"error,omitempty"`
+}
+
+// DeadLetterRedriveRoute 将死信 topic 中的 push message 重新发送到原 topic。
+// 重新发送的消息会清除重试次数，重新走完整的重试流程。
+//
+// 请求：
+//
+//	POST /admin/v1/dead-letter/redrive
+//	{"limit": 100, "idleTimeoutMs": 5000}
+//
+// 响应：
+//
+//	200：{"redriven": 100}
+//	500：部分消息重新发送失败，{"redriven": 10, "error": "..."}
+//	501：没有开启消费失败处理。
+type DeadLetterRedriveRoute struct {
+	redriver *gateway.DeadLetterRedriver
+	logger   *zap.Logger
+}
+
+func (r *DeadLetterRedriveRoute) Pattern() string {
+	return "POST /admin/v1/dead-letter/redrive"
+}
+
+func (r *DeadLetterRedriveRoute) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if r.redriver == nil {
+		WriteError(w, http.StatusNotImplemented, errors.New("consumer failure handling is not enabled"))
+		return
+	}
+
+	body := &DeadLetterRedriveRequest{}
+	if err := ReadJSON(req, body); err != nil {
+		WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	limit := body.Limit
+	if limit <= 0 {
+		limit = defaultRedriveLimit
+	}
+	limit = min(limit, maxRedriveLimit)
+
+	redriven, err := r.redriver.Redrive(req.Context(), limit, time.Duration(body.IdleTimeoutMs)*time.Millisecond)
+	if err != nil {
+		r.logger.Error(
+			"[synp-admin] failed to redrive dead letter messages",
+			zap.Int("redriven", redriven),
+			zap.Error(err),
+		)
+		WriteJSON(w, http.StatusInternalServerError, DeadLetterRedriveResponse{Redriven: redriven, Error: err.Error()})
+		return
+	}
+
+	r.logger.Info("[synp-admin] dead letter messages redriven", zap.Int("redriven", redriven))
+	WriteJSON(w, http.StatusOK, DeadLetterRedriveResponse{Redriven: redriven})
+}
+
+func NewDeadLetterRedriveRoute(redriver *gateway.DeadLetterRedriver, logger *zap.Logger) *DeadLetterRedriveRoute {
+	return &DeadLetterRedriveRoute{
+		redriver: redriver,
+		logger:   logger,
+	}
+}
@@ -0,0 +1,2 @@
+// Package admin 提供了网关的管理 API ( HTTP )，供后端 ( 业务服务端 ) 和运维使用。
+package admin
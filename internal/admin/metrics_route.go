@@ -0,0 +1,32 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var _ Route = (*MetricsRoute)(nil)
+
+// MetricsRoute 以 Prometheus 格式暴露网关的监控指标 ( 如发送队列溢出次数 )。
+//
+// 请求：
+//
+//	GET /admin/v1/metrics
+//
+// 抓取时同样需要携带 Authorization: Bearer <token>。
+type MetricsRoute struct {
+	handler http.Handler
+}
+
+func (r *MetricsRoute) Pattern() string {
+	return "GET /admin/v1/metrics"
+}
+
+func (r *MetricsRoute) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.handler.ServeHTTP(w, req)
+}
+
+func NewMetricsRoute() *MetricsRoute {
+	return &MetricsRoute{handler: promhttp.Handler()}
+}
@@ -0,0 +1,93 @@
+package admin
+
+import (
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+var AdminFxModule = fx.Module(
+	"admin",
+	fx.Provide(
+		fx.Annotate(
+			newServer,
+			fx.ParamTags(`group:"admin-route"`),
+		),
+
+		// 在线状态查询。
+		fx.Annotate(
+			NewPresenceQueryRoute,
+			fx.As(new(Route)),
+			fx.ResultTags(`group:"admin-route"`),
+		),
+
+		// RPC 响应 HTTP 回调。
+		fx.Annotate(
+			NewRPCReplyRoute,
+			fx.As(new(Route)),
+			fx.ResultTags(`group:"admin-route"`),
+		),
+
+		// 后端直接推送 ( 单条 / 批量 / 房间 / 广播 )。
+		fx.Annotate(
+			NewPushRoute,
+			fx.As(new(Route)),
+			fx.ResultTags(`group:"admin-route"`),
+		),
+		fx.Annotate(
+			NewBatchPushRoute,
+			fx.As(new(Route)),
+			fx.ResultTags(`group:"admin-route"`),
+		),
+		fx.Annotate(
+			NewRoomPushRoute,
+			fx.As(new(Route)),
+			fx.ResultTags(`group:"admin-route"`),
+		),
+		fx.Annotate(
+			NewBroadcastRoute,
+			fx.As(new(Route)),
+			fx.ResultTags(`group:"admin-route"`),
+		),
+
+		// 房间成员管理。
+		fx.Annotate(
+			NewRoomMembersRoute,
+			fx.As(new(Route)),
+			fx.ResultTags(`group:"admin-route"`),
+		),
+
+		// 监控指标。
+		fx.Annotate(
+			NewMetricsRoute,
+			fx.As(new(Route)),
+			fx.ResultTags(`group:"admin-route"`),
+		),
+
+		// 重新发送死信消息。
+		fx.Annotate(
+			NewDeadLetterRedriveRoute,
+			fx.As(new(Route)),
+			fx.ResultTags(`group:"admin-route"`),
+		),
+	),
+)
+
+func newServer(routes []Route, logger *zap.Logger) (*Server, error) {
+	type config struct {
+		Host  string `mapstructure:"host"`
+		Port  int    `mapstructure:"port"`
+		Token string `mapstructure:"token"`
+	}
+
+	cfg := config{}
+	if err := viper.UnmarshalKey("synp.admin", &cfg); err != nil {
+		return nil, err
+	}
+
+	return NewServer(Config{
+		Host:  cfg.Host,
+		Port:  cfg.Port,
+		Token: cfg.Token,
+	}, routes, logger)
+}
@@ -0,0 +1,82 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/jrmarcco/synp/internal/pkg/presence"
+	"go.uber.org/zap"
+)
+
+const defaultMaxPresenceQuerySize = 500
+
+var _ Route = (*PresenceQueryRoute)(nil)
+
+// PresenceQueryRoute 批量查询用户在线状态。
+//
+// 请求：
+//
+//	POST /admin/v1/presence/query
+//	{"bid": 1, "uids": [1, 2, 3]}
+//
+// 响应：
+//
+//	{"presences": [{"bid": 1, "uid": 1, "online": true, "devices": [...]}, ...]}
+type PresenceQueryRoute struct {
+	store        presence.Store
+	maxQuerySize int
+
+	logger *zap.Logger
+}
+
+func (r *PresenceQueryRoute) Pattern() string {
+	return "POST /admin/v1/presence/query"
+}
+
+func (r *PresenceQueryRoute) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	type request struct {
+		BID  uint64   `json:"bid"`
+		UIDs []uint64 `json:"uids"`
+	}
+
+	type response struct {
+		Presences []presence.Presence `json:"presences"`
+	}
+
+	body := request{}
+	if err := ReadJSON(req, &body); err != nil {
+		WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if body.BID == 0 {
+		WriteError(w, http.StatusBadRequest, fmt.Errorf("empty bid"))
+		return
+	}
+	if len(body.UIDs) > r.maxQuerySize {
+		WriteError(w, http.StatusBadRequest, fmt.Errorf("too many uids, max %d", r.maxQuerySize))
+		return
+	}
+
+	presences, err := r.store.BatchQuery(req.Context(), body.BID, body.UIDs)
+	if err != nil {
+		r.logger.Error(
+			"[synp-admin] failed to query presence",
+			zap.Uint64("bid", body.BID),
+			zap.Int("uid_cnt", len(body.UIDs)),
+			zap.Error(err),
+		)
+		WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, response{Presences: presences})
+}
+
+func NewPresenceQueryRoute(store presence.Store, logger *zap.Logger) *PresenceQueryRoute {
+	return &PresenceQueryRoute{
+		store:        store,
+		maxQuerySize: defaultMaxPresenceQuerySize,
+		logger:       logger,
+	}
+}
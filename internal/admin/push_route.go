@@ -0,0 +1,407 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/jrmarcco/synp/internal/pkg/message"
+	"github.com/jrmarcco/synp/internal/pkg/push"
+	"github.com/jrmarcco/synp/internal/pkg/room"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	maxBatchPushSize = 1000
+	maxRoomMembers   = 10000
+)
+
+var (
+	_ Route = (*PushRoute)(nil)
+	_ Route = (*BatchPushRoute)(nil)
+	_ Route = (*RoomPushRoute)(nil)
+	_ Route = (*BroadcastRoute)(nil)
+	_ Route = (*RoomMembersRoute)(nil)
+)
+
+// PushOptions 为推送消息的扩展选项 ( synp-api 尚未定义的字段 )，批量推送时对所有消息生效。
+type PushOptions struct {
+	Priority      string `json:"priority"`      // 发送优先级：high / normal ( 默认 )
+	ConflationKey string `json:"conflationKey"` // 合并 key：同一连接上合并 key 相同的消息只投递最新的一条
+	ExpireAt      int64  `json:"expireAt"`      // 过期时间 ( 毫秒时间戳 )，0 表示永不过期
+	TTLMs         int64  `json:"ttlMs"`         // 有效期 ( 毫秒 )，从收到请求开始计算，与 expireAt 同时指定时取较早的一个
+}
+
+// expireAt 返回消息的过期时间，零值表示永不过期。
+func (o PushOptions) expireAt(now time.Time) (time.Time, error) {
+	if o.ExpireAt < 0 || o.TTLMs < 0 {
+		return time.Time{}, errors.New("expireAt and ttlMs must not be negative")
+	}
+
+	var expireAt time.Time
+	if o.ExpireAt > 0 {
+		expireAt = time.UnixMilli(o.ExpireAt)
+	}
+	if o.TTLMs > 0 {
+		ttlExpireAt := now.Add(time.Duration(o.TTLMs) * time.Millisecond)
+		if expireAt.IsZero() || ttlExpireAt.Before(expireAt) {
+			expireAt = ttlExpireAt
+		}
+	}
+	return expireAt, nil
+}
+
+// PushRequest 为推送单条消息的请求。
+type PushRequest struct {
+	PushOptions
+
+	Message      json.RawMessage `json:"message"`      // messagev1.PushMessage ( protojson )
+	AckTimeoutMs int64           `json:"ackTimeoutMs"` // 等待前端 ack 的时间 ( 毫秒 )，0 表示不等待
+}
+
+// BatchPushRequest 为批量推送的请求。
+type BatchPushRequest struct {
+	PushOptions
+
+	Messages     []json.RawMessage `json:"messages"`
+	AckTimeoutMs int64             `json:"ackTimeoutMs"`
+}
+
+// RoomPushRequest 为推送房间消息的请求，消息的 receiver_id 会被忽略。
+type RoomPushRequest struct {
+	PushOptions
+
+	Room         string          `json:"room"`
+	Message      json.RawMessage `json:"message"`
+	AckTimeoutMs int64           `json:"ackTimeoutMs"`
+}
+
+// BroadcastRequest 为广播的请求，消息的 receiver_id 会被忽略。
+type BroadcastRequest struct {
+	PushOptions
+
+	Message json.RawMessage `json:"message"`
+}
+
+// PushResults 为批量推送及房间推送的响应，结果与请求的消息 ( 房间成员 ) 一一对应。
+type PushResults struct {
+	Results []push.Result `json:"results"`
+}
+
+// PushRoute 直接推送单条消息，同步返回投递结果。
+//
+// 请求：
+//
+//	POST /admin/v1/push
+//	{"message": {"messageId": "...", "bizId": "1", "receiverId": "2", "body": "..."}, "priority": "high", "ackTimeoutMs": 3000}
+//
+// 响应：
+//
+//	200：{"messageId": "...", "receiverId": "2", "status": "acked", "nodeId": "...", "conns": 1}
+//	     status 取值：acked / delivered / offline_stored / unknown_receiver / failed
+//	400：请求格式错误。
+type PushRoute struct {
+	pusher *push.Pusher
+}
+
+func (r *PushRoute) Pattern() string {
+	return "POST /admin/v1/push"
+}
+
+func (r *PushRoute) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body := &PushRequest{}
+	if err := ReadJSON(req, body); err != nil {
+		WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	msg, err := readPushMessage(body.Message, body.PushOptions)
+	if err == nil {
+		err = push.Validate(msg)
+	}
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, r.pusher.Push(req.Context(), msg, ackTimeout(body.AckTimeoutMs)))
+}
+
+func NewPushRoute(pusher *push.Pusher) *PushRoute {
+	return &PushRoute{pusher: pusher}
+}
+
+// BatchPushRoute 批量推送消息，同步返回每条消息的投递结果。
+//
+// 请求：
+//
+//	POST /admin/v1/push/batch
+//	{"messages": [{...}, {...}], "ackTimeoutMs": 0}
+//
+// 响应：
+//
+//	200：{"results": [{...}, {...}]}
+//	400：请求格式错误或消息数超过 1000。
+type BatchPushRoute struct {
+	pusher *push.Pusher
+}
+
+func (r *BatchPushRoute) Pattern() string {
+	return "POST /admin/v1/push/batch"
+}
+
+func (r *BatchPushRoute) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body := &BatchPushRequest{}
+	if err := ReadJSON(req, body); err != nil {
+		WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if len(body.Messages) == 0 || len(body.Messages) > maxBatchPushSize {
+		WriteError(w, http.StatusBadRequest, fmt.Errorf("messages size must be between 1 and %d", maxBatchPushSize))
+		return
+	}
+
+	msgs := make([]*messagev1.PushMessage, 0, len(body.Messages))
+	for i, raw := range body.Messages {
+		msg, err := readPushMessage(raw, body.PushOptions)
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, fmt.Errorf("messages[%d]: %w", i, err))
+			return
+		}
+		msgs = append(msgs, msg)
+	}
+
+	WriteJSON(w, http.StatusOK, PushResults{Results: r.pusher.PushBatch(req.Context(), msgs, ackTimeout(body.AckTimeoutMs))})
+}
+
+func NewBatchPushRoute(pusher *push.Pusher) *BatchPushRoute {
+	return &BatchPushRoute{pusher: pusher}
+}
+
+// RoomPushRoute 推送消息给房间的所有成员，同步返回每个成员的投递结果。
+//
+// 请求：
+//
+//	POST /admin/v1/push/room
+//	{"room": "...", "message": {"messageId": "...", "bizId": "1", "body": "..."}, "ackTimeoutMs": 0}
+//
+// 响应：
+//
+//	200：{"results": [{...}, {...}]}
+//	400：请求格式错误或房间成员数超过 10000 ( 应使用消息队列推送 )。
+type RoomPushRoute struct {
+	pusher *push.Pusher
+	store  room.Store
+	logger *zap.Logger
+}
+
+func (r *RoomPushRoute) Pattern() string {
+	return "POST /admin/v1/push/room"
+}
+
+func (r *RoomPushRoute) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body := &RoomPushRequest{}
+	if err := ReadJSON(req, body); err != nil {
+		WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if body.Room == "" {
+		WriteError(w, http.StatusBadRequest, errors.New("empty room"))
+		return
+	}
+
+	msg, err := readPushMessage(body.Message, body.PushOptions)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if msg.GetBizId() == 0 {
+		WriteError(w, http.StatusBadRequest, errors.New("empty biz_id"))
+		return
+	}
+
+	uids, err := r.store.Members(req.Context(), msg.GetBizId(), body.Room)
+	if err != nil {
+		r.logger.Error(
+			"[synp-admin] failed to query room members",
+			zap.Uint64("biz_id", msg.GetBizId()),
+			zap.String("room", body.Room),
+			zap.Error(err),
+		)
+		WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if len(uids) > maxRoomMembers {
+		WriteError(w, http.StatusBadRequest, fmt.Errorf("too many room members: %d > %d", len(uids), maxRoomMembers))
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, PushResults{Results: r.pusher.PushUsers(req.Context(), msg, uids, ackTimeout(body.AckTimeoutMs))})
+}
+
+func NewRoomPushRoute(pusher *push.Pusher, store room.Store, logger *zap.Logger) *RoomPushRoute {
+	return &RoomPushRoute{
+		pusher: pusher,
+		store:  store,
+		logger: logger,
+	}
+}
+
+// BroadcastRoute 推送消息给同一业务下所有在线用户 ( 所有节点 )，不等待 ack，也不保存离线消息。
+//
+// 请求：
+//
+//	POST /admin/v1/push/broadcast
+//	{"message": {"messageId": "...", "bizId": "1", "body": "..."}}
+//
+// 响应：
+//
+//	200：{"conns": 100, "nodes": 3}，conns 为本节点投递的连接数，nodes 为收到广播的节点数。
+//	500：本节点已经投递，转发到其他节点失败，{"conns": 100, "nodes": 1, "error": "..."}
+type BroadcastRoute struct {
+	pusher *push.Pusher
+	logger *zap.Logger
+}
+
+func (r *BroadcastRoute) Pattern() string {
+	return "POST /admin/v1/push/broadcast"
+}
+
+func (r *BroadcastRoute) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body := &BroadcastRequest{}
+	if err := ReadJSON(req, body); err != nil {
+		WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	msg, err := readPushMessage(body.Message, body.PushOptions)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	res, err := r.pusher.Broadcast(req.Context(), msg)
+	if err != nil {
+		if errors.Is(err, push.ErrInvalidMessage) {
+			WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		r.logger.Error(
+			"[synp-admin] failed to broadcast message",
+			zap.String("message_id", msg.GetMessageId()),
+			zap.Error(err),
+		)
+		WriteJSON(w, http.StatusInternalServerError, struct {
+			push.BroadcastResult
+			Error string `json:"error"`
+		}{res, err.Error()})
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, res)
+}
+
+func NewBroadcastRoute(pusher *push.Pusher, logger *zap.Logger) *BroadcastRoute {
+	return &BroadcastRoute{
+		pusher: pusher,
+		logger: logger,
+	}
+}
+
+// RoomMembersRequest 为修改房间成员的请求，先加入后移出。
+type RoomMembersRequest struct {
+	BID   uint64   `json:"bid"`
+	Room  string   `json:"room"`
+	Join  []uint64 `json:"join"`
+	Leave []uint64 `json:"leave"`
+}
+
+// RoomMembersRoute 修改房间成员。
+//
+// 请求：
+//
+//	POST /admin/v1/room/members
+//	{"bid": 1, "room": "...", "join": [1, 2], "leave": [3]}
+//
+// 响应：
+//
+//	200：修改成功。
+type RoomMembersRoute struct {
+	store  room.Store
+	logger *zap.Logger
+}
+
+func (r *RoomMembersRoute) Pattern() string {
+	return "POST /admin/v1/room/members"
+}
+
+func (r *RoomMembersRoute) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body := &RoomMembersRequest{}
+	if err := ReadJSON(req, body); err != nil {
+		WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if body.BID == 0 || body.Room == "" {
+		WriteError(w, http.StatusBadRequest, errors.New("empty bid or room"))
+		return
+	}
+
+	err := r.store.Join(req.Context(), body.BID, body.Room, body.Join)
+	if err == nil {
+		err = r.store.Leave(req.Context(), body.BID, body.Room, body.Leave)
+	}
+	if err != nil {
+		r.logger.Error(
+			"[synp-admin] failed to update room members",
+			zap.Uint64("biz_id", body.BID),
+			zap.String("room", body.Room),
+			zap.Error(err),
+		)
+		WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, struct{}{})
+}
+
+func NewRoomMembersRoute(store room.Store, logger *zap.Logger) *RoomMembersRoute {
+	return &RoomMembersRoute{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// readPushMessage 解析 protojson 格式的推送消息并设置扩展选项。
+func readPushMessage(raw json.RawMessage, opts PushOptions) (*messagev1.PushMessage, error) {
+	if len(raw) == 0 {
+		return nil, errors.New("empty message")
+	}
+
+	msg := &messagev1.PushMessage{}
+	if err := protojson.Unmarshal(raw, msg); err != nil {
+		return nil, fmt.Errorf("failed to decode message: %w", err)
+	}
+
+	priority, err := message.ParsePushPriority(opts.Priority)
+	if err != nil {
+		return nil, err
+	}
+	message.SetPushPriority(msg, priority)
+	message.SetConflationKey(msg, opts.ConflationKey)
+
+	expireAt, err := opts.expireAt(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	message.SetExpireAt(msg, expireAt)
+	return msg, nil
+}
+
+func ackTimeout(ms int64) time.Duration {
+	return time.Duration(max(ms, 0)) * time.Millisecond
+}
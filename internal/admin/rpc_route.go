@@ -0,0 +1,70 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/jrmarcco/synp/internal/pkg/rpc"
+	"go.uber.org/zap"
+)
+
+var _ Route = (*RPCReplyRoute)(nil)
+
+// RPCReplyRoute 接收业务服务端通过 HTTP 回调回复的 RPC 响应。
+// 回调地址需要直接指向发起调用的节点 ( 请求中的 replyTo.callbackUrl )。
+//
+// 请求：
+//
+//	POST /admin/v1/rpc/reply
+//	{"correlationId": "...", "code": 0, "message": "", "data": {...}}
+//
+// 响应：
+//
+//	200：响应已回复给前端。
+//	404：调用不存在 ( 已超时、已取消或不属于本节点 )。
+type RPCReplyRoute struct {
+	manager *rpc.Manager
+	logger  *zap.Logger
+}
+
+func (r *RPCReplyRoute) Pattern() string {
+	return "POST /admin/v1/rpc/reply"
+}
+
+func (r *RPCReplyRoute) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	reply := &rpc.Reply{}
+	if err := ReadJSON(req, reply); err != nil {
+		WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if reply.CorrelationID == "" {
+		WriteError(w, http.StatusBadRequest, fmt.Errorf("empty correlation id"))
+		return
+	}
+
+	if err := r.manager.Resolve(reply); err != nil {
+		if errors.Is(err, rpc.ErrCallNotFound) {
+			WriteError(w, http.StatusNotFound, err)
+			return
+		}
+
+		r.logger.Error(
+			"[synp-admin] failed to resolve rpc reply",
+			zap.String("correlation_id", reply.CorrelationID),
+			zap.Error(err),
+		)
+		WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	WriteJSON(w, http.StatusOK, struct{}{})
+}
+
+func NewRPCReplyRoute(manager *rpc.Manager, logger *zap.Logger) *RPCReplyRoute {
+	return &RPCReplyRoute{
+		manager: manager,
+		logger:  logger,
+	}
+}
@@ -0,0 +1,146 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultHost              = "127.0.0.1"
+	defaultPort              = 17002
+	defaultReadHeaderTimeout = 5 * time.Second
+	defaultMaxBodyBytes      = 1 << 20 // 1MB
+)
+
+var ErrTokenRequired = errors.New("admin token is required")
+
+// ErrPlaceholderToken 表示访问令牌仍然是配置文件中的占位符 ( 如 <admin-token> )。
+var ErrPlaceholderToken = errors.New("admin token is a placeholder, set a real token")
+
+// Route 是管理 API 的路由。
+type Route interface {
+	http.Handler
+
+	// Pattern 返回路由规则，格式同 http.ServeMux，例如 "POST /admin/v1/presence/query"。
+	Pattern() string
+}
+
+// Config 为管理 API 的相关配置。
+type Config struct {
+	Host  string // IP 地址，默认 127.0.0.1 ( 只允许本机访问 )
+	Port  int    // 端口号，默认 17002
+	Token string // 访问令牌，请求需携带 Authorization: Bearer <token>
+}
+
+func (cfg Config) Address() string {
+	return fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
+}
+
+// Server 为管理 API 的 HTTP 服务器。
+type Server struct {
+	config Config
+
+	httpServer *http.Server
+
+	logger *zap.Logger
+}
+
+func (s *Server) Start() error {
+	ln, err := net.Listen("tcp", s.config.Address())
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		if err := s.httpServer.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("[synp-admin] admin server stopped unexpectedly", zap.Error(err))
+		}
+	}()
+
+	s.logger.Info("[synp-admin] admin server started", zap.String("address", s.config.Address()))
+	return nil
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.httpServer.Shutdown(ctx)
+}
+
+// authenticate 校验访问令牌。
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	expected := []byte("Bearer " + s.config.Token)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actual := []byte(r.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(actual, expected) != 1 {
+			WriteError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, defaultMaxBodyBytes)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ReadJSON 解析请求体。
+func ReadJSON(r *http.Request, val any) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(val); err != nil {
+		return fmt.Errorf("failed to decode request body: %w", err)
+	}
+	return nil
+}
+
+// WriteJSON 写入 JSON 响应。
+func WriteJSON(w http.ResponseWriter, status int, val any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(val)
+}
+
+// WriteError 写入错误响应。
+func WriteError(w http.ResponseWriter, status int, err error) {
+	WriteJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func NewServer(config Config, routes []Route, logger *zap.Logger) (*Server, error) {
+	token := strings.TrimSpace(config.Token)
+	if token == "" {
+		return nil, ErrTokenRequired
+	}
+	if strings.HasPrefix(token, "<") && strings.HasSuffix(token, ">") {
+		return nil, ErrPlaceholderToken
+	}
+	if config.Host == "" {
+		config.Host = defaultHost
+	}
+	if config.Port == 0 {
+		config.Port = defaultPort
+	}
+
+	s := &Server{
+		config: config,
+		logger: logger,
+	}
+
+	mux := http.NewServeMux()
+	for _, route := range routes {
+		mux.Handle(route.Pattern(), route)
+		logger.Info("[synp-admin] route registered", zap.String("pattern", route.Pattern()))
+	}
+
+	s.httpServer = &http.Server{
+		Handler:           s.authenticate(mux),
+		ReadHeaderTimeout: defaultReadHeaderTimeout,
+	}
+	return s, nil
+}
@@ -4,6 +4,11 @@ import (
 	"context"
 
 	"github.com/jrmarcco/synp"
+	"github.com/jrmarcco/synp/internal/admin"
+	"github.com/jrmarcco/synp/internal/pkg/offline"
+	"github.com/jrmarcco/synp/internal/pkg/presence"
+	"github.com/jrmarcco/synp/internal/pkg/push"
+	"github.com/jrmarcco/synp/internal/pkg/rpc"
 	"github.com/jrmarcco/synp/internal/ws"
 	"github.com/jrmarcco/synp/internal/ws/gateway"
 	"go.uber.org/fx"
@@ -13,14 +18,21 @@ import (
 var AppFxModule = fx.Module("app", fx.Invoke(initApp))
 
 type app struct {
-	wsSvr synp.Server
+	wsSvr    synp.Server
+	adminSvr *admin.Server
 }
 
 func (app *app) Start() error {
+	if err := app.adminSvr.Start(); err != nil {
+		return err
+	}
 	return app.wsSvr.Start()
 }
 
-func (app *app) Stop() error {
+func (app *app) Stop(ctx context.Context) error {
+	if err := app.adminSvr.Shutdown(ctx); err != nil {
+		return err
+	}
 	return app.wsSvr.GracefulShutdown()
 }
 
@@ -33,6 +45,12 @@ type appFxParams struct {
 
 	Consumers map[string]*gateway.Consumer
 
+	AdminServer  *admin.Server
+	PresenceHub  *presence.Hub
+	RPCManager   *rpc.Manager
+	OfflineStore offline.Store
+	Pusher       *push.Pusher
+
 	Logger    *zap.Logger
 	Lifecycle fx.Lifecycle
 }
@@ -41,18 +59,23 @@ func initApp(params appFxParams) *app {
 	wsCfg := ws.DefaultConfig()
 	wsSvr := ws.NewServer(
 		wsCfg, params.Upgrader, params.ConnManager, params.ConnHandler, params.Consumers, params.Logger,
+		ws.SvrWithPresenceHub(params.PresenceHub),
+		ws.SvrWithRPCManager(params.RPCManager),
+		ws.SvrWithOfflineStore(params.OfflineStore),
+		ws.SvrWithPusher(params.Pusher),
 	)
 
 	app := &app{
-		wsSvr: wsSvr,
+		wsSvr:    wsSvr,
+		adminSvr: params.AdminServer,
 	}
 
 	params.Lifecycle.Append(fx.Hook{
 		OnStart: func(_ context.Context) error {
 			return app.Start()
 		},
-		OnStop: func(_ context.Context) error {
-			return app.Stop()
+		OnStop: func(ctx context.Context) error {
+			return app.Stop(ctx)
 		},
 	})
 
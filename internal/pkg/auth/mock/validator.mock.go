@@ -0,0 +1,81 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: types.go
+//
+// Generated by this command:
+//
+//	mockgen -source=types.go -destination=mock/validator.mock.go -package=authmock -typed Validator
+//
+
+// Package authmock is a generated GoMock package.
+package authmock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	session "github.com/jrmarcco/synp/internal/pkg/session"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockValidator is a mock of Validator interface.
+type MockValidator struct {
+	ctrl     *gomock.Controller
+	recorder *MockValidatorMockRecorder
+	isgomock struct{}
+}
+
+// MockValidatorMockRecorder is the mock recorder for MockValidator.
+type MockValidatorMockRecorder struct {
+	mock *MockValidator
+}
+
+// NewMockValidator creates a new mock instance.
+func NewMockValidator(ctrl *gomock.Controller) *MockValidator {
+	mock := &MockValidator{ctrl: ctrl}
+	mock.recorder = &MockValidatorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockValidator) EXPECT() *MockValidatorMockRecorder {
+	return m.recorder
+}
+
+// Validate mocks base method.
+func (m *MockValidator) Validate(ctx context.Context, token string) (session.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Validate", ctx, token)
+	ret0, _ := ret[0].(session.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Validate indicates an expected call of Validate.
+func (mr *MockValidatorMockRecorder) Validate(ctx, token any) *MockValidatorValidateCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Validate", reflect.TypeOf((*MockValidator)(nil).Validate), ctx, token)
+	return &MockValidatorValidateCall{Call: call}
+}
+
+// MockValidatorValidateCall wrap *gomock.Call
+type MockValidatorValidateCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockValidatorValidateCall) Return(arg0 session.User, arg1 error) *MockValidatorValidateCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockValidatorValidateCall) Do(f func(context.Context, string) (session.User, error)) *MockValidatorValidateCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockValidatorValidateCall) DoAndReturn(f func(context.Context, string) (session.User, error)) *MockValidatorValidateCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
@@ -0,0 +1,153 @@
+// Package bufpool 提供按容量分级复用的字节缓冲区。
+//
+// 缓冲区带有引用计数，可以在多个持有者之间共享 ( 如同一条消息只编码一次，放入多个连接的发送队列 )，
+// 最后一个持有者释放后放回对应容量等级的池中。
+// 注意：
+//
+//  1. 释放后不能再访问缓冲区及通过 Bytes 取得的切片；
+//  2. 没有释放的缓冲区由 GC 回收，不会泄漏，只是不能复用。
+package bufpool
+
+import (
+	"io"
+	"math/bits"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	minClassBits = 8  // 最小容量等级 256B
+	maxClassBits = 20 // 最大容量等级 1MB
+
+	// MinSize 为池化缓冲区的最小容量。
+	MinSize = 1 << minClassBits
+	// MaxSize 为池化缓冲区的最大容量，容量超过 MaxSize 的缓冲区释放后直接丢弃。
+	MaxSize = 1 << maxClassBits
+
+	// minRead 为 ReadFrom 每次读取时至少保留的空闲容量。
+	minRead = 512
+)
+
+// pools 按容量等级保存缓冲区，第 i 级缓冲区的容量不小于 1 << (i + minClassBits)。
+var pools [maxClassBits - minClassBits + 1]sync.Pool
+
+// Buffer 为带引用计数的字节缓冲区，通过 Get 获取时引用计数为 1。
+type Buffer struct {
+	// B 为缓冲区的内容，可以直接追加写入 ( 如 append、MarshalAppend )。
+	B []byte
+
+	refs atomic.Int32
+}
+
+// Bytes 返回缓冲区的内容，只在缓冲区释放前有效。
+func (b *Buffer) Bytes() []byte {
+	return b.B
+}
+
+// Len 返回缓冲区内容的长度。
+func (b *Buffer) Len() int {
+	return len(b.B)
+}
+
+// Write 将 p 追加到缓冲区，实现 io.Writer。
+func (b *Buffer) Write(p []byte) (int, error) {
+	b.B = append(b.B, p...)
+	return len(p), nil
+}
+
+// ReadFrom 从 r 读取数据追加到缓冲区直到 io.EOF，实现 io.ReaderFrom。
+// 容量不足时从池中获取更大的缓冲区，原来的内存放回池中。
+func (b *Buffer) ReadFrom(r io.Reader) (int64, error) {
+	var total int64
+	for {
+		if cap(b.B)-len(b.B) < minRead {
+			b.grow(minRead)
+		}
+
+		n, err := r.Read(b.B[len(b.B):cap(b.B)])
+		b.B = b.B[:len(b.B)+n]
+		total += int64(n)
+
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+// Retain 增加一次引用，每次 Retain 都需要对应一次 Release。
+func (b *Buffer) Retain() {
+	b.refs.Add(1)
+}
+
+// Release 释放一次引用，引用计数为 0 时缓冲区放回池中。
+func (b *Buffer) Release() {
+	switch refs := b.refs.Add(-1); {
+	case refs > 0:
+		return
+	case refs < 0:
+		panic("bufpool: release of released buffer")
+	}
+	put(b)
+}
+
+// grow 保证缓冲区至少有 n 字节的空闲容量。
+func (b *Buffer) grow(n int) {
+	larger := Get(max(2*cap(b.B), len(b.B)+n))
+	larger.B = append(larger.B, b.B...)
+
+	// 交换底层内存后释放较小的内存。
+	b.B, larger.B = larger.B, b.B[:0]
+	larger.Release()
+}
+
+// Get 从池中获取容量不小于 size 的空缓冲区，引用计数为 1。
+// size 超过 MaxSize 时直接分配，释放后不会放回池中。
+func Get(size int) *Buffer {
+	var b *Buffer
+	if i, ok := class(size); ok {
+		if v := pools[i].Get(); v != nil {
+			b, _ = v.(*Buffer)
+		}
+		if b == nil {
+			b = &Buffer{B: make([]byte, 0, 1<<(i+minClassBits))}
+		}
+	} else {
+		b = &Buffer{B: make([]byte, 0, size)}
+	}
+
+	b.refs.Store(1)
+	return b
+}
+
+// Wrap 将 p 包装为引用计数为 1 的缓冲区 ( 不复制 )。
+// 缓冲区释放后 p 可能被复用，调用方不能再使用 p。
+func Wrap(p []byte) *Buffer {
+	b := &Buffer{B: p}
+	b.refs.Store(1)
+	return b
+}
+
+// class 返回容量不小于 size 的最小等级，size 超过 MaxSize 时返回 false。
+func class(size int) (int, bool) {
+	if size <= MinSize {
+		return 0, true
+	}
+	if size > MaxSize {
+		return 0, false
+	}
+	return bits.Len(uint(size-1)) - minClassBits, true
+}
+
+// put 按容量向下取整放回对应等级的池中，保证从该等级获取的缓冲区容量足够。
+func put(b *Buffer) {
+	size := cap(b.B)
+	if size < MinSize || size > MaxSize {
+		return
+	}
+
+	b.B = b.B[:0]
+	pools[bits.Len(uint(size))-1-minClassBits].Put(b)
+}
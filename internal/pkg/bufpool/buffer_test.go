@@ -0,0 +1,76 @@
+package bufpool
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGet(t *testing.T) {
+	t.Parallel()
+
+	tcs := []struct {
+		name    string
+		size    int
+		wantCap int
+	}{
+		{name: "zero", size: 0, wantCap: MinSize},
+		{name: "min", size: MinSize, wantCap: MinSize},
+		{name: "round up", size: MinSize + 1, wantCap: 2 * MinSize},
+		{name: "max", size: MaxSize, wantCap: MaxSize},
+		{name: "unpooled", size: MaxSize + 1, wantCap: MaxSize + 1},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := Get(tc.size)
+			defer b.Release()
+
+			assert.Equal(t, 0, b.Len())
+			assert.GreaterOrEqual(t, cap(b.B), tc.wantCap)
+		})
+	}
+}
+
+func TestBuffer_Release(t *testing.T) {
+	t.Parallel()
+
+	// 容量小于 MinSize 的缓冲区不会放回池中，释放后不会被其他测试复用。
+	b := Wrap(make([]byte, 0, 16))
+	b.Retain()
+
+	b.Release()
+	assert.NotPanics(t, b.Release)
+	assert.Panics(t, b.Release)
+}
+
+func TestBuffer_ReadFrom(t *testing.T) {
+	t.Parallel()
+
+	data := strings.Repeat("synp", 4*MinSize)
+
+	b := Get(0)
+	defer b.Release()
+
+	_, _ = b.Write([]byte("head:"))
+	n, err := b.ReadFrom(strings.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)), n)
+	assert.Equal(t, "head:"+data, string(b.Bytes()))
+}
+
+func BenchmarkBuffer_ReadFrom(b *testing.B) {
+	data := bytes.Repeat([]byte("x"), 4<<10)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		buf := Get(len(data) + 1)
+		_, _ = buf.ReadFrom(bytes.NewReader(data))
+		buf.Release()
+	}
+}
@@ -0,0 +1,226 @@
+package chunk
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jrmarcco/jit/bean/option"
+	commonv1 "github.com/jrmarcco/synp-api/api/go/common/v1"
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/jrmarcco/synp/internal/pkg/message"
+)
+
+const (
+	// DefaultMaxPayloadSize 还原后的消息会作为一条消息发送到消息队列，
+	// 需要小于生产者的消息大小上限 ( 如 kafka.producer.max_message_bytes )，并为消息的其他字段预留空间。
+	DefaultMaxPayloadSize    = 960 << 10
+	DefaultMaxUploadsPerConn = 4
+	DefaultMaxBufferedBytes  = 256 << 20
+	DefaultUploadTimeout     = time.Minute
+)
+
+// Assembler 将前端上传的分片还原为原消息。
+//
+// 每个连接最多同时进行 maxUploads 个上传，原消息 body 最长 maxPayloadSize，
+// 超过 timeout 没有收到新分片的上传被丢弃，前端需要重新上传。
+// 分片数据在收到时追加，不会按前端声明的总长度预先分配内存。
+//
+// 所有连接上未完成的上传按声明的总长度占用节点的缓冲额度 ( maxBufferedBytes )，
+// 额度不足时拒绝新的上传，避免大量连接同时上传时耗尽内存。
+type Assembler struct {
+	mu       sync.Mutex
+	uploads  map[string]map[string]*upload // connID -> uploadID -> upload
+	buffered int64                         // 未完成的上传占用的额度
+
+	maxPayloadSize   int64
+	maxUploads       int
+	maxBufferedBytes int64
+	timeout          time.Duration
+	lastSweep        time.Time
+}
+
+type upload struct {
+	first     *Chunk // 第一个分片，用于校验后续分片的原消息字段
+	payload   []byte
+	updatedAt time.Time
+}
+
+// Add 添加连接上的分片，收到所有分片并校验通过后返回还原的原消息，否则返回 nil。
+// 重复的分片直接忽略；出错时丢弃整个上传 ( 偏移不连续除外 )。
+func (a *Assembler) Add(connID string, c *Chunk) (*messagev1.Message, error) {
+	if err := a.validate(c); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.sweep(now)
+
+	uploads := a.uploads[connID]
+	u, ok := uploads[c.UploadID]
+	if !ok {
+		if c.Offset != 0 {
+			// 上传已经超时 ( 或节点重启 )，前端需要重新上传。
+			return nil, fmt.Errorf("%w: %s", ErrUnknownUpload, c.UploadID)
+		}
+		if len(uploads) >= a.maxUploads {
+			return nil, fmt.Errorf("%w, max %d", ErrTooManyUploads, a.maxUploads)
+		}
+		if a.buffered+c.Total > a.maxBufferedBytes {
+			return nil, ErrBufferExhausted
+		}
+
+		if uploads == nil {
+			uploads = make(map[string]*upload)
+			a.uploads[connID] = uploads
+		}
+		first := *c
+		first.Data = nil
+		u = &upload{first: &first}
+		uploads[c.UploadID] = u
+		a.buffered += c.Total
+	} else if !u.matches(c) {
+		a.remove(connID, c.UploadID)
+		return nil, fmt.Errorf("%w: chunk of upload %s does not match the first chunk", ErrInvalidChunk, c.UploadID)
+	}
+
+	u.updatedAt = now
+	received := int64(len(u.payload))
+	switch {
+	case c.Offset+int64(len(c.Data)) <= received:
+		// 重复的分片。
+		return nil, nil
+	case c.Offset != received:
+		return nil, fmt.Errorf("%w: expected offset %d", ErrUnexpectedOffset, received)
+	}
+
+	u.payload = append(u.payload, c.Data...)
+	if int64(len(u.payload)) < c.Total {
+		return nil, nil
+	}
+
+	a.remove(connID, c.UploadID)
+	if Checksum(u.payload) != u.first.Checksum {
+		return nil, fmt.Errorf("%w: upload %s", ErrChecksumMismatch, c.UploadID)
+	}
+
+	return &messagev1.Message{
+		MessageId:     u.first.MessageID,
+		Cmd:           u.first.Cmd,
+		SerializeType: u.first.SerializeType,
+		Body:          u.payload,
+	}, nil
+}
+
+// ClearByConn 丢弃连接上所有未完成的上传 ( 连接断开时 )。
+func (a *Assembler) ClearByConn(connID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for uploadID := range a.uploads[connID] {
+		a.remove(connID, uploadID)
+	}
+}
+
+func (a *Assembler) validate(c *Chunk) error {
+	switch {
+	case c.UploadID == "":
+		return fmt.Errorf("%w: empty upload id", ErrInvalidChunk)
+	case c.MessageID == "":
+		return fmt.Errorf("%w: empty message id", ErrInvalidChunk)
+	case c.Cmd == commonv1.CommandType_COMMAND_TYPE_HEARTBEAT || c.Cmd == message.CommandTypeChunk:
+		return fmt.Errorf("%w: unsupported command type %d", ErrInvalidChunk, c.Cmd)
+	case c.Total <= 0 || c.Offset < 0 || len(c.Data) == 0 || c.Offset+int64(len(c.Data)) > c.Total:
+		return fmt.Errorf("%w: invalid offset or length", ErrInvalidChunk)
+	case c.Total > a.maxPayloadSize:
+		return fmt.Errorf("%w, max %d", ErrPayloadTooLarge, a.maxPayloadSize)
+	case c.Offset == 0 && len(c.Checksum) != 2*32:
+		return fmt.Errorf("%w: invalid checksum", ErrInvalidChunk)
+	}
+	return nil
+}
+
+// sweep 丢弃超时的上传，最多每 timeout 清除一次，调用方需要持有锁。
+func (a *Assembler) sweep(now time.Time) {
+	if now.Sub(a.lastSweep) < a.timeout {
+		return
+	}
+
+	a.lastSweep = now
+	for connID, uploads := range a.uploads {
+		for uploadID, u := range uploads {
+			if now.Sub(u.updatedAt) >= a.timeout {
+				a.remove(connID, uploadID)
+			}
+		}
+	}
+}
+
+func (a *Assembler) remove(connID, uploadID string) {
+	uploads := a.uploads[connID]
+	if u, ok := uploads[uploadID]; ok {
+		a.buffered -= u.first.Total
+	}
+	delete(uploads, uploadID)
+	if len(uploads) == 0 {
+		delete(a.uploads, connID)
+	}
+}
+
+// matches 判断分片是否属于同一个原消息。
+func (u *upload) matches(c *Chunk) bool {
+	first := u.first
+	return c.Total == first.Total &&
+		c.MessageID == first.MessageID &&
+		c.Cmd == first.Cmd &&
+		c.SerializeType == first.SerializeType &&
+		(c.Checksum == "" || c.Checksum == first.Checksum)
+}
+
+// AssemblerWithLimits 设置原消息 body 的最大长度及每个连接同时进行的上传数，小于 1 时使用默认值。
+func AssemblerWithLimits(maxPayloadSize int64, maxUploads int) option.Opt[Assembler] {
+	return func(a *Assembler) {
+		if maxPayloadSize > 0 {
+			a.maxPayloadSize = maxPayloadSize
+		}
+		if maxUploads > 0 {
+			a.maxUploads = maxUploads
+		}
+	}
+}
+
+// AssemblerWithMaxBufferedBytes 设置所有连接上未完成的上传最多占用的字节数 ( 按声明的总长度计算 )。
+func AssemblerWithMaxBufferedBytes(maxBufferedBytes int64) option.Opt[Assembler] {
+	return func(a *Assembler) {
+		if maxBufferedBytes > 0 {
+			a.maxBufferedBytes = maxBufferedBytes
+		}
+	}
+}
+
+// AssemblerWithTimeout 设置上传的超时时间，超过该时间没有收到新分片的上传被丢弃。
+func AssemblerWithTimeout(timeout time.Duration) option.Opt[Assembler] {
+	return func(a *Assembler) {
+		if timeout > 0 {
+			a.timeout = timeout
+		}
+	}
+}
+
+func NewAssembler(opts ...option.Opt[Assembler]) *Assembler {
+	a := &Assembler{
+		uploads:          make(map[string]map[string]*upload),
+		maxPayloadSize:   DefaultMaxPayloadSize,
+		maxUploads:       DefaultMaxUploadsPerConn,
+		maxBufferedBytes: DefaultMaxBufferedBytes,
+		timeout:          DefaultUploadTimeout,
+		lastSweep:        time.Now(),
+	}
+
+	option.Apply(a, opts...)
+	return a
+}
@@ -0,0 +1,139 @@
+package chunk
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	commonv1 "github.com/jrmarcco/synp-api/api/go/common/v1"
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/jrmarcco/synp/internal/pkg/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssembler_Add(t *testing.T) {
+	t.Parallel()
+
+	body := bytes.Repeat([]byte("0123456789"), 10)
+	msg := &messagev1.Message{
+		MessageId: "m1",
+		Cmd:       commonv1.CommandType_COMMAND_TYPE_UPSTREAM,
+		Body:      body,
+	}
+	message.SetConflationKey(msg, "ticker")
+	message.SetSeq(msg, 7)
+
+	msgs, err := Split(msg, 30)
+	require.NoError(t, err)
+	require.Len(t, msgs, 4)
+
+	chunks := make([]*Chunk, len(msgs))
+	for i, m := range msgs {
+		// 分片沿用原消息的扩展字段，但不沿用合并 key。
+		assert.Equal(t, message.CommandTypeChunk, m.GetCmd())
+		assert.Equal(t, uint64(7), message.Seq(m))
+		assert.Empty(t, message.ConflationKey(m))
+
+		chunks[i] = &Chunk{}
+		require.NoError(t, json.Unmarshal(m.GetBody(), chunks[i]))
+	}
+
+	a := NewAssembler(AssemblerWithLimits(1000, 1))
+
+	// 不连续的分片。
+	_, err = a.Add("c1", chunks[1])
+	require.ErrorIs(t, err, ErrUnknownUpload)
+
+	assembled, err := a.Add("c1", chunks[0])
+	require.NoError(t, err)
+	assert.Nil(t, assembled)
+	_, err = a.Add("c1", chunks[2])
+	require.ErrorIs(t, err, ErrUnexpectedOffset)
+
+	// 超过连接上同时进行的上传数。
+	other := *chunks[0]
+	other.UploadID = "u2"
+	_, err = a.Add("c1", &other)
+	require.ErrorIs(t, err, ErrTooManyUploads)
+
+	// 重复的分片直接忽略。
+	for _, c := range chunks[:3] {
+		assembled, err = a.Add("c1", c)
+		require.NoError(t, err)
+		assert.Nil(t, assembled)
+	}
+
+	assembled, err = a.Add("c1", chunks[3])
+	require.NoError(t, err)
+	require.NotNil(t, assembled)
+	assert.Equal(t, "m1", assembled.GetMessageId())
+	assert.Equal(t, commonv1.CommandType_COMMAND_TYPE_UPSTREAM, assembled.GetCmd())
+	assert.Equal(t, body, assembled.GetBody())
+
+	// 与第一个分片不一致的分片。
+	mismatched := *chunks[1]
+	mismatched.Checksum = Checksum([]byte("other"))
+	_, err = a.Add("c1", chunks[0])
+	require.NoError(t, err)
+	_, err = a.Add("c1", &mismatched)
+	require.ErrorIs(t, err, ErrInvalidChunk)
+
+	// 数据与校验和不一致。
+	corrupted := *chunks[3]
+	corrupted.Data = bytes.Repeat([]byte("x"), len(corrupted.Data))
+	for _, c := range chunks[:3] {
+		_, err = a.Add("c1", c)
+		require.NoError(t, err)
+	}
+	_, err = a.Add("c1", &corrupted)
+	require.ErrorIs(t, err, ErrChecksumMismatch)
+
+	// 超过最大长度。
+	large := *chunks[0]
+	large.Total = 1001
+	_, err = a.Add("c2", &large)
+	require.ErrorIs(t, err, ErrPayloadTooLarge)
+}
+
+func TestAssembler_MaxBufferedBytes(t *testing.T) {
+	t.Parallel()
+
+	body := bytes.Repeat([]byte("0123456789"), 10)
+	msgs, err := Split(&messagev1.Message{
+		MessageId: "m1",
+		Cmd:       commonv1.CommandType_COMMAND_TYPE_UPSTREAM,
+		Body:      body,
+	}, 60)
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+
+	chunks := make([]*Chunk, len(msgs))
+	for i, m := range msgs {
+		chunks[i] = &Chunk{}
+		require.NoError(t, json.Unmarshal(m.GetBody(), chunks[i]))
+	}
+
+	a := NewAssembler(AssemblerWithMaxBufferedBytes(150))
+
+	_, err = a.Add("c1", chunks[0])
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), a.buffered)
+
+	// 额度不足时拒绝新的上传 ( 包括其他连接 )。
+	_, err = a.Add("c2", chunks[0])
+	require.ErrorIs(t, err, ErrBufferExhausted)
+
+	// 上传完成后释放额度。
+	assembled, err := a.Add("c1", chunks[1])
+	require.NoError(t, err)
+	require.NotNil(t, assembled)
+	assert.Zero(t, a.buffered)
+
+	// 连接断开时释放额度。
+	_, err = a.Add("c2", chunks[0])
+	require.NoError(t, err)
+	a.ClearByConn("c2")
+	assert.Zero(t, a.buffered)
+	assert.Empty(t, a.uploads)
+}
@@ -0,0 +1,116 @@
+package chunk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jrmarcco/synp"
+	commonv1 "github.com/jrmarcco/synp-api/api/go/common/v1"
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/jrmarcco/synp/internal/pkg/message"
+)
+
+const DefaultChunkSize = 256 << 10
+
+// PushFunc 返回分片发送的 PushFunc：body 超过 chunkSize 的消息拆分为分片消息后依次通过 next 发送，
+// 其余消息直接通过 next 发送。重传时同样分片发送。
+func PushFunc(next message.PushFunc, chunkSize int) message.PushFunc {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	return func(conn synp.Conn, msg *messagev1.Message) error {
+		if len(msg.GetBody()) <= chunkSize || msg.GetCmd() == message.CommandTypeChunk {
+			return next(conn, msg)
+		}
+
+		chunks, err := Split(msg, chunkSize)
+		if err != nil {
+			return err
+		}
+		for _, c := range chunks {
+			if err = next(conn, c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// BroadcastFunc 返回分片发送的 BroadcastFunc：body 超过 chunkSize 的消息只拆分一次，
+// 每个分片通过 next 发送到所有连接 ( 分片同样只编码一次 )，其余消息直接通过 next 发送。
+//
+// 返回每个分片都成功发送的连接数：连接关闭后发送总是失败，取各分片成功连接数的最小值。
+func BroadcastFunc(next message.BroadcastFunc, chunkSize int) message.BroadcastFunc {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	return func(conns []synp.Conn, msg *messagev1.Message, seqs []uint64) (int, error) {
+		if len(msg.GetBody()) <= chunkSize || msg.GetCmd() == message.CommandTypeChunk {
+			return next(conns, msg, seqs)
+		}
+
+		chunks, err := Split(msg, chunkSize)
+		if err != nil {
+			return 0, err
+		}
+
+		// 某个连接发送失败时仍然向其余连接发送后续分片。
+		var (
+			sent = len(conns)
+			errs []error
+		)
+		for _, c := range chunks {
+			n, err := next(conns, c, seqs)
+			sent = min(sent, n)
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return sent, errors.Join(errs...)
+	}
+}
+
+// Split 将消息按 chunkSize 拆分为分片消息，分片消息的 message id 为 "{原消息 message id}:{偏移}"。
+//
+// 分片消息沿用原消息的扩展字段 ( 优先级、过期时间、序号 )，
+// 但不沿用合并 key，避免发送队列中同一消息的分片相互替换。
+func Split(msg *messagev1.Message, chunkSize int) ([]*messagev1.Message, error) {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	body := msg.GetBody()
+	checksum := Checksum(body)
+	msgs := make([]*messagev1.Message, 0, (len(body)+chunkSize-1)/chunkSize)
+	for offset := 0; offset < len(body); offset += chunkSize {
+		payload, err := json.Marshal(&Chunk{
+			UploadID: msg.GetMessageId(),
+			Offset:   int64(offset),
+			Total:    int64(len(body)),
+			Checksum: checksum,
+			Data:     body[offset:min(offset+chunkSize, len(body))],
+
+			MessageID:     msg.GetMessageId(),
+			Cmd:           msg.GetCmd(),
+			SerializeType: msg.GetSerializeType(),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal chunk: %w", err)
+		}
+
+		chunkMsg := &messagev1.Message{
+			MessageId:     fmt.Sprintf("%s:%d", msg.GetMessageId(), offset),
+			Cmd:           message.CommandTypeChunk,
+			SerializeType: commonv1.SerializeType_SERIALIZE_TYPE_JSON,
+			Body:          payload,
+		}
+		chunkMsg.ProtoReflect().SetUnknown(msg.ProtoReflect().GetUnknown())
+		message.SetConflationKey(chunkMsg, "")
+
+		msgs = append(msgs, chunkMsg)
+	}
+	return msgs, nil
+}
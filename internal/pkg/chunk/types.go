@@ -0,0 +1,50 @@
+// Package chunk 提供了大消息的分片传输协议。
+//
+// 单个 WebSocket 消息的长度受 synp.conn.manager.max_message_size 限制，
+// 更大的消息拆分为多个 message.CommandTypeChunk 消息传输，body 为 Chunk：
+//
+//  1. 上行：前端按偏移顺序发送分片，网关对每个分片回复 upstream ack ( message id 为分片消息的 message id )，
+//     收到所有分片并校验通过后还原为原消息，按普通消息去重并处理 ( 如转发到消息队列 )；
+//  2. 下行：body 超过阈值的消息由 PushFunc 拆分后依次发送，前端还原后按原消息的 message id 回复 ack。
+//
+// 分片按偏移顺序传输：重复的分片 ( 偏移小于已接收的长度 ) 直接确认，
+// 不连续的分片返回错误 ( 错误信息包含期望的偏移 )，前端从该偏移继续发送。
+package chunk
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+
+	commonv1 "github.com/jrmarcco/synp-api/api/go/common/v1"
+)
+
+var (
+	ErrInvalidChunk     = errors.New("invalid chunk")
+	ErrUnknownUpload    = errors.New("unknown upload")
+	ErrUnexpectedOffset = errors.New("unexpected chunk offset")
+	ErrPayloadTooLarge  = errors.New("chunked payload too large")
+	ErrTooManyUploads   = errors.New("too many concurrent uploads")
+	ErrChecksumMismatch = errors.New("chunk checksum mismatch")
+	ErrBufferExhausted  = errors.New("chunk upload buffer exhausted, retry later")
+)
+
+// Chunk 为分片消息 ( message.CommandTypeChunk ) 的 body。
+type Chunk struct {
+	UploadID string `json:"uploadId"` // 传输 ID，同一连接上唯一 ( 下行为原消息的 message id )
+	Offset   int64  `json:"offset"`   // 分片在原消息 body 中的偏移
+	Total    int64  `json:"total"`    // 原消息 body 的总长度
+	Checksum string `json:"checksum"` // 原消息 body 的 sha256 ( 十六进制 )
+	Data     []byte `json:"data"`     // 分片数据 ( json 编码为 base64 )
+
+	// 原消息的字段，还原时使用。
+	MessageID     string                 `json:"messageId"`
+	Cmd           commonv1.CommandType   `json:"cmd"`
+	SerializeType commonv1.SerializeType `json:"serializeType,omitempty"`
+}
+
+// Checksum 返回 payload 的校验和 ( sha256，十六进制 )。
+func Checksum(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
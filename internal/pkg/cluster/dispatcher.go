@@ -0,0 +1,129 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/jrmarcco/synp"
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/jrmarcco/synp/internal/pkg/message"
+	"github.com/jrmarcco/synp/internal/pkg/presence"
+	"github.com/jrmarcco/synp/internal/pkg/session"
+	"google.golang.org/protobuf/proto"
+)
+
+// Dispatcher 负责将消息直接投递给目标用户的所有连接。
+// 本节点上的连接直接投递，其他节点上的连接根据在线状态找到所在节点后通过 Relay 转发。
+//
+// 注：
+//
+//	Dispatcher 只负责投递，不做持久化和重传，适用于瞬时信号等允许丢失的消息。
+type Dispatcher struct {
+	nodeID string
+
+	connManager   synp.ConnManager
+	store         presence.Store
+	relay         Relay
+	broadcastFunc message.BroadcastFunc
+}
+
+// Dispatch 投递消息，返回本节点成功投递的连接数。
+func (d *Dispatcher) Dispatch(ctx context.Context, bid uint64, uids []uint64, msg *messagev1.Message) (int, error) {
+	delivered := d.deliverLocal(bid, uids, msg)
+
+	presences, err := d.store.BatchQuery(ctx, bid, uids)
+	if err != nil {
+		return delivered, fmt.Errorf("failed to locate users: %w", err)
+	}
+
+	// 按节点聚合目标用户。
+	nodes := make(map[string][]uint64)
+	for _, p := range presences {
+		for _, state := range p.Devices {
+			if state.NodeID == d.nodeID || slices.Contains(nodes[state.NodeID], p.UID) {
+				continue
+			}
+			nodes[state.NodeID] = append(nodes[state.NodeID], p.UID)
+		}
+	}
+	if len(nodes) == 0 {
+		return delivered, nil
+	}
+
+	payload, err := proto.Marshal(msg)
+	if err != nil {
+		return delivered, fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	for nodeID, nodeUIDs := range nodes {
+		if err = d.relay.Publish(ctx, nodeID, &Envelope{
+			Kind:    KindMessage,
+			From:    d.nodeID,
+			BID:     bid,
+			UIDs:    nodeUIDs,
+			Payload: payload,
+		}); err != nil {
+			slog.Warn(
+				"[synp-cluster-dispatcher] failed to relay message",
+				"node_id", nodeID,
+				"message_id", msg.GetMessageId(),
+				"error", err,
+			)
+		}
+	}
+	return delivered, nil
+}
+
+// handleMessage 处理其他节点转发的消息。
+func (d *Dispatcher) handleMessage(_ context.Context, env *Envelope) error {
+	msg := &messagev1.Message{}
+	if err := proto.Unmarshal(env.Payload, msg); err != nil {
+		return fmt.Errorf("failed to unmarshal message: %w", err)
+	}
+
+	d.deliverLocal(env.BID, env.UIDs, msg)
+	return nil
+}
+
+// deliverLocal 投递给目标用户在本节点上的所有连接，消息只编码一次。
+func (d *Dispatcher) deliverLocal(bid uint64, uids []uint64, msg *messagev1.Message) int {
+	var conns []synp.Conn
+	for _, uid := range uids {
+		if userConns, ok := d.connManager.FindUserConn(session.User{BID: bid, UID: uid}); ok {
+			conns = append(conns, userConns...)
+		}
+	}
+
+	delivered, err := d.broadcastFunc(conns, msg, nil)
+	if err != nil {
+		slog.Warn(
+			"[synp-cluster-dispatcher] failed to deliver message",
+			"message_id", msg.GetMessageId(),
+			"conns", len(conns),
+			"delivered", delivered,
+			"error", err,
+		)
+	}
+	return delivered
+}
+
+func NewDispatcher(
+	nodeID string,
+	connManager synp.ConnManager,
+	store presence.Store,
+	relay Relay,
+	broadcastFunc message.BroadcastFunc,
+) *Dispatcher {
+	d := &Dispatcher{
+		nodeID:        nodeID,
+		connManager:   connManager,
+		store:         store,
+		relay:         relay,
+		broadcastFunc: broadcastFunc,
+	}
+
+	relay.Register(KindMessage, d.handleMessage)
+	return d
+}
@@ -0,0 +1,125 @@
+package cluster_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jrmarcco/synp"
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/jrmarcco/synp/internal/pkg/cluster"
+	clustermock "github.com/jrmarcco/synp/internal/pkg/cluster/mock"
+	"github.com/jrmarcco/synp/internal/pkg/presence"
+	presencemock "github.com/jrmarcco/synp/internal/pkg/presence/mock"
+	"github.com/jrmarcco/synp/internal/pkg/session"
+	synpmock "github.com/jrmarcco/synp/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestDispatcher_Dispatch(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+
+	// uid 1 在本节点，uid 2 的两个设备都在 node-2，uid 3 在 node-3。
+	conn := synpmock.NewUserConn(ctrl, session.User{BID: 1, UID: 1, Device: session.DevicePC})
+	connManager := synpmock.NewMockConnManager(ctrl)
+	connManager.EXPECT().FindUserConn(gomock.Any()).DoAndReturn(func(user session.User) ([]synp.Conn, bool) {
+		if user.UID == 1 {
+			return []synp.Conn{conn}, true
+		}
+		return nil, false
+	}).AnyTimes()
+
+	store := presencemock.NewMockStore(ctrl)
+	store.EXPECT().BatchQuery(gomock.Any(), uint64(1), []uint64{1, 2, 3}).Return([]presence.Presence{
+		{BID: 1, UID: 1, Online: true, Devices: []presence.DeviceState{{Device: session.DevicePC, NodeID: "node-1"}}},
+		{BID: 1, UID: 2, Online: true, Devices: []presence.DeviceState{
+			{Device: session.DevicePC, NodeID: "node-2"},
+			{Device: session.DeviceMobile, NodeID: "node-2"},
+		}},
+		{BID: 1, UID: 3, Online: true, Devices: []presence.DeviceState{{Device: session.DevicePC, NodeID: "node-3"}}},
+	}, nil)
+
+	relay := clustermock.NewMockRelay(ctrl)
+	relay.EXPECT().Register(cluster.KindMessage, gomock.Any())
+
+	envelopes := make(map[string]*cluster.Envelope)
+	relay.EXPECT().Publish(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, nodeID string, env *cluster.Envelope) error {
+			envelopes[nodeID] = env
+			if nodeID == "node-3" {
+				return errors.New("relay unavailable")
+			}
+			return nil
+		},
+	).Times(2)
+
+	var delivered []synp.Conn
+	broadcastFunc := func(conns []synp.Conn, _ *messagev1.Message, _ []uint64) (int, error) {
+		delivered = append(delivered, conns...)
+		return len(conns), nil
+	}
+
+	d := cluster.NewDispatcher("node-1", connManager, store, relay, broadcastFunc)
+
+	// 转发失败不影响本节点的投递结果。
+	msg := &messagev1.Message{MessageId: "m1", Body: []byte("typing")}
+	n, err := d.Dispatch(t.Context(), 1, []uint64{1, 2, 3}, msg)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []synp.Conn{conn}, delivered)
+
+	// 每个节点只转发一次，同一用户的多个设备不重复。
+	require.Len(t, envelopes, 2)
+	env := envelopes["node-2"]
+	assert.Equal(t, cluster.KindMessage, env.Kind)
+	assert.Equal(t, "node-1", env.From)
+	assert.Equal(t, uint64(1), env.BID)
+	assert.Equal(t, []uint64{2}, env.UIDs)
+	assert.Equal(t, []uint64{3}, envelopes["node-3"].UIDs)
+
+	relayed := &messagev1.Message{}
+	require.NoError(t, proto.Unmarshal(env.Payload, relayed))
+	assert.True(t, proto.Equal(msg, relayed))
+}
+
+func TestDispatcher_HandleMessage(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+
+	conn := synpmock.NewUserConn(ctrl, session.User{BID: 1, UID: 2, Device: session.DevicePC})
+	connManager := synpmock.NewMockConnManager(ctrl)
+	connManager.EXPECT().FindUserConn(session.User{BID: 1, UID: 2}).Return([]synp.Conn{conn}, true)
+
+	var handle cluster.HandleFunc
+	relay := clustermock.NewMockRelay(ctrl)
+	relay.EXPECT().Register(cluster.KindMessage, gomock.Any()).Do(func(_ string, fn cluster.HandleFunc) {
+		handle = fn
+	})
+
+	var received []*messagev1.Message
+	broadcastFunc := func(conns []synp.Conn, msg *messagev1.Message, _ []uint64) (int, error) {
+		received = append(received, msg)
+		return len(conns), nil
+	}
+
+	cluster.NewDispatcher("node-2", connManager, presencemock.NewMockStore(ctrl), relay, broadcastFunc)
+	require.NotNil(t, handle)
+
+	// 其他节点转发的消息只投递给本节点的连接，不再查询在线状态及转发。
+	payload, err := proto.Marshal(&messagev1.Message{MessageId: "m1"})
+	require.NoError(t, err)
+	require.NoError(t, handle(t.Context(), &cluster.Envelope{
+		Kind: cluster.KindMessage, From: "node-1", BID: 1, UIDs: []uint64{2}, Payload: payload,
+	}))
+	require.Len(t, received, 1)
+	assert.Equal(t, "m1", received[0].GetMessageId())
+
+	// payload 格式错误。
+	assert.Error(t, handle(t.Context(), &cluster.Envelope{Kind: cluster.KindMessage, Payload: []byte("invalid")}))
+}
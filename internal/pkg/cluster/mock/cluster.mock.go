@@ -0,0 +1,231 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: types.go
+//
+// Generated by this command:
+//
+//	mockgen -source=types.go -destination=mock/cluster.mock.go -package=clustermock -typed Relay
+//
+
+// Package clustermock is a generated GoMock package.
+package clustermock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	cluster "github.com/jrmarcco/synp/internal/pkg/cluster"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockRelay is a mock of Relay interface.
+type MockRelay struct {
+	ctrl     *gomock.Controller
+	recorder *MockRelayMockRecorder
+	isgomock struct{}
+}
+
+// MockRelayMockRecorder is the mock recorder for MockRelay.
+type MockRelayMockRecorder struct {
+	mock *MockRelay
+}
+
+// NewMockRelay creates a new mock instance.
+func NewMockRelay(ctrl *gomock.Controller) *MockRelay {
+	mock := &MockRelay{ctrl: ctrl}
+	mock.recorder = &MockRelayMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRelay) EXPECT() *MockRelayMockRecorder {
+	return m.recorder
+}
+
+// Broadcast mocks base method.
+func (m *MockRelay) Broadcast(ctx context.Context, env *cluster.Envelope) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Broadcast", ctx, env)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Broadcast indicates an expected call of Broadcast.
+func (mr *MockRelayMockRecorder) Broadcast(ctx, env any) *MockRelayBroadcastCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Broadcast", reflect.TypeOf((*MockRelay)(nil).Broadcast), ctx, env)
+	return &MockRelayBroadcastCall{Call: call}
+}
+
+// MockRelayBroadcastCall wrap *gomock.Call
+type MockRelayBroadcastCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockRelayBroadcastCall) Return(arg0 int, arg1 error) *MockRelayBroadcastCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockRelayBroadcastCall) Do(f func(context.Context, *cluster.Envelope) (int, error)) *MockRelayBroadcastCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockRelayBroadcastCall) DoAndReturn(f func(context.Context, *cluster.Envelope) (int, error)) *MockRelayBroadcastCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// Close mocks base method.
+func (m *MockRelay) Close() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Close")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockRelayMockRecorder) Close() *MockRelayCloseCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockRelay)(nil).Close))
+	return &MockRelayCloseCall{Call: call}
+}
+
+// MockRelayCloseCall wrap *gomock.Call
+type MockRelayCloseCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockRelayCloseCall) Return(arg0 error) *MockRelayCloseCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockRelayCloseCall) Do(f func() error) *MockRelayCloseCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockRelayCloseCall) DoAndReturn(f func() error) *MockRelayCloseCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// Publish mocks base method.
+func (m *MockRelay) Publish(ctx context.Context, nodeID string, env *cluster.Envelope) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Publish", ctx, nodeID, env)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Publish indicates an expected call of Publish.
+func (mr *MockRelayMockRecorder) Publish(ctx, nodeID, env any) *MockRelayPublishCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Publish", reflect.TypeOf((*MockRelay)(nil).Publish), ctx, nodeID, env)
+	return &MockRelayPublishCall{Call: call}
+}
+
+// MockRelayPublishCall wrap *gomock.Call
+type MockRelayPublishCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockRelayPublishCall) Return(arg0 error) *MockRelayPublishCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockRelayPublishCall) Do(f func(context.Context, string, *cluster.Envelope) error) *MockRelayPublishCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockRelayPublishCall) DoAndReturn(f func(context.Context, string, *cluster.Envelope) error) *MockRelayPublishCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// Register mocks base method.
+func (m *MockRelay) Register(kind string, fn cluster.HandleFunc) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Register", kind, fn)
+}
+
+// Register indicates an expected call of Register.
+func (mr *MockRelayMockRecorder) Register(kind, fn any) *MockRelayRegisterCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Register", reflect.TypeOf((*MockRelay)(nil).Register), kind, fn)
+	return &MockRelayRegisterCall{Call: call}
+}
+
+// MockRelayRegisterCall wrap *gomock.Call
+type MockRelayRegisterCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockRelayRegisterCall) Return() *MockRelayRegisterCall {
+	c.Call = c.Call.Return()
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockRelayRegisterCall) Do(f func(string, cluster.HandleFunc)) *MockRelayRegisterCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockRelayRegisterCall) DoAndReturn(f func(string, cluster.HandleFunc)) *MockRelayRegisterCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// Start mocks base method.
+func (m *MockRelay) Start(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Start", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Start indicates an expected call of Start.
+func (mr *MockRelayMockRecorder) Start(ctx any) *MockRelayStartCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Start", reflect.TypeOf((*MockRelay)(nil).Start), ctx)
+	return &MockRelayStartCall{Call: call}
+}
+
+// MockRelayStartCall wrap *gomock.Call
+type MockRelayStartCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockRelayStartCall) Return(arg0 error) *MockRelayStartCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockRelayStartCall) Do(f func(context.Context) error) *MockRelayStartCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockRelayStartCall) DoAndReturn(f func(context.Context) error) *MockRelayStartCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
@@ -0,0 +1,131 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/jrmarcco/synp/internal/pkg/cluster"
+	"github.com/redis/go-redis/v9"
+)
+
+const defaultChannelPrefix = "synp:relay"
+
+var _ cluster.Relay = (*Relay)(nil)
+
+// Relay 为节点间转发的 Redis Pub/Sub 实现。
+// 每个节点订阅自己的 channel：{prefix}:{node_id}，以及所有节点共享的广播 channel：{prefix}@broadcast。
+//
+// 注：
+//
+//	Redis Pub/Sub 不保证送达 ( 节点不在线时消息直接丢弃 )，
+//	只适用于瞬时信号等允许丢失的消息。
+type Relay struct {
+	rdb    redis.UniversalClient
+	prefix string
+	nodeID string
+
+	handlers map[string]cluster.HandleFunc
+
+	pubsub    *redis.PubSub
+	closeOnce sync.Once
+}
+
+func (r *Relay) Publish(ctx context.Context, nodeID string, env *cluster.Envelope) error {
+	val, err := json.Marshal(env)
+	if err != nil {
+		return fmt.Errorf("failed to marshal envelope: %w", err)
+	}
+	return r.rdb.Publish(ctx, r.channel(nodeID), val).Err()
+}
+
+func (r *Relay) Broadcast(ctx context.Context, env *cluster.Envelope) (int, error) {
+	val, err := json.Marshal(env)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal envelope: %w", err)
+	}
+
+	receivers, err := r.rdb.Publish(ctx, r.broadcastChannel(), val).Result()
+	if err != nil {
+		return 0, err
+	}
+	return int(receivers), nil
+}
+
+func (r *Relay) Register(kind string, fn cluster.HandleFunc) {
+	r.handlers[kind] = fn
+}
+
+func (r *Relay) Start(ctx context.Context) error {
+	channels := []string{r.channel(r.nodeID), r.broadcastChannel()}
+	r.pubsub = r.rdb.Subscribe(ctx, channels...)
+
+	// 等待所有 channel 的订阅确认，确保启动后不会丢失消息。
+	for range channels {
+		if _, err := r.pubsub.Receive(ctx); err != nil {
+			return fmt.Errorf("failed to subscribe relay channel: %w", err)
+		}
+	}
+
+	go r.receive(ctx)
+
+	slog.Info("[synp-cluster-relay] relay started", "channel", r.channel(r.nodeID))
+	return nil
+}
+
+func (r *Relay) receive(ctx context.Context) {
+	for msg := range r.pubsub.Channel() {
+		env := &cluster.Envelope{}
+		if err := json.Unmarshal([]byte(msg.Payload), env); err != nil {
+			slog.Error("[synp-cluster-relay] failed to unmarshal envelope", "error", err)
+			continue
+		}
+
+		fn, ok := r.handlers[env.Kind]
+		if !ok {
+			slog.Warn("[synp-cluster-relay] unknown envelope kind", "kind", env.Kind, "from", env.From)
+			continue
+		}
+
+		if err := fn(ctx, env); err != nil {
+			slog.Error(
+				"[synp-cluster-relay] failed to handle envelope",
+				"kind", env.Kind,
+				"from", env.From,
+				"error", err,
+			)
+		}
+	}
+}
+
+func (r *Relay) channel(nodeID string) string {
+	return fmt.Sprintf("%s:%s", r.prefix, nodeID)
+}
+
+func (r *Relay) broadcastChannel() string {
+	return r.prefix + "@broadcast"
+}
+
+func (r *Relay) Close() error {
+	var err error
+	r.closeOnce.Do(func() {
+		if r.pubsub != nil {
+			err = r.pubsub.Close()
+		}
+	})
+	return err
+}
+
+func NewRelay(rdb redis.UniversalClient, nodeID, prefix string) *Relay {
+	if prefix == "" {
+		prefix = defaultChannelPrefix
+	}
+	return &Relay{
+		rdb:      rdb,
+		prefix:   prefix,
+		nodeID:   nodeID,
+		handlers: make(map[string]cluster.HandleFunc),
+	}
+}
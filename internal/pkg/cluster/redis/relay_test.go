@@ -0,0 +1,92 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/jrmarcco/synp/internal/pkg/cluster"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelay_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	mr := miniredis.RunT(t)
+	node1, received1 := newTestRelay(t, mr, "node-1")
+	_, received2 := newTestRelay(t, mr, "node-2")
+
+	// 只有目标节点收到，envelope 内容不变。
+	env := &cluster.Envelope{
+		Kind:    cluster.KindMessage,
+		From:    "node-1",
+		BID:     1,
+		UIDs:    []uint64{2, 3},
+		Payload: []byte{0x0a, 0x02, 'm', '1'},
+	}
+	require.NoError(t, node1.Publish(t.Context(), "node-2", env))
+	assert.Equal(t, env, receive(t, received2))
+	assert.Empty(t, received1)
+
+	// 未知类型的 envelope 被丢弃。
+	require.NoError(t, node1.Publish(t.Context(), "node-2", &cluster.Envelope{Kind: "unknown"}))
+
+	// 广播到所有节点 ( 包括本节点 )。
+	receivers, err := node1.Broadcast(t.Context(), &cluster.Envelope{Kind: cluster.KindMessage, From: "node-1", BID: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 2, receivers)
+	assert.Equal(t, "node-1", receive(t, received1).From)
+	assert.Equal(t, "node-1", receive(t, received2).From)
+	assert.Empty(t, received2)
+}
+
+func TestRelay_Prefix(t *testing.T) {
+	t.Parallel()
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	r := NewRelay(rdb, "node-1", "")
+	assert.Equal(t, "synp:relay:node-1", r.channel("node-1"))
+	assert.Equal(t, "synp:relay@broadcast", r.broadcastChannel())
+
+	r = NewRelay(rdb, "node-1", "gateway")
+	assert.Equal(t, "gateway:node-2", r.channel("node-2"))
+}
+
+// newTestRelay 创建并启动节点的 Relay，收到的 KindMessage envelope 写入返回的 channel。
+func newTestRelay(t *testing.T, mr *miniredis.Miniredis, nodeID string) (*Relay, chan *cluster.Envelope) {
+	t.Helper()
+
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	received := make(chan *cluster.Envelope, 10)
+
+	r := NewRelay(rdb, nodeID, "")
+	r.Register(cluster.KindMessage, func(_ context.Context, env *cluster.Envelope) error {
+		received <- env
+		return nil
+	})
+	require.NoError(t, r.Start(t.Context()))
+
+	t.Cleanup(func() {
+		_ = r.Close()
+		_ = rdb.Close()
+	})
+	return r, received
+}
+
+func receive(t *testing.T, received chan *cluster.Envelope) *cluster.Envelope {
+	t.Helper()
+
+	select {
+	case env := <-received:
+		return env
+	case <-time.After(time.Second):
+		require.FailNow(t, "envelope not received")
+		return nil
+	}
+}
@@ -0,0 +1,37 @@
+package cluster
+
+import "context"
+
+//go:generate mockgen -source=types.go -destination=mock/cluster.mock.go -package=clustermock -typed Relay
+
+// KindMessage 表示 envelope 中为需要直接投递给连接的 messagev1.Message。
+const KindMessage = "message"
+
+// Envelope 为网关节点之间转发的消息。
+type Envelope struct {
+	Kind string `json:"kind"`
+	From string `json:"from"` // 来源节点 ID
+
+	// 目标用户，目标节点会投递给这些用户在本节点上的所有连接。
+	BID  uint64   `json:"bid"`
+	UIDs []uint64 `json:"uids"`
+
+	Payload []byte `json:"payload"`
+}
+
+// HandleFunc 为 envelope 的处理函数。
+type HandleFunc func(ctx context.Context, env *Envelope) error
+
+// Relay 负责在网关节点之间转发消息。
+type Relay interface {
+	// Publish 将 envelope 转发到指定节点。
+	Publish(ctx context.Context, nodeID string, env *Envelope) error
+	// Broadcast 将 envelope 转发到所有节点 ( 包括本节点 )，返回收到 envelope 的节点数。
+	Broadcast(ctx context.Context, env *Envelope) (int, error)
+	// Register 注册指定类型 envelope 的处理函数，需要在 Start 之前调用。
+	Register(kind string, fn HandleFunc)
+
+	// Start 开始接收其他节点转发到本节点的 envelope。
+	Start(ctx context.Context) error
+	Close() error
+}
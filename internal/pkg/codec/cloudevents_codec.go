@@ -0,0 +1,136 @@
+package codec
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	cloudEventsSpecVersion = "1.0"
+	cloudEventsSource      = "synp"
+)
+
+var ErrInvalidCloudEvent = errors.New("invalid cloud event")
+
+var _ Codec = (*CloudEventsCodec)(nil)
+
+// cloudEvent 为 CloudEvents 1.0 structured mode ( json ) 的事件。
+// 扩展属性会被忽略。
+type cloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+	DataBase64      string          `json:"data_base64,omitempty"`
+}
+
+// CloudEventsCodec 是 CloudEvents structured mode ( application/cloudevents+json ) 的 Codec。
+// 事件的 data 按 datacontenttype 解码 ( 默认 application/json )，
+// 二进制格式 ( 如 application/protobuf ) 的 data 需要使用 data_base64 字段。
+type CloudEventsCodec struct {
+	lookup func(contentType string) (Codec, error)
+}
+
+func (c *CloudEventsCodec) Name() string {
+	return "cloudevents"
+}
+
+// Marshal 将消息编码为 json data 的 CloudEvents 事件。
+// 事件 id 优先使用消息的 message_id，type 为消息的 proto 全名。
+func (c *CloudEventsCodec) Marshal(val any) ([]byte, error) {
+	protoMsg, ok := val.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("failed to marshal message: invalid message type, expected proto.Message, got %T", val)
+	}
+
+	dataCodec, err := c.lookup(ContentTypeJSON)
+	if err != nil {
+		return nil, err
+	}
+	data, err := dataCodec.Marshal(protoMsg)
+	if err != nil {
+		return nil, err
+	}
+
+	id := ""
+	if m, ok := val.(interface{ GetMessageId() string }); ok {
+		id = m.GetMessageId()
+	}
+	if id == "" {
+		id = randomID()
+	}
+
+	return json.Marshal(&cloudEvent{
+		SpecVersion:     cloudEventsSpecVersion,
+		ID:              id,
+		Source:          cloudEventsSource,
+		Type:            string(protoMsg.ProtoReflect().Descriptor().FullName()),
+		DataContentType: ContentTypeJSON,
+		Data:            data,
+	})
+}
+
+func (c *CloudEventsCodec) MarshalAppend(b []byte, val any) ([]byte, error) {
+	data, err := c.Marshal(val)
+	if err != nil {
+		return nil, err
+	}
+	return append(b, data...), nil
+}
+
+func (c *CloudEventsCodec) Unmarshal(data []byte, val any) error {
+	event := &cloudEvent{}
+	if err := json.Unmarshal(data, event); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidCloudEvent, err)
+	}
+	if event.SpecVersion != cloudEventsSpecVersion {
+		return fmt.Errorf("%w: unsupported specversion %q", ErrInvalidCloudEvent, event.SpecVersion)
+	}
+
+	contentType := event.DataContentType
+	if contentType == "" {
+		contentType = ContentTypeJSON
+	}
+	if normalizeContentType(contentType) == ContentTypeCloudEventsJSON {
+		return fmt.Errorf("%w: nested cloud event", ErrInvalidCloudEvent)
+	}
+
+	dataCodec, err := c.lookup(contentType)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case event.DataBase64 != "":
+		payload, err := base64.StdEncoding.DecodeString(event.DataBase64)
+		if err != nil {
+			return fmt.Errorf("%w: invalid data_base64: %w", ErrInvalidCloudEvent, err)
+		}
+		return dataCodec.Unmarshal(payload, val)
+	case len(event.Data) != 0:
+		return dataCodec.Unmarshal(event.Data, val)
+	default:
+		return fmt.Errorf("%w: empty data", ErrInvalidCloudEvent)
+	}
+}
+
+func randomID() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// NewCloudEventsCodec 创建 CloudEvents Codec，lookup 用于按 datacontenttype 选择 data 的 Codec。
+func NewCloudEventsCodec(lookup func(contentType string) (Codec, error)) *CloudEventsCodec {
+	return &CloudEventsCodec{
+		lookup: lookup,
+	}
+}
@@ -1,5 +1,10 @@
 package codec
 
+import (
+	"github.com/jrmarcco/synp/internal/pkg/bufpool"
+	"google.golang.org/protobuf/proto"
+)
+
 //go:generate mockgen -source=codec.go -destination=mock/codec.mock.go -package=codecmock -typed Codec
 
 // Codec 是消息编码/解码接口。
@@ -10,5 +15,34 @@ package codec
 type Codec interface {
 	Name() string
 	Marshal(val any) ([]byte, error)
+	// MarshalAppend 将消息编码后追加到 b，返回追加后的切片，用于编码到池化的缓冲区。
+	MarshalAppend(b []byte, val any) ([]byte, error)
 	Unmarshal(data []byte, val any) error
 }
+
+// UnknownAppender 为可以在编码结果后直接追加未知字段的 Codec。
+//
+// 消息最后追加未知字段 raw 后的编码结果，等于原消息的编码结果再追加 AppendUnknown 返回的字节，
+// 同一条消息发送到多个连接时只需要编码一次，连接相关的扩展字段 ( 如序号 ) 作为后缀单独发送。
+type UnknownAppender interface {
+	AppendUnknown(b []byte, raw []byte) []byte
+}
+
+// MarshalBuffer 将消息编码到池化的缓冲区，调用方使用完成后需要调用 Buffer.Release。
+// 缓冲区按消息的 protobuf 编码长度预留容量，json 等更长的编码由 MarshalAppend 扩容。
+func MarshalBuffer(c Codec, val any) (*bufpool.Buffer, error) {
+	var size int
+	if protoMsg, ok := val.(proto.Message); ok {
+		size = proto.Size(protoMsg)
+	}
+	buf := bufpool.Get(size)
+
+	b, err := c.MarshalAppend(buf.B, val)
+	if err != nil {
+		buf.Release()
+		return nil, err
+	}
+
+	buf.B = b
+	return buf, nil
+}
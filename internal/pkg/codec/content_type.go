@@ -0,0 +1,81 @@
+package codec
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+)
+
+// 后端 ( 业务服务端 ) 发送消息时可以使用的 content-type。
+const (
+	ContentTypeJSON            = "application/json"
+	ContentTypeProtobuf        = "application/protobuf"
+	ContentTypeXProtobuf       = "application/x-protobuf"
+	ContentTypeCloudEventsJSON = "application/cloudevents+json"
+)
+
+var ErrUnsupportedContentType = errors.New("unsupported content type")
+
+// Registry 按 content-type 选择 Codec。
+// 注:
+//
+//	Codec 用于前端与网关之间的消息，Registry 则用于解码后端通过消息队列发送的消息，
+//	后端在消息的 content-type header 中声明消息体的编码格式。
+type Registry struct {
+	codecs             map[string]Codec
+	defaultContentType string
+}
+
+// Register 注册 content-type 对应的 Codec，已存在时覆盖。
+func (r *Registry) Register(contentType string, codec Codec) {
+	r.codecs[normalizeContentType(contentType)] = codec
+}
+
+// Lookup 返回 content-type 对应的 Codec，contentType 为空时使用默认 content-type。
+// content-type 的参数 ( 如 charset ) 会被忽略。
+func (r *Registry) Lookup(contentType string) (Codec, error) {
+	if strings.TrimSpace(contentType) == "" {
+		contentType = r.defaultContentType
+	}
+
+	codec, ok := r.codecs[normalizeContentType(contentType)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedContentType, contentType)
+	}
+	return codec, nil
+}
+
+func normalizeContentType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(contentType))
+	}
+	return mediaType
+}
+
+// NewRegistry 创建空的 Registry，消息没有 content-type 时使用 defaultContentType。
+func NewRegistry(defaultContentType string) *Registry {
+	return &Registry{
+		codecs:             make(map[string]Codec),
+		defaultContentType: defaultContentType,
+	}
+}
+
+// NewDefaultRegistry 创建支持 protojson、protobuf 及 CloudEvents ( structured mode ) 的 Registry。
+// 消息没有 content-type 时按 protojson 解码，兼容原有的 json 格式。
+//
+// CloudEvents binary mode 不需要额外处理：
+// 事件属性在 ce_* header 中，content-type header 即为消息体的编码格式。
+func NewDefaultRegistry() *Registry {
+	r := NewRegistry(ContentTypeJSON)
+
+	jsonCodec := NewJSONCodec(JSONCodecWithDiscardUnknown())
+	protoCodec := NewProtoCodec()
+
+	r.Register(ContentTypeJSON, jsonCodec)
+	r.Register(ContentTypeProtobuf, protoCodec)
+	r.Register(ContentTypeXProtobuf, protoCodec)
+	r.Register(ContentTypeCloudEventsJSON, NewCloudEventsCodec(r.Lookup))
+	return r
+}
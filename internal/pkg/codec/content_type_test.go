@@ -0,0 +1,148 @@
+package codec
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestRegistry_Lookup(t *testing.T) {
+	t.Parallel()
+
+	r := NewDefaultRegistry()
+
+	tcs := []struct {
+		name        string
+		contentType string
+		wantName    string
+		wantErr     error
+	}{
+		{name: "default", contentType: "", wantName: "json"},
+		{name: "json with charset", contentType: "application/json; charset=utf-8", wantName: "json"},
+		{name: "protobuf", contentType: "application/protobuf", wantName: "proto"},
+		{name: "x-protobuf upper case", contentType: "Application/X-Protobuf", wantName: "proto"},
+		{name: "cloudevents", contentType: "application/cloudevents+json", wantName: "cloudevents"},
+		{name: "unsupported", contentType: "text/plain", wantErr: ErrUnsupportedContentType},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			c, err := r.Lookup(tc.contentType)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantName, c.Name())
+		})
+	}
+}
+
+func TestRegistry_DecodePushMessage(t *testing.T) {
+	t.Parallel()
+
+	r := NewDefaultRegistry()
+	expected := &messagev1.PushMessage{
+		MessageId:  "test_message_id",
+		BizId:      1,
+		ReceiverId: 2,
+		Body:       []byte("hello"),
+	}
+
+	// 兼容使用 encoding/json 编码的消息，并忽略未知字段。
+	legacy, err := json.Marshal(expected)
+	require.NoError(t, err)
+	var fields map[string]any
+	require.NoError(t, json.Unmarshal(legacy, &fields))
+	fields["unknown_field"] = "x"
+	legacy, err = json.Marshal(fields)
+	require.NoError(t, err)
+
+	protoBytes, err := proto.Marshal(expected)
+	require.NoError(t, err)
+
+	jsonBytes, err := NewJSONCodec().Marshal(expected)
+	require.NoError(t, err)
+
+	structured := fmt.Sprintf(
+		`{"specversion":"1.0","id":"1","source":"biz","type":"push","data":%s}`,
+		jsonBytes,
+	)
+	structuredBase64 := fmt.Sprintf(
+		`{"specversion":"1.0","id":"1","source":"biz","type":"push","datacontenttype":"application/protobuf","data_base64":%q}`,
+		base64.StdEncoding.EncodeToString(protoBytes),
+	)
+
+	tcs := []struct {
+		name        string
+		contentType string
+		payload     []byte
+		wantErr     bool
+	}{
+		{name: "legacy json", contentType: "", payload: legacy},
+		{name: "protojson", contentType: ContentTypeJSON, payload: jsonBytes},
+		{name: "protobuf", contentType: ContentTypeProtobuf, payload: protoBytes},
+		{name: "cloudevents json data", contentType: ContentTypeCloudEventsJSON, payload: []byte(structured)},
+		{name: "cloudevents base64 data", contentType: ContentTypeCloudEventsJSON, payload: []byte(structuredBase64)},
+		{
+			name:        "cloudevents invalid specversion",
+			contentType: ContentTypeCloudEventsJSON,
+			payload:     []byte(`{"specversion":"0.3","data":{}}`),
+			wantErr:     true,
+		},
+		{
+			name:        "cloudevents empty data",
+			contentType: ContentTypeCloudEventsJSON,
+			payload:     []byte(`{"specversion":"1.0","id":"1","source":"biz","type":"push"}`),
+			wantErr:     true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			c, err := r.Lookup(tc.contentType)
+			require.NoError(t, err)
+
+			actual := &messagev1.PushMessage{}
+			err = c.Unmarshal(tc.payload, actual)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, proto.Equal(expected, actual))
+		})
+	}
+}
+
+func TestCloudEventsCodec(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewDefaultRegistry().Lookup(ContentTypeCloudEventsJSON)
+	require.NoError(t, err)
+	assert.Equal(t, "cloudevents", c.Name())
+
+	expected := &messagev1.PushMessage{MessageId: "test_message_id", BizId: 1, ReceiverId: 2}
+	payload, err := c.Marshal(expected)
+	require.NoError(t, err)
+
+	event := map[string]any{}
+	require.NoError(t, json.Unmarshal(payload, &event))
+	assert.Equal(t, "1.0", event["specversion"])
+	assert.Equal(t, "test_message_id", event["id"])
+	assert.Equal(t, string(expected.ProtoReflect().Descriptor().FullName()), event["type"])
+
+	actual := &messagev1.PushMessage{}
+	require.NoError(t, c.Unmarshal(payload, actual))
+	assert.True(t, proto.Equal(expected, actual))
+}
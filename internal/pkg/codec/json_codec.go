@@ -1,15 +1,29 @@
 package codec
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"strconv"
 
+	"github.com/jrmarcco/jit/bean/option"
 	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
 )
 
 var _ Codec = (*JSONCodec)(nil)
 
-type JSONCodec struct{}
+// JSONCodec 使用 protojson 编码/解码消息。
+//
+// protojson 不会编码未知字段，网关扩展的字段 ( 如消息序号 ) 需要通过 JSONCodecWithVarintField 映射为 json 字段，
+// 所以 JSONCodec 没有实现 UnknownAppender：追加的字段必须写在 json 对象内部，无法作为后缀单独发送。
+type JSONCodec struct {
+	unmarshalOpts protojson.UnmarshalOptions
+
+	// varintFields 为映射为 json 字段的未知 varint 字段 ( 字段编号 -> json 字段名 )。
+	varintFields map[protowire.Number]string
+}
 
 func (c *JSONCodec) Name() string {
 	return "json"
@@ -20,7 +34,61 @@ func (c *JSONCodec) Marshal(val any) ([]byte, error) {
 	if !ok {
 		return nil, fmt.Errorf("failed to marshal message: invalid message type, expected proto.Message, got %T", val)
 	}
-	return protojson.Marshal(protoMsg)
+	return c.MarshalAppend(nil, protoMsg)
+}
+
+func (c *JSONCodec) MarshalAppend(b []byte, val any) ([]byte, error) {
+	protoMsg, ok := val.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("failed to marshal message: invalid message type, expected proto.Message, got %T", val)
+	}
+
+	start := len(b)
+	b, err := protojson.MarshalOptions{}.MarshalAppend(b, protoMsg)
+	if err != nil || len(c.varintFields) == 0 {
+		return b, err
+	}
+	return c.appendVarintFields(b, start, protoMsg), nil
+}
+
+// appendVarintFields 将消息中需要映射的未知字段写入 b[start:] 中 json 对象的末尾。
+func (c *JSONCodec) appendVarintFields(b []byte, start int, msg proto.Message) []byte {
+	var fields []byte
+	unknown := msg.ProtoReflect().GetUnknown()
+	for len(unknown) > 0 {
+		num, typ, n := protowire.ConsumeTag(unknown)
+		if n < 0 {
+			break
+		}
+		m := protowire.ConsumeFieldValue(num, typ, unknown[n:])
+		if m < 0 {
+			break
+		}
+
+		if name, ok := c.varintFields[num]; ok && typ == protowire.VarintType {
+			v, _ := protowire.ConsumeVarint(unknown[n:])
+			fields = append(fields, ',')
+			fields = strconv.AppendQuote(fields, name)
+			fields = append(fields, ':')
+			fields = strconv.AppendUint(fields, v, 10)
+		}
+		unknown = unknown[n+m:]
+	}
+	if len(fields) == 0 {
+		return b
+	}
+
+	end := start + bytes.LastIndexByte(b[start:], '}')
+	if end < start {
+		return b
+	}
+	// 空对象不需要分隔的逗号。
+	if len(bytes.TrimSpace(b[start+1:end])) == 0 {
+		fields = fields[1:]
+	}
+
+	b = append(b[:end], fields...)
+	return append(b, '}')
 }
 
 func (c *JSONCodec) Unmarshal(data []byte, val any) error {
@@ -28,9 +96,79 @@ func (c *JSONCodec) Unmarshal(data []byte, val any) error {
 	if !ok {
 		return fmt.Errorf("failed to unmarshal message: invalid message type, expected proto.Message, got %T", val)
 	}
-	return protojson.Unmarshal(data, protoMsg)
+
+	err := c.unmarshalOpts.Unmarshal(data, protoMsg)
+	if err == nil || len(c.varintFields) == 0 {
+		return err
+	}
+
+	// 可能携带映射的字段，还原为未知字段后重新解码。
+	if unknown, rest, ok := c.extractVarintFields(data); ok {
+		if err = c.unmarshalOpts.Unmarshal(rest, protoMsg); err != nil {
+			return err
+		}
+		ref := protoMsg.ProtoReflect()
+		ref.SetUnknown(append(ref.GetUnknown(), unknown...))
+		return nil
+	}
+	return err
+}
+
+// extractVarintFields 从 json 对象中移除映射的字段，返回字段的原始编码 ( 包括 tag ) 以及剩余的 json 对象。
+// 没有映射的字段或字段值不是非负整数时返回 false。
+func (c *JSONCodec) extractVarintFields(data []byte) ([]byte, []byte, bool) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, nil, false
+	}
+
+	var unknown []byte
+	for num, name := range c.varintFields {
+		raw, ok := obj[name]
+		if !ok {
+			continue
+		}
+		v, err := strconv.ParseUint(string(raw), 10, 64)
+		if err != nil {
+			return nil, nil, false
+		}
+		delete(obj, name)
+
+		unknown = protowire.AppendTag(unknown, num, protowire.VarintType)
+		unknown = protowire.AppendVarint(unknown, v)
+	}
+	if len(unknown) == 0 {
+		return nil, nil, false
+	}
+
+	rest, err := json.Marshal(obj)
+	if err != nil {
+		return nil, nil, false
+	}
+	return unknown, rest, true
+}
+
+// JSONCodecWithDiscardUnknown 解码时忽略未知字段。
+// 适用于解码后端 ( 业务服务端 ) 发送的消息，后端使用更新版本的协议时不会解码失败。
+func JSONCodecWithDiscardUnknown() option.Opt[JSONCodec] {
+	return func(c *JSONCodec) {
+		c.unmarshalOpts.DiscardUnknown = true
+	}
+}
+
+// JSONCodecWithVarintField 将编号为 num 的未知 varint 字段映射为名为 name 的 json 字段 ( 数字 )，解码时还原为未知字段。
+// name 不能与消息已有的 json 字段名重复。
+func JSONCodecWithVarintField(num protowire.Number, name string) option.Opt[JSONCodec] {
+	return func(c *JSONCodec) {
+		if c.varintFields == nil {
+			c.varintFields = make(map[protowire.Number]string)
+		}
+		c.varintFields[num] = name
+	}
 }
 
-func NewJSONCodec() *JSONCodec {
-	return &JSONCodec{}
+func NewJSONCodec(opts ...option.Opt[JSONCodec]) *JSONCodec {
+	c := &JSONCodec{}
+	option.Apply(c, opts...)
+	return c
 }
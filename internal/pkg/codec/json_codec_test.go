@@ -3,8 +3,11 @@ package codec
 import (
 	"testing"
 
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	"google.golang.org/protobuf/encoding/protowire"
 )
 
 func TestJsonCodec(t *testing.T) {
@@ -15,3 +18,51 @@ func TestJsonCodec(t *testing.T) {
 
 	suite.Run(t, &CodecSuite{codec: jsonCodec})
 }
+
+func TestJsonCodec_VarintField(t *testing.T) {
+	t.Parallel()
+
+	jsonCodec := NewJSONCodec(JSONCodecWithVarintField(103, "seq"))
+	suite.Run(t, &CodecSuite{codec: jsonCodec})
+
+	tcs := []struct {
+		name string
+		msg  *messagev1.Message
+		want string
+	}{
+		{
+			name: "message",
+			msg:  &messagev1.Message{MessageId: "m1"},
+			want: `{"messageId":"m1","seq":7}`,
+		}, {
+			name: "empty message",
+			msg:  &messagev1.Message{},
+			want: `{"seq":7}`,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			seq := protowire.AppendTag(nil, 103, protowire.VarintType)
+			seq = protowire.AppendVarint(seq, 7)
+			// 映射的字段之外的未知字段直接忽略。
+			raw := protowire.AppendTag(nil, 100, protowire.VarintType)
+			raw = protowire.AppendVarint(raw, 1)
+			tc.msg.ProtoReflect().SetUnknown(append(raw, seq...))
+
+			payload, err := jsonCodec.Marshal(tc.msg)
+			require.NoError(t, err)
+			assert.JSONEq(t, tc.want, string(payload))
+
+			decoded := &messagev1.Message{}
+			require.NoError(t, jsonCodec.Unmarshal(payload, decoded))
+			assert.Equal(t, tc.msg.GetMessageId(), decoded.GetMessageId())
+			assert.Equal(t, seq, []byte(decoded.ProtoReflect().GetUnknown()))
+		})
+	}
+
+	// 字段值不是整数时解码失败。
+	require.Error(t, jsonCodec.Unmarshal([]byte(`{"messageId":"m1","seq":"x"}`), &messagev1.Message{}))
+}
@@ -0,0 +1,256 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: codec.go
+//
+// Generated by this command:
+//
+//	mockgen -source=codec.go -destination=mock/codec.mock.go -package=codecmock -typed Codec
+//
+
+// Package codecmock is a generated GoMock package.
+package codecmock
+
+import (
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockCodec is a mock of Codec interface.
+type MockCodec struct {
+	ctrl     *gomock.Controller
+	recorder *MockCodecMockRecorder
+	isgomock struct{}
+}
+
+// MockCodecMockRecorder is the mock recorder for MockCodec.
+type MockCodecMockRecorder struct {
+	mock *MockCodec
+}
+
+// NewMockCodec creates a new mock instance.
+func NewMockCodec(ctrl *gomock.Controller) *MockCodec {
+	mock := &MockCodec{ctrl: ctrl}
+	mock.recorder = &MockCodecMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCodec) EXPECT() *MockCodecMockRecorder {
+	return m.recorder
+}
+
+// Marshal mocks base method.
+func (m *MockCodec) Marshal(val any) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Marshal", val)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Marshal indicates an expected call of Marshal.
+func (mr *MockCodecMockRecorder) Marshal(val any) *MockCodecMarshalCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Marshal", reflect.TypeOf((*MockCodec)(nil).Marshal), val)
+	return &MockCodecMarshalCall{Call: call}
+}
+
+// MockCodecMarshalCall wrap *gomock.Call
+type MockCodecMarshalCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockCodecMarshalCall) Return(arg0 []byte, arg1 error) *MockCodecMarshalCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockCodecMarshalCall) Do(f func(any) ([]byte, error)) *MockCodecMarshalCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockCodecMarshalCall) DoAndReturn(f func(any) ([]byte, error)) *MockCodecMarshalCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// MarshalAppend mocks base method.
+func (m *MockCodec) MarshalAppend(b []byte, val any) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarshalAppend", b, val)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarshalAppend indicates an expected call of MarshalAppend.
+func (mr *MockCodecMockRecorder) MarshalAppend(b, val any) *MockCodecMarshalAppendCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarshalAppend", reflect.TypeOf((*MockCodec)(nil).MarshalAppend), b, val)
+	return &MockCodecMarshalAppendCall{Call: call}
+}
+
+// MockCodecMarshalAppendCall wrap *gomock.Call
+type MockCodecMarshalAppendCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockCodecMarshalAppendCall) Return(arg0 []byte, arg1 error) *MockCodecMarshalAppendCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockCodecMarshalAppendCall) Do(f func([]byte, any) ([]byte, error)) *MockCodecMarshalAppendCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockCodecMarshalAppendCall) DoAndReturn(f func([]byte, any) ([]byte, error)) *MockCodecMarshalAppendCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// Name mocks base method.
+func (m *MockCodec) Name() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Name")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// Name indicates an expected call of Name.
+func (mr *MockCodecMockRecorder) Name() *MockCodecNameCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Name", reflect.TypeOf((*MockCodec)(nil).Name))
+	return &MockCodecNameCall{Call: call}
+}
+
+// MockCodecNameCall wrap *gomock.Call
+type MockCodecNameCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockCodecNameCall) Return(arg0 string) *MockCodecNameCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockCodecNameCall) Do(f func() string) *MockCodecNameCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockCodecNameCall) DoAndReturn(f func() string) *MockCodecNameCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// Unmarshal mocks base method.
+func (m *MockCodec) Unmarshal(data []byte, val any) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unmarshal", data, val)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unmarshal indicates an expected call of Unmarshal.
+func (mr *MockCodecMockRecorder) Unmarshal(data, val any) *MockCodecUnmarshalCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unmarshal", reflect.TypeOf((*MockCodec)(nil).Unmarshal), data, val)
+	return &MockCodecUnmarshalCall{Call: call}
+}
+
+// MockCodecUnmarshalCall wrap *gomock.Call
+type MockCodecUnmarshalCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockCodecUnmarshalCall) Return(arg0 error) *MockCodecUnmarshalCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockCodecUnmarshalCall) Do(f func([]byte, any) error) *MockCodecUnmarshalCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockCodecUnmarshalCall) DoAndReturn(f func([]byte, any) error) *MockCodecUnmarshalCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// MockUnknownAppender is a mock of UnknownAppender interface.
+type MockUnknownAppender struct {
+	ctrl     *gomock.Controller
+	recorder *MockUnknownAppenderMockRecorder
+	isgomock struct{}
+}
+
+// MockUnknownAppenderMockRecorder is the mock recorder for MockUnknownAppender.
+type MockUnknownAppenderMockRecorder struct {
+	mock *MockUnknownAppender
+}
+
+// NewMockUnknownAppender creates a new mock instance.
+func NewMockUnknownAppender(ctrl *gomock.Controller) *MockUnknownAppender {
+	mock := &MockUnknownAppender{ctrl: ctrl}
+	mock.recorder = &MockUnknownAppenderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUnknownAppender) EXPECT() *MockUnknownAppenderMockRecorder {
+	return m.recorder
+}
+
+// AppendUnknown mocks base method.
+func (m *MockUnknownAppender) AppendUnknown(b, raw []byte) []byte {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AppendUnknown", b, raw)
+	ret0, _ := ret[0].([]byte)
+	return ret0
+}
+
+// AppendUnknown indicates an expected call of AppendUnknown.
+func (mr *MockUnknownAppenderMockRecorder) AppendUnknown(b, raw any) *MockUnknownAppenderAppendUnknownCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AppendUnknown", reflect.TypeOf((*MockUnknownAppender)(nil).AppendUnknown), b, raw)
+	return &MockUnknownAppenderAppendUnknownCall{Call: call}
+}
+
+// MockUnknownAppenderAppendUnknownCall wrap *gomock.Call
+type MockUnknownAppenderAppendUnknownCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockUnknownAppenderAppendUnknownCall) Return(arg0 []byte) *MockUnknownAppenderAppendUnknownCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockUnknownAppenderAppendUnknownCall) Do(f func([]byte, []byte) []byte) *MockUnknownAppenderAppendUnknownCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockUnknownAppenderAppendUnknownCall) DoAndReturn(f func([]byte, []byte) []byte) *MockUnknownAppenderAppendUnknownCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
@@ -6,7 +6,10 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
-var _ Codec = (*ProtoCodec)(nil)
+var (
+	_ Codec           = (*ProtoCodec)(nil)
+	_ UnknownAppender = (*ProtoCodec)(nil)
+)
 
 type ProtoCodec struct{}
 
@@ -22,6 +25,19 @@ func (c *ProtoCodec) Marshal(val any) ([]byte, error) {
 	return proto.Marshal(protoMsg)
 }
 
+func (c *ProtoCodec) MarshalAppend(b []byte, val any) ([]byte, error) {
+	protoMsg, ok := val.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("failed to marshal message: invalid message type, expected proto.Message, got %T", val)
+	}
+	return proto.MarshalOptions{}.MarshalAppend(b, protoMsg)
+}
+
+// AppendUnknown 直接追加未知字段，protobuf 将未知字段按原样编码在已知字段之后。
+func (c *ProtoCodec) AppendUnknown(b []byte, raw []byte) []byte {
+	return append(b, raw...)
+}
+
 func (c *ProtoCodec) Unmarshal(data []byte, val any) error {
 	protoMsg, ok := val.(proto.Message)
 	if !ok {
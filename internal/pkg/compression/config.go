@@ -9,7 +9,14 @@ type Config struct {
 	ServerNoContextTakeover bool
 	ClientMaxWindowBits     int
 	ClientNoContextTakeover bool
-	Level                   int
+	Level                   int // flate 压缩级别 ( -2 - 9 )
+
+	// 长度小于 MinSize 的消息不压缩 ( 压缩小消息的开销通常大于节省的流量 )。
+	MinSize int
+
+	// 启用压缩且不为 nil 时可以与客户端协商 zstd 共享字典扩展 ( 见 ZstdExtensionName )，
+	// 客户端同时请求两种扩展时按客户端的顺序只启用其中一个。
+	Zstd *Zstd
 }
 
 // ToParamters 将 Config 转换为 wsflate 参数。
@@ -28,4 +35,15 @@ type State struct {
 
 	Ext    *wsflate.Extension
 	Params wsflate.Parameters
+
+	Level   int
+	MinSize int
+
+	// 协商了 zstd 共享字典扩展时不为 nil，此时压缩的消息使用 zstd 而不是 flate。
+	Zstd *Zstd `json:"-"`
+}
+
+// Compress 返回长度为 size 的消息是否需要压缩，空消息不压缩。
+func (s *State) Compress(size int) bool {
+	return s != nil && s.Enabled && size > 0 && size >= s.MinSize
 }
@@ -0,0 +1,123 @@
+package compression
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gobwas/httphead"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// ZstdExtensionName 为 zstd 共享字典压缩扩展的名称。
+	//
+	// 客户端通过 Sec-WebSocket-Extensions: x-synp-zstd; dict=<字典 ID> 请求使用扩展，
+	// 字典 ID 与服务端的字典一致时服务端原样返回该扩展。
+	// 启用后压缩的消息设置 RSV1 标记 ( 与 permessage-deflate 相同，所以两者只能启用一个 )，
+	// 消息内容为使用共享字典压缩的单个 zstd 帧 ( 需要包含解压后的长度 )。
+	ZstdExtensionName = "x-synp-zstd"
+
+	zstdParamDict = "dict"
+)
+
+// ErrZstdContentSize 表示 zstd 帧没有包含解压后的长度。
+var ErrZstdContentSize = errors.New("zstd frame content size is unknown")
+
+// Zstd 为使用共享字典的 zstd 编解码器，并发安全，所有连接共享同一个实例。
+type Zstd struct {
+	dictID uint32
+
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+// DictID 返回共享字典的 ID。
+func (z *Zstd) DictID() uint32 {
+	return z.dictID
+}
+
+// Encode 将 src 压缩后追加到 dst。
+func (z *Zstd) Encode(dst, src []byte) []byte {
+	return z.encoder.EncodeAll(src, dst)
+}
+
+// ContentSize 返回 zstd 帧解压后的长度。
+func (z *Zstd) ContentSize(src []byte) (uint64, error) {
+	var header zstd.Header
+	if err := header.Decode(src); err != nil {
+		return 0, err
+	}
+	if !header.HasFCS {
+		return 0, ErrZstdContentSize
+	}
+	return header.FrameContentSize, nil
+}
+
+// Decode 将 src 解压后追加到 dst，调用方需要先通过 ContentSize 检查解压后的长度。
+func (z *Zstd) Decode(dst, src []byte) ([]byte, error) {
+	return z.decoder.DecodeAll(src, dst)
+}
+
+// Negotiate 协商 zstd 共享字典扩展，客户端请求的字典 ID 与服务端一致时返回 true 及响应的扩展。
+func (z *Zstd) Negotiate(opt httphead.Option) (httphead.Option, bool) {
+	if string(opt.Name) != ZstdExtensionName {
+		return httphead.Option{}, false
+	}
+
+	val, ok := opt.Parameters.Get(zstdParamDict)
+	if !ok {
+		return httphead.Option{}, false
+	}
+	id, err := strconv.ParseUint(string(val), 10, 32)
+	if err != nil || uint32(id) != z.dictID {
+		return httphead.Option{}, false
+	}
+
+	return httphead.NewOption(ZstdExtensionName, map[string]string{
+		zstdParamDict: strconv.FormatUint(id, 10),
+	}), true
+}
+
+// Close 释放编解码器占用的资源。
+func (z *Zstd) Close() error {
+	z.decoder.Close()
+	return z.encoder.Close()
+}
+
+// NewZstd 使用训练好的字典 ( 如 zstd --train 生成的字典文件 ) 创建编解码器。
+// level 为 zstd 的压缩级别 ( 1 - 22 )，maxDecodedSize 为解压后的最大长度。
+func NewZstd(dict []byte, level int, maxDecodedSize int64) (*Zstd, error) {
+	d, err := zstd.InspectDictionary(dict)
+	if err != nil {
+		return nil, fmt.Errorf("invalid zstd dictionary: %w", err)
+	}
+
+	encoder, err := zstd.NewWriter(
+		nil,
+		zstd.WithEncoderDict(dict),
+		zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []zstd.DOption{
+		zstd.WithDecoderDicts(dict),
+		zstd.WithDecoderConcurrency(0),
+	}
+	if maxDecodedSize > 0 {
+		opts = append(opts, zstd.WithDecoderMaxMemory(uint64(maxDecodedSize)))
+	}
+	decoder, err := zstd.NewReader(nil, opts...)
+	if err != nil {
+		_ = encoder.Close()
+		return nil, err
+	}
+
+	return &Zstd{
+		dictID:  d.ID(),
+		encoder: encoder,
+		decoder: decoder,
+	}, nil
+}
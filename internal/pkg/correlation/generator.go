@@ -0,0 +1,36 @@
+// Package correlation 提供跨节点请求 ( RPC 调用、HTTP 推送转发等 ) 使用的 correlation id 生成器。
+//
+// correlation id 的格式为 {node_id}-{启动时间}-{序号}：
+// 节点 ID 用于区分响应属于哪个节点，启动时间用于区分同一节点重启前后的请求，
+// 避免重启前发出的请求的响应被误认为是重启后的请求的响应。
+package correlation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Generator 为 correlation id 生成器，并发安全。
+type Generator struct {
+	prefix string
+	seq    atomic.Uint64
+}
+
+// Next 生成新的 correlation id。
+func (g *Generator) Next() string {
+	return g.prefix + strconv.FormatUint(g.seq.Add(1), 36)
+}
+
+// Owns 判断 correlation id 是否由当前生成器生成 ( 即属于当前节点的本次启动 )。
+func (g *Generator) Owns(id string) bool {
+	return strings.HasPrefix(id, g.prefix)
+}
+
+func NewGenerator(nodeID string) *Generator {
+	return &Generator{
+		prefix: fmt.Sprintf("%s-%s-", nodeID, strconv.FormatInt(time.Now().UnixNano(), 36)),
+	}
+}
@@ -0,0 +1,23 @@
+package correlation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerator(t *testing.T) {
+	t.Parallel()
+
+	g := NewGenerator("node-1")
+	id1, id2 := g.Next(), g.Next()
+	assert.NotEqual(t, id1, id2)
+	assert.True(t, strings.HasPrefix(id1, "node-1-"))
+	assert.True(t, g.Owns(id1))
+	assert.True(t, g.Owns(id2))
+
+	// 其他节点或同一节点重启后生成的 id 不属于当前生成器。
+	assert.False(t, g.Owns(NewGenerator("node-2").Next()))
+	assert.False(t, g.Owns("node-1-0-1"))
+}
@@ -0,0 +1,76 @@
+package dedup
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/jrmarcco/jit/bean/option"
+)
+
+var _ Deduper = (*Layered)(nil)
+
+// Layered 为分层去重器：先使用本地去重器，本地没有标记过的消息再使用共享存储 ( 如 Redis ) 去重。
+//
+// 客户端在同一连接上的重发由本地去重器识别，不需要访问共享存储；
+// 共享存储只用于识别重新连接 ( 可能在其他节点 ) 后的重发，没有配置共享存储时只使用本地去重。
+// 共享存储不可用时按 FailMode 处理。
+type Layered struct {
+	local    Deduper
+	remote   Deduper
+	failMode FailMode
+}
+
+func (d *Layered) Mark(ctx context.Context, key Key) (bool, error) {
+	ok, err := d.local.Mark(ctx, key)
+	if err != nil || !ok || d.remote == nil {
+		return ok, err
+	}
+
+	ok, err = d.remote.Mark(ctx, key)
+	if err == nil {
+		// 共享存储中已经标记过的消息保留本地标记，同一连接上的后续重发不再访问共享存储。
+		return ok, nil
+	}
+
+	if d.failMode == FailOpen {
+		slog.Warn(
+			"[synp-deduper] failed to mark message in remote store, fallback to local dedup",
+			"conn_id", key.ConnID,
+			"message_id", key.MessageID,
+			"error", err,
+		)
+		return true, nil
+	}
+
+	// 拒绝消息，客户端重发时重新去重。
+	_ = d.local.Unmark(ctx, key)
+	return false, err
+}
+
+func (d *Layered) Unmark(ctx context.Context, key Key) error {
+	_ = d.local.Unmark(ctx, key)
+	if d.remote == nil {
+		return nil
+	}
+	return d.remote.Unmark(ctx, key)
+}
+
+// LayeredWithRemote 设置共享存储去重器及其不可用时的处理方式。
+func LayeredWithRemote(remote Deduper, failMode FailMode) option.Opt[Layered] {
+	return func(d *Layered) {
+		d.remote = remote
+		if failMode != "" {
+			d.failMode = failMode
+		}
+	}
+}
+
+func NewLayered(local Deduper, opts ...option.Opt[Layered]) *Layered {
+	d := &Layered{
+		local:    local,
+		failMode: FailClosed,
+	}
+
+	option.Apply(d, opts...)
+	return d
+}
@@ -0,0 +1,123 @@
+package dedup
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalDeduper(t *testing.T) {
+	t.Parallel()
+
+	d := NewLocalDeduper(2, 20*time.Millisecond)
+	ctx := t.Context()
+	key := func(connID, messageID string) Key {
+		return Key{ConnID: connID, BID: 1, UID: 1, MessageID: messageID}
+	}
+
+	ok, err := d.Mark(ctx, key("c1", "m1"))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, _ = d.Mark(ctx, key("c1", "m1"))
+	assert.False(t, ok)
+
+	// 按连接去重。
+	ok, _ = d.Mark(ctx, key("c2", "m1"))
+	assert.True(t, ok)
+
+	// 超过容量时淘汰最早的 message id ( m1 在重复收到时刷新过 )。
+	ok, _ = d.Mark(ctx, key("c1", "m2"))
+	assert.True(t, ok)
+	ok, _ = d.Mark(ctx, key("c1", "m3"))
+	assert.True(t, ok)
+	ok, _ = d.Mark(ctx, key("c1", "m1"))
+	assert.True(t, ok)
+
+	// 取消标记后允许重发。
+	require.NoError(t, d.Unmark(ctx, key("c1", "m3")))
+	ok, _ = d.Mark(ctx, key("c1", "m3"))
+	assert.True(t, ok)
+
+	// 超过时间窗口后淘汰。
+	time.Sleep(30 * time.Millisecond)
+	ok, _ = d.Mark(ctx, key("c1", "m3"))
+	assert.True(t, ok)
+}
+
+func TestLayered(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	remote := &testRemote{marked: make(map[string]bool)}
+	key := Key{ConnID: "c1", BID: 1, UID: 1, MessageID: "m1"}
+
+	// 共享存储标记过的消息 ( 在其他连接上收到过 ) 为重复消息。
+	remote.marked[ScopeBID.Of(key)] = true
+	d := NewLayered(NewLocalDeduper(0, time.Minute), LayeredWithRemote(remote, FailClosed))
+	ok, err := d.Mark(ctx, key)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 1, remote.calls)
+
+	// 同一连接上的重发由本地去重识别。
+	ok, _ = d.Mark(ctx, key)
+	assert.False(t, ok)
+	assert.Equal(t, 1, remote.calls)
+
+	// fail-closed：共享存储不可用时拒绝消息，恢复后可以重发。
+	remote.err = errors.New("redis down")
+	key.MessageID = "m2"
+	_, err = d.Mark(ctx, key)
+	require.Error(t, err)
+	remote.err = nil
+	ok, err = d.Mark(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// fail-open：共享存储不可用时只使用本地去重。
+	d = NewLayered(NewLocalDeduper(0, time.Minute), LayeredWithRemote(remote, FailOpen))
+	remote.err = errors.New("redis down")
+	key.MessageID = "m3"
+	ok, err = d.Mark(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, _ = d.Mark(ctx, key)
+	assert.False(t, ok)
+}
+
+func TestScope_Of(t *testing.T) {
+	t.Parallel()
+
+	key := Key{ConnID: "1:2:pc", BID: 1, UID: 2, MessageID: "m1"}
+	assert.Equal(t, "1:m1", ScopeBID.Of(key))
+	assert.Equal(t, "1:2:m1", ScopeUID.Of(key))
+}
+
+type testRemote struct {
+	marked map[string]bool
+	calls  int
+	err    error
+}
+
+func (r *testRemote) Mark(_ context.Context, key Key) (bool, error) {
+	r.calls++
+	if r.err != nil {
+		return false, r.err
+	}
+
+	k := ScopeBID.Of(key)
+	if r.marked[k] {
+		return false, nil
+	}
+	r.marked[k] = true
+	return true, nil
+}
+
+func (r *testRemote) Unmark(_ context.Context, key Key) error {
+	delete(r.marked, ScopeBID.Of(key))
+	return r.err
+}
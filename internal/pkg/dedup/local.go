@@ -0,0 +1,159 @@
+package dedup
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jrmarcco/jit/xsync"
+)
+
+const (
+	DefaultLocalCapacity = 1024
+	DefaultLocalWindow   = 10 * time.Second
+)
+
+var _ Deduper = (*LocalDeduper)(nil)
+
+// LocalDeduper 为去重器的进程内实现，不需要网络请求。
+//
+// 每个连接 ID ( bid:uid:device ) 一个有界的 LRU：最多保留最近的 capacity 个 message id，
+// 超过 window 没有再次收到的 message id 被淘汰，重复收到时刷新时间 ( 客户端仍在重发 )。
+// 使用精确的 LRU 而不是概率过滤器 ( 如 cuckoo filter )，不会把新消息误判为重复消息。
+//
+// 只能识别同一节点、同一连接 ID 上的重复消息，
+// 客户端重新连接到其他节点后重发的消息需要共享存储 ( 见 Layered ) 去重。
+// window 需要覆盖客户端的重发时间 ( 从第一次发送到放弃重发 )。
+type LocalDeduper struct {
+	conns *xsync.Map[string, *lru]
+
+	capacity int
+	window   time.Duration
+
+	lastSweep atomic.Int64 // 毫秒
+}
+
+func (d *LocalDeduper) Mark(_ context.Context, key Key) (bool, error) {
+	now := time.Now()
+	d.sweep(now)
+
+	for {
+		filter, ok := d.conns.Load(key.ConnID)
+		if !ok {
+			filter, _ = d.conns.LoadOrStore(key.ConnID, newLRU())
+		}
+
+		filter.mu.Lock()
+		if filter.dead {
+			// 过滤器已经被清除，重新加载。
+			filter.mu.Unlock()
+			continue
+		}
+		ok = filter.mark(key.MessageID, now, d.capacity, d.window)
+		filter.mu.Unlock()
+		return ok, nil
+	}
+}
+
+func (d *LocalDeduper) Unmark(_ context.Context, key Key) error {
+	if filter, ok := d.conns.Load(key.ConnID); ok {
+		filter.mu.Lock()
+		filter.unmark(key.MessageID)
+		filter.mu.Unlock()
+	}
+	return nil
+}
+
+// sweep 清除超过 window 没有收到消息的连接的过滤器，最多每 window 清除一次。
+func (d *LocalDeduper) sweep(now time.Time) {
+	last := d.lastSweep.Load()
+	if now.UnixMilli()-last < d.window.Milliseconds() || !d.lastSweep.CompareAndSwap(last, now.UnixMilli()) {
+		return
+	}
+
+	d.conns.Range(func(connID string, filter *lru) bool {
+		filter.mu.Lock()
+		if filter.idle(now, d.window) {
+			filter.dead = true
+			d.conns.Delete(connID)
+		}
+		filter.mu.Unlock()
+		return true
+	})
+}
+
+// NewLocalDeduper 创建进程内的去重器，每个连接最多保留 capacity 个 message id，保留 window 时间。
+func NewLocalDeduper(capacity int, window time.Duration) *LocalDeduper {
+	if capacity <= 0 {
+		capacity = DefaultLocalCapacity
+	}
+	if window <= 0 {
+		window = DefaultLocalWindow
+	}
+
+	d := &LocalDeduper{
+		conns:    &xsync.Map[string, *lru]{},
+		capacity: capacity,
+		window:   window,
+	}
+	d.lastSweep.Store(time.Now().UnixMilli())
+	return d
+}
+
+// lru 为单个连接的 message id 过滤器，按最后一次收到的时间排序 ( 最早的在前 )。
+type lru struct {
+	mu    sync.Mutex
+	dead  bool
+	order *list.List               // *lruEntry
+	index map[string]*list.Element // messageID -> element
+}
+
+type lruEntry struct {
+	messageID string
+	seenAt    time.Time
+}
+
+// mark 标记 message id，message id 已经存在时刷新时间并返回 false。
+func (l *lru) mark(messageID string, now time.Time, capacity int, window time.Duration) bool {
+	// 淘汰超过 window 的 message id。
+	for e := l.order.Front(); e != nil && now.Sub(e.Value.(*lruEntry).seenAt) >= window; e = l.order.Front() {
+		l.remove(e)
+	}
+
+	if e, ok := l.index[messageID]; ok {
+		e.Value.(*lruEntry).seenAt = now
+		l.order.MoveToBack(e)
+		return false
+	}
+
+	l.index[messageID] = l.order.PushBack(&lruEntry{messageID: messageID, seenAt: now})
+	if l.order.Len() > capacity {
+		l.remove(l.order.Front())
+	}
+	return true
+}
+
+func (l *lru) unmark(messageID string) {
+	if e, ok := l.index[messageID]; ok {
+		l.remove(e)
+	}
+}
+
+func (l *lru) idle(now time.Time, window time.Duration) bool {
+	e := l.order.Back()
+	return e == nil || now.Sub(e.Value.(*lruEntry).seenAt) >= window
+}
+
+func (l *lru) remove(e *list.Element) {
+	delete(l.index, e.Value.(*lruEntry).messageID)
+	l.order.Remove(e)
+}
+
+func newLRU() *lru {
+	return &lru{
+		order: list.New(),
+		index: make(map[string]*list.Element),
+	}
+}
@@ -0,0 +1,35 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/jrmarcco/synp/internal/pkg/dedup"
+	"github.com/redis/go-redis/v9"
+)
+
+var _ dedup.Deduper = (*Deduper)(nil)
+
+// Deduper 为去重器的 Redis 实现，标记在 expiration 后自动过期。
+// 标记的 key 为消息在去重范围内的唯一标识 ( 见 dedup.Scope )。
+type Deduper struct {
+	rdb        redis.Cmdable
+	expiration time.Duration
+	scope      dedup.Scope
+}
+
+func (d *Deduper) Mark(ctx context.Context, key dedup.Key) (bool, error) {
+	return d.rdb.SetNX(ctx, d.scope.Of(key), key.MessageID, d.expiration).Result()
+}
+
+func (d *Deduper) Unmark(ctx context.Context, key dedup.Key) error {
+	return d.rdb.Del(ctx, d.scope.Of(key)).Err()
+}
+
+func NewDeduper(rdb redis.Cmdable, expiration time.Duration, scope dedup.Scope) *Deduper {
+	return &Deduper{
+		rdb:        rdb,
+		expiration: expiration,
+		scope:      scope,
+	}
+}
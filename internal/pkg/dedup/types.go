@@ -0,0 +1,88 @@
+package dedup
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jrmarcco/synp"
+)
+
+// Deduper 为上行消息去重器。
+type Deduper interface {
+	// Mark 标记消息已接收，消息已经被标记过 ( 重复消息 ) 时返回 false。
+	Mark(ctx context.Context, key Key) (bool, error)
+	// Unmark 取消标记，消息处理失败时调用，允许客户端重发该消息。
+	Unmark(ctx context.Context, key Key) error
+}
+
+// Key 为上行消息的去重标识。
+// 本地去重按连接划分，共享存储 ( 如 Redis ) 去重按 Scope 划分。
+type Key struct {
+	ConnID    string
+	BID       uint64
+	UID       uint64
+	MessageID string
+}
+
+// NewKey 返回连接上消息的去重标识。
+func NewKey(conn synp.Conn, messageID string) Key {
+	user := conn.Session().User()
+	return Key{
+		ConnID:    conn.ID(),
+		BID:       user.BID,
+		UID:       user.UID,
+		MessageID: messageID,
+	}
+}
+
+// Scope 为共享存储去重的范围，即 message id 在什么范围内唯一。
+type Scope string
+
+const (
+	// ScopeBID 同一业务下 message id 唯一 ( 默认 )。
+	ScopeBID Scope = "bid"
+	// ScopeUID 同一用户 message id 唯一，不同用户的 message id 可以重复 ( 如客户端自增 id )。
+	ScopeUID Scope = "uid"
+)
+
+// Of 返回消息在该范围内的唯一标识。
+func (s Scope) Of(key Key) string {
+	if s == ScopeUID {
+		return fmt.Sprintf("%d:%d:%s", key.BID, key.UID, key.MessageID)
+	}
+	return fmt.Sprintf("%d:%s", key.BID, key.MessageID)
+}
+
+// ParseScope 解析去重范围，为空时返回 ScopeBID。
+func ParseScope(s string) (Scope, error) {
+	switch Scope(s) {
+	case "", ScopeBID:
+		return ScopeBID, nil
+	case ScopeUID:
+		return ScopeUID, nil
+	default:
+		return "", fmt.Errorf("unknown dedup scope: %s", s)
+	}
+}
+
+// FailMode 为共享存储不可用时的处理方式。
+type FailMode string
+
+const (
+	// FailClosed 拒绝消息 ( 返回错误，客户端重发 )，保证不会重复处理 ( 默认 )。
+	FailClosed FailMode = "closed"
+	// FailOpen 只使用本地去重并继续处理消息，保证可用性，重新连接到其他节点后重发的消息可能被重复处理。
+	FailOpen FailMode = "open"
+)
+
+// ParseFailMode 解析共享存储不可用时的处理方式，为空时返回 FailClosed。
+func ParseFailMode(s string) (FailMode, error) {
+	switch FailMode(s) {
+	case "", FailClosed:
+		return FailClosed, nil
+	case FailOpen:
+		return FailOpen, nil
+	default:
+		return "", fmt.Errorf("unknown dedup fail mode: %s", s)
+	}
+}
@@ -0,0 +1,93 @@
+package limiter
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jrmarcco/jit/xsync"
+)
+
+// windowCounter 为单个 key 的窗口计数。
+type windowCounter struct {
+	mu          sync.Mutex
+	windowStart time.Time
+	cnt         int
+	dead        bool // 已经被清理，需要重新加载
+}
+
+// WindowLimiter 按 key 限流的固定窗口限流器。
+// 每个 key 在一个窗口内最多允许 limit 次请求。
+//
+// 注：
+//
+//	与 TokenLimiter 一样，这是一个非阻塞的限流器，达到上限时立即返回 false。
+//	过期的 key 会在 Allow 调用时惰性清理。
+type WindowLimiter struct {
+	limit  int
+	window time.Duration
+
+	counters  *xsync.Map[string, *windowCounter]
+	nextSweep atomic.Int64 // 下一次清理的时间 ( 纳秒 )
+}
+
+// Allow 判断 key 是否允许通过。
+func (l *WindowLimiter) Allow(key string) bool {
+	now := time.Now()
+	l.sweep(now)
+
+	for {
+		counter, _ := l.counters.LoadOrStore(key, &windowCounter{windowStart: now})
+
+		counter.mu.Lock()
+		if counter.dead {
+			// 计数器已经被清理，重新加载。
+			counter.mu.Unlock()
+			continue
+		}
+		allowed := counter.allow(now, l.limit, l.window)
+		counter.mu.Unlock()
+		return allowed
+	}
+}
+
+// allow 在当前窗口内计数，调用方需要持有锁。
+func (c *windowCounter) allow(now time.Time, limit int, window time.Duration) bool {
+	if now.Sub(c.windowStart) >= window {
+		c.windowStart = now
+		c.cnt = 0
+	}
+
+	if c.cnt >= limit {
+		return false
+	}
+	c.cnt++
+	return true
+}
+
+// sweep 清理过期的 key，每个窗口最多清理一次。
+func (l *WindowLimiter) sweep(now time.Time) {
+	next := l.nextSweep.Load()
+	if now.UnixNano() < next || !l.nextSweep.CompareAndSwap(next, now.Add(l.window).UnixNano()) {
+		return
+	}
+
+	l.counters.Range(func(key string, counter *windowCounter) bool {
+		// 持有锁时标记并删除，避免并发的 Allow 在删除之后继续使用该计数器。
+		counter.mu.Lock()
+		if now.Sub(counter.windowStart) >= l.window {
+			counter.dead = true
+			l.counters.Delete(key)
+		}
+		counter.mu.Unlock()
+		return true
+	})
+}
+
+func NewWindowLimiter(limit int, window time.Duration) *WindowLimiter {
+	return &WindowLimiter{
+		limit:    limit,
+		window:   window,
+		counters: &xsync.Map[string, *windowCounter]{},
+	}
+}
@@ -0,0 +1,97 @@
+package limiter
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWindowLimiter_Allow(t *testing.T) {
+	t.Parallel()
+
+	l := NewWindowLimiter(2, time.Hour)
+
+	assert.True(t, l.Allow("a"))
+	assert.True(t, l.Allow("a"))
+	// 达到上限后拒绝。
+	assert.False(t, l.Allow("a"))
+	assert.False(t, l.Allow("a"))
+
+	// 不同 key 分别计数。
+	assert.True(t, l.Allow("b"))
+}
+
+func TestWindowLimiter_Rollover(t *testing.T) {
+	t.Parallel()
+
+	const window = 20 * time.Millisecond
+
+	l := NewWindowLimiter(1, window)
+	assert.True(t, l.Allow("a"))
+	assert.False(t, l.Allow("a"))
+
+	// 进入下一个窗口后重新计数。
+	time.Sleep(window)
+	assert.True(t, l.Allow("a"))
+	assert.False(t, l.Allow("a"))
+}
+
+func TestWindowLimiter_Sweep(t *testing.T) {
+	t.Parallel()
+
+	const window = 20 * time.Millisecond
+
+	l := NewWindowLimiter(1, window)
+	assert.True(t, l.Allow("a"))
+	assert.True(t, l.Allow("b"))
+
+	// 窗口结束后，下一次 Allow 清理过期的 key。
+	time.Sleep(window)
+	assert.True(t, l.Allow("c"))
+
+	_, ok := l.counters.Load("a")
+	assert.False(t, ok)
+	_, ok = l.counters.Load("b")
+	assert.False(t, ok)
+	_, ok = l.counters.Load("c")
+	assert.True(t, ok)
+}
+
+func TestWindowLimiter_SweepConcurrent(t *testing.T) {
+	t.Parallel()
+
+	const (
+		window   = 5 * time.Millisecond
+		limit    = 3
+		windows  = 4
+		parallel = 8
+	)
+
+	// 清理与 Allow 并发时，被清理的计数器不会被继续使用 ( 导致计数重置 )。
+	l := NewWindowLimiter(limit, window)
+
+	var (
+		wg      sync.WaitGroup
+		allowed atomic.Int64
+	)
+	start := time.Now()
+	for range parallel {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for time.Since(start) < windows*window {
+				if l.Allow("a") {
+					allowed.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 每个窗口最多 limit 次，窗口边界不对齐时最多多出一个窗口。
+	elapsed := int64(time.Since(start) / window)
+	assert.LessOrEqual(t, allowed.Load(), (elapsed+1)*limit)
+}
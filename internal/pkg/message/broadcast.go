@@ -0,0 +1,90 @@
+package message
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jrmarcco/synp"
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/jrmarcco/synp/internal/pkg/codec"
+)
+
+// BroadcastFunc 将同一条消息推送到多个连接 ( 多端登录、房间等扇出场景 )，返回成功放入发送队列的连接数。
+// seqs 不为空时 seqs[i] 为消息在 conns[i] 上的序号 ( 为 0 时不携带序号 )，msg 本身不能携带序号。
+// 某个连接发送失败时继续发送其余连接，返回所有失败连接的错误。
+type BroadcastFunc func(conns []synp.Conn, msg *messagev1.Message, seqs []uint64) (int, error)
+
+// DefaultBroadcastFunc 创建默认的扇出推送函数：消息只编码一次，所有连接共享同一个池化的缓冲区。
+//
+// codec 实现 codec.UnknownAppender 时，连接上的序号作为后缀单独发送，不需要按连接重新编码；
+// 否则 ( 如 json codec ) 携带序号的消息按连接单独编码。
+func DefaultBroadcastFunc(c codec.Codec) BroadcastFunc {
+	pushFunc := DefaultPushFunc(c)
+	appender, _ := c.(codec.UnknownAppender)
+
+	return func(conns []synp.Conn, msg *messagev1.Message, seqs []uint64) (int, error) {
+		if len(conns) == 0 {
+			return 0, nil
+		}
+
+		buf, err := codec.MarshalBuffer(c, msg)
+		if err != nil {
+			slog.Error(
+				"[synp-message] failed to marshal message",
+				"codec_name", c.Name(),
+				"message", msg.String(),
+				"error", err,
+			)
+			return 0, fmt.Errorf("%w: %w", ErrMarshalMessage, err)
+		}
+		defer buf.Release()
+
+		// 所有连接的序号后缀共用一块内存 ( 通常每个后缀为 tag 2 字节 + 1~3 字节的序号 )，
+		// 扩容后已经切出的后缀仍然引用原来的内存，不受影响。
+		var (
+			suffixes []byte
+			raw      [16]byte // 序号字段的原始编码，tag 2 字节 + varint 最多 10 字节
+		)
+		if appender != nil && len(seqs) > 0 {
+			suffixes = make([]byte, 0, 5*len(seqs))
+		}
+
+		var (
+			sent int
+			errs []error
+		)
+		base := newFrame(msg)
+		for i, conn := range conns {
+			var seq uint64
+			if i < len(seqs) {
+				seq = seqs[i]
+			}
+
+			frame := base
+			switch {
+			case seq == 0:
+			case appender != nil:
+				start := len(suffixes)
+				suffixes = appender.AppendUnknown(suffixes, appendSeq(raw[:0], seq))
+				frame.Suffix = suffixes[start:len(suffixes):len(suffixes)]
+			default:
+				if err = pushFunc(conn, WithSeq(msg, seq)); err != nil {
+					errs = append(errs, fmt.Errorf("conn %s: %w", conn.ID(), err))
+					continue
+				}
+				sent++
+				continue
+			}
+
+			buf.Retain()
+			frame.Buffer = buf
+			if err = conn.SendFrame(frame); err != nil {
+				errs = append(errs, fmt.Errorf("conn %s: failed to send message: %w", conn.ID(), err))
+				continue
+			}
+			sent++
+		}
+		return sent, errors.Join(errs...)
+	}
+}
@@ -0,0 +1,149 @@
+package message
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jrmarcco/synp"
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/jrmarcco/synp/internal/pkg/bufpool"
+	"github.com/jrmarcco/synp/internal/pkg/codec"
+	synpmock "github.com/jrmarcco/synp/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestDefaultBroadcastFunc(t *testing.T) {
+	t.Parallel()
+
+	msg := &messagev1.Message{MessageId: "m1", Body: []byte("body")}
+	SetPushPriority(msg, synp.PriorityHigh)
+	SetExpireAt(msg, time.Now().Add(time.Minute))
+
+	seqs := []uint64{1, 0, 300}
+
+	tcs := []struct {
+		name   string
+		codec  codec.Codec
+		shared bool // 所有连接是否共享同一个编码结果
+	}{
+		{
+			name:   "proto",
+			codec:  codec.NewProtoCodec(),
+			shared: true,
+		},
+		{
+			// json codec 不能追加序号后缀，携带序号的消息按连接单独编码。
+			name:  "json",
+			codec: codec.NewJSONCodec(JSONCodecWithSeq()),
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			conns := make([]synp.Conn, len(seqs))
+			frames := make([]synp.Frame, len(seqs))
+			for i := range conns {
+				conn := synpmock.NewMockConn(ctrl)
+				conn.EXPECT().ID().Return(strconv.Itoa(i)).AnyTimes()
+				conn.EXPECT().SendFrame(gomock.Any()).DoAndReturn(func(frame synp.Frame) error {
+					frames[i] = frame
+					return nil
+				})
+				conns[i] = conn
+			}
+
+			sent, err := DefaultBroadcastFunc(tc.codec)(conns, msg, seqs)
+			require.NoError(t, err)
+			assert.Equal(t, len(conns), sent)
+
+			var shared *bufpool.Buffer
+			for i, frame := range frames {
+				assert.Equal(t, synp.PriorityHigh, frame.Priority)
+				assert.Equal(t, "m1", frame.Key)
+
+				// 所有连接共享同一个编码结果，只有序号后缀不同。
+				if tc.shared {
+					if shared == nil {
+						shared = frame.Buffer
+					}
+					assert.Same(t, shared, frame.Buffer)
+				}
+
+				want, err := tc.codec.Marshal(WithSeq(msg, seqs[i]))
+				require.NoError(t, err)
+				assert.Equal(t, want, append(frame.Bytes(), frame.Suffix...))
+				frame.Release()
+			}
+		})
+	}
+}
+
+// BenchmarkFanOut 对比 10 万个连接的扇出推送 ( 不包括连接发送队列的开销 )：
+//
+//  1. marshal_per_conn：按连接编码到新分配的内存 ( 没有使用缓冲区池 )；
+//  2. pooled_per_conn：按连接编码到池化的缓冲区 ( PushFunc )；
+//  3. encode_once：只编码一次，所有连接共享缓冲区 ( BroadcastFunc )。
+func BenchmarkFanOut(b *testing.B) {
+	const connCount = 100_000
+
+	c := codec.NewProtoCodec()
+	msg := &messagev1.Message{MessageId: "m1", Body: make([]byte, 512)}
+	SetPushPriority(msg, synp.PriorityHigh)
+
+	conns := make([]synp.Conn, connCount)
+	seqs := make([]uint64, connCount)
+	msgs := make([]*messagev1.Message, connCount)
+	for i := range conns {
+		conns[i] = releaseConn{}
+		seqs[i] = uint64(i + 1)
+		msgs[i] = WithSeq(msg, seqs[i])
+	}
+
+	b.Run("marshal_per_conn", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			for i, conn := range conns {
+				payload, _ := c.Marshal(msgs[i])
+				frame := newFrame(msgs[i])
+				frame.Payload = payload
+				_ = conn.SendFrame(frame)
+			}
+		}
+	})
+
+	b.Run("pooled_per_conn", func(b *testing.B) {
+		pushFunc := DefaultPushFunc(c)
+
+		b.ReportAllocs()
+		for b.Loop() {
+			for i, conn := range conns {
+				_ = pushFunc(conn, msgs[i])
+			}
+		}
+	})
+
+	b.Run("encode_once", func(b *testing.B) {
+		broadcastFunc := DefaultBroadcastFunc(c)
+
+		b.ReportAllocs()
+		for b.Loop() {
+			_, _ = broadcastFunc(conns, msg, seqs)
+		}
+	})
+}
+
+// releaseConn 模拟发送完成，收到消息后直接释放，用于基准测试 ( 避免 mock 的开销 )。
+type releaseConn struct {
+	synp.Conn
+}
+
+func (releaseConn) SendFrame(frame synp.Frame) error {
+	frame.Release()
+	return nil
+}
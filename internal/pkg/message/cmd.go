@@ -0,0 +1,45 @@
+package message
+
+import commonv1 "github.com/jrmarcco/synp-api/api/go/common/v1"
+
+// 网关扩展的指令类型。
+//
+// 这些指令尚未在 synp-api 中定义，由网关先行扩展。
+// proto3 的枚举是开放的，未定义的取值可以正常编解码 ( json 编码为数字 )。
+// 取值从 100 开始，避免与 synp-api 后续新增的指令冲突。
+// 注意：
+//
+//	synp-api 正式定义这些指令后，需要替换为 commonv1 中的定义并保持取值一致。
+const (
+	// 在线状态订阅指令：frontend -> gateway。
+	CommandTypePresenceSubscribe commonv1.CommandType = 100
+	// 在线状态变更通知：gateway -> frontend。
+	CommandTypePresenceNotify commonv1.CommandType = 101
+
+	// 瞬时信号指令 ( 如正在输入、光标位置 )：frontend -> gateway -> frontend。
+	// 瞬时信号不去重、不持久化、不重传，也不会回复 ack。
+	CommandTypeEphemeral commonv1.CommandType = 102
+
+	// RPC 请求指令：frontend -> gateway -> backend。
+	CommandTypeRPCRequest commonv1.CommandType = 103
+	// RPC 响应指令：backend -> gateway -> frontend，message id 与请求一致。
+	CommandTypeRPCResponse commonv1.CommandType = 104
+
+	// 已读回执指令：frontend -> gateway -> backend，body 为 receipt.ReadRequest。
+	CommandTypeRead commonv1.CommandType = 105
+
+	// 分片指令：frontend <-> gateway，body 为 chunk.Chunk。
+	// 分片按偏移幂等，不去重；还原后的原消息按原消息的指令去重。
+	CommandTypeChunk commonv1.CommandType = 106
+)
+
+// NeedDedup 判断指令是否需要去重。
+// 心跳和瞬时信号允许丢失和重复，分片按偏移幂等，跳过去重可以省去一次 Redis 请求。
+func NeedDedup(cmd commonv1.CommandType) bool {
+	switch cmd {
+	case commonv1.CommandType_COMMAND_TYPE_HEARTBEAT, CommandTypeEphemeral, CommandTypeChunk:
+		return false
+	default:
+		return true
+	}
+}
@@ -1,49 +1,125 @@
 package downstream
 
 import (
+	"log/slog"
+	"time"
+
+	"github.com/jrmarcco/jit/bean/option"
 	"github.com/jrmarcco/synp"
 	commonv1 "github.com/jrmarcco/synp-api/api/go/common/v1"
 	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
 	"github.com/jrmarcco/synp/internal/pkg/message"
 	"github.com/jrmarcco/synp/internal/pkg/retransmit"
+	"google.golang.org/protobuf/proto"
 )
 
 var _ DMsgHandler = (*BackendMsgHandler)(nil)
 
+// ExpireFunc 在丢弃已经过期的消息时调用 ( 如发布过期回执 )。
+type ExpireFunc func(msg *messagev1.PushMessage)
+
 // BackendMsgHandler 是 backend 消息处理器的实现，用于处理后端推送的消息。
 type BackendMsgHandler struct {
-	pushFunc          message.PushFunc
+	broadcastFunc     message.BroadcastFunc
 	retransmitManager *retransmit.Manager
+	expireFunc        ExpireFunc
 }
 
 func (h *BackendMsgHandler) Handle(conns []synp.Conn, pushMsg *messagev1.PushMessage) error {
+	if message.Expired(pushMsg, time.Now()) {
+		// 消息已经过期，没有投递的意义，直接丢弃。
+		slog.Debug(
+			"[synp-backend-msg-handler] drop expired message",
+			"message_id", pushMsg.GetMessageId(),
+			"expire_at", message.ExpireAt(pushMsg),
+		)
+		h.expire(conns, pushMsg)
+		return nil
+	}
+
 	downstreamMsg := &messagev1.Message{
 		Cmd:       commonv1.CommandType_COMMAND_TYPE_DOWNSTREAM,
 		MessageId: pushMsg.GetMessageId(),
 		Body:      pushMsg.GetBody(),
 	}
+	// 沿用 push message 的优先级、合并 key 及过期时间 ( 包括重传 )。
+	message.SetPushPriority(downstreamMsg, message.PushPriority(pushMsg))
+	message.SetConflationKey(downstreamMsg, message.ConflationKey(pushMsg))
+	message.SetExpireAt(downstreamMsg, message.ExpireAt(pushMsg))
 
 	// 设置重试。
-	// 当前端返回 ack 消息后，停止重试。
-	defer h.retransmitManager.Start(conns, downstreamMsg)
+	// 当前端返回 ack 消息 ( 或发送队列溢出时消息被保存为离线消息 ) 后，停止重试。
+	// 注意：
+	//
+	//  1. 需要在发送之前启动重试，否则发送时停止的重试会在发送之后被重新启动；
+	//  2. 发送到各个连接的消息携带了在该连接上分配的序号，前端可以通过序号累积 ack。
+	msgs := h.retransmitManager.Start(conns, downstreamMsg)
+
+	// 消息只编码一次，各个连接只有序号不同。
+	seqs := make([]uint64, len(msgs))
+	for i, msg := range msgs {
+		seqs[i] = message.Seq(msg)
+	}
+	sent, err := h.broadcastFunc(conns, downstreamMsg, seqs)
+	if err != nil && sent > 0 {
+		// 部分连接发送失败 ( 通常为已经关闭的连接 )，由重传负责补发，不影响其他连接。
+		slog.Warn(
+			"[synp-backend-msg-handler] failed to send message to some connections",
+			"message_id", downstreamMsg.GetMessageId(),
+			"conns", len(conns),
+			"sent", sent,
+			"error", err,
+		)
+		err = nil
+	}
 
+	// 更新连接活跃时间，发送失败的连接通常已经关闭，更新不会生效。
 	for _, conn := range conns {
-		if err := h.pushFunc(conn, downstreamMsg); err != nil {
-			return err
+		conn.UpdateActivityTime()
+	}
+	return err
+}
+
+// expire 按接收者调用 expireFunc。
+// 同一条消息可能一次投递给多个用户的连接 ( 如房间推送 )，此时消息的 receiver_id 只是其中之一，
+// 需要按连接的用户分别生成消息，避免只有一个接收者收到过期回执。
+func (h *BackendMsgHandler) expire(conns []synp.Conn, pushMsg *messagev1.PushMessage) {
+	if h.expireFunc == nil {
+		return
+	}
+
+	h.expireFunc(pushMsg)
+	reported := map[uint64]struct{}{pushMsg.GetReceiverId(): {}}
+	for _, conn := range conns {
+		uid := conn.Session().User().UID
+		if _, ok := reported[uid]; ok {
+			continue
 		}
+		reported[uid] = struct{}{}
 
-		// 成功发送消息到前端，更新连接活跃时间。
-		conn.UpdateActivityTime()
+		msg, _ := proto.Clone(pushMsg).(*messagev1.PushMessage)
+		msg.ReceiverId = uid
+		h.expireFunc(msg)
+	}
+}
+
+// BackendMsgHandlerWithExpireFunc 设置丢弃过期消息时的回调。
+func BackendMsgHandlerWithExpireFunc(fn ExpireFunc) option.Opt[BackendMsgHandler] {
+	return func(h *BackendMsgHandler) {
+		h.expireFunc = fn
 	}
-	return nil
 }
 
 func NewBackendMsgHandler(
-	pushFunc message.PushFunc,
+	broadcastFunc message.BroadcastFunc,
 	retransmitManager *retransmit.Manager,
+	opts ...option.Opt[BackendMsgHandler],
 ) *BackendMsgHandler {
-	return &BackendMsgHandler{
-		pushFunc:          pushFunc,
+	h := &BackendMsgHandler{
+		broadcastFunc:     broadcastFunc,
 		retransmitManager: retransmitManager,
 	}
+
+	option.Apply(h, opts...)
+	return h
 }
@@ -0,0 +1,80 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: types.go
+//
+// Generated by this command:
+//
+//	mockgen -source=types.go -destination=mock/downstream.mock.go -package=downstreammock -typed Handler
+//
+
+// Package downstreammock is a generated GoMock package.
+package downstreammock
+
+import (
+	reflect "reflect"
+
+	synp "github.com/jrmarcco/synp"
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockDMsgHandler is a mock of DMsgHandler interface.
+type MockDMsgHandler struct {
+	ctrl     *gomock.Controller
+	recorder *MockDMsgHandlerMockRecorder
+	isgomock struct{}
+}
+
+// MockDMsgHandlerMockRecorder is the mock recorder for MockDMsgHandler.
+type MockDMsgHandlerMockRecorder struct {
+	mock *MockDMsgHandler
+}
+
+// NewMockDMsgHandler creates a new mock instance.
+func NewMockDMsgHandler(ctrl *gomock.Controller) *MockDMsgHandler {
+	mock := &MockDMsgHandler{ctrl: ctrl}
+	mock.recorder = &MockDMsgHandlerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDMsgHandler) EXPECT() *MockDMsgHandlerMockRecorder {
+	return m.recorder
+}
+
+// Handle mocks base method.
+func (m *MockDMsgHandler) Handle(conns []synp.Conn, msg *messagev1.PushMessage) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Handle", conns, msg)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Handle indicates an expected call of Handle.
+func (mr *MockDMsgHandlerMockRecorder) Handle(conns, msg any) *MockDMsgHandlerHandleCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Handle", reflect.TypeOf((*MockDMsgHandler)(nil).Handle), conns, msg)
+	return &MockDMsgHandlerHandleCall{Call: call}
+}
+
+// MockDMsgHandlerHandleCall wrap *gomock.Call
+type MockDMsgHandlerHandleCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockDMsgHandlerHandleCall) Return(arg0 error) *MockDMsgHandlerHandleCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockDMsgHandlerHandleCall) Do(f func([]synp.Conn, *messagev1.PushMessage) error) *MockDMsgHandlerHandleCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockDMsgHandlerHandleCall) DoAndReturn(f func([]synp.Conn, *messagev1.PushMessage) error) *MockDMsgHandlerHandleCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
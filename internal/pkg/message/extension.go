@@ -0,0 +1,194 @@
+package message
+
+import (
+	"time"
+
+	"github.com/jrmarcco/jit/bean/option"
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/jrmarcco/synp/internal/pkg/codec"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// 网关扩展的消息字段编号。
+//
+// PushMessage 和 Message 尚未在 synp-api 中定义这些字段，由网关先行扩展：
+// 字段写入消息的未知字段 ( unknown fields )，proto 编解码、proto.Clone 都会保留这些字段，
+// 所以转发到其他节点后不会丢失，不认识这些字段的客户端会直接忽略。
+// 字段编号从 100 开始，避免与 synp-api 后续新增的字段冲突。
+// 注意：
+//
+//  1. 离线消息以 protobuf 二进制保存，会保留这些字段；protojson 不会编码未知字段，使用 json codec 的前端收到的消息不会携带这些字段，
+//     序号除外 ( 见 JSONCodecWithSeq )；
+//  2. synp-api 正式定义这些字段后，需要替换为 messagev1 中的定义并保持字段编号一致。
+const (
+	priorityFieldNumber      protowire.Number = 100 // 发送优先级 ( varint )
+	conflationKeyFieldNumber protowire.Number = 101 // 合并 key ( bytes )
+	expireAtFieldNumber      protowire.Number = 102 // 过期时间 ( varint，毫秒时间戳 )
+	seqFieldNumber           protowire.Number = 103 // 连接上的序号 ( varint )
+)
+
+// rangeUnknown 遍历消息的未知字段，fn 返回 false 时停止遍历。
+// raw 为字段值 ( 不包含 tag ) 的原始编码。
+func rangeUnknown(msg proto.Message, fn func(num protowire.Number, typ protowire.Type, raw []byte) bool) {
+	unknown := msg.ProtoReflect().GetUnknown()
+	for len(unknown) > 0 {
+		num, typ, n := protowire.ConsumeTag(unknown)
+		if n < 0 {
+			return
+		}
+		m := protowire.ConsumeFieldValue(num, typ, unknown[n:])
+		if m < 0 {
+			return
+		}
+		if !fn(num, typ, unknown[n:n+m]) {
+			return
+		}
+		unknown = unknown[n+m:]
+	}
+}
+
+// getVarint 返回最后一个编号为 num 的 varint 字段。
+func getVarint(msg proto.Message, num protowire.Number) (uint64, bool) {
+	var (
+		val uint64
+		ok  bool
+	)
+	rangeUnknown(msg, func(n protowire.Number, typ protowire.Type, raw []byte) bool {
+		if n == num && typ == protowire.VarintType {
+			val, _ = protowire.ConsumeVarint(raw)
+			ok = true
+		}
+		return true
+	})
+	return val, ok
+}
+
+// getBytes 返回最后一个编号为 num 的 bytes 字段。
+func getBytes(msg proto.Message, num protowire.Number) ([]byte, bool) {
+	var (
+		val []byte
+		ok  bool
+	)
+	rangeUnknown(msg, func(n protowire.Number, typ protowire.Type, raw []byte) bool {
+		if n == num && typ == protowire.BytesType {
+			val, _ = protowire.ConsumeBytes(raw)
+			ok = true
+		}
+		return true
+	})
+	return val, ok
+}
+
+// setUnknown 移除编号为 num 的字段，再通过 appendFn 追加新的字段值 ( appendFn 为 nil 时只移除 )。
+func setUnknown(msg proto.Message, num protowire.Number, appendFn func(b []byte) []byte) {
+	var unknown []byte
+	rangeUnknown(msg, func(n protowire.Number, typ protowire.Type, raw []byte) bool {
+		if n != num {
+			unknown = protowire.AppendTag(unknown, n, typ)
+			unknown = append(unknown, raw...)
+		}
+		return true
+	})
+
+	if appendFn != nil {
+		unknown = appendFn(unknown)
+	}
+	msg.ProtoReflect().SetUnknown(unknown)
+}
+
+// ConflationKey 返回消息的合并 key，没有设置时返回空字符串。
+//
+// 同一个连接上合并 key 相同的消息只需要投递最新的一条 ( 如行情、比分 )：
+// 新消息会替换发送队列中 key 相同的旧消息，并停止旧消息的重传。
+func ConflationKey(msg proto.Message) string {
+	val, _ := getBytes(msg, conflationKeyFieldNumber)
+	return string(val)
+}
+
+// SetConflationKey 设置消息的合并 key，key 为空时移除。
+func SetConflationKey(msg proto.Message, key string) {
+	if key == "" {
+		setUnknown(msg, conflationKeyFieldNumber, nil)
+		return
+	}
+
+	setUnknown(msg, conflationKeyFieldNumber, func(b []byte) []byte {
+		b = protowire.AppendTag(b, conflationKeyFieldNumber, protowire.BytesType)
+		return protowire.AppendString(b, key)
+	})
+}
+
+// ExpireAt 返回消息的过期时间，没有设置时返回零值 ( 永不过期 )。
+//
+// 过期的消息没有投递的意义 ( 如“车辆 1 分钟后到达” )，
+// 会在消费、发送队列、重传以及离线消息保存和推送时被丢弃。
+func ExpireAt(msg proto.Message) time.Time {
+	val, ok := getVarint(msg, expireAtFieldNumber)
+	if !ok || val == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(int64(val))
+}
+
+// SetExpireAt 设置消息的过期时间 ( 精确到毫秒 )，expireAt 为零值时移除。
+func SetExpireAt(msg proto.Message, expireAt time.Time) {
+	if expireAt.IsZero() {
+		setUnknown(msg, expireAtFieldNumber, nil)
+		return
+	}
+
+	setUnknown(msg, expireAtFieldNumber, func(b []byte) []byte {
+		b = protowire.AppendTag(b, expireAtFieldNumber, protowire.VarintType)
+		return protowire.AppendVarint(b, uint64(max(expireAt.UnixMilli(), 1)))
+	})
+}
+
+// Expired 判断消息在 now 时是否已经过期。
+func Expired(msg proto.Message, now time.Time) bool {
+	expireAt := ExpireAt(msg)
+	return !expireAt.IsZero() && !now.Before(expireAt)
+}
+
+// Seq 返回 downstream 消息在连接上的序号，没有设置时返回 0。
+//
+// 序号由 retransmit.Manager 按连接递增分配 ( 从 1 开始 )，重传时保持不变，
+// 前端可以通过累积 ack 一次确认序号不大于 N 的所有消息。序号只在连接内有效，重新连接后可能重新分配。
+func Seq(msg proto.Message) uint64 {
+	val, _ := getVarint(msg, seqFieldNumber)
+	return val
+}
+
+// SetSeq 设置消息在连接上的序号，seq 为 0 时移除。
+func SetSeq(msg proto.Message, seq uint64) {
+	if seq == 0 {
+		setUnknown(msg, seqFieldNumber, nil)
+		return
+	}
+
+	setUnknown(msg, seqFieldNumber, func(b []byte) []byte {
+		return appendSeq(b, seq)
+	})
+}
+
+// WithSeq 返回携带序号 seq 的消息副本，seq 为 0 时直接返回 msg。
+func WithSeq(msg *messagev1.Message, seq uint64) *messagev1.Message {
+	if seq == 0 {
+		return msg
+	}
+
+	cloned, _ := proto.Clone(msg).(*messagev1.Message)
+	SetSeq(cloned, seq)
+	return cloned
+}
+
+// JSONCodecWithSeq 将序号映射为 json 消息的 seq 字段，使用 json codec 的前端同样可以使用累积 ack。
+func JSONCodecWithSeq() option.Opt[codec.JSONCodec] {
+	return codec.JSONCodecWithVarintField(seqFieldNumber, "seq")
+}
+
+// appendSeq 追加序号字段的原始编码 ( 包括 tag )。
+func appendSeq(b []byte, seq uint64) []byte {
+	b = protowire.AppendTag(b, seqFieldNumber, protowire.VarintType)
+	return protowire.AppendVarint(b, seq)
+}
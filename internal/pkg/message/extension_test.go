@@ -0,0 +1,81 @@
+package message
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jrmarcco/synp"
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/jrmarcco/synp/internal/pkg/codec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestConflationKey(t *testing.T) {
+	t.Parallel()
+
+	msg := &messagev1.PushMessage{MessageId: "m1"}
+	assert.Empty(t, ConflationKey(msg))
+
+	SetConflationKey(msg, "ticker")
+	SetPushPriority(msg, synp.PriorityHigh)
+	SetConflationKey(msg, "score")
+
+	payload, err := proto.Marshal(msg)
+	require.NoError(t, err)
+	decoded := &messagev1.PushMessage{}
+	require.NoError(t, proto.Unmarshal(payload, decoded))
+	assert.Equal(t, "score", ConflationKey(decoded))
+	assert.Equal(t, synp.PriorityHigh, PushPriority(decoded))
+
+	// 移除合并 key 不影响其他扩展字段。
+	SetConflationKey(decoded, "")
+	assert.Empty(t, ConflationKey(decoded))
+	assert.Equal(t, synp.PriorityHigh, PushPriority(decoded))
+}
+
+func TestExpireAt(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	msg := &messagev1.PushMessage{MessageId: "m1"}
+	assert.True(t, ExpireAt(msg).IsZero())
+	assert.False(t, Expired(msg, now))
+
+	SetExpireAt(msg, now.Add(time.Minute))
+	SetConflationKey(msg, "ride")
+
+	payload, err := proto.Marshal(msg)
+	require.NoError(t, err)
+	decoded := &messagev1.PushMessage{}
+	require.NoError(t, proto.Unmarshal(payload, decoded))
+	assert.Equal(t, now.Add(time.Minute).UnixMilli(), ExpireAt(decoded).UnixMilli())
+	assert.False(t, Expired(decoded, now))
+	assert.True(t, Expired(decoded, now.Add(time.Minute)))
+
+	// 零值移除过期时间。
+	SetExpireAt(decoded, time.Time{})
+	assert.True(t, ExpireAt(decoded).IsZero())
+	assert.Equal(t, "ride", ConflationKey(decoded))
+}
+
+func TestSeq_JSONCodec(t *testing.T) {
+	t.Parallel()
+
+	// protojson 不会编码未知字段，序号需要映射为 json 字段。
+	msg := WithSeq(&messagev1.Message{MessageId: "m1"}, 42)
+	payload, err := codec.NewJSONCodec().Marshal(msg)
+	require.NoError(t, err)
+	assert.NotContains(t, string(payload), "seq")
+
+	jsonCodec := codec.NewJSONCodec(JSONCodecWithSeq())
+	payload, err = jsonCodec.Marshal(msg)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"messageId":"m1","seq":42}`, string(payload))
+
+	decoded := &messagev1.Message{}
+	require.NoError(t, jsonCodec.Unmarshal(payload, decoded))
+	assert.Equal(t, "m1", decoded.GetMessageId())
+	assert.Equal(t, uint64(42), Seq(decoded))
+}
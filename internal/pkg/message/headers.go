@@ -0,0 +1,33 @@
+package message
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/jrmarcco/synp"
+	"github.com/jrmarcco/synp/internal/pkg/xmq"
+)
+
+// NewGatewayHeaders 生成网关附加到上行消息的元数据 header。
+// 身份信息取自连接的 session ( 即校验过的 token )，而不是消息 body。
+func NewGatewayHeaders(conn synp.Conn, nodeID, codecName string, receivedAt time.Time) xmq.Headers {
+	user := conn.Session().User()
+
+	headers := xmq.Headers{
+		xmq.HeaderBID:        strconv.FormatUint(user.BID, 10),
+		xmq.HeaderUID:        strconv.FormatUint(user.UID, 10),
+		xmq.HeaderDevice:     string(user.Device),
+		xmq.HeaderConnID:     conn.ID(),
+		xmq.HeaderReceivedAt: strconv.FormatInt(receivedAt.UnixMilli(), 10),
+	}
+	if nodeID != "" {
+		headers[xmq.HeaderNodeID] = nodeID
+	}
+	if user.ClientIP != "" {
+		headers[xmq.HeaderClientIP] = user.ClientIP
+	}
+	if codecName != "" {
+		headers[xmq.HeaderCodec] = codecName
+	}
+	return headers
+}
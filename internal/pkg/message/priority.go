@@ -0,0 +1,60 @@
+package message
+
+import (
+	"fmt"
+
+	"github.com/jrmarcco/synp"
+	commonv1 "github.com/jrmarcco/synp-api/api/go/common/v1"
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// PushPriority 返回消息的优先级，没有设置时返回 synp.PriorityNormal。
+// 后端 ( 业务服务端 ) 只能使用 high 和 normal，更高的优先级会被降为 synp.PriorityHigh，避免业务消息抢占控制帧。
+func PushPriority(msg proto.Message) synp.Priority {
+	val, _ := getVarint(msg, priorityFieldNumber)
+	return synp.Priority(min(val, uint64(synp.PriorityHigh)))
+}
+
+// SetPushPriority 设置消息的优先级，synp.PriorityNormal 为默认值，不会写入消息。
+func SetPushPriority(msg proto.Message, priority synp.Priority) {
+	if priority == synp.PriorityNormal {
+		setUnknown(msg, priorityFieldNumber, nil)
+		return
+	}
+
+	setUnknown(msg, priorityFieldNumber, func(b []byte) []byte {
+		b = protowire.AppendTag(b, priorityFieldNumber, protowire.VarintType)
+		return protowire.AppendVarint(b, uint64(min(priority, synp.PriorityHigh)))
+	})
+}
+
+// ParsePushPriority 解析后端 ( 业务服务端 ) 指定的优先级，取值为 high 或 normal，为空时返回 synp.PriorityNormal。
+func ParsePushPriority(s string) (synp.Priority, error) {
+	switch s {
+	case "", synp.PriorityNormal.String():
+		return synp.PriorityNormal, nil
+	case synp.PriorityHigh.String():
+		return synp.PriorityHigh, nil
+	default:
+		return synp.PriorityNormal, fmt.Errorf("invalid priority %q, must be high or normal", s)
+	}
+}
+
+// PriorityOf 根据指令类型返回消息的发送优先级。
+// downstream 等业务消息使用 PushPriority 返回的优先级。
+func PriorityOf(msg *messagev1.Message) synp.Priority {
+	switch msg.GetCmd() {
+	case commonv1.CommandType_COMMAND_TYPE_HEARTBEAT,
+		commonv1.CommandType_COMMAND_TYPE_REDIRECT,
+		commonv1.CommandType_COMMAND_TYPE_RATE_LIMIT_EXCEEDED:
+		return synp.PriorityControl
+	case commonv1.CommandType_COMMAND_TYPE_UPSTREAM_ACK:
+		return synp.PriorityAck
+	case CommandTypeRPCResponse:
+		return synp.PriorityHigh
+	default:
+		return PushPriority(msg)
+	}
+}
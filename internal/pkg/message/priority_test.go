@@ -0,0 +1,102 @@
+package message
+
+import (
+	"testing"
+
+	"github.com/jrmarcco/synp"
+	commonv1 "github.com/jrmarcco/synp-api/api/go/common/v1"
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestPushPriority(t *testing.T) {
+	t.Parallel()
+
+	// 零值为 normal，没有设置优先级的消息不会被当作控制帧发送。
+	assert.Equal(t, synp.PriorityNormal, synp.Priority(0))
+
+	msg := &messagev1.PushMessage{MessageId: "m1", BizId: 1, ReceiverId: 2}
+	assert.Equal(t, synp.PriorityNormal, PushPriority(msg))
+
+	SetPushPriority(msg, synp.PriorityHigh)
+	assert.Equal(t, synp.PriorityHigh, PushPriority(msg))
+
+	// 优先级在编解码及复制后保留。
+	payload, err := proto.Marshal(msg)
+	require.NoError(t, err)
+	decoded := &messagev1.PushMessage{}
+	require.NoError(t, proto.Unmarshal(payload, decoded))
+	assert.Equal(t, "m1", decoded.GetMessageId())
+	assert.Equal(t, synp.PriorityHigh, PushPriority(decoded))
+	assert.Equal(t, synp.PriorityHigh, PushPriority(proto.Clone(decoded)))
+
+	// 业务消息不能使用比 high 更高的优先级。
+	SetPushPriority(msg, synp.PriorityControl)
+	assert.Equal(t, synp.PriorityHigh, PushPriority(msg))
+	raw := protowire.AppendTag(nil, priorityFieldNumber, protowire.VarintType)
+	raw = protowire.AppendVarint(raw, uint64(synp.PriorityControl))
+	msg.ProtoReflect().SetUnknown(raw)
+	assert.Equal(t, synp.PriorityHigh, PushPriority(msg))
+
+	// 设置为 normal 时移除字段。
+	SetPushPriority(msg, synp.PriorityNormal)
+	assert.Equal(t, synp.PriorityNormal, PushPriority(msg))
+	assert.Empty(t, msg.ProtoReflect().GetUnknown())
+}
+
+func TestPriorityOf(t *testing.T) {
+	t.Parallel()
+
+	tcs := []struct {
+		name string
+		msg  *messagev1.Message
+		want synp.Priority
+	}{
+		{
+			name: "heartbeat",
+			msg:  &messagev1.Message{Cmd: commonv1.CommandType_COMMAND_TYPE_HEARTBEAT},
+			want: synp.PriorityControl,
+		}, {
+			name: "upstream ack",
+			msg:  &messagev1.Message{Cmd: commonv1.CommandType_COMMAND_TYPE_UPSTREAM_ACK},
+			want: synp.PriorityAck,
+		}, {
+			name: "rpc response",
+			msg:  &messagev1.Message{Cmd: CommandTypeRPCResponse},
+			want: synp.PriorityHigh,
+		}, {
+			name: "downstream",
+			msg:  &messagev1.Message{Cmd: commonv1.CommandType_COMMAND_TYPE_DOWNSTREAM},
+			want: synp.PriorityNormal,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, PriorityOf(tc.msg))
+		})
+	}
+
+	msg := &messagev1.Message{Cmd: commonv1.CommandType_COMMAND_TYPE_DOWNSTREAM}
+	SetPushPriority(msg, synp.PriorityHigh)
+	assert.Equal(t, synp.PriorityHigh, PriorityOf(msg))
+}
+
+func TestParsePushPriority(t *testing.T) {
+	t.Parallel()
+
+	p, err := ParsePushPriority("")
+	require.NoError(t, err)
+	assert.Equal(t, synp.PriorityNormal, p)
+
+	p, err = ParsePushPriority("high")
+	require.NoError(t, err)
+	assert.Equal(t, synp.PriorityHigh, p)
+
+	_, err = ParsePushPriority("control")
+	assert.Error(t, err)
+}
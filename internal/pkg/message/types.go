@@ -15,27 +15,31 @@ var ErrMarshalMessage = errors.New("failed to marshal message")
 type PushFunc func(conn synp.Conn, msg *messagev1.Message) error
 
 // DefaultPushFunc 创建默认推送消息到前端 ( 业务客户端 ) 的函数的默认实现，用于将结构化消息通过连接发送。
-// 该函数将消息编码后通过 Conn.Send 发送，适用于 retransmit.Manager 的 taskFunc 参数。
+// 该函数将消息编码后按 PriorityOf 返回的优先级通过 Conn.SendFrame 发送，适用于 retransmit.Manager 的 taskFunc 参数。
+//
+// 消息编码到池化的缓冲区，发送完成后由连接释放。
 //
 // 参数：
-//   - codec: 消息编解码器
+//   - c: 消息编解码器
 //
 // 返回：
 //   - retransmit.TaskFunc: 可用于发送消息和重传的函数
-func DefaultPushFunc(codec codec.Codec) PushFunc {
+func DefaultPushFunc(c codec.Codec) PushFunc {
 	return func(conn synp.Conn, msg *messagev1.Message) error {
-		payload, err := codec.Marshal(msg)
+		buf, err := codec.MarshalBuffer(c, msg)
 		if err != nil {
 			slog.Error(
 				"[synp-message] failed to marshal message",
-				"codec_name", codec.Name(),
+				"codec_name", c.Name(),
 				"message", msg.String(),
 				"error", err,
 			)
 			return fmt.Errorf("failed to marshal message: %w", err)
 		}
 
-		if err = conn.Send(payload); err != nil {
+		frame := newFrame(msg)
+		frame.Buffer = buf
+		if err = conn.SendFrame(frame); err != nil {
 			slog.Error(
 				"[synp-message] failed to send message",
 				"conn_id", conn.ID(),
@@ -46,3 +50,15 @@ func DefaultPushFunc(codec codec.Codec) PushFunc {
 		return nil
 	}
 }
+
+// newFrame 按消息的优先级、合并 key 及过期时间创建 Frame ( 不包括编码结果 )。
+func newFrame(msg *messagev1.Message) synp.Frame {
+	return synp.Frame{
+		Priority: PriorityOf(msg),
+		Key:      msg.GetMessageId(),
+		Message:  msg,
+
+		ConflationKey: ConflationKey(msg),
+		ExpireAt:      ExpireAt(msg),
+	}
+}
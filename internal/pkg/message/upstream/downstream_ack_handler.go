@@ -1,6 +1,9 @@
 package upstream
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 
 	"github.com/jrmarcco/synp"
@@ -9,30 +12,97 @@ import (
 	"github.com/jrmarcco/synp/internal/pkg/retransmit"
 )
 
+// DefaultMaxAckMessageIDs 为单次批量 ack 的最大消息数。
+const DefaultMaxAckMessageIDs = 1000
+
+// AckRequest 为前端批量 ack 及累积 ack 的请求 ( COMMAND_TYPE_DOWNSTREAM_ACK 消息的 body )。
+//
+// body 为空时按单条 ack 处理，消息 ID 即 ack 消息的 message_id ( 兼容旧版本前端 )；
+// 否则 ack 消息的 message_id 只用于去重，确认的消息由 body 指定：
+//
+//  1. MessageIDs 为批量 ack，确认列表中的所有消息；
+//  2. UpToSeq 为累积 ack，确认序号 ( 见 message.Seq，json 格式的消息为 seq 字段 ) 不大于 UpToSeq 的所有消息。
+//
+// 注意：
+//
+//	发送队列按优先级发送，前端收到消息的顺序可能与序号不一致，
+//	前端只能在收到序号不大于 N 的所有消息后累积 ack N，其余消息使用批量 ack。
+//	序号只在连接内有效：重新连接后恢复重传的消息保持原来的序号，
+//	新的消息从恢复的消息的最大序号继续分配，已经 ack 的序号可能被重新使用。
+type AckRequest struct {
+	MessageIDs []string `json:"messageIds,omitempty"`
+	UpToSeq    uint64   `json:"upToSeq,omitempty"`
+}
+
 var _ UMsgHandler = (*DownstreamAckHandler)(nil)
 
 type DownstreamAckHandler struct {
 	retransmitManager *retransmit.Manager
+	listeners         []DownstreamAckListener
 }
 
 func (h *DownstreamAckHandler) Handle(conn synp.Conn, msg *messagev1.Message) error {
+	req, err := h.decode(msg)
+	if err != nil {
+		slog.Warn(
+			"[synp-downstream-ack-handler] invalid downstream ack request",
+			"conn_id", conn.ID(),
+			"message_id", msg.GetMessageId(),
+			"error", err,
+		)
+		return err
+	}
+
 	// 停止向前端推送 downstream 消息的重试。
-	h.retransmitManager.Stop(conn.ID(), msg.MessageId)
+	// 批量 ack 的消息可能还没有在本节点恢复重传，对所有消息通知监听器；
+	// 累积 ack 只能确认本节点上等待 ack 的消息。
+	acked := req.MessageIDs
+	h.retransmitManager.Ack(conn.ID(), req.MessageIDs...)
+	if req.UpToSeq > 0 {
+		acked = append(acked, h.retransmitManager.AckUpTo(conn.ID(), req.UpToSeq)...)
+	}
+
+	for _, id := range acked {
+		for _, listener := range h.listeners {
+			listener.OnDownstreamAck(conn, id)
+		}
+	}
 
 	slog.Debug(
 		"[synp-downstream-ack-handler] received downstream ack message",
 		"conn_id", conn.ID(),
 		"message_id", msg.MessageId,
+		"acked_cnt", len(acked),
+		"up_to_seq", req.UpToSeq,
 	)
 	return nil
 }
 
+func (h *DownstreamAckHandler) decode(msg *messagev1.Message) (*AckRequest, error) {
+	if len(msg.GetBody()) == 0 {
+		return &AckRequest{MessageIDs: []string{msg.GetMessageId()}}, nil
+	}
+
+	req := &AckRequest{}
+	if err := json.Unmarshal(msg.GetBody(), req); err != nil {
+		return nil, fmt.Errorf("invalid ack request: %w", err)
+	}
+	if len(req.MessageIDs) == 0 && req.UpToSeq == 0 {
+		return nil, errors.New("empty ack request")
+	}
+	if len(req.MessageIDs) > DefaultMaxAckMessageIDs {
+		return nil, fmt.Errorf("too many message ids, max %d", DefaultMaxAckMessageIDs)
+	}
+	return req, nil
+}
+
 func (h *DownstreamAckHandler) CmdType() commonv1.CommandType {
 	return commonv1.CommandType_COMMAND_TYPE_DOWNSTREAM_ACK
 }
 
-func NewDownstreamAckHandler(retransmitManager *retransmit.Manager) *DownstreamAckHandler {
+func NewDownstreamAckHandler(retransmitManager *retransmit.Manager, listeners []DownstreamAckListener) *DownstreamAckHandler {
 	return &DownstreamAckHandler{
 		retransmitManager: retransmitManager,
+		listeners:         listeners,
 	}
 }
@@ -0,0 +1,148 @@
+package upstream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jrmarcco/synp"
+	commonv1 "github.com/jrmarcco/synp-api/api/go/common/v1"
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/jrmarcco/synp/internal/pkg/cluster"
+	"github.com/jrmarcco/synp/internal/pkg/limiter"
+	"github.com/jrmarcco/synp/internal/pkg/message"
+	"github.com/jrmarcco/synp/internal/pkg/session"
+)
+
+const (
+	DefaultEphemeralRateLimit  = 5
+	DefaultEphemeralWindow     = time.Second
+	DefaultEphemeralMaxTargets = 20
+	DefaultEphemeralTimeout    = time.Second
+)
+
+// EphemeralRequest 为瞬时信号请求，即 CommandTypeEphemeral 上行消息的 body ( json )。
+type EphemeralRequest struct {
+	ToUIDs []uint64        `json:"toUids"`
+	Kind   string          `json:"kind"` // 信号类型，如 typing、cursor，由业务自定义
+	Data   json.RawMessage `json:"data"`
+}
+
+// EphemeralSignal 为投递给目标用户的瞬时信号，即 CommandTypeEphemeral 下行消息的 body ( json )。
+type EphemeralSignal struct {
+	FromUID    uint64          `json:"fromUid"`
+	FromDevice session.Device  `json:"fromDevice"`
+	Kind       string          `json:"kind"`
+	Data       json.RawMessage `json:"data"`
+	Timestamp  int64           `json:"timestamp"` // 毫秒
+}
+
+var _ UMsgHandler = (*EphemeralMsgHandler)(nil)
+
+// EphemeralMsgHandler 是瞬时信号消息处理器的实现。
+// 瞬时信号由网关直接转发给同一业务下的目标用户，
+// 不经过消息队列，不去重、不持久化、不重传，也不回复 ack。
+//
+// 瞬时信号有独立的限流策略 ( 固定窗口，按连接计数 )，超过限制的信号直接丢弃。
+type EphemeralMsgHandler struct {
+	maxTargets int
+	timeout    time.Duration
+
+	limiter    *limiter.WindowLimiter
+	dispatcher *cluster.Dispatcher
+	pushFunc   message.PushFunc
+}
+
+func (h *EphemeralMsgHandler) Handle(conn synp.Conn, msg *messagev1.Message) error {
+	if !h.limiter.Allow(conn.ID()) {
+		slog.Debug(
+			"[synp-ephemeral-msg-handler] ephemeral signal rate limited",
+			"conn_id", conn.ID(),
+		)
+		// 通知前端限流，通知失败不影响结果。
+		_ = h.pushFunc(conn, &messagev1.Message{
+			MessageId: msg.GetMessageId(),
+			Cmd:       commonv1.CommandType_COMMAND_TYPE_RATE_LIMIT_EXCEEDED,
+		})
+		return synp.ErrRateLimited
+	}
+
+	req := &EphemeralRequest{}
+	if err := json.Unmarshal(msg.GetBody(), req); err != nil {
+		return fmt.Errorf("invalid ephemeral request: %w", err)
+	}
+	if len(req.ToUIDs) == 0 {
+		return nil
+	}
+	if len(req.ToUIDs) > h.maxTargets {
+		return fmt.Errorf("too many ephemeral targets, max %d, got %d", h.maxTargets, len(req.ToUIDs))
+	}
+
+	user := conn.Session().User()
+	body, err := json.Marshal(&EphemeralSignal{
+		FromUID:    user.UID,
+		FromDevice: user.Device,
+		Kind:       req.Kind,
+		Data:       req.Data,
+		Timestamp:  time.Now().UnixMilli(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal ephemeral signal: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+
+	delivered, err := h.dispatcher.Dispatch(ctx, user.BID, req.ToUIDs, &messagev1.Message{
+		MessageId:     msg.GetMessageId(),
+		Cmd:           message.CommandTypeEphemeral,
+		SerializeType: commonv1.SerializeType_SERIALIZE_TYPE_JSON,
+		Body:          body,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to dispatch ephemeral signal: %w", err)
+	}
+
+	slog.Debug(
+		"[synp-ephemeral-msg-handler] ephemeral signal dispatched",
+		"conn_id", conn.ID(),
+		"kind", req.Kind,
+		"target_cnt", len(req.ToUIDs),
+		"local_delivered_cnt", delivered,
+	)
+	return nil
+}
+
+func (h *EphemeralMsgHandler) CmdType() commonv1.CommandType {
+	return message.CommandTypeEphemeral
+}
+
+// NewEphemeralMsgHandler 创建瞬时信号消息处理器。
+// 每个连接在 window 时间内最多发送 rateLimit 个信号，每个信号最多发送给 maxTargets 个用户。
+func NewEphemeralMsgHandler(
+	rateLimit int,
+	window time.Duration,
+	maxTargets int,
+	dispatcher *cluster.Dispatcher,
+	pushFunc message.PushFunc,
+) *EphemeralMsgHandler {
+	if rateLimit <= 0 {
+		rateLimit = DefaultEphemeralRateLimit
+	}
+	if window <= 0 {
+		window = DefaultEphemeralWindow
+	}
+	if maxTargets <= 0 {
+		maxTargets = DefaultEphemeralMaxTargets
+	}
+
+	return &EphemeralMsgHandler{
+		maxTargets: maxTargets,
+		timeout:    DefaultEphemeralTimeout,
+		limiter:    limiter.NewWindowLimiter(rateLimit, window),
+		dispatcher: dispatcher,
+		pushFunc:   pushFunc,
+	}
+}
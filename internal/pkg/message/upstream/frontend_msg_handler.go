@@ -2,27 +2,79 @@ package upstream
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"time"
 
+	"github.com/jrmarcco/jit/bean/option"
+	"github.com/jrmarcco/jit/retry"
+	"github.com/jrmarcco/jit/xsync"
 	"github.com/jrmarcco/synp"
 	commonv1 "github.com/jrmarcco/synp-api/api/go/common/v1"
 	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
 	"github.com/jrmarcco/synp/internal/pkg/codec"
+	"github.com/jrmarcco/synp/internal/pkg/dedup"
 	"github.com/jrmarcco/synp/internal/pkg/message"
+	"github.com/jrmarcco/synp/internal/pkg/route"
 	"github.com/jrmarcco/synp/internal/pkg/xmq"
 	"github.com/jrmarcco/synp/internal/pkg/xmq/produce"
 	"google.golang.org/protobuf/encoding/protojson"
 )
 
-var _ UMsgHandler = (*FrontendMsgHandler)(nil)
+const (
+	DefaultProduceInitRetryInterval = 100 * time.Millisecond
+	DefaultProduceMaxRetryInterval  = time.Second
+	DefaultProduceMaxRetryCount     = 3
+	DefaultOrderFenceTTL            = 30 * time.Second
+	DefaultMaxInFlightPerConn       = 64
+)
+
+var (
+	_ UMsgHandler = (*FrontendMsgHandler)(nil)
+	_ ConnCleaner = (*FrontendMsgHandler)(nil)
+)
+
+// orderFence 记录连接上最终发送失败的消息。
+type orderFence struct {
+	conn      synp.Conn
+	messageID string
+	expireAt  time.Time
+}
 
 // FrontendMsgHandler 是前端消息处理器的实现，用于处理前端 ( 业务客户端 ) 发送的消息。
+//
+// 同步转发时的顺序保证：
+//
+//	同一连接的消息由接收循环串行处理，发送到 kafka 失败时在原地重试 ( 相同的 key )，
+//	重试期间不会处理该连接的后续消息，所以重试不会打乱顺序。
+//	重试达到上限后，该连接会被 "隔离"：在失败的消息重新发送成功 ( 或隔离过期 ) 之前，
+//	后续消息直接回复失败，避免客户端重发失败的消息时排在后续消息之后。
+//
+// 异步转发时：
+//
+//	消息跨连接批量发送，broker 确认后才回复 ack，连接的吞吐不再受 kafka 往返延迟限制。
+//	同一分区的消息仍然保持发送顺序 ( writer 内部重试不会打乱顺序 )，
+//	但消息最终失败时，同一连接中已经在途的后续消息可能先于客户端重发的消息送达。
 type FrontendMsgHandler struct {
-	mqTopic          string        // 消息队列 topic
+	nodeID string // 当前网关节点 ID，附加到消息 header
+
+	router           *route.Router // 消息路由，决定消息发送到哪个 topic
 	onReceiveTimeout time.Duration // 接收消息超时时间
 
+	initRetryInterval time.Duration
+	maxRetryInterval  time.Duration
+	maxRetryCount     int32
+
+	fenceTTL time.Duration
+	fences   *xsync.Map[string, *orderFence] // conn id -> 隔离
+
+	// 异步转发，为空时使用同步转发。
+	asyncProducer produce.AsyncProducer
+	inFlight      *connInFlight
+
+	deduper dedup.Deduper
+
 	codec    codec.Codec
 	producer produce.Producer
 	pushFunc message.PushFunc
@@ -31,19 +83,61 @@ type FrontendMsgHandler struct {
 func (h *FrontendMsgHandler) Handle(conn synp.Conn, msg *messagev1.Message) error {
 	// 接收到前端消息，更新连接活跃时间。
 	conn.UpdateActivityTime()
+	receivedAt := time.Now()
+
+	if err := h.checkFence(conn, msg); err != nil {
+		return h.ack(conn, msg, err)
+	}
+
+	mqMsg, err := h.buildMQMessage(conn, msg, receivedAt)
+	if err != nil {
+		return h.ack(conn, msg, err)
+	}
+
+	// 转发消息到业务服务端。
+	if h.asyncProducer != nil {
+		return h.forwardAsync(conn, msg, mqMsg)
+	}
+	return h.complete(conn, msg, mqMsg, h.produce(conn, mqMsg))
+}
+
+// complete 处理转发结果并回复 ack。
+// 转发失败时隔离连接并取消消息的去重标记，允许客户端重发。
+func (h *FrontendMsgHandler) complete(conn synp.Conn, msg *messagev1.Message, mqMsg *xmq.Message, err error) error {
+	if err == nil {
+		// 无法路由的消息已经发送到死信 topic，重发也无法成功，不需要隔离。
+		return h.ack(conn, msg, deadLetterErr(mqMsg))
+	}
+
+	slog.Error(
+		"[synp-frontend-msg-handler] failed to forward message to backend with messsage queue",
+		"conn_id", conn.ID(),
+		"message_id", msg.GetMessageId(),
+		"topic", mqMsg.Topic,
+		"error", err,
+	)
+
+	h.fences.Store(conn.ID(), &orderFence{
+		conn:      conn,
+		messageID: msg.GetMessageId(),
+		expireAt:  time.Now().Add(h.fenceTTL),
+	})
+	h.unmark(conn, msg)
 
+	return h.ack(conn, msg, fmt.Errorf("failed to forward message: %w", err))
+}
+
+// ack 回复 UPSTREAM_ACK，err 不为空时表示消息处理失败。
+func (h *FrontendMsgHandler) ack(conn synp.Conn, msg *messagev1.Message, err error) error {
 	ackPayload := &messagev1.AckPayload{
 		Success:   true,
 		Timestamp: time.Now().UnixMilli(),
 	}
-
-	// 转发消息到业务服务端。
-	if err := h.forwardToBackend(msg); err != nil {
+	if err != nil {
 		ackPayload.Success = false
 		ackPayload.ErrorMessage = err.Error()
 	}
 
-	// 发送消息到业务服务端。
 	body, err := protojson.Marshal(ackPayload)
 	if err != nil {
 		slog.Error(
@@ -62,9 +156,53 @@ func (h *FrontendMsgHandler) Handle(conn synp.Conn, msg *messagev1.Message) erro
 	})
 }
 
-// forwardToBackend 转发消息到业务服务端。
+func (h *FrontendMsgHandler) unmark(conn synp.Conn, msg *messagev1.Message) {
+	if h.deduper == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.onReceiveTimeout)
+	defer cancel()
+
+	if err := h.deduper.Unmark(ctx, dedup.NewKey(conn, msg.GetMessageId())); err != nil {
+		slog.Error(
+			"[synp-frontend-msg-handler] failed to unmark message",
+			"conn_id", conn.ID(),
+			"message_id", msg.GetMessageId(),
+			"error", err,
+		)
+	}
+}
+
+// checkFence 检查连接是否被隔离，只有失败的消息允许通过。
+func (h *FrontendMsgHandler) checkFence(conn synp.Conn, msg *messagev1.Message) error {
+	fence, ok := h.fences.Load(conn.ID())
+	if !ok {
+		return nil
+	}
+
+	if fence.messageID == msg.GetMessageId() || time.Now().After(fence.expireAt) {
+		h.fences.Delete(conn.ID())
+		return nil
+	}
+	return fmt.Errorf("blocked by failed message %s, resend it first", fence.messageID)
+}
+
+// ClearByConn 在连接断开时删除连接的隔离。
+// 同一设备的新连接使用相同的 conn id，只删除属于该连接的隔离。
+func (h *FrontendMsgHandler) ClearByConn(conn synp.Conn) {
+	if fence, ok := h.fences.Load(conn.ID()); ok && fence.conn == conn {
+		h.fences.Delete(conn.ID())
+	}
+}
+
+// buildMQMessage 生成转发到业务服务端的消息。
 // 通信方式为推送消息到 kafka，由业务服务端订阅并处理。
-func (h *FrontendMsgHandler) forwardToBackend(msg *messagev1.Message) error {
+// 消息根据路由表发送到不同的 topic，无法路由的消息发送到死信 topic，路由错误记录在 HeaderDeadLetterReason 中。
+// 网关元数据 ( 身份信息、节点 ID、接收时间等 ) 通过 kafka header 传递，也可以用于路由。
+func (h *FrontendMsgHandler) buildMQMessage(
+	conn synp.Conn, msg *messagev1.Message, receivedAt time.Time,
+) (*xmq.Message, error) {
 	val, err := protojson.Marshal(msg)
 	if err != nil {
 		slog.Error(
@@ -72,27 +210,184 @@ func (h *FrontendMsgHandler) forwardToBackend(msg *messagev1.Message) error {
 			"message", msg.String(),
 			"error", err,
 		)
-		return fmt.Errorf("failed to marshal message: %w", err)
+		return nil, fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	in := &route.Input{
+		User:    conn.Session().User(),
+		Msg:     msg,
+		Headers: message.NewGatewayHeaders(conn, h.nodeID, h.codec.Name(), receivedAt),
 	}
 
 	mqMsg := &xmq.Message{
-		Topic: h.mqTopic,
-		Key:   []byte(msg.GetMessageId()),
-		Val:   val,
+		Headers: in.Headers,
+		Val:     val,
+	}
+
+	target, routeErr := h.router.Route(in)
+	if routeErr != nil {
+		slog.Warn(
+			"[synp-frontend-msg-handler] failed to route message, send to dead letter",
+			"conn_id", conn.ID(),
+			"message_id", msg.GetMessageId(),
+			"error", routeErr,
+		)
+
+		target = h.router.DeadLetter(in)
+		mqMsg.Headers[route.HeaderDeadLetterReason] = routeErr.Error()
+	}
+	mqMsg.Topic = target.Topic
+	mqMsg.Key = target.Key
+
+	return mqMsg, nil
+}
+
+// deadLetterErr 返回消息无法路由的原因，消息没有发送到死信 topic 时返回 nil。
+func deadLetterErr(mqMsg *xmq.Message) error {
+	reason, ok := mqMsg.Headers[route.HeaderDeadLetterReason]
+	if !ok {
+		return nil
+	}
+	return errors.New(reason)
+}
+
+// forwardAsync 异步转发消息，broker 确认后才回复 ack，期间连接可以继续接收后续消息。
+//
+// 背压：
+//
+//	连接上未完成的消息达到上限时阻塞该连接的消息处理 ( 接收队列满后不再读取 socket，由 TCP 流控传递给客户端 )，
+//	只在该连接的消息处理 goroutine 中等待，不影响其他连接；
+//	生产者全局未完成的消息达到上限时回复 RATE_LIMIT_EXCEEDED，通知客户端降低发送速率。
+func (h *FrontendMsgHandler) forwardAsync(conn synp.Conn, msg *messagev1.Message, mqMsg *xmq.Message) error {
+	if !h.inFlight.acquire(conn) {
+		// 连接已关闭。
+		return nil
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), h.onReceiveTimeout)
 	defer cancel()
 
-	if err := h.producer.Produce(ctx, mqMsg); err != nil {
-		slog.Error(
-			"[synp-frontend-msg-handler] failed to forward message to backend with messsage queue",
+	future, err := h.asyncProducer.ProduceAsync(ctx, mqMsg)
+	if err != nil {
+		h.inFlight.release(conn)
+
+		if errors.Is(err, produce.ErrProducerBusy) {
+			slog.Warn(
+				"[synp-frontend-msg-handler] producer busy, notify client to slow down",
+				"conn_id", conn.ID(),
+				"message_id", msg.GetMessageId(),
+			)
+			// 通知前端限流，通知失败不影响结果。
+			_ = h.pushFunc(conn, &messagev1.Message{
+				MessageId: msg.GetMessageId(),
+				Cmd:       commonv1.CommandType_COMMAND_TYPE_RATE_LIMIT_EXCEEDED,
+			})
+			// 返回 ErrRateLimited，由连接处理器取消去重标记。
+			return fmt.Errorf("%w: %w", synp.ErrRateLimited, err)
+		}
+		return h.complete(conn, msg, mqMsg, err)
+	}
+
+	go func() {
+		defer h.inFlight.release(conn)
+
+		select {
+		case <-future.Done():
+		case <-conn.Closed():
+			return
+		}
+
+		if err := h.complete(conn, msg, mqMsg, future.Err()); err != nil {
+			slog.Error(
+				"[synp-frontend-msg-handler] failed to ack message",
+				"conn_id", conn.ID(),
+				"message_id", msg.GetMessageId(),
+				"error", err,
+			)
+		}
+	}()
+	return nil
+}
+
+// produce 发送消息到 kafka，失败时使用指数退避策略原地重试。
+func (h *FrontendMsgHandler) produce(conn synp.Conn, mqMsg *xmq.Message) error {
+	// 这里可以忽略 error。
+	// 创建 FrontendMsgHandler 的时候就应该确保重试策略的参数正确。
+	retryStrategy, _ := retry.NewExponentialBackoffStrategy(
+		h.initRetryInterval, h.maxRetryInterval, h.maxRetryCount,
+	)
+
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), h.onReceiveTimeout)
+		err := h.producer.Produce(ctx, mqMsg)
+		cancel()
+		if err == nil {
+			return nil
+		}
+
+		duration, ok := retryStrategy.Next()
+		if !ok {
+			return err
+		}
+
+		slog.Warn(
+			"[synp-frontend-msg-handler] failed to produce message, retry later",
+			"conn_id", conn.ID(),
+			"topic", mqMsg.Topic,
+			"retry_after", duration,
 			"error", err,
 		)
-		return fmt.Errorf("failed to forward message: %w", err)
+
+		select {
+		case <-conn.Closed():
+			return err
+		case <-time.After(duration):
+		}
 	}
+}
 
-	return nil
+// FrontendMsgHandlerWithRetry 设置发送到 kafka 失败时的重试策略，maxRetryCount 为 0 时表示不重试。
+func FrontendMsgHandlerWithRetry(initRetryInterval, maxRetryInterval time.Duration, maxRetryCount int32) option.Opt[FrontendMsgHandler] {
+	return func(h *FrontendMsgHandler) {
+		if initRetryInterval > 0 && maxRetryInterval >= initRetryInterval && maxRetryCount >= 0 {
+			h.initRetryInterval = initRetryInterval
+			h.maxRetryInterval = maxRetryInterval
+			h.maxRetryCount = maxRetryCount
+		}
+	}
+}
+
+func FrontendMsgHandlerWithNodeID(nodeID string) option.Opt[FrontendMsgHandler] {
+	return func(h *FrontendMsgHandler) {
+		h.nodeID = nodeID
+	}
+}
+
+// FrontendMsgHandlerWithAsync 开启异步转发，maxInFlightPerConn 为单个连接未完成消息的上限。
+func FrontendMsgHandlerWithAsync(producer produce.AsyncProducer, maxInFlightPerConn int) option.Opt[FrontendMsgHandler] {
+	return func(h *FrontendMsgHandler) {
+		if maxInFlightPerConn <= 0 {
+			maxInFlightPerConn = DefaultMaxInFlightPerConn
+		}
+		h.asyncProducer = producer
+		h.inFlight = newConnInFlight(maxInFlightPerConn)
+	}
+}
+
+// FrontendMsgHandlerWithDeduper 设置去重器，转发失败时取消消息的去重标记。
+func FrontendMsgHandlerWithDeduper(deduper dedup.Deduper) option.Opt[FrontendMsgHandler] {
+	return func(h *FrontendMsgHandler) {
+		h.deduper = deduper
+	}
+}
+
+// FrontendMsgHandlerWithFenceTTL 设置连接隔离的最长时间。
+func FrontendMsgHandlerWithFenceTTL(ttl time.Duration) option.Opt[FrontendMsgHandler] {
+	return func(h *FrontendMsgHandler) {
+		if ttl > 0 {
+			h.fenceTTL = ttl
+		}
+	}
 }
 
 func (h *FrontendMsgHandler) CmdType() commonv1.CommandType {
@@ -100,18 +395,29 @@ func (h *FrontendMsgHandler) CmdType() commonv1.CommandType {
 }
 
 func NewFrontendMsgHandler(
-	mqTopic string,
+	router *route.Router,
 	onReceiveTimeout time.Duration,
 	codec codec.Codec,
 	producer produce.Producer,
 	pushFunc message.PushFunc,
+	opts ...option.Opt[FrontendMsgHandler],
 ) *FrontendMsgHandler {
-	return &FrontendMsgHandler{
-		mqTopic:          mqTopic,
+	h := &FrontendMsgHandler{
+		router:           router,
 		onReceiveTimeout: onReceiveTimeout,
 
+		initRetryInterval: DefaultProduceInitRetryInterval,
+		maxRetryInterval:  DefaultProduceMaxRetryInterval,
+		maxRetryCount:     DefaultProduceMaxRetryCount,
+
+		fenceTTL: DefaultOrderFenceTTL,
+		fences:   &xsync.Map[string, *orderFence]{},
+
 		codec:    codec,
 		producer: producer,
 		pushFunc: pushFunc,
 	}
+
+	option.Apply(h, opts...)
+	return h
 }
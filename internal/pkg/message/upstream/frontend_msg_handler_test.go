@@ -0,0 +1,241 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jrmarcco/synp"
+	commonv1 "github.com/jrmarcco/synp-api/api/go/common/v1"
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/jrmarcco/synp/internal/pkg/codec"
+	"github.com/jrmarcco/synp/internal/pkg/dedup"
+	"github.com/jrmarcco/synp/internal/pkg/route"
+	"github.com/jrmarcco/synp/internal/pkg/session"
+	"github.com/jrmarcco/synp/internal/pkg/xmq"
+	"github.com/jrmarcco/synp/internal/pkg/xmq/produce"
+	synpmock "github.com/jrmarcco/synp/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func TestFrontendMsgHandler_Async(t *testing.T) {
+	t.Parallel()
+
+	router, err := route.NewRouter(route.Config{
+		Default:    route.RuleConfig{Topic: "upstream"},
+		DeadLetter: route.RuleConfig{Topic: "upstream.dlt"},
+	})
+	require.NoError(t, err)
+
+	producer := &testAsyncProducer{maxInFlight: 2}
+	pusher := &testPusher{}
+	deduper := &testDeduper{}
+	h := NewFrontendMsgHandler(
+		router, time.Second, codec.NewJSONCodec(), nil, pusher.push,
+		FrontendMsgHandlerWithNodeID("node-1"),
+		FrontendMsgHandlerWithAsync(producer, 2),
+		FrontendMsgHandlerWithDeduper(deduper),
+	)
+	ctrl := gomock.NewController(t)
+	conn := newTestConn(ctrl)
+
+	// 发送后不等待 broker 确认，确认后才回复 ack。
+	require.NoError(t, h.Handle(conn, &messagev1.Message{MessageId: "m-1"}))
+	require.NoError(t, h.Handle(conn, &messagev1.Message{MessageId: "m-2"}))
+	assert.Empty(t, pusher.messages())
+
+	msgs := producer.messages()
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "1", msgs[0].Headers[xmq.HeaderBID])
+	assert.Equal(t, "node-1", msgs[0].Headers[xmq.HeaderNodeID])
+	assert.Equal(t, "json", msgs[0].Headers[xmq.HeaderCodec])
+
+	// 生产者繁忙时通知客户端限流。
+	err = h.Handle(newTestConn(ctrl), &messagev1.Message{MessageId: "m-3"})
+	require.ErrorIs(t, err, synp.ErrRateLimited)
+	assert.Equal(t, commonv1.CommandType_COMMAND_TYPE_RATE_LIMIT_EXCEEDED, pusher.last(t).GetCmd())
+
+	producer.complete(0, nil)
+	producer.complete(1, errors.New("broker unavailable"))
+	require.Eventually(t, func() bool { return len(pusher.messages()) == 3 }, time.Second, 10*time.Millisecond)
+
+	acks := map[string]bool{}
+	for _, msg := range pusher.messages()[1:] {
+		ack := &messagev1.AckPayload{}
+		require.NoError(t, protojson.Unmarshal(msg.GetBody(), ack))
+		acks[msg.GetMessageId()] = ack.GetSuccess()
+	}
+	assert.Equal(t, map[string]bool{"m-1": true, "m-2": false}, acks)
+
+	// 失败的消息取消去重标记，连接被隔离直到失败的消息重发。
+	assert.Equal(t, []string{"m-2"}, deduper.unmarked())
+	require.NoError(t, h.Handle(conn, &messagev1.Message{MessageId: "m-4"}))
+	ack := &messagev1.AckPayload{}
+	require.NoError(t, protojson.Unmarshal(pusher.last(t).GetBody(), ack))
+	assert.False(t, ack.GetSuccess())
+}
+
+func TestFrontendMsgHandler_DeadLetter(t *testing.T) {
+	t.Parallel()
+
+	// 没有默认路由，消息无法路由。
+	router, err := route.NewRouter(route.Config{
+		DeadLetter: route.RuleConfig{Topic: "upstream.dlt"},
+	})
+	require.NoError(t, err)
+
+	producer := &testAsyncProducer{maxInFlight: 1}
+	pusher := &testPusher{}
+	h := NewFrontendMsgHandler(
+		router, time.Second, codec.NewJSONCodec(), nil, pusher.push,
+		FrontendMsgHandlerWithAsync(producer, 1),
+	)
+	ctrl := gomock.NewController(t)
+	conn := newTestConn(ctrl)
+
+	require.NoError(t, h.Handle(conn, &messagev1.Message{MessageId: "m-1"}))
+	msgs := producer.messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "upstream.dlt", msgs[0].Topic)
+	assert.Contains(t, msgs[0].Headers[route.HeaderDeadLetterReason], route.ErrUnroutable.Error())
+
+	// 发送到死信 topic 后回复失败，但不隔离连接。
+	producer.complete(0, nil)
+	require.Eventually(t, func() bool { return len(pusher.messages()) == 1 }, time.Second, 10*time.Millisecond)
+	ack := &messagev1.AckPayload{}
+	require.NoError(t, protojson.Unmarshal(pusher.last(t).GetBody(), ack))
+	assert.False(t, ack.GetSuccess())
+	assert.Contains(t, ack.GetErrorMessage(), route.ErrUnroutable.Error())
+
+	_, ok := h.fences.Load(conn.ID())
+	assert.False(t, ok)
+}
+
+func TestFrontendMsgHandler_ClearByConn(t *testing.T) {
+	t.Parallel()
+
+	router, err := route.NewRouter(route.Config{
+		Default:    route.RuleConfig{Topic: "upstream"},
+		DeadLetter: route.RuleConfig{Topic: "upstream.dlt"},
+	})
+	require.NoError(t, err)
+
+	producer := &testAsyncProducer{maxInFlight: 1}
+	pusher := &testPusher{}
+	h := NewFrontendMsgHandler(
+		router, time.Second, codec.NewJSONCodec(), nil, pusher.push,
+		FrontendMsgHandlerWithAsync(producer, 1),
+	)
+	ctrl := gomock.NewController(t)
+	conn := newTestConn(ctrl)
+
+	// 发送失败后连接被隔离。
+	require.NoError(t, h.Handle(conn, &messagev1.Message{MessageId: "m-1"}))
+	producer.complete(0, errors.New("broker unavailable"))
+	require.Eventually(t, func() bool {
+		_, ok := h.fences.Load(conn.ID())
+		return ok
+	}, time.Second, 10*time.Millisecond)
+
+	// 同一设备的新连接断开时不删除旧连接的隔离。
+	h.ClearByConn(newTestConn(ctrl))
+	_, ok := h.fences.Load(conn.ID())
+	assert.True(t, ok)
+
+	h.ClearByConn(conn)
+	_, ok = h.fences.Load(conn.ID())
+	assert.False(t, ok)
+}
+
+type testAsyncProducer struct {
+	maxInFlight int
+
+	mu      sync.Mutex
+	msgs    []*xmq.Message
+	futures []*produce.Future
+}
+
+func (p *testAsyncProducer) ProduceAsync(_ context.Context, msg *xmq.Message) (*produce.Future, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.futures) >= p.maxInFlight {
+		return nil, produce.ErrProducerBusy
+	}
+	future := produce.NewFuture()
+	p.msgs = append(p.msgs, msg)
+	p.futures = append(p.futures, future)
+	return future, nil
+}
+
+func (p *testAsyncProducer) complete(i int, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.futures[i].Complete(err)
+}
+
+func (p *testAsyncProducer) messages() []*xmq.Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*xmq.Message(nil), p.msgs...)
+}
+
+type testDeduper struct {
+	mu  sync.Mutex
+	ids []string
+}
+
+func (d *testDeduper) Mark(_ context.Context, _ dedup.Key) (bool, error) {
+	return true, nil
+}
+
+func (d *testDeduper) Unmark(_ context.Context, key dedup.Key) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.ids = append(d.ids, key.MessageID)
+	return nil
+}
+
+func (d *testDeduper) unmarked() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.ids...)
+}
+
+type testPusher struct {
+	mu   sync.Mutex
+	msgs []*messagev1.Message
+}
+
+func (p *testPusher) push(_ synp.Conn, msg *messagev1.Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.msgs = append(p.msgs, msg)
+	return nil
+}
+
+func (p *testPusher) messages() []*messagev1.Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*messagev1.Message(nil), p.msgs...)
+}
+
+func (p *testPusher) last(t *testing.T) *messagev1.Message {
+	t.Helper()
+
+	msgs := p.messages()
+	require.NotEmpty(t, msgs)
+	return msgs[len(msgs)-1]
+}
+
+func newTestConn(ctrl *gomock.Controller) synp.Conn {
+	conn := synpmock.NewUserConn(ctrl, session.User{BID: 1, UID: 1, Device: session.DevicePC})
+	conn.EXPECT().UpdateActivityTime().AnyTimes()
+	conn.EXPECT().Closed().Return(make(chan struct{})).AnyTimes()
+	return conn
+}
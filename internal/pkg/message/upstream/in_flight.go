@@ -0,0 +1,69 @@
+package upstream
+
+import (
+	"sync"
+
+	"github.com/jrmarcco/synp"
+)
+
+// inFlightSem 为单个连接未完成消息的信号量。
+type inFlightSem struct {
+	ch   chan struct{}
+	refs int // 持有及等待信号量的数量，为 0 时删除
+}
+
+// connInFlight 限制每个连接未完成 ( 等待 broker 确认 ) 的消息数量。
+type connInFlight struct {
+	limit int
+
+	mu   sync.Mutex
+	sems map[synp.Conn]*inFlightSem
+}
+
+// acquire 获取信号量，达到上限时阻塞直到有消息完成，连接关闭时返回 false。
+func (c *connInFlight) acquire(conn synp.Conn) bool {
+	c.mu.Lock()
+	sem, ok := c.sems[conn]
+	if !ok {
+		sem = &inFlightSem{ch: make(chan struct{}, c.limit)}
+		c.sems[conn] = sem
+	}
+	sem.refs++
+	c.mu.Unlock()
+
+	select {
+	case sem.ch <- struct{}{}:
+		return true
+	case <-conn.Closed():
+		c.mu.Lock()
+		c.unref(conn, sem)
+		c.mu.Unlock()
+		return false
+	}
+}
+
+func (c *connInFlight) release(conn synp.Conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sem, ok := c.sems[conn]
+	if !ok {
+		return
+	}
+	<-sem.ch
+	c.unref(conn, sem)
+}
+
+func (c *connInFlight) unref(conn synp.Conn, sem *inFlightSem) {
+	sem.refs--
+	if sem.refs == 0 {
+		delete(c.sems, conn)
+	}
+}
+
+func newConnInFlight(limit int) *connInFlight {
+	return &connInFlight{
+		limit: limit,
+		sems:  make(map[synp.Conn]*inFlightSem),
+	}
+}
@@ -0,0 +1,239 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: types.go
+//
+// Generated by this command:
+//
+//	mockgen -source=types.go -destination=mock/upstream.mock.go -package=upstreammock -typed Handler
+//
+
+// Package upstreammock is a generated GoMock package.
+package upstreammock
+
+import (
+	reflect "reflect"
+
+	synp "github.com/jrmarcco/synp"
+	commonv1 "github.com/jrmarcco/synp-api/api/go/common/v1"
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockUMsgHandler is a mock of UMsgHandler interface.
+type MockUMsgHandler struct {
+	ctrl     *gomock.Controller
+	recorder *MockUMsgHandlerMockRecorder
+	isgomock struct{}
+}
+
+// MockUMsgHandlerMockRecorder is the mock recorder for MockUMsgHandler.
+type MockUMsgHandlerMockRecorder struct {
+	mock *MockUMsgHandler
+}
+
+// NewMockUMsgHandler creates a new mock instance.
+func NewMockUMsgHandler(ctrl *gomock.Controller) *MockUMsgHandler {
+	mock := &MockUMsgHandler{ctrl: ctrl}
+	mock.recorder = &MockUMsgHandlerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUMsgHandler) EXPECT() *MockUMsgHandlerMockRecorder {
+	return m.recorder
+}
+
+// CmdType mocks base method.
+func (m *MockUMsgHandler) CmdType() commonv1.CommandType {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CmdType")
+	ret0, _ := ret[0].(commonv1.CommandType)
+	return ret0
+}
+
+// CmdType indicates an expected call of CmdType.
+func (mr *MockUMsgHandlerMockRecorder) CmdType() *MockUMsgHandlerCmdTypeCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CmdType", reflect.TypeOf((*MockUMsgHandler)(nil).CmdType))
+	return &MockUMsgHandlerCmdTypeCall{Call: call}
+}
+
+// MockUMsgHandlerCmdTypeCall wrap *gomock.Call
+type MockUMsgHandlerCmdTypeCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockUMsgHandlerCmdTypeCall) Return(arg0 commonv1.CommandType) *MockUMsgHandlerCmdTypeCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockUMsgHandlerCmdTypeCall) Do(f func() commonv1.CommandType) *MockUMsgHandlerCmdTypeCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockUMsgHandlerCmdTypeCall) DoAndReturn(f func() commonv1.CommandType) *MockUMsgHandlerCmdTypeCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// Handle mocks base method.
+func (m *MockUMsgHandler) Handle(conn synp.Conn, msg *messagev1.Message) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Handle", conn, msg)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Handle indicates an expected call of Handle.
+func (mr *MockUMsgHandlerMockRecorder) Handle(conn, msg any) *MockUMsgHandlerHandleCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Handle", reflect.TypeOf((*MockUMsgHandler)(nil).Handle), conn, msg)
+	return &MockUMsgHandlerHandleCall{Call: call}
+}
+
+// MockUMsgHandlerHandleCall wrap *gomock.Call
+type MockUMsgHandlerHandleCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockUMsgHandlerHandleCall) Return(arg0 error) *MockUMsgHandlerHandleCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockUMsgHandlerHandleCall) Do(f func(synp.Conn, *messagev1.Message) error) *MockUMsgHandlerHandleCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockUMsgHandlerHandleCall) DoAndReturn(f func(synp.Conn, *messagev1.Message) error) *MockUMsgHandlerHandleCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// MockConnCleaner is a mock of ConnCleaner interface.
+type MockConnCleaner struct {
+	ctrl     *gomock.Controller
+	recorder *MockConnCleanerMockRecorder
+	isgomock struct{}
+}
+
+// MockConnCleanerMockRecorder is the mock recorder for MockConnCleaner.
+type MockConnCleanerMockRecorder struct {
+	mock *MockConnCleaner
+}
+
+// NewMockConnCleaner creates a new mock instance.
+func NewMockConnCleaner(ctrl *gomock.Controller) *MockConnCleaner {
+	mock := &MockConnCleaner{ctrl: ctrl}
+	mock.recorder = &MockConnCleanerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockConnCleaner) EXPECT() *MockConnCleanerMockRecorder {
+	return m.recorder
+}
+
+// ClearByConn mocks base method.
+func (m *MockConnCleaner) ClearByConn(conn synp.Conn) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ClearByConn", conn)
+}
+
+// ClearByConn indicates an expected call of ClearByConn.
+func (mr *MockConnCleanerMockRecorder) ClearByConn(conn any) *MockConnCleanerClearByConnCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClearByConn", reflect.TypeOf((*MockConnCleaner)(nil).ClearByConn), conn)
+	return &MockConnCleanerClearByConnCall{Call: call}
+}
+
+// MockConnCleanerClearByConnCall wrap *gomock.Call
+type MockConnCleanerClearByConnCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockConnCleanerClearByConnCall) Return() *MockConnCleanerClearByConnCall {
+	c.Call = c.Call.Return()
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockConnCleanerClearByConnCall) Do(f func(synp.Conn)) *MockConnCleanerClearByConnCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockConnCleanerClearByConnCall) DoAndReturn(f func(synp.Conn)) *MockConnCleanerClearByConnCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// MockDownstreamAckListener is a mock of DownstreamAckListener interface.
+type MockDownstreamAckListener struct {
+	ctrl     *gomock.Controller
+	recorder *MockDownstreamAckListenerMockRecorder
+	isgomock struct{}
+}
+
+// MockDownstreamAckListenerMockRecorder is the mock recorder for MockDownstreamAckListener.
+type MockDownstreamAckListenerMockRecorder struct {
+	mock *MockDownstreamAckListener
+}
+
+// NewMockDownstreamAckListener creates a new mock instance.
+func NewMockDownstreamAckListener(ctrl *gomock.Controller) *MockDownstreamAckListener {
+	mock := &MockDownstreamAckListener{ctrl: ctrl}
+	mock.recorder = &MockDownstreamAckListenerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDownstreamAckListener) EXPECT() *MockDownstreamAckListenerMockRecorder {
+	return m.recorder
+}
+
+// OnDownstreamAck mocks base method.
+func (m *MockDownstreamAckListener) OnDownstreamAck(conn synp.Conn, messageID string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnDownstreamAck", conn, messageID)
+}
+
+// OnDownstreamAck indicates an expected call of OnDownstreamAck.
+func (mr *MockDownstreamAckListenerMockRecorder) OnDownstreamAck(conn, messageID any) *MockDownstreamAckListenerOnDownstreamAckCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnDownstreamAck", reflect.TypeOf((*MockDownstreamAckListener)(nil).OnDownstreamAck), conn, messageID)
+	return &MockDownstreamAckListenerOnDownstreamAckCall{Call: call}
+}
+
+// MockDownstreamAckListenerOnDownstreamAckCall wrap *gomock.Call
+type MockDownstreamAckListenerOnDownstreamAckCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockDownstreamAckListenerOnDownstreamAckCall) Return() *MockDownstreamAckListenerOnDownstreamAckCall {
+	c.Call = c.Call.Return()
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockDownstreamAckListenerOnDownstreamAckCall) Do(f func(synp.Conn, string)) *MockDownstreamAckListenerOnDownstreamAckCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockDownstreamAckListenerOnDownstreamAckCall) DoAndReturn(f func(synp.Conn, string)) *MockDownstreamAckListenerOnDownstreamAckCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
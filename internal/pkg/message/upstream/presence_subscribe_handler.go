@@ -0,0 +1,79 @@
+package upstream
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jrmarcco/synp"
+	commonv1 "github.com/jrmarcco/synp-api/api/go/common/v1"
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/jrmarcco/synp/internal/pkg/message"
+	"github.com/jrmarcco/synp/internal/pkg/presence"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+var _ UMsgHandler = (*PresenceSubscribeHandler)(nil)
+
+// PresenceSubscribeHandler 是在线状态订阅消息处理器的实现。
+// 前端 ( 业务客户端 ) 订阅同一业务下的用户列表，网关在这些用户上下线时推送通知。
+type PresenceSubscribeHandler struct {
+	hub      *presence.Hub
+	pushFunc message.PushFunc
+}
+
+func (h *PresenceSubscribeHandler) Handle(conn synp.Conn, msg *messagev1.Message) error {
+	conn.UpdateActivityTime()
+
+	ackPayload := &messagev1.AckPayload{
+		Success:   true,
+		Timestamp: time.Now().UnixMilli(),
+	}
+
+	uids, err := h.subscribe(conn, msg)
+	if err != nil {
+		slog.Warn(
+			"[synp-presence-subscribe-handler] failed to subscribe presence",
+			"conn_id", conn.ID(),
+			"message_id", msg.GetMessageId(),
+			"error", err,
+		)
+		ackPayload.Success = false
+		ackPayload.ErrorMessage = err.Error()
+	}
+
+	// 先回复 ack，再推送在线状态快照。
+	body, err := protojson.Marshal(ackPayload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal ack payload: %w", err)
+	}
+	if err = h.pushFunc(conn, &messagev1.Message{
+		MessageId: msg.GetMessageId(),
+		Cmd:       commonv1.CommandType_COMMAND_TYPE_UPSTREAM_ACK,
+		Body:      body,
+	}); err != nil {
+		return err
+	}
+
+	return h.hub.Snapshot(conn, uids)
+}
+
+func (h *PresenceSubscribeHandler) subscribe(conn synp.Conn, msg *messagev1.Message) ([]uint64, error) {
+	req := &presence.SubscribeRequest{}
+	if err := json.Unmarshal(msg.GetBody(), req); err != nil {
+		return nil, fmt.Errorf("invalid subscribe request: %w", err)
+	}
+	return h.hub.Subscribe(conn, req.UIDs)
+}
+
+func (h *PresenceSubscribeHandler) CmdType() commonv1.CommandType {
+	return message.CommandTypePresenceSubscribe
+}
+
+func NewPresenceSubscribeHandler(hub *presence.Hub, pushFunc message.PushFunc) *PresenceSubscribeHandler {
+	return &PresenceSubscribeHandler{
+		hub:      hub,
+		pushFunc: pushFunc,
+	}
+}
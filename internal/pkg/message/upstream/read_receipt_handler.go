@@ -0,0 +1,86 @@
+package upstream
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jrmarcco/synp"
+	commonv1 "github.com/jrmarcco/synp-api/api/go/common/v1"
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/jrmarcco/synp/internal/pkg/message"
+	"github.com/jrmarcco/synp/internal/pkg/receipt"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// DefaultMaxReadMessageIDs 为单次上报已读的最大消息数。
+const DefaultMaxReadMessageIDs = 100
+
+var _ UMsgHandler = (*ReadReceiptHandler)(nil)
+
+// ReadReceiptHandler 是已读回执消息处理器的实现。
+// 前端上报已读的消息 ID，网关以 read 回执的形式发布给后端。
+type ReadReceiptHandler struct {
+	reporter *receipt.Reporter
+	pushFunc message.PushFunc
+}
+
+func (h *ReadReceiptHandler) Handle(conn synp.Conn, msg *messagev1.Message) error {
+	conn.UpdateActivityTime()
+
+	ackPayload := &messagev1.AckPayload{
+		Success:   true,
+		Timestamp: time.Now().UnixMilli(),
+	}
+
+	req, err := h.decode(msg)
+	if err != nil {
+		slog.Warn(
+			"[synp-read-receipt-handler] invalid read request",
+			"conn_id", conn.ID(),
+			"message_id", msg.GetMessageId(),
+			"error", err,
+		)
+		ackPayload.Success = false
+		ackPayload.ErrorMessage = err.Error()
+	} else {
+		h.reporter.Read(conn, req.MessageIDs)
+	}
+
+	body, err := protojson.Marshal(ackPayload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal ack payload: %w", err)
+	}
+	return h.pushFunc(conn, &messagev1.Message{
+		MessageId: msg.GetMessageId(),
+		Cmd:       commonv1.CommandType_COMMAND_TYPE_UPSTREAM_ACK,
+		Body:      body,
+	})
+}
+
+func (h *ReadReceiptHandler) decode(msg *messagev1.Message) (*receipt.ReadRequest, error) {
+	req := &receipt.ReadRequest{}
+	if err := json.Unmarshal(msg.GetBody(), req); err != nil {
+		return nil, fmt.Errorf("invalid read request: %w", err)
+	}
+	if len(req.MessageIDs) == 0 {
+		return nil, errors.New("empty message ids")
+	}
+	if len(req.MessageIDs) > DefaultMaxReadMessageIDs {
+		return nil, fmt.Errorf("too many message ids, max %d", DefaultMaxReadMessageIDs)
+	}
+	return req, nil
+}
+
+func (h *ReadReceiptHandler) CmdType() commonv1.CommandType {
+	return message.CommandTypeRead
+}
+
+func NewReadReceiptHandler(reporter *receipt.Reporter, pushFunc message.PushFunc) *ReadReceiptHandler {
+	return &ReadReceiptHandler{
+		reporter: reporter,
+		pushFunc: pushFunc,
+	}
+}
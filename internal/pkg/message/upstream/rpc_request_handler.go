@@ -0,0 +1,32 @@
+package upstream
+
+import (
+	"github.com/jrmarcco/synp"
+	commonv1 "github.com/jrmarcco/synp-api/api/go/common/v1"
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/jrmarcco/synp/internal/pkg/message"
+	"github.com/jrmarcco/synp/internal/pkg/rpc"
+)
+
+var _ UMsgHandler = (*RPCRequestHandler)(nil)
+
+// RPCRequestHandler 是 RPC 请求消息处理器的实现。
+// 与 FrontendMsgHandler 不同，前端收到的不是 UPSTREAM_ACK，而是业务服务端的响应 ( CommandTypeRPCResponse )。
+type RPCRequestHandler struct {
+	manager *rpc.Manager
+}
+
+func (h *RPCRequestHandler) Handle(conn synp.Conn, msg *messagev1.Message) error {
+	conn.UpdateActivityTime()
+	return h.manager.Call(conn, msg)
+}
+
+func (h *RPCRequestHandler) CmdType() commonv1.CommandType {
+	return message.CommandTypeRPCRequest
+}
+
+func NewRPCRequestHandler(manager *rpc.Manager) *RPCRequestHandler {
+	return &RPCRequestHandler{
+		manager: manager,
+	}
+}
@@ -18,3 +18,15 @@ type UMsgHandler interface {
 	// CmdType 返回消息类型。
 	CmdType() commonv1.CommandType
 }
+
+// ConnCleaner 为 UMsgHandler 的可选接口，保存了连接相关状态的处理器需要实现，在连接断开时清理。
+type ConnCleaner interface {
+	ClearByConn(conn synp.Conn)
+}
+
+// DownstreamAckListener 监听前端对 downstream 消息的 ack。
+type DownstreamAckListener interface {
+	// OnDownstreamAck 在收到 ack 并停止重传后调用，不能阻塞。
+	// 批量 ack 时对每条消息分别调用，累积 ack 时只对本节点上等待 ack 的消息调用。
+	OnDownstreamAck(conn synp.Conn, messageID string)
+}
@@ -0,0 +1,90 @@
+// Package netpoll 提供基于 epoll 的连接可读事件通知及执行事件回调的 worker 池 ( 仅支持 Linux )。
+//
+// 连接注册后只在可读 ( 或对端关闭 ) 时通知一次，处理完成后需要调用 Poller.Resume 重新注册，
+// 这样同一连接的事件不会被并发处理，也不需要为每个连接保留一个阻塞读取的 goroutine。
+package netpoll
+
+import (
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrNotSupported 表示当前平台或连接不支持事件通知 ( 如非 Linux 平台、TLS 连接 )。
+	ErrNotSupported = errors.New("netpoll: not supported")
+	// ErrClosed 表示 Poller 或 Pool 已经关闭。
+	ErrClosed = errors.New("netpoll: closed")
+)
+
+// Event 为连接上发生的事件。
+type Event uint8
+
+const (
+	// EventRead 表示连接可读。
+	EventRead Event = 1 << iota
+	// EventHup 表示对端关闭连接或连接出错，此时读取会立即返回错误。
+	EventHup
+)
+
+// Desc 为注册到 Poller 的连接描述。
+type Desc struct {
+	fd  int
+	gen int32 // 注册序号，区分文件描述符被关闭后复用的不同连接
+}
+
+// Pool 为固定数量 goroutine 的 worker 池，用于执行连接的读写任务。
+type Pool struct {
+	mu     sync.RWMutex
+	closed bool
+
+	tasks chan func()
+	wg    sync.WaitGroup
+}
+
+// Submit 提交任务，任务队列已满时阻塞等待。
+func (p *Pool) Submit(task func()) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return ErrClosed
+	}
+	p.tasks <- task
+	return nil
+}
+
+// Close 拒绝新任务，等待已经提交的任务执行完成。
+func (p *Pool) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.tasks)
+	p.mu.Unlock()
+
+	p.wg.Wait()
+}
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+
+	for task := range p.tasks {
+		task()
+	}
+}
+
+// NewPool 创建 workers 个 goroutine 的 worker 池，queueSize 为等待执行的任务队列大小。
+func NewPool(workers, queueSize int) *Pool {
+	p := &Pool{
+		tasks: make(chan func(), max(queueSize, 0)),
+	}
+
+	workers = max(workers, 1)
+	p.wg.Add(workers)
+	for range workers {
+		go p.work()
+	}
+	return p
+}
@@ -0,0 +1,203 @@
+//go:build linux
+
+package netpoll
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"syscall"
+)
+
+const (
+	maxEvents = 256 // 每次 epoll_wait 最多返回的事件数
+
+	readEvents = syscall.EPOLLIN | syscall.EPOLLRDHUP | syscall.EPOLLONESHOT
+	hupEvents  = syscall.EPOLLRDHUP | syscall.EPOLLHUP | syscall.EPOLLERR
+)
+
+// Poller 为基于 epoll 的可读事件通知器。
+// 事件回调在 Poller 的事件 goroutine 中执行，回调中不能阻塞 ( 通常提交给 Pool 处理 )。
+type Poller struct {
+	epfd int
+	wake [2]int // 关闭时通过管道唤醒 epoll_wait
+
+	mu       sync.Mutex
+	closed   bool
+	gen      int32
+	handlers map[int]handler // fd -> handler
+
+	done chan struct{}
+}
+
+type handler struct {
+	gen int32
+	fn  func(Event)
+}
+
+// Add 注册连接的可读事件，连接可读或对端关闭时调用一次 fn。
+// 处理完成后需要调用 Resume 才会再次通知，连接关闭前需要调用 Remove。
+func (p *Poller) Add(conn net.Conn, fn func(Event)) (*Desc, error) {
+	connFd, err := fd(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil, ErrClosed
+	}
+
+	p.gen++
+	desc := &Desc{fd: connFd, gen: p.gen}
+	if err = p.ctl(syscall.EPOLL_CTL_ADD, desc); err != nil {
+		return nil, err
+	}
+	p.handlers[connFd] = handler{gen: desc.gen, fn: fn}
+	return desc, nil
+}
+
+// Resume 重新注册连接的可读事件，连接已经删除时返回 ErrClosed。
+func (p *Poller) Resume(desc *Desc) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// 文件描述符可能已经被新连接复用，不能修改新连接的注册。
+	if h, ok := p.handlers[desc.fd]; !ok || h.gen != desc.gen {
+		return ErrClosed
+	}
+	return p.ctl(syscall.EPOLL_CTL_MOD, desc)
+}
+
+// Remove 删除连接的注册，删除后不会再调用事件回调 ( 正在执行的回调除外 )。
+func (p *Poller) Remove(desc *Desc) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if h, ok := p.handlers[desc.fd]; !ok || h.gen != desc.gen {
+		return nil
+	}
+	delete(p.handlers, desc.fd)
+	return p.ctl(syscall.EPOLL_CTL_DEL, desc)
+}
+
+// Close 关闭 Poller，等待事件 goroutine 退出。
+func (p *Poller) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	clear(p.handlers)
+	p.mu.Unlock()
+
+	_, err := syscall.Write(p.wake[1], []byte{0})
+	<-p.done
+
+	return errors.Join(
+		err,
+		syscall.Close(p.wake[0]),
+		syscall.Close(p.wake[1]),
+		syscall.Close(p.epfd),
+	)
+}
+
+func (p *Poller) ctl(op int, desc *Desc) error {
+	event := &syscall.EpollEvent{Events: readEvents, Fd: int32(desc.fd), Pad: desc.gen}
+	if err := syscall.EpollCtl(p.epfd, op, desc.fd, event); err != nil {
+		return &net.OpError{Op: "epoll_ctl", Net: "netpoll", Err: err}
+	}
+	return nil
+}
+
+func (p *Poller) wait() {
+	defer close(p.done)
+
+	events := make([]syscall.EpollEvent, maxEvents)
+	for {
+		n, err := syscall.EpollWait(p.epfd, events, -1)
+		if err != nil {
+			if errors.Is(err, syscall.EINTR) {
+				continue
+			}
+			return
+		}
+
+		for i := range n {
+			event := events[i]
+			if int(event.Fd) == p.wake[0] {
+				return
+			}
+
+			// 同一批事件中的连接可能已经删除，文件描述符也可能已经被新连接复用。
+			p.mu.Lock()
+			h, ok := p.handlers[int(event.Fd)]
+			p.mu.Unlock()
+			if !ok || h.gen != event.Pad {
+				continue
+			}
+
+			var ev Event
+			if event.Events&syscall.EPOLLIN != 0 {
+				ev |= EventRead
+			}
+			if event.Events&hupEvents != 0 {
+				ev |= EventHup
+			}
+			h.fn(ev)
+		}
+	}
+}
+
+// fd 返回连接的文件描述符，文件描述符仍然由 conn 持有，连接关闭前需要从 Poller 中删除。
+func fd(conn net.Conn) (int, error) {
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return 0, ErrNotSupported
+	}
+
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+
+	var rtn int
+	if err = raw.Control(func(fd uintptr) {
+		rtn = int(fd)
+	}); err != nil {
+		return 0, err
+	}
+	return rtn, nil
+}
+
+// New 创建 Poller 并启动事件 goroutine。
+func New() (*Poller, error) {
+	epfd, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Poller{
+		epfd:     epfd,
+		handlers: make(map[int]handler),
+		done:     make(chan struct{}),
+	}
+	if err = syscall.Pipe2(p.wake[:], syscall.O_NONBLOCK|syscall.O_CLOEXEC); err != nil {
+		_ = syscall.Close(epfd)
+		return nil, err
+	}
+
+	event := &syscall.EpollEvent{Events: syscall.EPOLLIN, Fd: int32(p.wake[0])}
+	if err = syscall.EpollCtl(epfd, syscall.EPOLL_CTL_ADD, p.wake[0], event); err != nil {
+		_ = syscall.Close(p.wake[0])
+		_ = syscall.Close(p.wake[1])
+		_ = syscall.Close(epfd)
+		return nil, err
+	}
+
+	go p.wait()
+	return p, nil
+}
@@ -0,0 +1,104 @@
+//go:build linux
+
+package netpoll
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoller(t *testing.T) {
+	t.Parallel()
+
+	p, err := New()
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, p.Close())
+	}()
+
+	client, server := tcpPair(t)
+
+	events := make(chan Event, 4)
+	desc, err := p.Add(server, func(ev Event) {
+		events <- ev
+	})
+	require.NoError(t, err)
+
+	// 可读时只通知一次，Resume 之前不会再次通知。
+	_, err = client.Write([]byte("a"))
+	require.NoError(t, err)
+	assert.Equal(t, EventRead, <-events)
+
+	_, err = client.Write([]byte("b"))
+	require.NoError(t, err)
+	assertNoEvent(t, events)
+
+	// 数据没有读取，Resume 后立即通知。
+	require.NoError(t, p.Resume(desc))
+	assert.Equal(t, EventRead, <-events)
+
+	buf := make([]byte, 2)
+	_, err = server.Read(buf)
+	require.NoError(t, err)
+
+	// 对端关闭连接。
+	require.NoError(t, client.Close())
+	require.NoError(t, p.Resume(desc))
+	assert.NotZero(t, <-events&EventHup)
+
+	// 删除后不再通知，Resume 返回 ErrClosed。
+	require.NoError(t, p.Remove(desc))
+	require.ErrorIs(t, p.Resume(desc), ErrClosed)
+	assertNoEvent(t, events)
+}
+
+func TestPool(t *testing.T) {
+	t.Parallel()
+
+	p := NewPool(4, 0)
+
+	done := make(chan int, 16)
+	for i := range 16 {
+		require.NoError(t, p.Submit(func() {
+			done <- i
+		}))
+	}
+
+	// Close 等待已经提交的任务完成。
+	p.Close()
+	assert.Len(t, done, 16)
+	assert.ErrorIs(t, p.Submit(func() {}), ErrClosed)
+}
+
+func tcpPair(t *testing.T) (net.Conn, net.Conn) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	client, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	server, err := ln.Accept()
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = client.Close()
+		_ = server.Close()
+	})
+	return client, server
+}
+
+func assertNoEvent(t *testing.T, events <-chan Event) {
+	t.Helper()
+
+	select {
+	case ev := <-events:
+		assert.Failf(t, "unexpected event", "event: %d", ev)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,28 @@
+//go:build !linux
+
+package netpoll
+
+import "net"
+
+// Poller 在非 Linux 平台上不可用，New 总是返回 ErrNotSupported。
+type Poller struct{}
+
+func (p *Poller) Add(net.Conn, func(Event)) (*Desc, error) {
+	return nil, ErrNotSupported
+}
+
+func (p *Poller) Resume(*Desc) error {
+	return ErrNotSupported
+}
+
+func (p *Poller) Remove(*Desc) error {
+	return ErrNotSupported
+}
+
+func (p *Poller) Close() error {
+	return nil
+}
+
+func New() (*Poller, error) {
+	return nil, ErrNotSupported
+}
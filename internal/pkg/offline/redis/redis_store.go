@@ -0,0 +1,112 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/jrmarcco/synp/internal/pkg/offline"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	DefaultMaxMessages = 1000
+	DefaultTTL         = 7 * 24 * time.Hour
+)
+
+var _ offline.Store = (*Store)(nil)
+
+// Store 为离线消息存储的 Redis 实现。
+// 每个用户的离线消息存储为一个 list，每次保存时刷新过期时间：
+//
+//	synp:offline:{bid}:{uid} -> [messagev1.PushMessage ( protobuf ), ...]
+//
+// 使用 protobuf 二进制编码，保留网关扩展字段 ( 优先级、合并 key、过期时间等 )，
+// 兼容之前以 protojson 编码保存的离线消息。
+type Store struct {
+	rdb redis.Cmdable
+
+	maxMessages int64
+	ttl         time.Duration
+}
+
+func (s *Store) Save(ctx context.Context, msg *messagev1.PushMessage) error {
+	val, err := proto.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal offline message: %w", err)
+	}
+
+	key := s.key(msg.GetBizId(), msg.GetReceiverId())
+	_, err = s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.RPush(ctx, key, val)
+		pipe.LTrim(ctx, key, -s.maxMessages, -1)
+		pipe.Expire(ctx, key, s.ttl)
+		return nil
+	})
+	return err
+}
+
+func (s *Store) Take(ctx context.Context, bid, uid uint64, limit int) ([]*messagev1.PushMessage, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	key := s.key(bid, uid)
+
+	var rangeCmd *redis.StringSliceCmd
+	_, err := s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		rangeCmd = pipe.LRange(ctx, key, 0, int64(limit-1))
+		pipe.LTrim(ctx, key, int64(limit), -1)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	vals := rangeCmd.Val()
+	msgs := make([]*messagev1.PushMessage, 0, len(vals))
+	for _, val := range vals {
+		msg, err := decode(val)
+		if err != nil {
+			// 格式错误的消息无法推送，跳过。
+			slog.Warn("[synp-offline-store] invalid offline message", "key", key, "error", err)
+			continue
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+// decode 解码离线消息，以 '{' 开头的是之前以 protojson 编码保存的消息。
+func decode(val string) (*messagev1.PushMessage, error) {
+	msg := &messagev1.PushMessage{}
+	if strings.HasPrefix(val, "{") {
+		return msg, protojson.Unmarshal([]byte(val), msg)
+	}
+	return msg, proto.Unmarshal([]byte(val), msg)
+}
+
+func (s *Store) key(bid, uid uint64) string {
+	return fmt.Sprintf("synp:offline:%d:%d", bid, uid)
+}
+
+// NewStore 创建离线消息存储，单个用户最多保存 maxMessages 条离线消息，保存 ttl 时间。
+func NewStore(rdb redis.Cmdable, maxMessages int64, ttl time.Duration) *Store {
+	if maxMessages <= 0 {
+		maxMessages = DefaultMaxMessages
+	}
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+
+	return &Store{
+		rdb:         rdb,
+		maxMessages: maxMessages,
+		ttl:         ttl,
+	}
+}
@@ -0,0 +1,17 @@
+package offline
+
+import (
+	"context"
+
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+)
+
+// Store 为离线消息存储。
+// 推送给不在线 ( 不在本节点 ) 用户的消息会被保存，用户下次连接时按保存顺序重新推送。
+type Store interface {
+	// Save 保存离线消息，接收者由消息的 BizId 和 ReceiverId 决定。
+	// 单个用户的离线消息超过上限时丢弃最早的消息。
+	Save(ctx context.Context, msg *messagev1.PushMessage) error
+	// Take 按保存顺序取出并删除用户的离线消息，最多 limit 条。
+	Take(ctx context.Context, bid, uid uint64, limit int) ([]*messagev1.PushMessage, error)
+}
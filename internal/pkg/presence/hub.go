@@ -0,0 +1,309 @@
+package presence
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jrmarcco/jit/bean/option"
+	"github.com/jrmarcco/synp"
+	commonv1 "github.com/jrmarcco/synp-api/api/go/common/v1"
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/jrmarcco/synp/internal/pkg/message"
+	"github.com/jrmarcco/synp/internal/pkg/session"
+)
+
+const (
+	DefaultMaxSubscriptions = 200
+	DefaultCoalesceWindow   = time.Second
+)
+
+var ErrTooManySubscriptions = errors.New("too many presence subscriptions")
+
+// SubscribeRequest 为在线状态订阅请求，即 CommandTypePresenceSubscribe 消息的 body ( json )。
+// 每次订阅会替换连接之前的订阅列表，uids 为空表示取消订阅。
+type SubscribeRequest struct {
+	UIDs []uint64 `json:"uids"`
+}
+
+// Status 为用户的在线状态。
+type Status struct {
+	UID       uint64           `json:"uid"`
+	Online    bool             `json:"online"`
+	Devices   []session.Device `json:"devices"`
+	Timestamp int64            `json:"timestamp"` // 毫秒
+}
+
+// Notification 为在线状态变更通知，即 CommandTypePresenceNotify 消息的 body ( json )。
+type Notification struct {
+	BID      uint64   `json:"bid"`
+	Statuses []Status `json:"statuses"`
+}
+
+// target 为被订阅的用户。
+type target struct {
+	bid uint64
+	uid uint64
+}
+
+// Hub 负责管理前端 ( 业务客户端 ) 的在线状态订阅，并将在线状态变更推送给订阅者。
+//
+// 合并 ( coalesce ):
+//
+//	同一用户的状态变更会在 coalesceWindow 内合并，只推送窗口结束时的最终状态。
+//	如果最终状态与上一次推送的状态一致 ( 如快速下线又上线 )，则不推送。
+type Hub struct {
+	store         Store
+	broadcastFunc message.BroadcastFunc
+
+	maxSubscriptions int
+	coalesceWindow   time.Duration
+	requestTimeout   time.Duration
+
+	mu            sync.RWMutex
+	subscribers   map[target]map[synp.Conn]struct{}
+	subscriptions map[synp.Conn][]uint64
+
+	pendingMu sync.Mutex
+	pendings  map[target]*Status // 合并窗口内的最新状态
+	notified  map[target]*Status // 上一次推送的状态
+}
+
+// Subscribe 替换连接的订阅列表，返回去重后的订阅列表。
+func (h *Hub) Subscribe(conn synp.Conn, uids []uint64) ([]uint64, error) {
+	uids = slices.Compact(slices.Sorted(slices.Values(uids)))
+	if len(uids) > h.maxSubscriptions {
+		return nil, fmt.Errorf("%w: max %d, got %d", ErrTooManySubscriptions, h.maxSubscriptions, len(uids))
+	}
+
+	bid := conn.Session().User().BID
+
+	h.mu.Lock()
+	h.unsubscribeLocked(conn, bid)
+	if len(uids) > 0 {
+		h.subscriptions[conn] = uids
+		for _, uid := range uids {
+			t := target{bid: bid, uid: uid}
+			conns, ok := h.subscribers[t]
+			if !ok {
+				conns = make(map[synp.Conn]struct{})
+				h.subscribers[t] = conns
+			}
+			conns[conn] = struct{}{}
+		}
+	}
+	h.mu.Unlock()
+
+	return uids, nil
+}
+
+// Snapshot 向连接推送指定用户的当前在线状态。
+func (h *Hub) Snapshot(conn synp.Conn, uids []uint64) error {
+	if len(uids) == 0 {
+		return nil
+	}
+
+	bid := conn.Session().User().BID
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.requestTimeout)
+	defer cancel()
+
+	presences, err := h.store.BatchQuery(ctx, bid, uids)
+	if err != nil {
+		return fmt.Errorf("failed to query presence snapshot: %w", err)
+	}
+
+	now := time.Now().UnixMilli()
+	statuses := make([]Status, 0, len(presences))
+	for _, p := range presences {
+		statuses = append(statuses, Status{
+			UID:       p.UID,
+			Online:    p.Online,
+			Devices:   deviceList(p.Devices),
+			Timestamp: now,
+		})
+	}
+	return h.push([]synp.Conn{conn}, &Notification{BID: bid, Statuses: statuses})
+}
+
+// Unsubscribe 取消连接的所有订阅，通常在连接断开时调用。
+func (h *Hub) Unsubscribe(conn synp.Conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.unsubscribeLocked(conn, conn.Session().User().BID)
+}
+
+func (h *Hub) unsubscribeLocked(conn synp.Conn, bid uint64) {
+	uids, ok := h.subscriptions[conn]
+	if !ok {
+		return
+	}
+	delete(h.subscriptions, conn)
+
+	for _, uid := range uids {
+		t := target{bid: bid, uid: uid}
+		conns := h.subscribers[t]
+		delete(conns, conn)
+		if len(conns) == 0 {
+			delete(h.subscribers, t)
+
+			h.pendingMu.Lock()
+			delete(h.notified, t)
+			h.pendingMu.Unlock()
+		}
+	}
+}
+
+// Notify 处理在线状态事件，事件来自集群内任意网关节点。
+func (h *Hub) Notify(event *Event) {
+	t := target{bid: event.BID, uid: event.UID}
+
+	h.mu.RLock()
+	_, ok := h.subscribers[t]
+	h.mu.RUnlock()
+	if !ok {
+		// 没有订阅者。
+		return
+	}
+
+	// 排序后便于比较状态是否变化。
+	devices := slices.Clone(event.Devices)
+	slices.Sort(devices)
+
+	status := &Status{
+		UID:       event.UID,
+		Online:    event.Type != EventOffline,
+		Devices:   devices,
+		Timestamp: event.Timestamp,
+	}
+
+	h.pendingMu.Lock()
+	defer h.pendingMu.Unlock()
+
+	if _, ok := h.pendings[t]; ok {
+		// 合并窗口内，只保留最新状态。
+		h.pendings[t] = status
+		return
+	}
+
+	h.pendings[t] = status
+	time.AfterFunc(h.coalesceWindow, func() {
+		h.flush(t)
+	})
+}
+
+// Consume 解析并处理消息队列中的在线状态事件 ( json )。
+func (h *Hub) Consume(val []byte) error {
+	event := &Event{}
+	if err := json.Unmarshal(val, event); err != nil {
+		return fmt.Errorf("failed to unmarshal presence event: %w", err)
+	}
+	h.Notify(event)
+	return nil
+}
+
+// flush 在合并窗口结束后推送最终状态。
+func (h *Hub) flush(t target) {
+	h.pendingMu.Lock()
+	status, ok := h.pendings[t]
+	delete(h.pendings, t)
+
+	last, notified := h.notified[t]
+	if !ok || (notified && last.Online == status.Online && slices.Equal(last.Devices, status.Devices)) {
+		// 状态没有变化。
+		h.pendingMu.Unlock()
+		return
+	}
+	h.pendingMu.Unlock()
+
+	h.mu.RLock()
+	conns := make([]synp.Conn, 0, len(h.subscribers[t]))
+	for conn := range h.subscribers[t] {
+		conns = append(conns, conn)
+	}
+	h.mu.RUnlock()
+
+	if len(conns) == 0 {
+		return
+	}
+
+	h.pendingMu.Lock()
+	h.notified[t] = status
+	h.pendingMu.Unlock()
+
+	notification := &Notification{BID: t.bid, Statuses: []Status{*status}}
+	if err := h.push(conns, notification); err != nil {
+		slog.Warn(
+			"[synp-presence-hub] failed to push presence notification",
+			"conns", len(conns),
+			"uid", status.UID,
+			"error", err,
+		)
+	}
+}
+
+// push 推送通知给所有订阅者，通知只编码一次。
+func (h *Hub) push(conns []synp.Conn, notification *Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("failed to marshal presence notification: %w", err)
+	}
+
+	_, err = h.broadcastFunc(conns, &messagev1.Message{
+		MessageId:     fmt.Sprintf("presence:%d:%d", notification.BID, time.Now().UnixNano()),
+		Cmd:           message.CommandTypePresenceNotify,
+		SerializeType: commonv1.SerializeType_SERIALIZE_TYPE_JSON,
+		Body:          body,
+	}, nil)
+	return err
+}
+
+func HubWithMaxSubscriptions(maxSubscriptions int) option.Opt[Hub] {
+	return func(h *Hub) {
+		if maxSubscriptions > 0 {
+			h.maxSubscriptions = maxSubscriptions
+		}
+	}
+}
+
+func HubWithCoalesceWindow(coalesceWindow time.Duration) option.Opt[Hub] {
+	return func(h *Hub) {
+		if coalesceWindow > 0 {
+			h.coalesceWindow = coalesceWindow
+		}
+	}
+}
+
+func HubWithRequestTimeout(requestTimeout time.Duration) option.Opt[Hub] {
+	return func(h *Hub) {
+		if requestTimeout > 0 {
+			h.requestTimeout = requestTimeout
+		}
+	}
+}
+
+func NewHub(store Store, broadcastFunc message.BroadcastFunc, opts ...option.Opt[Hub]) *Hub {
+	h := &Hub{
+		store:         store,
+		broadcastFunc: broadcastFunc,
+
+		maxSubscriptions: DefaultMaxSubscriptions,
+		coalesceWindow:   DefaultCoalesceWindow,
+		requestTimeout:   DefaultRequestTimeout,
+
+		subscribers:   make(map[target]map[synp.Conn]struct{}),
+		subscriptions: make(map[synp.Conn][]uint64),
+
+		pendings: make(map[target]*Status),
+		notified: make(map[target]*Status),
+	}
+
+	option.Apply(h, opts...)
+	return h
+}
@@ -0,0 +1,85 @@
+package presence
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jrmarcco/synp"
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/jrmarcco/synp/internal/pkg/session"
+	synpmock "github.com/jrmarcco/synp/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHub_Subscribe(t *testing.T) {
+	t.Parallel()
+
+	hub := NewHub(nil, nil, HubWithMaxSubscriptions(2))
+	conn := synpmock.NewUserConn(gomock.NewController(t), session.User{BID: 1, UID: 1, Device: session.DevicePC})
+
+	uids, err := hub.Subscribe(conn, []uint64{3, 2, 3})
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{2, 3}, uids)
+
+	_, err = hub.Subscribe(conn, []uint64{2, 3, 4})
+	assert.ErrorIs(t, err, ErrTooManySubscriptions)
+
+	hub.Unsubscribe(conn)
+	assert.Empty(t, hub.subscribers)
+	assert.Empty(t, hub.subscriptions)
+}
+
+func TestHub_Notify(t *testing.T) {
+	t.Parallel()
+
+	const window = 20 * time.Millisecond
+
+	var mu sync.Mutex
+	var notifications []Notification
+	broadcastFunc := func(conns []synp.Conn, msg *messagev1.Message, _ []uint64) (int, error) {
+		n := Notification{}
+		if err := json.Unmarshal(msg.GetBody(), &n); err != nil {
+			return 0, err
+		}
+		mu.Lock()
+		notifications = append(notifications, n)
+		mu.Unlock()
+		return len(conns), nil
+	}
+
+	hub := NewHub(nil, broadcastFunc, HubWithCoalesceWindow(window))
+	conn := synpmock.NewUserConn(gomock.NewController(t), session.User{BID: 1, UID: 1, Device: session.DevicePC})
+	_, err := hub.Subscribe(conn, []uint64{2})
+	require.NoError(t, err)
+
+	received := func() []Notification {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]Notification(nil), notifications...)
+	}
+
+	// 未订阅的用户不推送。
+	hub.Notify(&Event{Type: EventOnline, BID: 1, UID: 3, Devices: []session.Device{session.DevicePC}})
+
+	// 窗口内多次变更只推送最终状态。
+	hub.Notify(&Event{Type: EventOnline, BID: 1, UID: 2, Devices: []session.Device{session.DevicePC}})
+	hub.Notify(&Event{Type: EventOffline, BID: 1, UID: 2})
+	hub.Notify(&Event{Type: EventOnline, BID: 1, UID: 2, Devices: []session.Device{session.DeviceMobile}})
+
+	require.Eventually(t, func() bool { return len(received()) == 1 }, time.Second, window)
+	first := received()[0]
+	assert.Equal(t, uint64(1), first.BID)
+	require.Len(t, first.Statuses, 1)
+	assert.True(t, first.Statuses[0].Online)
+	assert.Equal(t, []session.Device{session.DeviceMobile}, first.Statuses[0].Devices)
+
+	// 快速下线又上线，最终状态与上一次推送一致，不推送。
+	hub.Notify(&Event{Type: EventOffline, BID: 1, UID: 2})
+	hub.Notify(&Event{Type: EventOnline, BID: 1, UID: 2, Devices: []session.Device{session.DeviceMobile}})
+	time.Sleep(3 * window)
+	assert.Len(t, received(), 1)
+}
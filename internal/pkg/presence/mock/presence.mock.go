@@ -0,0 +1,277 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: types.go
+//
+// Generated by this command:
+//
+//	mockgen -source=types.go -destination=mock/presence.mock.go -package=presencemock -typed Store
+//
+
+// Package presencemock is a generated GoMock package.
+package presencemock
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	presence "github.com/jrmarcco/synp/internal/pkg/presence"
+	session "github.com/jrmarcco/synp/internal/pkg/session"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockStore is a mock of Store interface.
+type MockStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockStoreMockRecorder is the mock recorder for MockStore.
+type MockStoreMockRecorder struct {
+	mock *MockStore
+}
+
+// NewMockStore creates a new mock instance.
+func NewMockStore(ctrl *gomock.Controller) *MockStore {
+	mock := &MockStore{ctrl: ctrl}
+	mock.recorder = &MockStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStore) EXPECT() *MockStoreMockRecorder {
+	return m.recorder
+}
+
+// BatchQuery mocks base method.
+func (m *MockStore) BatchQuery(ctx context.Context, bid uint64, uids []uint64) ([]presence.Presence, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BatchQuery", ctx, bid, uids)
+	ret0, _ := ret[0].([]presence.Presence)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BatchQuery indicates an expected call of BatchQuery.
+func (mr *MockStoreMockRecorder) BatchQuery(ctx, bid, uids any) *MockStoreBatchQueryCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchQuery", reflect.TypeOf((*MockStore)(nil).BatchQuery), ctx, bid, uids)
+	return &MockStoreBatchQueryCall{Call: call}
+}
+
+// MockStoreBatchQueryCall wrap *gomock.Call
+type MockStoreBatchQueryCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockStoreBatchQueryCall) Return(arg0 []presence.Presence, arg1 error) *MockStoreBatchQueryCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockStoreBatchQueryCall) Do(f func(context.Context, uint64, []uint64) ([]presence.Presence, error)) *MockStoreBatchQueryCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockStoreBatchQueryCall) DoAndReturn(f func(context.Context, uint64, []uint64) ([]presence.Presence, error)) *MockStoreBatchQueryCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// Devices mocks base method.
+func (m *MockStore) Devices(ctx context.Context, bid, uid uint64) ([]presence.DeviceState, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Devices", ctx, bid, uid)
+	ret0, _ := ret[0].([]presence.DeviceState)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Devices indicates an expected call of Devices.
+func (mr *MockStoreMockRecorder) Devices(ctx, bid, uid any) *MockStoreDevicesCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Devices", reflect.TypeOf((*MockStore)(nil).Devices), ctx, bid, uid)
+	return &MockStoreDevicesCall{Call: call}
+}
+
+// MockStoreDevicesCall wrap *gomock.Call
+type MockStoreDevicesCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockStoreDevicesCall) Return(arg0 []presence.DeviceState, arg1 error) *MockStoreDevicesCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockStoreDevicesCall) Do(f func(context.Context, uint64, uint64) ([]presence.DeviceState, error)) *MockStoreDevicesCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockStoreDevicesCall) DoAndReturn(f func(context.Context, uint64, uint64) ([]presence.DeviceState, error)) *MockStoreDevicesCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// Offline mocks base method.
+func (m *MockStore) Offline(ctx context.Context, user session.User, token string, linger time.Duration) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Offline", ctx, user, token, linger)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Offline indicates an expected call of Offline.
+func (mr *MockStoreMockRecorder) Offline(ctx, user, token, linger any) *MockStoreOfflineCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Offline", reflect.TypeOf((*MockStore)(nil).Offline), ctx, user, token, linger)
+	return &MockStoreOfflineCall{Call: call}
+}
+
+// MockStoreOfflineCall wrap *gomock.Call
+type MockStoreOfflineCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockStoreOfflineCall) Return(arg0 bool, arg1 error) *MockStoreOfflineCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockStoreOfflineCall) Do(f func(context.Context, session.User, string, time.Duration) (bool, error)) *MockStoreOfflineCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockStoreOfflineCall) DoAndReturn(f func(context.Context, session.User, string, time.Duration) (bool, error)) *MockStoreOfflineCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// Online mocks base method.
+func (m *MockStore) Online(ctx context.Context, user session.User, state presence.DeviceState) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Online", ctx, user, state)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Online indicates an expected call of Online.
+func (mr *MockStoreMockRecorder) Online(ctx, user, state any) *MockStoreOnlineCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Online", reflect.TypeOf((*MockStore)(nil).Online), ctx, user, state)
+	return &MockStoreOnlineCall{Call: call}
+}
+
+// MockStoreOnlineCall wrap *gomock.Call
+type MockStoreOnlineCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockStoreOnlineCall) Return(arg0 bool, arg1 error) *MockStoreOnlineCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockStoreOnlineCall) Do(f func(context.Context, session.User, presence.DeviceState) (bool, error)) *MockStoreOnlineCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockStoreOnlineCall) DoAndReturn(f func(context.Context, session.User, presence.DeviceState) (bool, error)) *MockStoreOnlineCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// Refresh mocks base method.
+func (m *MockStore) Refresh(ctx context.Context, user session.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Refresh", ctx, user)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Refresh indicates an expected call of Refresh.
+func (mr *MockStoreMockRecorder) Refresh(ctx, user any) *MockStoreRefreshCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Refresh", reflect.TypeOf((*MockStore)(nil).Refresh), ctx, user)
+	return &MockStoreRefreshCall{Call: call}
+}
+
+// MockStoreRefreshCall wrap *gomock.Call
+type MockStoreRefreshCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockStoreRefreshCall) Return(arg0 error) *MockStoreRefreshCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockStoreRefreshCall) Do(f func(context.Context, session.User) error) *MockStoreRefreshCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockStoreRefreshCall) DoAndReturn(f func(context.Context, session.User) error) *MockStoreRefreshCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// Settle mocks base method.
+func (m *MockStore) Settle(ctx context.Context, user session.User, token string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Settle", ctx, user, token)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Settle indicates an expected call of Settle.
+func (mr *MockStoreMockRecorder) Settle(ctx, user, token any) *MockStoreSettleCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Settle", reflect.TypeOf((*MockStore)(nil).Settle), ctx, user, token)
+	return &MockStoreSettleCall{Call: call}
+}
+
+// MockStoreSettleCall wrap *gomock.Call
+type MockStoreSettleCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockStoreSettleCall) Return(arg0 bool, arg1 error) *MockStoreSettleCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockStoreSettleCall) Do(f func(context.Context, session.User, string) (bool, error)) *MockStoreSettleCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockStoreSettleCall) DoAndReturn(f func(context.Context, session.User, string) (bool, error)) *MockStoreSettleCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
@@ -0,0 +1,17 @@
+local val = redis.call("GET", KEYS[1])
+if not val then
+    return 0
+end
+
+local state = cjson.decode(val)
+if state["token"] ~= ARGV[1] then
+    return 0
+end
+
+redis.call("DEL", KEYS[1])
+-- 记录等待确认下线的标记。
+local linger = tonumber(ARGV[2])
+if linger > 0 then
+    redis.call("SET", KEYS[2], ARGV[1], "PX", linger)
+end
+return 1
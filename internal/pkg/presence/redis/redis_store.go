@@ -0,0 +1,162 @@
+package redis
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jrmarcco/synp/internal/pkg/presence"
+	"github.com/jrmarcco/synp/internal/pkg/session"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	//go:embed lua/presence_offline.lua
+	presenceOfflineLua string
+	//go:embed lua/presence_settle.lua
+	presenceSettleLua string
+)
+
+const DefaultTTL = 60 * time.Second
+
+var _ presence.Store = (*Store)(nil)
+
+// Store 为在线状态存储的 Redis 实现。
+// 每个设备的在线状态存储为一个独立的 key：
+//
+//	synp:presence:{bid}:{uid}:{device} -> presence.DeviceState ( json )
+//
+// 这里不使用 hash 是因为 hash 无法对单个 field 设置过期时间。
+//
+// 设备等待确认下线的标记存储为:
+//
+//	synp:presence:leaving:{bid}:{uid}:{device} -> token
+type Store struct {
+	rdb redis.Cmdable
+	ttl time.Duration
+}
+
+func (s *Store) Online(ctx context.Context, user session.User, state presence.DeviceState) (bool, error) {
+	val, err := json.Marshal(state)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal device state: %w", err)
+	}
+
+	var del *redis.IntCmd
+	if _, err = s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, s.key(user.BID, user.UID, user.Device), val, s.ttl)
+		del = pipe.Del(ctx, s.leavingKey(user.BID, user.UID, user.Device))
+		return nil
+	}); err != nil {
+		return false, err
+	}
+	return del.Val() > 0, nil
+}
+
+func (s *Store) Refresh(ctx context.Context, user session.User) error {
+	return s.rdb.Expire(ctx, s.key(user.BID, user.UID, user.Device), s.ttl).Err()
+}
+
+func (s *Store) Offline(ctx context.Context, user session.User, token string, linger time.Duration) (bool, error) {
+	// 使用 lua 脚本保证比较、删除及记录标记的原子性。
+	res, err := s.rdb.Eval(
+		ctx,
+		presenceOfflineLua,
+		[]string{s.key(user.BID, user.UID, user.Device), s.leavingKey(user.BID, user.UID, user.Device)},
+		token, linger.Milliseconds(),
+	).Int()
+	if err != nil {
+		return false, err
+	}
+	return res == 1, nil
+}
+
+func (s *Store) Settle(ctx context.Context, user session.User, token string) (bool, error) {
+	res, err := s.rdb.Eval(
+		ctx,
+		presenceSettleLua,
+		[]string{s.leavingKey(user.BID, user.UID, user.Device)},
+		token,
+	).Int()
+	if err != nil {
+		return false, err
+	}
+	return res == 1, nil
+}
+
+func (s *Store) Devices(ctx context.Context, bid, uid uint64) ([]presence.DeviceState, error) {
+	res, err := s.BatchQuery(ctx, bid, []uint64{uid})
+	if err != nil {
+		return nil, err
+	}
+	return res[0].Devices, nil
+}
+
+func (s *Store) BatchQuery(ctx context.Context, bid uint64, uids []uint64) ([]presence.Presence, error) {
+	if len(uids) == 0 {
+		return []presence.Presence{}, nil
+	}
+
+	deviceCnt := len(session.AllDevices)
+
+	keys := make([]string, 0, len(uids)*deviceCnt)
+	for _, uid := range uids {
+		for _, device := range session.AllDevices {
+			keys = append(keys, s.key(bid, uid, device))
+		}
+	}
+
+	// 一次 MGET 完成批量查询。
+	vals, err := s.rdb.MGet(ctx, keys...).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	res := make([]presence.Presence, 0, len(uids))
+	for i, uid := range uids {
+		p := presence.Presence{
+			BID:     bid,
+			UID:     uid,
+			Devices: []presence.DeviceState{},
+		}
+
+		for _, val := range vals[i*deviceCnt : (i+1)*deviceCnt] {
+			str, ok := val.(string)
+			if !ok {
+				// key 不存在时 val 为 nil。
+				continue
+			}
+
+			var state presence.DeviceState
+			if err := json.Unmarshal([]byte(str), &state); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal device state: %w", err)
+			}
+			p.Devices = append(p.Devices, state)
+		}
+
+		p.Online = len(p.Devices) > 0
+		res = append(res, p)
+	}
+	return res, nil
+}
+
+func (s *Store) key(bid, uid uint64, device session.Device) string {
+	return fmt.Sprintf("synp:presence:%d:%d:%s", bid, uid, device)
+}
+
+func (s *Store) leavingKey(bid, uid uint64, device session.Device) string {
+	return fmt.Sprintf("synp:presence:leaving:%d:%d:%s", bid, uid, device)
+}
+
+func NewStore(rdb redis.Cmdable, ttl time.Duration) *Store {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Store{
+		rdb: rdb,
+		ttl: ttl,
+	}
+}
@@ -0,0 +1,138 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/jrmarcco/synp/internal/pkg/presence"
+	"github.com/jrmarcco/synp/internal/pkg/session"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(t *testing.T) (*Store, *miniredis.Miniredis) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	return NewStore(rdb, time.Minute), mr
+}
+
+func TestStore_OfflineToken(t *testing.T) {
+	t.Parallel()
+
+	store, _ := newTestStore(t)
+	ctx := context.Background()
+	user := session.User{BID: 1, UID: 2, Device: session.DevicePC}
+
+	reconnected, err := store.Online(ctx, user, presence.DeviceState{Device: user.Device, NodeID: "node-a", Token: "t1"})
+	require.NoError(t, err)
+	assert.False(t, reconnected)
+
+	// token 不一致 ( 同一设备的新连接 ) 时不删除。
+	deleted, err := store.Offline(ctx, user, "t0", 0)
+	require.NoError(t, err)
+	assert.False(t, deleted)
+
+	devices, err := store.Devices(ctx, user.BID, user.UID)
+	require.NoError(t, err)
+	require.Len(t, devices, 1)
+	assert.Equal(t, "t1", devices[0].Token)
+
+	deleted, err = store.Offline(ctx, user, "t1", 0)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+
+	devices, err = store.Devices(ctx, user.BID, user.UID)
+	require.NoError(t, err)
+	assert.Empty(t, devices)
+
+	// 没有等待确认下线的标记。
+	settled, err := store.Settle(ctx, user, "t1")
+	require.NoError(t, err)
+	assert.False(t, settled)
+}
+
+func TestStore_Linger(t *testing.T) {
+	t.Parallel()
+
+	store, mr := newTestStore(t)
+	ctx := context.Background()
+	user := session.User{BID: 1, UID: 2, Device: session.DeviceMobile}
+
+	_, err := store.Online(ctx, user, presence.DeviceState{Device: user.Device, NodeID: "node-a", Token: "t1"})
+	require.NoError(t, err)
+	deleted, err := store.Offline(ctx, user, "t1", time.Second)
+	require.NoError(t, err)
+	require.True(t, deleted)
+
+	// 在其他节点重连，清除标记。
+	reconnected, err := store.Online(ctx, user, presence.DeviceState{Device: user.Device, NodeID: "node-b", Token: "t2"})
+	require.NoError(t, err)
+	assert.True(t, reconnected)
+
+	settled, err := store.Settle(ctx, user, "t1")
+	require.NoError(t, err)
+	assert.False(t, settled)
+
+	// 再次断开，只有最后一次断开的 token 可以确认下线。
+	deleted, err = store.Offline(ctx, user, "t2", time.Second)
+	require.NoError(t, err)
+	require.True(t, deleted)
+
+	settled, err = store.Settle(ctx, user, "t1")
+	require.NoError(t, err)
+	assert.False(t, settled)
+
+	settled, err = store.Settle(ctx, user, "t2")
+	require.NoError(t, err)
+	assert.True(t, settled)
+
+	// 标记过期后重连不视为重连。
+	_, err = store.Online(ctx, user, presence.DeviceState{Device: user.Device, NodeID: "node-a", Token: "t3"})
+	require.NoError(t, err)
+	_, err = store.Offline(ctx, user, "t3", time.Second)
+	require.NoError(t, err)
+	mr.FastForward(2 * time.Second)
+
+	reconnected, err = store.Online(ctx, user, presence.DeviceState{Device: user.Device, NodeID: "node-a", Token: "t4"})
+	require.NoError(t, err)
+	assert.False(t, reconnected)
+}
+
+func TestStore_BatchQuery(t *testing.T) {
+	t.Parallel()
+
+	store, mr := newTestStore(t)
+	ctx := context.Background()
+
+	pc := session.User{BID: 1, UID: 2, Device: session.DevicePC}
+	mobile := session.User{BID: 1, UID: 2, Device: session.DeviceMobile}
+	for _, user := range []session.User{pc, mobile} {
+		_, err := store.Online(ctx, user, presence.DeviceState{Device: user.Device, NodeID: "node-a", Token: "t"})
+		require.NoError(t, err)
+	}
+
+	res, err := store.BatchQuery(ctx, 1, []uint64{2, 3})
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	assert.True(t, res[0].Online)
+	assert.Len(t, res[0].Devices, 2)
+	assert.False(t, res[1].Online)
+	assert.Empty(t, res[1].Devices)
+
+	// 没有续期的设备过期下线。
+	mr.FastForward(30 * time.Second)
+	require.NoError(t, store.Refresh(ctx, pc))
+	mr.FastForward(40 * time.Second)
+
+	devices, err := store.Devices(ctx, 1, 2)
+	require.NoError(t, err)
+	require.Len(t, devices, 1)
+	assert.Equal(t, session.DevicePC, devices[0].Device)
+}
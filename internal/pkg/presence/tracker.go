@@ -0,0 +1,405 @@
+package presence
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jrmarcco/jit/bean/option"
+	"github.com/jrmarcco/jit/xsync"
+	"github.com/jrmarcco/synp"
+	"github.com/jrmarcco/synp/internal/pkg/session"
+	"github.com/jrmarcco/synp/internal/pkg/xmq"
+	"github.com/jrmarcco/synp/internal/pkg/xmq/produce"
+)
+
+const (
+	DefaultDebounce        = 5 * time.Second
+	DefaultRefreshInterval = 20 * time.Second
+	DefaultRequestTimeout  = 3 * time.Second
+
+	// 等待确认下线的标记的有效时间为去抖动时间的倍数。
+	lingerFactor = 2
+)
+
+// connState 为本节点连接的在线状态信息。
+type connState struct {
+	token       string
+	lastRefresh atomic.Int64 // 毫秒
+}
+
+// pendingOffline 为等待确认的延迟下线。
+type pendingOffline struct {
+	user  session.User
+	token string
+	timer *time.Timer
+}
+
+// Tracker 负责维护本节点连接的在线状态，并在状态变化时发布事件。
+//
+// 去抖动 ( debounce ):
+//
+//	连接断开后不会立即发布 offline / device_changed 事件，而是延迟 debounce 时间。
+//	如果在此期间同一设备重新连接 ( 无论是否在本节点 )，则不发布任何事件。
+//	这样可以避免网络抖动导致的频繁上下线。
+//
+//	等待确认下线的标记保存在 Store 中 ( 见 Store.Offline )，重连的节点通过 Store.Online 识别重连，
+//	断开的节点在延迟结束后通过 Store.Settle 确认标记仍然有效才发布事件，
+//	所以重连到其他节点时两个节点都不会发布事件。
+type Tracker struct {
+	store    Store
+	producer produce.Producer
+
+	nodeID string
+	topic  string // 为空时不发布事件
+
+	debounce        time.Duration
+	refreshInterval time.Duration
+	requestTimeout  time.Duration
+
+	conns *xsync.Map[synp.Conn, *connState]
+
+	mu       sync.Mutex
+	pendings map[string]*pendingOffline // conn id -> 延迟下线
+
+	closed atomic.Bool
+}
+
+// Connect 记录连接上线。
+func (t *Tracker) Connect(conn synp.Conn) error {
+	if t.closed.Load() {
+		return nil
+	}
+
+	user := conn.Session().User()
+
+	token, err := t.newToken()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), t.requestTimeout)
+	defer cancel()
+
+	// 停止本节点的延迟下线，是否为重连由 Store 判断 ( 可能在其他节点断开 )。
+	t.cancelPendingOffline(conn.ID())
+
+	before, err := t.store.Devices(ctx, user.BID, user.UID)
+	if err != nil {
+		return fmt.Errorf("failed to load user devices: %w", err)
+	}
+
+	now := time.Now().UnixMilli()
+	state := DeviceState{
+		Device:   user.Device,
+		NodeID:   t.nodeID,
+		Token:    token,
+		OnlineAt: now,
+	}
+	reconnected, err := t.store.Online(ctx, user, state)
+	if err != nil {
+		return fmt.Errorf("failed to save device state: %w", err)
+	}
+
+	cs := &connState{token: token}
+	cs.lastRefresh.Store(now)
+	t.conns.Store(conn, cs)
+
+	if reconnected {
+		slog.Debug(
+			"[synp-presence-tracker] device reconnected within debounce window, skip event",
+			"conn_id", conn.ID(),
+		)
+		return nil
+	}
+
+	devices := deviceList(before)
+	if slices.Contains(devices, user.Device) {
+		// 同一设备的连接已存在 ( 通常是在其他节点上被替换 )，在线设备没有变化。
+		return nil
+	}
+
+	eventType := EventDeviceChanged
+	if len(devices) == 0 {
+		eventType = EventOnline
+	}
+	return t.publish(ctx, eventType, user, append(devices, user.Device))
+}
+
+// Disconnect 记录连接下线。
+func (t *Tracker) Disconnect(conn synp.Conn) error {
+	cs, ok := t.conns.LoadAndDelete(conn)
+	if !ok {
+		return nil
+	}
+
+	user := conn.Session().User()
+
+	ctx, cancel := context.WithTimeout(context.Background(), t.requestTimeout)
+	defer cancel()
+
+	var linger time.Duration
+	if t.debounce > 0 && !t.closed.Load() {
+		// 标记的有效时间大于去抖动时间，保证定时器触发时标记仍然存在。
+		linger = lingerFactor * t.debounce
+	}
+
+	deleted, err := t.store.Offline(ctx, user, cs.token, linger)
+	if err != nil {
+		return fmt.Errorf("failed to delete device state: %w", err)
+	}
+	if !deleted {
+		// 同一设备已经建立了新连接，不需要处理。
+		return nil
+	}
+
+	if linger == 0 {
+		return t.publishOffline(ctx, user)
+	}
+
+	connID := conn.ID()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if prev, ok := t.pendings[connID]; ok {
+		prev.timer.Stop()
+	}
+
+	pending := &pendingOffline{user: user, token: cs.token}
+	pending.timer = time.AfterFunc(t.debounce, func() {
+		t.mu.Lock()
+		if t.pendings[connID] != pending {
+			// 定时器已被取消或替换。
+			t.mu.Unlock()
+			return
+		}
+		delete(t.pendings, connID)
+		t.mu.Unlock()
+
+		if err := t.settleOffline(user, pending.token); err != nil {
+			slog.Error(
+				"[synp-presence-tracker] failed to settle offline",
+				"conn_id", connID,
+				"error", err,
+			)
+		}
+	})
+	t.pendings[connID] = pending
+	return nil
+}
+
+// Refresh 为连接的在线状态续期。
+// 续期频率受 refreshInterval 限制，可以在每次收到前端消息 ( 包括心跳 ) 时调用。
+func (t *Tracker) Refresh(conn synp.Conn) error {
+	cs, ok := t.conns.Load(conn)
+	if !ok {
+		return nil
+	}
+
+	now := time.Now().UnixMilli()
+	last := cs.lastRefresh.Load()
+	if now-last < t.refreshInterval.Milliseconds() {
+		return nil
+	}
+	if !cs.lastRefresh.CompareAndSwap(last, now) {
+		// 其他 goroutine 正在续期。
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), t.requestTimeout)
+	defer cancel()
+
+	return t.store.Refresh(ctx, conn.Session().User())
+}
+
+// cancelPendingOffline 取消连接的延迟下线定时器。
+func (t *Tracker) cancelPendingOffline(connID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if pending, ok := t.pendings[connID]; ok {
+		delete(t.pendings, connID)
+		pending.timer.Stop()
+	}
+}
+
+// settleOffline 在去抖动时间结束后确认设备下线，并发布事件。
+// 设备已经重新连接 ( 标记被清除 ) 时不发布事件。
+func (t *Tracker) settleOffline(user session.User, token string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), t.requestTimeout)
+	defer cancel()
+
+	settled, err := t.store.Settle(ctx, user, token)
+	if err != nil {
+		return fmt.Errorf("failed to settle device state: %w", err)
+	}
+	if !settled {
+		slog.Debug(
+			"[synp-presence-tracker] device reconnected within debounce window, skip event",
+			"bid", user.BID,
+			"uid", user.UID,
+			"device", user.Device,
+		)
+		return nil
+	}
+	return t.publishOffline(ctx, user)
+}
+
+// publishOffline 根据用户当前在线的设备发布 offline / device_changed 事件。
+func (t *Tracker) publishOffline(ctx context.Context, user session.User) error {
+	states, err := t.store.Devices(ctx, user.BID, user.UID)
+	if err != nil {
+		return fmt.Errorf("failed to load user devices: %w", err)
+	}
+
+	devices := deviceList(states)
+	if slices.Contains(devices, user.Device) {
+		// 设备已在其他节点重新连接。
+		return nil
+	}
+
+	eventType := EventDeviceChanged
+	if len(devices) == 0 {
+		eventType = EventOffline
+	}
+	return t.publish(ctx, eventType, user, devices)
+}
+
+func (t *Tracker) publish(ctx context.Context, eventType EventType, user session.User, devices []session.Device) error {
+	if t.topic == "" {
+		return nil
+	}
+
+	event := Event{
+		Type:      eventType,
+		BID:       user.BID,
+		UID:       user.UID,
+		Device:    user.Device,
+		Devices:   devices,
+		NodeID:    t.nodeID,
+		Timestamp: time.Now().UnixMilli(),
+	}
+
+	val, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal presence event: %w", err)
+	}
+
+	// 使用 ConnKey 作为消息 key，保证同一用户的事件有序。
+	if err = t.producer.Produce(ctx, &xmq.Message{
+		Topic: t.topic,
+		Key:   []byte(user.ConnKey()),
+		Val:   val,
+	}); err != nil {
+		return fmt.Errorf("failed to publish presence event: %w", err)
+	}
+
+	slog.Debug(
+		"[synp-presence-tracker] successfully published presence event",
+		"type", eventType,
+		"bid", user.BID,
+		"uid", user.UID,
+		"device", user.Device,
+	)
+	return nil
+}
+
+func (t *Tracker) newToken() (string, error) {
+	const tokenLen = 8
+
+	buf := make([]byte, tokenLen)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate presence token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// Close 关闭 Tracker，并立即处理所有未触发的延迟下线。
+func (t *Tracker) Close() {
+	if !t.closed.CompareAndSwap(false, true) {
+		return
+	}
+
+	t.mu.Lock()
+	pendings := t.pendings
+	t.pendings = make(map[string]*pendingOffline)
+	t.mu.Unlock()
+
+	for connID, pending := range pendings {
+		if !pending.timer.Stop() {
+			// 定时器已触发。
+			continue
+		}
+		if err := t.settleOffline(pending.user, pending.token); err != nil {
+			slog.Error(
+				"[synp-presence-tracker] failed to settle offline on close",
+				"conn_id", connID,
+				"error", err,
+			)
+		}
+	}
+
+	slog.Info("[synp-presence-tracker] presence tracker closed", "pending_settled_cnt", len(pendings))
+}
+
+func deviceList(states []DeviceState) []session.Device {
+	devices := make([]session.Device, 0, len(states))
+	for _, state := range states {
+		devices = append(devices, state.Device)
+	}
+	return devices
+}
+
+func TrackerWithTopic(topic string) option.Opt[Tracker] {
+	return func(t *Tracker) {
+		t.topic = topic
+	}
+}
+
+// TrackerWithDebounce 设置去抖动时间，小于等于 0 时表示不去抖动。
+func TrackerWithDebounce(debounce time.Duration) option.Opt[Tracker] {
+	return func(t *Tracker) {
+		t.debounce = debounce
+	}
+}
+
+func TrackerWithRefreshInterval(refreshInterval time.Duration) option.Opt[Tracker] {
+	return func(t *Tracker) {
+		if refreshInterval > 0 {
+			t.refreshInterval = refreshInterval
+		}
+	}
+}
+
+func TrackerWithRequestTimeout(requestTimeout time.Duration) option.Opt[Tracker] {
+	return func(t *Tracker) {
+		if requestTimeout > 0 {
+			t.requestTimeout = requestTimeout
+		}
+	}
+}
+
+func NewTracker(store Store, producer produce.Producer, nodeID string, opts ...option.Opt[Tracker]) *Tracker {
+	t := &Tracker{
+		store:    store,
+		producer: producer,
+		nodeID:   nodeID,
+
+		debounce:        DefaultDebounce,
+		refreshInterval: DefaultRefreshInterval,
+		requestTimeout:  DefaultRequestTimeout,
+
+		conns:    &xsync.Map[synp.Conn, *connState]{},
+		pendings: make(map[string]*pendingOffline),
+	}
+
+	option.Apply(t, opts...)
+	return t
+}
@@ -0,0 +1,185 @@
+package presence
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jrmarcco/synp/internal/pkg/session"
+	producemock "github.com/jrmarcco/synp/internal/pkg/xmq/produce/mock"
+	synpmock "github.com/jrmarcco/synp/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestTracker_Debounce(t *testing.T) {
+	t.Parallel()
+
+	const debounce = 20 * time.Millisecond
+
+	ctrl := gomock.NewController(t)
+	store := newTestStore()
+	producer, recorder := producemock.NewRecordingProducer(ctrl)
+	tracker := NewTracker(store, producer, "node-a", TrackerWithTopic("presence"), TrackerWithDebounce(debounce))
+	defer tracker.Close()
+
+	conn := synpmock.NewUserConn(ctrl, testUser)
+	require.NoError(t, tracker.Connect(conn))
+	assert.Equal(t, []EventType{EventOnline}, eventTypes(recorder))
+
+	// 去抖动时间内在本节点重连，不发布事件。
+	require.NoError(t, tracker.Disconnect(conn))
+	require.NoError(t, tracker.Connect(conn))
+	time.Sleep(3 * debounce)
+	assert.Equal(t, []EventType{EventOnline}, eventTypes(recorder))
+
+	require.NoError(t, tracker.Disconnect(conn))
+	require.Eventually(t, func() bool {
+		return len(eventTypes(recorder)) == 2
+	}, time.Second, debounce)
+	assert.Equal(t, []EventType{EventOnline, EventOffline}, eventTypes(recorder))
+}
+
+func TestTracker_ReconnectOnOtherNode(t *testing.T) {
+	t.Parallel()
+
+	const debounce = 20 * time.Millisecond
+
+	ctrl := gomock.NewController(t)
+	store := newTestStore()
+	producer, recorder := producemock.NewRecordingProducer(ctrl)
+	nodeA := NewTracker(store, producer, "node-a", TrackerWithTopic("presence"), TrackerWithDebounce(debounce))
+	defer nodeA.Close()
+	nodeB := NewTracker(store, producer, "node-b", TrackerWithTopic("presence"), TrackerWithDebounce(debounce))
+	defer nodeB.Close()
+
+	oldConn := synpmock.NewUserConn(ctrl, testUser)
+	require.NoError(t, nodeA.Connect(oldConn))
+	require.NoError(t, nodeA.Disconnect(oldConn))
+
+	// 去抖动时间内重连到其他节点，两个节点都不发布事件。
+	newConn := synpmock.NewUserConn(ctrl, testUser)
+	require.NoError(t, nodeB.Connect(newConn))
+	time.Sleep(3 * debounce)
+	assert.Equal(t, []EventType{EventOnline}, eventTypes(recorder))
+
+	// 之后在新节点断开，只发布一次下线事件。
+	require.NoError(t, nodeB.Disconnect(newConn))
+	require.Eventually(t, func() bool {
+		return len(eventTypes(recorder)) == 2
+	}, time.Second, debounce)
+	time.Sleep(3 * debounce)
+	assert.Equal(t, []EventType{EventOnline, EventOffline}, eventTypes(recorder))
+}
+
+func TestTracker_ReplacedConn(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	store := newTestStore()
+	producer, recorder := producemock.NewRecordingProducer(ctrl)
+	nodeA := NewTracker(store, producer, "node-a", TrackerWithTopic("presence"), TrackerWithDebounce(0))
+	nodeB := NewTracker(store, producer, "node-b", TrackerWithTopic("presence"), TrackerWithDebounce(0))
+
+	// 同一设备在其他节点建立新连接，旧连接断开时 token 不一致，不删除新连接的状态。
+	oldConn := synpmock.NewUserConn(ctrl, testUser)
+	require.NoError(t, nodeA.Connect(oldConn))
+	newConn := synpmock.NewUserConn(ctrl, testUser)
+	require.NoError(t, nodeB.Connect(newConn))
+	require.NoError(t, nodeA.Disconnect(oldConn))
+
+	devices, err := store.Devices(context.Background(), 1, 2)
+	require.NoError(t, err)
+	require.Len(t, devices, 1)
+	assert.Equal(t, "node-b", devices[0].NodeID)
+	assert.Equal(t, []EventType{EventOnline}, eventTypes(recorder))
+
+	// 不去抖动时立即发布下线事件。
+	require.NoError(t, nodeB.Disconnect(newConn))
+	assert.Equal(t, []EventType{EventOnline, EventOffline}, eventTypes(recorder))
+}
+
+var testUser = session.User{BID: 1, UID: 2, Device: session.DevicePC}
+
+// eventTypes 返回已经发布的事件类型，无法解析的消息直接跳过。
+func eventTypes(recorder *producemock.Recorder) []EventType {
+	msgs := recorder.Messages()
+	types := make([]EventType, 0, len(msgs))
+	for _, msg := range msgs {
+		var event Event
+		if err := json.Unmarshal(msg.Val, &event); err != nil {
+			continue
+		}
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+// testStore 为所有节点共享的内存 Store，标记不会过期。
+type testStore struct {
+	mu      sync.Mutex
+	states  map[session.Device]DeviceState
+	leaving map[session.Device]string
+}
+
+func (s *testStore) Online(_ context.Context, user session.User, state DeviceState) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.states[user.Device] = state
+	_, ok := s.leaving[user.Device]
+	delete(s.leaving, user.Device)
+	return ok, nil
+}
+
+func (s *testStore) Refresh(_ context.Context, _ session.User) error { return nil }
+
+func (s *testStore) Offline(_ context.Context, user session.User, token string, linger time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.states[user.Device].Token != token {
+		return false, nil
+	}
+	delete(s.states, user.Device)
+	if linger > 0 {
+		s.leaving[user.Device] = token
+	}
+	return true, nil
+}
+
+func (s *testStore) Settle(_ context.Context, user session.User, token string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.leaving[user.Device] != token {
+		return false, nil
+	}
+	delete(s.leaving, user.Device)
+	return true, nil
+}
+
+func (s *testStore) Devices(_ context.Context, _, _ uint64) ([]DeviceState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	states := make([]DeviceState, 0, len(s.states))
+	for _, state := range s.states {
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+func (s *testStore) BatchQuery(_ context.Context, _ uint64, _ []uint64) ([]Presence, error) {
+	return nil, nil
+}
+
+func newTestStore() *testStore {
+	return &testStore{
+		states:  make(map[session.Device]DeviceState),
+		leaving: make(map[session.Device]string),
+	}
+}
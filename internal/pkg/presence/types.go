@@ -0,0 +1,78 @@
+package presence
+
+import (
+	"context"
+	"time"
+
+	"github.com/jrmarcco/synp/internal/pkg/session"
+)
+
+//go:generate mockgen -source=types.go -destination=mock/presence.mock.go -package=presencemock -typed Store
+
+// EventType 为在线状态事件类型。
+type EventType string
+
+const (
+	EventOnline        EventType = "online"         // 用户上线 ( 第一个设备连接 )
+	EventOffline       EventType = "offline"        // 用户下线 ( 最后一个设备断开 )
+	EventDeviceChanged EventType = "device_changed" // 用户在线设备发生变化
+)
+
+// Event 为在线状态变更事件，会被发布到消息队列供后端 ( 业务服务端 ) 订阅。
+type Event struct {
+	Type EventType `json:"type"`
+
+	BID    uint64         `json:"bid"`
+	UID    uint64         `json:"uid"`
+	Device session.Device `json:"device"` // 触发事件的设备
+
+	// 事件发生后用户仍在线的设备列表。
+	Devices []session.Device `json:"devices"`
+
+	NodeID    string `json:"nodeId"`
+	Timestamp int64  `json:"timestamp"` // 毫秒
+}
+
+// DeviceState 为单个设备的在线状态。
+type DeviceState struct {
+	Device   session.Device `json:"device"`
+	NodeID   string         `json:"nodeId"`   // 连接所在的网关节点
+	Token    string         `json:"token"`    // 连接实例标识，用于区分同一设备的新旧连接
+	OnlineAt int64          `json:"onlineAt"` // 毫秒
+}
+
+// Presence 为用户的在线状态。
+type Presence struct {
+	BID     uint64        `json:"bid"`
+	UID     uint64        `json:"uid"`
+	Online  bool          `json:"online"`
+	Devices []DeviceState `json:"devices"`
+}
+
+// Store 为在线状态存储。
+// 在线状态以 ( BID, UID, Device ) 为粒度存储，并带有过期时间，
+// 网关需要通过 Refresh 定期续期，节点宕机时在线状态会自动过期。
+//
+// 去抖动的状态 ( 设备断开后等待确认下线 ) 同样保存在存储中，所有节点共享，
+// 设备在去抖动时间内重新连接到任意节点都可以识别为重连。
+type Store interface {
+	// Online 保存设备在线状态，并清除设备等待确认下线的标记。
+	// bool 返回值表示设备是否在等待确认下线 ( 即去抖动时间内重新连接 )。
+	Online(ctx context.Context, user session.User, state DeviceState) (bool, error)
+	// Refresh 为设备在线状态续期。
+	Refresh(ctx context.Context, user session.User) error
+	// Offline 删除设备在线状态。
+	// 只有当存储的 token 与参数一致时才会删除，防止误删同一设备的新连接。
+	// linger 大于 0 时同时为设备记录等待确认下线的标记 ( 保存 token )，标记在 linger 后过期。
+	// bool 返回值表示是否删除成功。
+	Offline(ctx context.Context, user session.User, token string, linger time.Duration) (bool, error)
+	// Settle 清除设备等待确认下线的标记。
+	// 只有当标记中的 token 与参数一致时才会清除，bool 返回值表示是否清除成功，
+	// 标记已被重连清除 ( 或被之后的断开替换 ) 时返回 false，此时不需要发布下线事件。
+	Settle(ctx context.Context, user session.User, token string) (bool, error)
+
+	// Devices 返回用户当前在线的设备。
+	Devices(ctx context.Context, bid, uid uint64) ([]DeviceState, error)
+	// BatchQuery 批量查询同一业务下多个用户的在线状态。
+	BatchQuery(ctx context.Context, bid uint64, uids []uint64) ([]Presence, error)
+}
@@ -0,0 +1,98 @@
+package providers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jrmarcco/synp/internal/pkg/chunk"
+	"github.com/jrmarcco/synp/internal/pkg/codec"
+	"github.com/jrmarcco/synp/internal/pkg/message"
+	"github.com/spf13/viper"
+)
+
+type downstreamChunkConfig struct {
+	ChunkSize int `mapstructure:"chunk_size"`
+}
+
+func loadDownstreamChunkConfig() (downstreamChunkConfig, error) {
+	cfg := downstreamChunkConfig{}
+	if err := viper.UnmarshalKey("synp.chunk.downstream", &cfg); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// newPushFunc 创建推送消息到前端的函数，开启下行分片时 body 过大的消息分片发送。
+func newPushFunc(codec codec.Codec) (message.PushFunc, error) {
+	cfg, err := loadDownstreamChunkConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	pushFunc := message.DefaultPushFunc(codec)
+	if cfg.ChunkSize > 0 {
+		pushFunc = chunk.PushFunc(pushFunc, cfg.ChunkSize)
+	}
+	return pushFunc, nil
+}
+
+// newBroadcastFunc 创建扇出推送消息的函数 ( 消息只编码一次 )，开启下行分片时 body 过大的消息分片发送。
+func newBroadcastFunc(codec codec.Codec) (message.BroadcastFunc, error) {
+	cfg, err := loadDownstreamChunkConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	broadcastFunc := message.DefaultBroadcastFunc(codec)
+	if cfg.ChunkSize > 0 {
+		broadcastFunc = chunk.BroadcastFunc(broadcastFunc, cfg.ChunkSize)
+	}
+	return broadcastFunc, nil
+}
+
+// newChunkAssembler 创建上行分片的 Assembler，没有开启上行分片时返回 nil。
+func newChunkAssembler() (*chunk.Assembler, error) {
+	type config struct {
+		Enabled           bool          `mapstructure:"enabled"`
+		MaxPayloadSize    int64         `mapstructure:"max_payload_size"`
+		MaxUploadsPerConn int           `mapstructure:"max_uploads_per_conn"`
+		MaxBufferedBytes  int64         `mapstructure:"max_buffered_bytes"`
+		UploadTimeout     time.Duration `mapstructure:"upload_timeout"`
+	}
+
+	cfg := config{}
+	if err := viper.UnmarshalKey("synp.chunk.upstream", &cfg); err != nil {
+		return nil, err
+	}
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	// 还原后的消息作为一条消息发送到消息队列，超过生产者的消息大小上限时无法发送。
+	maxPayloadSize := cfg.MaxPayloadSize
+	if maxPayloadSize <= 0 {
+		maxPayloadSize = chunk.DefaultMaxPayloadSize
+	}
+	if limit := producerMaxMessageBytes(); limit > 0 && maxPayloadSize >= limit {
+		return nil, fmt.Errorf(
+			"synp.chunk.upstream.max_payload_size ( %d ) must be less than the producer max message bytes ( %d )",
+			maxPayloadSize, limit,
+		)
+	}
+
+	return chunk.NewAssembler(
+		chunk.AssemblerWithLimits(maxPayloadSize, cfg.MaxUploadsPerConn),
+		chunk.AssemblerWithMaxBufferedBytes(cfg.MaxBufferedBytes),
+		chunk.AssemblerWithTimeout(cfg.UploadTimeout),
+	), nil
+}
+
+// producerMaxMessageBytes 返回消息队列生产者的消息大小上限，没有配置上限时返回 0。
+func producerMaxMessageBytes() int64 {
+	switch viper.GetString("synp.mq.type") {
+	case "", mqTypeKafka:
+		return viper.GetInt64("kafka.producer.max_message_bytes")
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,66 @@
+package providers
+
+import (
+	"context"
+	"time"
+
+	"github.com/jrmarcco/synp"
+	nodev1 "github.com/jrmarcco/synp-api/api/go/node/v1"
+	"github.com/jrmarcco/synp/internal/pkg/cluster"
+	cr "github.com/jrmarcco/synp/internal/pkg/cluster/redis"
+	"github.com/jrmarcco/synp/internal/pkg/message"
+	"github.com/jrmarcco/synp/internal/pkg/message/upstream"
+	"github.com/jrmarcco/synp/internal/pkg/presence"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+)
+
+func newClusterRelay(rdb redis.UniversalClient, node *nodev1.Node, lifecycle fx.Lifecycle) (*cr.Relay, error) {
+	type config struct {
+		ChannelPrefix string `mapstructure:"channel_prefix"`
+	}
+
+	cfg := config{}
+	if err := viper.UnmarshalKey("synp.cluster.relay", &cfg); err != nil {
+		return nil, err
+	}
+
+	relay := cr.NewRelay(rdb, node.GetId(), cfg.ChannelPrefix)
+
+	lifecycle.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			return relay.Start(context.WithoutCancel(ctx))
+		},
+		OnStop: func(_ context.Context) error {
+			return relay.Close()
+		},
+	})
+
+	return relay, nil
+}
+
+func newClusterDispatcher(
+	node *nodev1.Node,
+	connManager synp.ConnManager,
+	store presence.Store,
+	relay cluster.Relay,
+	broadcastFunc message.BroadcastFunc,
+) *cluster.Dispatcher {
+	return cluster.NewDispatcher(node.GetId(), connManager, store, relay, broadcastFunc)
+}
+
+func newEphemeralMsgHandler(dispatcher *cluster.Dispatcher, pushFunc message.PushFunc) (*upstream.EphemeralMsgHandler, error) {
+	type config struct {
+		RateLimit  int           `mapstructure:"rate_limit"`
+		Window     time.Duration `mapstructure:"window"`
+		MaxTargets int           `mapstructure:"max_targets"`
+	}
+
+	cfg := config{}
+	if err := viper.UnmarshalKey("synp.handler.message.ephemeral", &cfg); err != nil {
+		return nil, err
+	}
+
+	return upstream.NewEphemeralMsgHandler(cfg.RateLimit, cfg.Window, cfg.MaxTargets, dispatcher, pushFunc), nil
+}
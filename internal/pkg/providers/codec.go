@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/jrmarcco/synp/internal/pkg/codec"
+	"github.com/jrmarcco/synp/internal/pkg/message"
 	"github.com/spf13/viper"
 )
 
@@ -19,7 +20,7 @@ func newCodec() (codec.Codec, error) {
 
 	switch cfg.Type {
 	case "json":
-		return codec.NewJSONCodec(), nil
+		return codec.NewJSONCodec(message.JSONCodecWithSeq()), nil
 	case "proto":
 		return codec.NewProtoCodec(), nil
 	default:
@@ -0,0 +1,64 @@
+package providers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jrmarcco/jit/bean/option"
+	"github.com/jrmarcco/synp/internal/pkg/dedup"
+	dr "github.com/jrmarcco/synp/internal/pkg/dedup/redis"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+)
+
+func newDeduper(rdb redis.Cmdable) (*dedup.Layered, error) {
+	type config struct {
+		CacheExpiration time.Duration `mapstructure:"cache_expiration"`
+
+		Dedup struct {
+			Scope    string `mapstructure:"scope"`
+			FailMode string `mapstructure:"fail_mode"`
+
+			Local struct {
+				Capacity int           `mapstructure:"capacity"`
+				Window   time.Duration `mapstructure:"window"`
+			} `mapstructure:"local"`
+
+			Remote struct {
+				Type string `mapstructure:"type"`
+			} `mapstructure:"remote"`
+		} `mapstructure:"dedup"`
+	}
+
+	cfg := config{}
+	if err := viper.UnmarshalKey("synp.conn.handler", &cfg); err != nil {
+		return nil, err
+	}
+
+	scope, err := dedup.ParseScope(cfg.Dedup.Scope)
+	if err != nil {
+		return nil, err
+	}
+	failMode, err := dedup.ParseFailMode(cfg.Dedup.FailMode)
+	if err != nil {
+		return nil, err
+	}
+
+	// 本地去重的时间窗口默认与 Redis 标记的过期时间一致 ( 覆盖客户端的重发时间 )。
+	window := cfg.Dedup.Local.Window
+	if window <= 0 {
+		window = cfg.CacheExpiration
+	}
+	local := dedup.NewLocalDeduper(cfg.Dedup.Local.Capacity, window)
+
+	var opts []option.Opt[dedup.Layered]
+	switch cfg.Dedup.Remote.Type {
+	case "", "redis":
+		opts = append(opts, dedup.LayeredWithRemote(dr.NewDeduper(rdb, cfg.CacheExpiration, scope), failMode))
+	case "none":
+	default:
+		return nil, fmt.Errorf("unknown dedup remote type: %s", cfg.Dedup.Remote.Type)
+	}
+
+	return dedup.NewLayered(local, opts...), nil
+}
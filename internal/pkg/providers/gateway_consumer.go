@@ -0,0 +1,196 @@
+package providers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jrmarcco/jit/bean/option"
+	nodev1 "github.com/jrmarcco/synp-api/api/go/node/v1"
+	pkgconsumer "github.com/jrmarcco/synp/internal/pkg/xmq/consumer"
+	"github.com/jrmarcco/synp/internal/pkg/xmq/produce"
+	"github.com/jrmarcco/synp/internal/ws/gateway"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+type gatewayConsumerConfig struct {
+	Topic          string        `mapstructure:"topic"`
+	GroupID        string        `mapstructure:"group_id"`
+	Members        int32         `mapstructure:"members"`
+	Concurrency    int           `mapstructure:"concurrency"`
+	QueueSize      int           `mapstructure:"queue_size"`
+	CommitInterval time.Duration `mapstructure:"commit_interval"`
+	Retry          struct {
+		InitRetryInterval time.Duration `mapstructure:"init_retry_interval"`
+		MaxRetryInterval  time.Duration `mapstructure:"max_retry_interval"`
+		MaxRetryCount     int32         `mapstructure:"max_retry_count"`
+	} `mapstructure:"retry"`
+}
+
+// failureConfig 为消费失败消息的处理配置 ( 重试 topic / 死信 topic )。
+type failureConfig struct {
+	Enabled           bool          `mapstructure:"enabled"`
+	RetryTopic        string        `mapstructure:"retry_topic"`
+	RetryDelay        time.Duration `mapstructure:"retry_delay"`
+	MaxDelayedRetries int           `mapstructure:"max_delayed_retries"`
+	DeadLetterTopic   string        `mapstructure:"dead_letter_topic"`
+	RedriveGroupID    string        `mapstructure:"redrive_group_id"`
+}
+
+func loadFailureConfig() (failureConfig, error) {
+	cfg := failureConfig{}
+	if err := viper.UnmarshalKey("synp.gateway.consumer.failure", &cfg); err != nil {
+		return cfg, fmt.Errorf("failed to unmarshal consumer failure config: %w", err)
+	}
+	return cfg, nil
+}
+
+// opts 返回消费者的 option，未配置的项使用默认值。
+func (cfg gatewayConsumerConfig) opts() []option.Opt[gateway.Consumer] {
+	return []option.Opt[gateway.Consumer]{
+		gateway.ConsumerWithConcurrency(cfg.Concurrency, cfg.QueueSize),
+		gateway.ConsumerWithCommitInterval(cfg.CommitInterval),
+		gateway.ConsumerWithRetry(cfg.Retry.InitRetryInterval, cfg.Retry.MaxRetryInterval, cfg.Retry.MaxRetryCount),
+	}
+}
+
+func newGatewayConsumers(
+	consumerFactory pkgconsumer.ConsumerFactory,
+	producer produce.Producer,
+	node *nodev1.Node,
+	logger *zap.Logger,
+) (map[string]*gateway.Consumer, error) {
+	consumers := make(map[string]*gateway.Consumer)
+
+	failureCfg, err := loadFailureConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var failurePolicy *gateway.FailurePolicy
+	if failureCfg.Enabled {
+		failurePolicy, err = gateway.NewFailurePolicy(
+			producer,
+			failureCfg.RetryTopic,
+			failureCfg.DeadLetterTopic,
+			failureCfg.RetryDelay,
+			failureCfg.MaxDelayedRetries,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create consumer failure policy: %w", err)
+		}
+	}
+
+	pushConsumer, err := pushMessageConsumer(consumerFactory, "event_message_downstream", failurePolicy, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create push message consumer: %w", err)
+	}
+	consumers[gateway.EventPushMessage] = pushConsumer
+
+	if failurePolicy != nil && failureCfg.RetryTopic != "" {
+		retryConsumer, err := pushMessageConsumer(consumerFactory, "event_message_downstream_retry", failurePolicy, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create push message retry consumer: %w", err)
+		}
+		consumers[gateway.EventPushMessageRetry] = retryConsumer
+	}
+
+	presenceConsumer, err := presenceConsumer(consumerFactory, node, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create presence consumer: %w", err)
+	}
+	consumers[gateway.EventPresence] = presenceConsumer
+
+	rpcReplyConsumer, err := rpcReplyConsumer(consumerFactory, node, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rpc reply consumer: %w", err)
+	}
+	consumers[gateway.EventRPCReply] = rpcReplyConsumer
+
+	return consumers, err
+}
+
+// pushMessageConsumer 创建 push message ( 或其重试 topic ) 消费者，处理失败的消息交给 failurePolicy 处理。
+func pushMessageConsumer(
+	consumerFactory pkgconsumer.ConsumerFactory,
+	key string,
+	failurePolicy *gateway.FailurePolicy,
+	logger *zap.Logger,
+) (*gateway.Consumer, error) {
+	cfg := gatewayConsumerConfig{}
+	if err := viper.UnmarshalKey("synp.gateway.consumer."+key, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal push message consumer config: %w", err)
+	}
+
+	opts := cfg.opts()
+	if failurePolicy != nil {
+		opts = append(opts, gateway.ConsumerWithFailurePolicy(failurePolicy))
+	}
+
+	return gateway.NewConsumer(
+		consumerFactory,
+		cfg.Topic,
+		cfg.GroupID,
+		cfg.Members,
+		logger,
+		opts...,
+	), nil
+}
+
+// newDeadLetterRedriver 创建死信消息重新发送器，没有开启失败处理时返回 nil。
+func newDeadLetterRedriver(
+	consumerFactory pkgconsumer.ConsumerFactory,
+	producer produce.Producer,
+) (*gateway.DeadLetterRedriver, error) {
+	failureCfg, err := loadFailureConfig()
+	if err != nil {
+		return nil, err
+	}
+	if !failureCfg.Enabled {
+		return nil, nil
+	}
+
+	return gateway.NewDeadLetterRedriver(
+		consumerFactory,
+		producer,
+		failureCfg.DeadLetterTopic,
+		failureCfg.RedriveGroupID,
+		viper.GetString("synp.gateway.consumer.event_message_downstream.topic"),
+	), nil
+}
+
+// presenceConsumer 创建在线状态事件消费者。
+// 每个网关节点都需要收到全部在线状态事件，所以消费者组 ID 需要拼接节点 ID。
+func presenceConsumer(consumerFactory pkgconsumer.ConsumerFactory, node *nodev1.Node, logger *zap.Logger) (*gateway.Consumer, error) {
+	cfg := gatewayConsumerConfig{}
+	if err := viper.UnmarshalKey("synp.gateway.consumer.event_presence", &cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal presence consumer config: %w", err)
+	}
+
+	return gateway.NewConsumer(
+		consumerFactory,
+		cfg.Topic,
+		fmt.Sprintf("%s-%s", cfg.GroupID, node.GetId()),
+		cfg.Members,
+		logger,
+		cfg.opts()...,
+	), nil
+}
+
+// rpcReplyConsumer 创建 RPC 响应消费者。
+// 所有节点共享 reply topic，每个节点都需要收到全部响应并跳过不属于自己的响应，所以消费者组 ID 需要拼接节点 ID。
+func rpcReplyConsumer(consumerFactory pkgconsumer.ConsumerFactory, node *nodev1.Node, logger *zap.Logger) (*gateway.Consumer, error) {
+	cfg := gatewayConsumerConfig{}
+	if err := viper.UnmarshalKey("synp.gateway.consumer.event_rpc_reply", &cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal rpc reply consumer config: %w", err)
+	}
+
+	return gateway.NewConsumer(
+		consumerFactory,
+		cfg.Topic,
+		fmt.Sprintf("%s-%s", cfg.GroupID, node.GetId()),
+		cfg.Members,
+		logger,
+		cfg.opts()...,
+	), nil
+}
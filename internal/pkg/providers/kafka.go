@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/jrmarcco/synp/internal/pkg/xmq/consumer"
+	"github.com/jrmarcco/synp/internal/pkg/xmq/produce"
 	"github.com/segmentio/kafka-go"
 	"github.com/segmentio/kafka-go/sasl"
 	"github.com/segmentio/kafka-go/sasl/scram"
@@ -17,10 +18,9 @@ import (
 	"go.uber.org/zap"
 )
 
-type kafkaFxResult struct {
-	fx.Out
-
+type kafkaClient struct {
 	Writer           *kafka.Writer
+	AsyncWriter      *kafka.Writer
 	ReaderCreateFunc consumer.KafkaReaderCreateFunc
 }
 
@@ -78,46 +78,68 @@ type kafkaOAuthConfig struct {
 	ClientSecret string `mapstructure:"client_secret"`
 }
 
-func newKafkaClient(zapLogger *zap.Logger, lifecycle fx.Lifecycle) (kafkaFxResult, error) {
+// newKafkaMQ 创建基于 kafka 的消息队列。
+func newKafkaMQ(zapLogger *zap.Logger, lifecycle fx.Lifecycle) (mqFxResult, error) {
+	client, err := newKafkaClient(zapLogger, lifecycle)
+	if err != nil {
+		return mqFxResult{}, err
+	}
+
+	return mqFxResult{
+		Producer:        produce.NewKafkaProducer(client.Writer),
+		AsyncProducer:   produce.NewKafkaAsyncProducer(client.AsyncWriter, viper.GetInt("kafka.producer.max_in_flight")),
+		ConsumerFactory: consumer.NewKafkaConsumerFactory(client.ReaderCreateFunc),
+	}, nil
+}
+
+func newKafkaClient(zapLogger *zap.Logger, lifecycle fx.Lifecycle) (*kafkaClient, error) {
 	cfg, err := loadKafkaConfig()
 	if err != nil {
-		return kafkaFxResult{}, err
+		return nil, err
 	}
 
 	// 配置 TLS。
 	tlsConfig, err := configureKafkaTLS(cfg.TLS, zapLogger)
 	if err != nil {
-		return kafkaFxResult{}, err
+		return nil, err
 	}
 
 	// 配置 SASL。
 	saslMechanism, err := configureKafkaSasl(cfg.SASL, zapLogger)
 	if err != nil {
-		return kafkaFxResult{}, err
+		return nil, err
 	}
 
 	// 创建 Writer（Producer）。
-	writer := &kafka.Writer{
-		Addr:         kafka.TCP(cfg.Brokers...),
-		Balancer:     &kafka.Hash{}, // 使用 Hash 负载均衡
-		Compression:  getKafkaCompression(cfg.Producer.Compression),
-		MaxAttempts:  cfg.Producer.RetryMax,
-		BatchSize:    cfg.Producer.BatchSize,
-		BatchTimeout: cfg.Producer.BatchTimeout,
-		ReadTimeout:  cfg.Consumer.ReadTimeout, // 从 broker 读取响应的超时
-		WriteTimeout: cfg.Producer.WriteTimeout,
-		RequiredAcks: getKafkaRequiredAcks(cfg.Producer.RequiredAcks),
-		Async:        false, // 同步模式
-		Transport: &kafka.Transport{
-			TLS:  tlsConfig,
-			SASL: saslMechanism,
-		},
-	}
+	newWriter := func(async bool) *kafka.Writer {
+		writer := &kafka.Writer{
+			Addr:         kafka.TCP(cfg.Brokers...),
+			Balancer:     &kafka.Hash{}, // 使用 Hash 负载均衡
+			Compression:  getKafkaCompression(cfg.Producer.Compression),
+			MaxAttempts:  cfg.Producer.RetryMax,
+			BatchSize:    cfg.Producer.BatchSize,
+			BatchTimeout: cfg.Producer.BatchTimeout,
+			ReadTimeout:  cfg.Consumer.ReadTimeout, // 从 broker 读取响应的超时
+			WriteTimeout: cfg.Producer.WriteTimeout,
+			RequiredAcks: getKafkaRequiredAcks(cfg.Producer.RequiredAcks),
+			Async:        async,
+			Transport: &kafka.Transport{
+				TLS:  tlsConfig,
+				SASL: saslMechanism,
+			},
+		}
 
-	// 如果启用幂等性，设置为精确一次语义。
-	if cfg.Producer.IdempotentEnabled {
-		writer.RequiredAcks = kafka.RequireAll
+		// 如果启用幂等性，设置为精确一次语义。
+		if cfg.Producer.IdempotentEnabled {
+			writer.RequiredAcks = kafka.RequireAll
+		}
+		return writer
 	}
+
+	// 同步模式。
+	writer := newWriter(false)
+	// 异步模式，跨连接批量发送上行消息，通过 Completion 回调获取发送结果。
+	asyncWriter := newWriter(true)
 	zapLogger.Info(
 		"[synp-ioc-kafka] successfully created kafka writer",
 		zap.Strings("brokers", cfg.Brokers),
@@ -165,6 +187,10 @@ func newKafkaClient(zapLogger *zap.Logger, lifecycle fx.Lifecycle) (kafkaFxResul
 	// 注册生命周期钩子。
 	lifecycle.Append(fx.Hook{
 		OnStop: func(_ context.Context) error {
+			// 先关闭异步 writer，等待未完成的消息发送完成。
+			if err := asyncWriter.Close(); err != nil {
+				zapLogger.Error("[synp-ioc-kafka] failed to close kafka async writer", zap.Error(err))
+			}
 			if err := writer.Close(); err != nil {
 				zapLogger.Error("[synp-ioc-kafka] failed to close kafka writer", zap.Error(err))
 				return fmt.Errorf("failed to close kafka writer: %w", err)
@@ -174,8 +200,9 @@ func newKafkaClient(zapLogger *zap.Logger, lifecycle fx.Lifecycle) (kafkaFxResul
 		},
 	})
 
-	return kafkaFxResult{
+	return &kafkaClient{
 		Writer:           writer,
+		AsyncWriter:      asyncWriter,
 		ReaderCreateFunc: readerFactory,
 	}, nil
 }
@@ -3,19 +3,42 @@ package providers
 import (
 	"time"
 
+	"github.com/jrmarcco/jit/bean/option"
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	nodev1 "github.com/jrmarcco/synp-api/api/go/node/v1"
 	"github.com/jrmarcco/synp/internal/pkg/codec"
+	"github.com/jrmarcco/synp/internal/pkg/dedup"
 	"github.com/jrmarcco/synp/internal/pkg/message"
 	"github.com/jrmarcco/synp/internal/pkg/message/downstream"
 	"github.com/jrmarcco/synp/internal/pkg/message/upstream"
+	"github.com/jrmarcco/synp/internal/pkg/receipt"
 	"github.com/jrmarcco/synp/internal/pkg/retransmit"
+	"github.com/jrmarcco/synp/internal/pkg/route"
 	"github.com/jrmarcco/synp/internal/pkg/xmq/produce"
 	"github.com/spf13/viper"
 )
 
-func newFrontendMsgHandler(codec codec.Codec, producer produce.Producer, pushFunc message.PushFunc) *upstream.FrontendMsgHandler {
+func newFrontendMsgHandler(
+	router *route.Router,
+	node *nodev1.Node,
+	codec codec.Codec,
+	producer produce.Producer,
+	asyncProducer produce.AsyncProducer,
+	deduper dedup.Deduper,
+	pushFunc message.PushFunc,
+) *upstream.FrontendMsgHandler {
 	type config struct {
-		Topic            string `mapstructure:"topic"`
-		OnReceiveTimeout int    `mapstructure:"on_receive_timeout"`
+		OnReceiveTimeout int `mapstructure:"on_receive_timeout"`
+
+		InitRetryInterval time.Duration `mapstructure:"init_retry_interval"`
+		MaxRetryInterval  time.Duration `mapstructure:"max_retry_interval"`
+		MaxRetryCount     int32         `mapstructure:"max_retry_count"`
+		OrderFenceTTL     time.Duration `mapstructure:"order_fence_ttl"`
+
+		Async struct {
+			Enabled            bool `mapstructure:"enabled"`
+			MaxInFlightPerConn int  `mapstructure:"max_in_flight_per_conn"`
+		} `mapstructure:"async"`
 	}
 
 	cfg := config{}
@@ -23,15 +46,37 @@ func newFrontendMsgHandler(codec codec.Codec, producer produce.Producer, pushFun
 		panic(err)
 	}
 
+	opts := []option.Opt[upstream.FrontendMsgHandler]{
+		upstream.FrontendMsgHandlerWithRetry(cfg.InitRetryInterval, cfg.MaxRetryInterval, cfg.MaxRetryCount),
+		upstream.FrontendMsgHandlerWithFenceTTL(cfg.OrderFenceTTL),
+		upstream.FrontendMsgHandlerWithNodeID(node.GetId()),
+		upstream.FrontendMsgHandlerWithDeduper(deduper),
+	}
+	if cfg.Async.Enabled {
+		opts = append(opts, upstream.FrontendMsgHandlerWithAsync(asyncProducer, cfg.Async.MaxInFlightPerConn))
+	}
+
 	return upstream.NewFrontendMsgHandler(
-		cfg.Topic,
+		router,
 		time.Duration(cfg.OnReceiveTimeout)*time.Millisecond,
 		codec,
 		producer,
 		pushFunc,
+		opts...,
 	)
 }
 
-func newBackendMsgHandler(pushFunc message.PushFunc, retransmitManager *retransmit.Manager) *downstream.BackendMsgHandler {
-	return downstream.NewBackendMsgHandler(pushFunc, retransmitManager)
+func newBackendMsgHandler(
+	broadcastFunc message.BroadcastFunc,
+	retransmitManager *retransmit.Manager,
+	reporter *receipt.Reporter,
+) *downstream.BackendMsgHandler {
+	return downstream.NewBackendMsgHandler(
+		broadcastFunc,
+		retransmitManager,
+		// 丢弃过期消息时发布过期回执。
+		downstream.BackendMsgHandlerWithExpireFunc(func(msg *messagev1.PushMessage) {
+			reporter.PushExpired(msg, receipt.StageConsume)
+		}),
+	)
 }
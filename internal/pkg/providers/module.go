@@ -2,46 +2,108 @@ package providers
 
 import (
 	"github.com/jrmarcco/synp/internal/pkg/auth"
-	"github.com/jrmarcco/synp/internal/pkg/message"
+	"github.com/jrmarcco/synp/internal/pkg/cluster"
+	"github.com/jrmarcco/synp/internal/pkg/dedup"
 	"github.com/jrmarcco/synp/internal/pkg/message/downstream"
 	"github.com/jrmarcco/synp/internal/pkg/message/upstream"
-	pkgconsumer "github.com/jrmarcco/synp/internal/pkg/xmq/consumer"
-	"github.com/jrmarcco/synp/internal/pkg/xmq/produce"
+	"github.com/jrmarcco/synp/internal/pkg/offline"
+	"github.com/jrmarcco/synp/internal/pkg/presence"
+	"github.com/jrmarcco/synp/internal/pkg/push"
+	"github.com/jrmarcco/synp/internal/pkg/room"
 	"go.uber.org/fx"
 )
 
 var (
 	ZapLoggerFxModule       = fx.Module("zap-logger", fx.Provide(newLogger))
-	RedisFxModule           = fx.Module("redis", fx.Provide(newRedisCmdable))
+	RedisFxModule           = fx.Module("redis", fx.Provide(newRedisClient))
 	CodecFxModule           = fx.Module("codec", fx.Provide(newCodec))
-	MessagePushFuncFxModule = fx.Module("message-push-func", fx.Provide(message.DefaultPushFunc))
+	MessagePushFuncFxModule = fx.Module("message-push-func", fx.Provide(newPushFunc, newBroadcastFunc))
+	ChunkFxModule           = fx.Module("chunk", fx.Provide(newChunkAssembler))
 	RetransmitFxModule      = fx.Module("retransmit", fx.Provide(newRetransmitManager))
-)
-
-var (
-	KafkaFxModule         = fx.Module("kafka", fx.Provide(newKafkaClient))
-	KafkaProducerFxModule = fx.Module(
-		"kafka-producer",
+	NodeFxModule            = fx.Module("node", fx.Provide(newNode))
+	PresenceFxModule        = fx.Module(
+		"presence",
+		fx.Provide(
+			fx.Annotate(
+				newPresenceStore,
+				fx.As(new(presence.Store)),
+			),
+			newPresenceTracker,
+			newPresenceHub,
+		),
+	)
+	OfflineFxModule = fx.Module(
+		"offline",
+		fx.Provide(
+			fx.Annotate(
+				newOfflineStore,
+				fx.As(new(offline.Store)),
+			),
+		),
+	)
+	// PushFxModule 提供后端直接推送 ( 管理 API ) 的 Pusher 及房间成员存储。
+	PushFxModule = fx.Module(
+		"push",
 		fx.Provide(
+			push.NewAckTracker,
 			fx.Annotate(
-				produce.NewKafkaProducer,
-				fx.As(new(produce.Producer)),
+				newPushAckListener,
+				fx.ResultTags(`group:"downstream-ack-listener"`),
+			),
+			newPusher,
+			fx.Annotate(
+				newRoomStore,
+				fx.As(new(room.Store)),
+			),
+		),
+	)
+	ReceiptFxModule = fx.Module(
+		"receipt",
+		fx.Provide(
+			newReceiptReporter,
+			fx.Annotate(
+				newReceiptAckListener,
+				fx.ResultTags(`group:"downstream-ack-listener"`),
 			),
 		),
 	)
-	KafkaConsumerFxModule = fx.Module(
-		"kafka-consumer",
+	RPCFxModule     = fx.Module("rpc", fx.Provide(newRPCManager))
+	RouteFxModule   = fx.Module("route", fx.Provide(newRouter))
+	ClusterFxModule = fx.Module(
+		"cluster",
 		fx.Provide(
 			fx.Annotate(
-				pkgconsumer.NewKafkaConsumerFactory,
-				fx.As(new(pkgconsumer.ConsumerFactory)),
+				newClusterRelay,
+				fx.As(new(cluster.Relay)),
 			),
-			newKafkaConsumers,
+			newClusterDispatcher,
+		),
+	)
+)
+
+var (
+	// MQFxModule 按配置创建消息队列 ( kafka / redis / nats / memory ) 的生产者及消费者工厂。
+	MQFxModule              = fx.Module("mq", fx.Provide(newMQ))
+	GatewayConsumerFxModule = fx.Module(
+		"gateway-consumer",
+		fx.Provide(
+			newGatewayConsumers,
+			newDeadLetterRedriver,
 		),
 	)
 )
 
 var (
+	DedupFxModule = fx.Module(
+		"dedup",
+		fx.Provide(
+			fx.Annotate(
+				newDeduper,
+				fx.As(new(dedup.Deduper)),
+			),
+		),
+	)
+
 	ValidatorFxModule = fx.Module(
 		"validator",
 		fx.Provide(
@@ -72,6 +134,35 @@ var (
 			// 下行消息 ack 处理器。
 			fx.Annotate(
 				upstream.NewDownstreamAckHandler,
+				fx.ParamTags(``, `group:"downstream-ack-listener"`),
+				fx.As(new(upstream.UMsgHandler)),
+				fx.ResultTags(`group:"upstream-message-handler"`),
+			),
+
+			// 已读回执消息处理器。
+			fx.Annotate(
+				upstream.NewReadReceiptHandler,
+				fx.As(new(upstream.UMsgHandler)),
+				fx.ResultTags(`group:"upstream-message-handler"`),
+			),
+
+			// 在线状态订阅消息处理器。
+			fx.Annotate(
+				upstream.NewPresenceSubscribeHandler,
+				fx.As(new(upstream.UMsgHandler)),
+				fx.ResultTags(`group:"upstream-message-handler"`),
+			),
+
+			// RPC 请求消息处理器。
+			fx.Annotate(
+				upstream.NewRPCRequestHandler,
+				fx.As(new(upstream.UMsgHandler)),
+				fx.ResultTags(`group:"upstream-message-handler"`),
+			),
+
+			// 瞬时信号消息处理器。
+			fx.Annotate(
+				newEphemeralMsgHandler,
 				fx.As(new(upstream.UMsgHandler)),
 				fx.ResultTags(`group:"upstream-message-handler"`),
 			),
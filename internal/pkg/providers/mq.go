@@ -0,0 +1,207 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jrmarcco/synp/internal/pkg/xmq/consumer"
+	"github.com/jrmarcco/synp/internal/pkg/xmq/memory"
+	"github.com/jrmarcco/synp/internal/pkg/xmq/produce"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// 支持的消息队列类型。
+const (
+	mqTypeKafka  = "kafka"
+	mqTypeRedis  = "redis"  // Redis Streams
+	mqTypeNats   = "nats"   // NATS JetStream
+	mqTypeMemory = "memory" // 进程内消息队列，用于测试及单节点部署
+)
+
+type mqFxResult struct {
+	fx.Out
+
+	Producer        produce.Producer
+	AsyncProducer   produce.AsyncProducer
+	ConsumerFactory consumer.ConsumerFactory
+}
+
+// newMQ 按配置 ( synp.mq.type ) 创建消息队列的生产者及消费者工厂，默认使用 kafka。
+func newMQ(rdb redis.Cmdable, zapLogger *zap.Logger, lifecycle fx.Lifecycle) (mqFxResult, error) {
+	mqType := viper.GetString("synp.mq.type")
+	if mqType == "" {
+		mqType = mqTypeKafka
+	}
+	zapLogger.Info("[synp-ioc-mq] creating message queue", zap.String("type", mqType))
+
+	switch mqType {
+	case mqTypeKafka:
+		return newKafkaMQ(zapLogger, lifecycle)
+	case mqTypeRedis:
+		return newRedisStreamMQ(rdb, lifecycle)
+	case mqTypeNats:
+		return newNatsMQ(zapLogger, lifecycle)
+	case mqTypeMemory:
+		return newMemoryMQ(lifecycle)
+	default:
+		return mqFxResult{}, fmt.Errorf(
+			"unsupported mq type: %s, expected '%s', '%s', '%s' or '%s'",
+			mqType, mqTypeKafka, mqTypeRedis, mqTypeNats, mqTypeMemory,
+		)
+	}
+}
+
+// withAsyncAdapter 为没有原生异步发送的消息队列创建异步生产者，并在停止时等待未完成的消息发送完成。
+func withAsyncAdapter(producer produce.Producer, factory consumer.ConsumerFactory, lifecycle fx.Lifecycle) mqFxResult {
+	asyncProducer := produce.NewAsyncProducerAdapter(producer, viper.GetInt("synp.mq.max_in_flight"))
+	lifecycle.Append(fx.Hook{
+		OnStop: func(_ context.Context) error {
+			return asyncProducer.Close()
+		},
+	})
+
+	return mqFxResult{
+		Producer:        producer,
+		AsyncProducer:   asyncProducer,
+		ConsumerFactory: factory,
+	}
+}
+
+// newRedisStreamMQ 创建基于 Redis Streams 的消息队列，复用全局的 redis 客户端。
+func newRedisStreamMQ(rdb redis.Cmdable, lifecycle fx.Lifecycle) (mqFxResult, error) {
+	type config struct {
+		StreamPrefix string        `mapstructure:"stream_prefix"`
+		MaxLen       int64         `mapstructure:"max_len"`
+		StartID      string        `mapstructure:"start_id"`
+		Block        time.Duration `mapstructure:"block"`
+		BatchSize    int64         `mapstructure:"batch_size"`
+		ClaimMinIdle time.Duration `mapstructure:"claim_min_idle"`
+	}
+
+	cfg := config{}
+	if err := viper.UnmarshalKey("redis.stream", &cfg); err != nil {
+		return mqFxResult{}, fmt.Errorf("failed to unmarshal redis stream config: %w", err)
+	}
+
+	producer := produce.NewRedisStreamProducer(rdb, cfg.StreamPrefix, cfg.MaxLen)
+	factory := consumer.NewRedisStreamConsumerFactory(rdb, consumer.RedisStreamConfig{
+		StreamPrefix: cfg.StreamPrefix,
+		StartID:      cfg.StartID,
+		Block:        cfg.Block,
+		BatchSize:    cfg.BatchSize,
+		ClaimMinIdle: cfg.ClaimMinIdle,
+	})
+	return withAsyncAdapter(producer, factory, lifecycle), nil
+}
+
+// newNatsMQ 创建基于 NATS JetStream 的消息队列。
+func newNatsMQ(zapLogger *zap.Logger, lifecycle fx.Lifecycle) (mqFxResult, error) {
+	type config struct {
+		URL      string `mapstructure:"url"`
+		User     string `mapstructure:"user"`
+		Password string `mapstructure:"password"`
+		Token    string `mapstructure:"token"`
+
+		Stream struct {
+			Name     string        `mapstructure:"name"`
+			Subjects []string      `mapstructure:"subjects"`
+			MaxAge   time.Duration `mapstructure:"max_age"`
+			Ensure   bool          `mapstructure:"ensure"` // 启动时创建或更新 stream
+		} `mapstructure:"stream"`
+
+		Consumer struct {
+			DeliverAll    bool          `mapstructure:"deliver_all"`
+			AckWait       time.Duration `mapstructure:"ack_wait"`
+			MaxAckPending int           `mapstructure:"max_ack_pending"`
+		} `mapstructure:"consumer"`
+	}
+
+	cfg := config{}
+	if err := viper.UnmarshalKey("nats", &cfg); err != nil {
+		return mqFxResult{}, fmt.Errorf("failed to unmarshal nats config: %w", err)
+	}
+
+	opts := []nats.Option{nats.Name("synp-gateway"), nats.MaxReconnects(-1)}
+	if cfg.User != "" {
+		opts = append(opts, nats.UserInfo(cfg.User, cfg.Password))
+	}
+	if cfg.Token != "" {
+		opts = append(opts, nats.Token(cfg.Token))
+	}
+
+	nc, err := nats.Connect(cfg.URL, opts...)
+	if err != nil {
+		return mqFxResult{}, fmt.Errorf("failed to connect nats: %w", err)
+	}
+	js, err := jetstream.New(nc)
+	if err != nil {
+		nc.Close()
+		return mqFxResult{}, fmt.Errorf("failed to create nats jetstream: %w", err)
+	}
+
+	if cfg.Stream.Ensure {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+			Name:     cfg.Stream.Name,
+			Subjects: cfg.Stream.Subjects,
+			MaxAge:   cfg.Stream.MaxAge,
+		})
+		if err != nil {
+			nc.Close()
+			return mqFxResult{}, fmt.Errorf("failed to create nats stream: %w", err)
+		}
+	}
+
+	zapLogger.Info(
+		"[synp-ioc-nats] successfully connected nats jetstream",
+		zap.String("url", cfg.URL),
+		zap.String("stream", cfg.Stream.Name),
+	)
+
+	// 停止时先等待异步生产者发送完成 ( 后注册的钩子先执行 )，再关闭连接。
+	lifecycle.Append(fx.Hook{
+		OnStop: func(_ context.Context) error {
+			if err := nc.Drain(); err != nil {
+				zapLogger.Error("[synp-ioc-nats] failed to drain nats connection", zap.Error(err))
+				return err
+			}
+			zapLogger.Info("[synp-ioc-nats] nats connection closed")
+			return nil
+		},
+	})
+
+	return withAsyncAdapter(
+		produce.NewNatsProducer(js),
+		consumer.NewNatsConsumerFactory(js, consumer.NatsConfig{
+			Stream:        cfg.Stream.Name,
+			DeliverAll:    cfg.Consumer.DeliverAll,
+			AckWait:       cfg.Consumer.AckWait,
+			MaxAckPending: cfg.Consumer.MaxAckPending,
+		}),
+		lifecycle,
+	), nil
+}
+
+// newMemoryMQ 创建进程内消息队列。
+func newMemoryMQ(lifecycle fx.Lifecycle) (mqFxResult, error) {
+	type config struct {
+		Partitions  int `mapstructure:"partitions"`
+		MaxMessages int `mapstructure:"max_messages"`
+	}
+
+	cfg := config{}
+	if err := viper.UnmarshalKey("synp.mq.memory", &cfg); err != nil {
+		return mqFxResult{}, fmt.Errorf("failed to unmarshal memory mq config: %w", err)
+	}
+
+	broker := memory.NewBroker(cfg.Partitions, cfg.MaxMessages)
+	return withAsyncAdapter(broker, broker, lifecycle), nil
+}
@@ -0,0 +1,44 @@
+package providers
+
+import (
+	"fmt"
+	"os"
+
+	nodev1 "github.com/jrmarcco/synp-api/api/go/node/v1"
+	"github.com/spf13/viper"
+)
+
+func newNode() (*nodev1.Node, error) {
+	type config struct {
+		ID       string   `mapstructure:"id"`
+		IP       string   `mapstructure:"ip"`
+		Port     int32    `mapstructure:"port"`
+		Weight   int32    `mapstructure:"weight"`
+		Location string   `mapstructure:"location"`
+		Labels   []string `mapstructure:"labels"`
+	}
+
+	cfg := config{}
+	if err := viper.UnmarshalKey("synp.node", &cfg); err != nil {
+		return nil, err
+	}
+
+	// 未配置节点 ID 时使用主机名，
+	// 容器环境下主机名即为 pod / container 名称，可以保证唯一。
+	if cfg.ID == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get hostname as node id: %w", err)
+		}
+		cfg.ID = hostname
+	}
+
+	return &nodev1.Node{
+		Id:       cfg.ID,
+		Ip:       cfg.IP,
+		Port:     cfg.Port,
+		Weight:   cfg.Weight,
+		Location: cfg.Location,
+		Labels:   cfg.Labels,
+	}, nil
+}
@@ -0,0 +1,24 @@
+package providers
+
+import (
+	"time"
+
+	or "github.com/jrmarcco/synp/internal/pkg/offline/redis"
+	"github.com/jrmarcco/synp/internal/pkg/receipt"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+)
+
+// newOfflineStore 创建离线消息存储，保存成功后发布 offline_stored 回执。
+func newOfflineStore(rdb redis.Cmdable, reporter *receipt.Reporter) (*receipt.OfflineStore, error) {
+	type config struct {
+		MaxMessages int64         `mapstructure:"max_messages"`
+		TTL         time.Duration `mapstructure:"ttl"`
+	}
+
+	cfg := config{}
+	if err := viper.UnmarshalKey("synp.offline", &cfg); err != nil {
+		return nil, err
+	}
+	return receipt.NewOfflineStore(or.NewStore(rdb, cfg.MaxMessages, cfg.TTL), reporter), nil
+}
@@ -0,0 +1,92 @@
+package providers
+
+import (
+	"context"
+	"time"
+
+	nodev1 "github.com/jrmarcco/synp-api/api/go/node/v1"
+	"github.com/jrmarcco/synp/internal/pkg/message"
+	"github.com/jrmarcco/synp/internal/pkg/presence"
+	pr "github.com/jrmarcco/synp/internal/pkg/presence/redis"
+	"github.com/jrmarcco/synp/internal/pkg/xmq/produce"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+)
+
+type presenceConfig struct {
+	Topic           string        `mapstructure:"topic"`
+	TTL             time.Duration `mapstructure:"ttl"`
+	Debounce        time.Duration `mapstructure:"debounce"`
+	RefreshInterval time.Duration `mapstructure:"refresh_interval"`
+	RequestTimeout  time.Duration `mapstructure:"request_timeout"`
+
+	Subscription presenceSubscriptionConfig `mapstructure:"subscription"`
+}
+
+type presenceSubscriptionConfig struct {
+	MaxUIDs        int           `mapstructure:"max_uids"`        // 单个连接最多订阅的用户数
+	CoalesceWindow time.Duration `mapstructure:"coalesce_window"` // 状态变更合并窗口
+}
+
+func loadPresenceConfig() (presenceConfig, error) {
+	cfg := presenceConfig{}
+	if err := viper.UnmarshalKey("synp.presence", &cfg); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+func newPresenceStore(rdb redis.Cmdable) (*pr.Store, error) {
+	cfg, err := loadPresenceConfig()
+	if err != nil {
+		return nil, err
+	}
+	return pr.NewStore(rdb, cfg.TTL), nil
+}
+
+func newPresenceTracker(
+	store presence.Store,
+	producer produce.Producer,
+	node *nodev1.Node,
+	lifecycle fx.Lifecycle,
+) (*presence.Tracker, error) {
+	cfg, err := loadPresenceConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	tracker := presence.NewTracker(
+		store,
+		producer,
+		node.GetId(),
+		presence.TrackerWithTopic(cfg.Topic),
+		presence.TrackerWithDebounce(cfg.Debounce),
+		presence.TrackerWithRefreshInterval(cfg.RefreshInterval),
+		presence.TrackerWithRequestTimeout(cfg.RequestTimeout),
+	)
+
+	lifecycle.Append(fx.Hook{
+		OnStop: func(_ context.Context) error {
+			tracker.Close()
+			return nil
+		},
+	})
+
+	return tracker, nil
+}
+
+func newPresenceHub(store presence.Store, broadcastFunc message.BroadcastFunc) (*presence.Hub, error) {
+	cfg, err := loadPresenceConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return presence.NewHub(
+		store,
+		broadcastFunc,
+		presence.HubWithMaxSubscriptions(cfg.Subscription.MaxUIDs),
+		presence.HubWithCoalesceWindow(cfg.Subscription.CoalesceWindow),
+		presence.HubWithRequestTimeout(cfg.RequestTimeout),
+	), nil
+}
@@ -0,0 +1,58 @@
+package providers
+
+import (
+	"time"
+
+	"github.com/jrmarcco/synp"
+	nodev1 "github.com/jrmarcco/synp-api/api/go/node/v1"
+	"github.com/jrmarcco/synp/internal/pkg/cluster"
+	"github.com/jrmarcco/synp/internal/pkg/message/upstream"
+	"github.com/jrmarcco/synp/internal/pkg/offline"
+	"github.com/jrmarcco/synp/internal/pkg/presence"
+	"github.com/jrmarcco/synp/internal/pkg/push"
+	rr "github.com/jrmarcco/synp/internal/pkg/room/redis"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+)
+
+func newPusher(
+	node *nodev1.Node,
+	connManager synp.ConnManager,
+	handler synp.Handler,
+	acks *push.AckTracker,
+	offlineStore offline.Store,
+	presenceStore presence.Store,
+	relay cluster.Relay,
+) (*push.Pusher, error) {
+	type config struct {
+		MaxAckTimeout    time.Duration `mapstructure:"max_ack_timeout"`
+		ForwardTimeout   time.Duration `mapstructure:"forward_timeout"`
+		BatchConcurrency int           `mapstructure:"batch_concurrency"`
+	}
+
+	cfg := config{}
+	if err := viper.UnmarshalKey("synp.push", &cfg); err != nil {
+		return nil, err
+	}
+
+	return push.NewPusher(
+		node.GetId(),
+		connManager,
+		handler,
+		acks,
+		push.PusherWithOfflineStore(offlineStore),
+		push.PusherWithCluster(presenceStore, relay),
+		push.PusherWithMaxAckTimeout(cfg.MaxAckTimeout),
+		push.PusherWithForwardTimeout(cfg.ForwardTimeout),
+		push.PusherWithBatchConcurrency(cfg.BatchConcurrency),
+	), nil
+}
+
+// newPushAckListener 将 AckTracker 注册为 downstream ack 的监听者。
+func newPushAckListener(acks *push.AckTracker) upstream.DownstreamAckListener {
+	return acks
+}
+
+func newRoomStore(rdb redis.Cmdable) *rr.Store {
+	return rr.NewStore(rdb)
+}
@@ -0,0 +1,35 @@
+package providers
+
+import (
+	"time"
+
+	nodev1 "github.com/jrmarcco/synp-api/api/go/node/v1"
+	"github.com/jrmarcco/synp/internal/pkg/message/upstream"
+	"github.com/jrmarcco/synp/internal/pkg/receipt"
+	"github.com/jrmarcco/synp/internal/pkg/xmq/produce"
+	"github.com/spf13/viper"
+)
+
+func newReceiptReporter(producer produce.AsyncProducer, node *nodev1.Node) (*receipt.Reporter, error) {
+	type config struct {
+		Topic          string        `mapstructure:"topic"`
+		RequestTimeout time.Duration `mapstructure:"request_timeout"`
+	}
+
+	cfg := config{}
+	if err := viper.UnmarshalKey("synp.receipt", &cfg); err != nil {
+		return nil, err
+	}
+
+	return receipt.NewReporter(
+		producer,
+		node.GetId(),
+		cfg.Topic,
+		receipt.ReporterWithRequestTimeout(cfg.RequestTimeout),
+	), nil
+}
+
+// newReceiptAckListener 将 Reporter 注册为 downstream ack 的监听者，发布 delivered 回执。
+func newReceiptAckListener(reporter *receipt.Reporter) upstream.DownstreamAckListener {
+	return reporter
+}
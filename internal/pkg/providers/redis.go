@@ -10,7 +10,15 @@ import (
 	"go.uber.org/zap"
 )
 
-func newRedisCmdable(zapLogger *zap.Logger, lifecycle fx.Lifecycle) (redis.Cmdable, error) {
+type redisFxResult struct {
+	fx.Out
+
+	Cmdable redis.Cmdable
+	// Client 用于 Cmdable 不支持的操作，如 Pub/Sub。
+	Client redis.UniversalClient
+}
+
+func newRedisClient(zapLogger *zap.Logger, lifecycle fx.Lifecycle) (redisFxResult, error) {
 	type config struct {
 		Addr     string `mapstructure:"addr"`
 		Password string `mapstructure:"password"`
@@ -18,7 +26,7 @@ func newRedisCmdable(zapLogger *zap.Logger, lifecycle fx.Lifecycle) (redis.Cmdab
 
 	cfg := config{}
 	if err := viper.UnmarshalKey("redis", &cfg); err != nil {
-		return nil, err
+		return redisFxResult{}, err
 	}
 
 	rdb := redis.NewClient(&redis.Options{
@@ -47,5 +55,8 @@ func newRedisCmdable(zapLogger *zap.Logger, lifecycle fx.Lifecycle) (redis.Cmdab
 		},
 	})
 
-	return rdb, nil
+	return redisFxResult{
+		Cmdable: rdb,
+		Client:  rdb,
+	}, nil
 }
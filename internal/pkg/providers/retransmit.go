@@ -2,30 +2,72 @@ package providers
 
 import (
 	"context"
+	"fmt"
 	"time"
 
+	"github.com/jrmarcco/jit/bean/option"
+	"github.com/jrmarcco/synp"
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
 	"github.com/jrmarcco/synp/internal/pkg/message"
+	"github.com/jrmarcco/synp/internal/pkg/receipt"
 	"github.com/jrmarcco/synp/internal/pkg/retransmit"
+	rr "github.com/jrmarcco/synp/internal/pkg/retransmit/redis"
+	"github.com/redis/go-redis/v9"
 	"github.com/spf13/viper"
 	"go.uber.org/fx"
 )
 
-func newRetransmitManager(pushFunc message.PushFunc, lifecycle fx.Lifecycle) (*retransmit.Manager, error) {
+func newRetransmitManager(
+	pushFunc message.PushFunc,
+	reporter *receipt.Reporter,
+	rdb redis.Cmdable,
+	lifecycle fx.Lifecycle,
+) (*retransmit.Manager, error) {
 	type config struct {
-		Interval int `mapstructure:"interval"`
-		MaxRetry int `mapstructure:"max_retry"`
+		InitialInterval time.Duration `mapstructure:"initial_interval"`
+		MaxInterval     time.Duration `mapstructure:"max_interval"`
+		Multiplier      float64       `mapstructure:"multiplier"`
+		Jitter          float64       `mapstructure:"jitter"`
+		MaxRetry        int32         `mapstructure:"max_retry"`
+		Tick            time.Duration `mapstructure:"tick"`
+
+		Store struct {
+			Type           string        `mapstructure:"type"`
+			TTL            time.Duration `mapstructure:"ttl"`
+			RequestTimeout time.Duration `mapstructure:"request_timeout"`
+		} `mapstructure:"store"`
 	}
 
 	cfg := config{}
-	if err := viper.UnmarshalKey("retransmit", &cfg); err != nil {
+	if err := viper.UnmarshalKey("synp.retransmit", &cfg); err != nil {
 		return nil, err
 	}
 
-	manager := retransmit.NewManager(
-		time.Duration(cfg.Interval)*time.Millisecond,
-		int32(cfg.MaxRetry),
-		pushFunc,
-	)
+	opts := []option.Opt[retransmit.Manager]{
+		retransmit.ManagerWithBackoff(retransmit.Backoff{
+			MaxInterval: cfg.MaxInterval,
+			Multiplier:  cfg.Multiplier,
+			Jitter:      cfg.Jitter,
+		}),
+		retransmit.ManagerWithTick(cfg.Tick),
+		// 放弃重传时发布投递失败回执。
+		retransmit.ManagerWithFailFunc(reporter.OnRetransmitFailed),
+		// 消息过期停止重传时发布过期回执。
+		retransmit.ManagerWithExpireFunc(func(conn synp.Conn, msg *messagev1.Message) {
+			reporter.Expired(conn, msg, receipt.StageRetransmit)
+		}),
+	}
+
+	switch cfg.Store.Type {
+	case "", "memory":
+		opts = append(opts, retransmit.ManagerWithStore(retransmit.NewMemoryStore(cfg.Store.TTL), cfg.Store.RequestTimeout))
+	case "redis":
+		opts = append(opts, retransmit.ManagerWithStore(rr.NewStore(rdb, cfg.Store.TTL), cfg.Store.RequestTimeout))
+	default:
+		return nil, fmt.Errorf("invalid retransmit store type %q", cfg.Store.Type)
+	}
+
+	manager := retransmit.NewManager(cfg.InitialInterval, cfg.MaxRetry, pushFunc, opts...)
 
 	lifecycle.Append(fx.Hook{
 		OnStop: func(_ context.Context) error {
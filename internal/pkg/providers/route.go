@@ -0,0 +1,77 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/jrmarcco/synp/internal/pkg/route"
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const defaultRouteConfigFile = "config/route.yaml"
+
+// newRouter 创建上行消息路由。
+// 路由表使用单独的配置文件，文件变更时自动重新加载，加载失败时保留原路由表。
+func newRouter(logger *zap.Logger, lifecycle fx.Lifecycle) (*route.Router, error) {
+	file := viper.GetString("synp.route.config_file")
+	if file == "" {
+		file = defaultRouteConfigFile
+	}
+
+	v := viper.New()
+	v.SetConfigFile(file)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read route config: %w", err)
+	}
+
+	cfg, err := loadRouteConfig(v)
+	if err != nil {
+		return nil, err
+	}
+
+	router, err := route.NewRouter(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	v.OnConfigChange(func(e fsnotify.Event) {
+		cfg, err := loadRouteConfig(v)
+		if err == nil {
+			err = router.Reload(cfg)
+		}
+		if err != nil {
+			logger.Error(
+				"[synp-ioc-route] failed to reload route config, keep the previous one",
+				zap.String("file", e.Name),
+				zap.Error(err),
+			)
+			return
+		}
+
+		logger.Info(
+			"[synp-ioc-route] route config reloaded",
+			zap.String("file", e.Name),
+			zap.Int("rule_cnt", len(cfg.Rules)),
+		)
+	})
+
+	lifecycle.Append(fx.Hook{
+		OnStart: func(_ context.Context) error {
+			v.WatchConfig()
+			return nil
+		},
+	})
+
+	return router, nil
+}
+
+func loadRouteConfig(v *viper.Viper) (route.Config, error) {
+	cfg := route.Config{}
+	if err := v.UnmarshalKey("route", &cfg); err != nil {
+		return cfg, fmt.Errorf("failed to unmarshal route config: %w", err)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,57 @@
+package providers
+
+import (
+	"context"
+	"time"
+
+	nodev1 "github.com/jrmarcco/synp-api/api/go/node/v1"
+	"github.com/jrmarcco/synp/internal/pkg/message"
+	"github.com/jrmarcco/synp/internal/pkg/rpc"
+	"github.com/jrmarcco/synp/internal/pkg/xmq/produce"
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+)
+
+func newRPCManager(
+	producer produce.Producer,
+	pushFunc message.PushFunc,
+	node *nodev1.Node,
+	lifecycle fx.Lifecycle,
+) (*rpc.Manager, error) {
+	type config struct {
+		Topic          string        `mapstructure:"topic"`
+		ReplyTopic     string        `mapstructure:"reply_topic"`
+		CallbackURL    string        `mapstructure:"callback_url"`
+		DefaultTimeout time.Duration `mapstructure:"default_timeout"`
+		MaxTimeout     time.Duration `mapstructure:"max_timeout"`
+		MaxPending     int           `mapstructure:"max_pending"`
+		RequestTimeout time.Duration `mapstructure:"request_timeout"`
+	}
+
+	cfg := config{}
+	if err := viper.UnmarshalKey("synp.rpc", &cfg); err != nil {
+		return nil, err
+	}
+
+	manager := rpc.NewManager(
+		producer,
+		pushFunc,
+		node.GetId(),
+		cfg.Topic,
+		rpc.ManagerWithReplyTopic(cfg.ReplyTopic),
+		rpc.ManagerWithCallbackURL(cfg.CallbackURL),
+		rpc.ManagerWithDefaultTimeout(cfg.DefaultTimeout),
+		rpc.ManagerWithMaxTimeout(cfg.MaxTimeout),
+		rpc.ManagerWithMaxPending(cfg.MaxPending),
+		rpc.ManagerWithRequestTimeout(cfg.RequestTimeout),
+	)
+
+	lifecycle.Append(fx.Hook{
+		OnStop: func(_ context.Context) error {
+			manager.Close()
+			return nil
+		},
+	})
+
+	return manager, nil
+}
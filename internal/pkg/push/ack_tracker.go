@@ -0,0 +1,71 @@
+package push
+
+import (
+	"sync"
+
+	"github.com/jrmarcco/synp"
+	"github.com/jrmarcco/synp/internal/pkg/message/upstream"
+)
+
+var _ upstream.DownstreamAckListener = (*AckTracker)(nil)
+
+// ackWaiter 等待同一条消息在任意一个连接上的 ack。
+type ackWaiter struct {
+	done chan struct{}
+	once sync.Once
+}
+
+func (w *ackWaiter) resolve() {
+	w.once.Do(func() { close(w.done) })
+}
+
+// AckTracker 记录等待前端 ack 的推送，收到 ack 时唤醒等待者。
+type AckTracker struct {
+	mu      sync.Mutex
+	waiters map[string]*ackWaiter // key (connId:messageId) -> ackWaiter
+}
+
+// watch 等待消息在 conns 中任意一个连接上的 ack，需要在发送消息之前调用。
+// 返回的 cancel 用于取消等待，等待结束后必须调用。
+func (t *AckTracker) watch(conns []synp.Conn, messageID string) (<-chan struct{}, func()) {
+	w := &ackWaiter{done: make(chan struct{})}
+
+	keys := make([]string, 0, len(conns))
+	t.mu.Lock()
+	for _, conn := range conns {
+		key := t.key(conn.ID(), messageID)
+		t.waiters[key] = w
+		keys = append(keys, key)
+	}
+	t.mu.Unlock()
+
+	return w.done, func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		for _, key := range keys {
+			if t.waiters[key] == w {
+				delete(t.waiters, key)
+			}
+		}
+	}
+}
+
+func (t *AckTracker) OnDownstreamAck(conn synp.Conn, messageID string) {
+	t.mu.Lock()
+	w, ok := t.waiters[t.key(conn.ID(), messageID)]
+	t.mu.Unlock()
+
+	if ok {
+		w.resolve()
+	}
+}
+
+func (t *AckTracker) key(connID, messageID string) string {
+	return connID + ":" + messageID
+}
+
+func NewAckTracker() *AckTracker {
+	return &AckTracker{
+		waiters: make(map[string]*ackWaiter),
+	}
+}
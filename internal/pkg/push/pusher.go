@@ -0,0 +1,730 @@
+package push
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jrmarcco/jit/bean/option"
+	"github.com/jrmarcco/synp"
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/jrmarcco/synp/internal/pkg/cluster"
+	"github.com/jrmarcco/synp/internal/pkg/correlation"
+	"github.com/jrmarcco/synp/internal/pkg/message"
+	"github.com/jrmarcco/synp/internal/pkg/offline"
+	"github.com/jrmarcco/synp/internal/pkg/presence"
+	"github.com/jrmarcco/synp/internal/pkg/session"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	DefaultMaxAckTimeout    = 30 * time.Second
+	DefaultForwardTimeout   = 3 * time.Second
+	DefaultBatchConcurrency = 32
+)
+
+// 节点间转发的 envelope 类型。
+const (
+	KindPush       = "push"           // 转发到接收者所在节点的推送请求
+	KindPushResult = "push_result"    // 接收者所在节点回复的投递结果
+	KindBroadcast  = "push_broadcast" // 广播
+)
+
+// forwardRequest 为转发到其他节点的推送请求。
+type forwardRequest struct {
+	CorrelationID string `json:"correlationId"`
+	Message       []byte `json:"message"`    // messagev1.PushMessage ( protobuf )
+	AckTimeout    int64  `json:"ackTimeout"` // 毫秒
+}
+
+// forwardReply 为其他节点回复的投递结果。
+type forwardReply struct {
+	CorrelationID string `json:"correlationId"`
+	Result        Result `json:"result"`
+}
+
+// Pusher 负责将后端直接推送的消息投递给前端，并同步返回投递结果。
+//
+// 投递流程：
+//
+//	1、接收者在本节点有连接时，通过 synp.Handler.OnReceiveFromBackend 投递 ( 与消息队列推送相同 )。
+//	2、接收者在其他节点有连接时 ( 根据在线状态 )，通过 cluster.Relay 转发到该节点投递并等待其回复结果。
+//	   接收者的多个设备可能分布在不同节点上，本节点投递与转发同时进行，结果合并后返回。
+//	3、接收者不在任何节点在线时保存为离线消息。
+//
+// 需要等待 ack 时，投递后等待前端的 downstream ack，超时后返回 StatusDelivered。
+type Pusher struct {
+	nodeID string
+	ids    *correlation.Generator
+
+	connManager synp.ConnManager
+	handler     synp.Handler
+	acks        *AckTracker
+
+	offlineStore  offline.Store
+	presenceStore presence.Store
+	relay         cluster.Relay
+
+	maxAckTimeout    time.Duration
+	forwardTimeout   time.Duration
+	batchConcurrency int
+
+	mu      sync.Mutex
+	pending map[string]chan Result // correlation id -> 等待转发结果的 channel
+}
+
+// Push 推送单条消息，ackTimeout 大于 0 时等待前端 ack ( 最长 maxAckTimeout )。
+func (p *Pusher) Push(ctx context.Context, msg *messagev1.PushMessage, ackTimeout time.Duration) Result {
+	if err := Validate(msg); err != nil {
+		return p.failed(msg, err)
+	}
+	if message.Expired(msg, time.Now()) {
+		return p.result(msg, StatusExpired)
+	}
+	ackTimeout = min(ackTimeout, p.maxAckTimeout)
+
+	var (
+		remote    Result
+		forwarded bool
+		wg        sync.WaitGroup
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		remote, forwarded = p.forward(ctx, msg, ackTimeout)
+	}()
+
+	local, delivered := p.deliverLocal(ctx, msg, ackTimeout)
+	wg.Wait()
+
+	switch {
+	case delivered && forwarded:
+		return merge(local, remote)
+	case delivered:
+		return local
+	case forwarded:
+		return remote
+	}
+	return p.saveOffline(ctx, msg)
+}
+
+// PushBatch 并发推送多条消息，返回的结果与 msgs 一一对应。
+func (p *Pusher) PushBatch(ctx context.Context, msgs []*messagev1.PushMessage, ackTimeout time.Duration) []Result {
+	results := make([]Result, len(msgs))
+	p.parallel(len(msgs), func(i int) {
+		results[i] = p.Push(ctx, msgs[i], ackTimeout)
+	})
+	return results
+}
+
+// PushUsers 推送同一条消息给同一业务下的多个用户 ( 如房间成员 )，会忽略 msg 的 receiver_id。
+// 接收者在本节点上的连接一次投递 ( 消息只编码一次 )，同时并发转发给接收者在其他节点上的连接，
+// 不在任何节点在线的接收者保存为离线消息。
+func (p *Pusher) PushUsers(ctx context.Context, msg *messagev1.PushMessage, uids []uint64, ackTimeout time.Duration) []Result {
+	msgs := make([]*messagev1.PushMessage, 0, len(uids))
+	for _, uid := range uids {
+		msgs = append(msgs, withReceiver(msg, uid))
+	}
+	if message.Expired(msg, time.Now()) {
+		return p.PushBatch(ctx, msgs, ackTimeout)
+	}
+	ackTimeout = min(ackTimeout, p.maxAckTimeout)
+
+	remote := make([]Result, len(msgs))
+	forwarded := make([]bool, len(msgs))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		p.parallel(len(msgs), func(i int) {
+			if Validate(msgs[i]) == nil {
+				remote[i], forwarded[i] = p.forward(ctx, msgs[i], ackTimeout)
+			}
+		})
+	}()
+
+	results := make([]Result, len(msgs))
+	remaining := p.deliverLocalUsers(ctx, msgs, ackTimeout, results)
+	wg.Wait()
+
+	isRemaining := make([]bool, len(msgs))
+	for _, i := range remaining {
+		isRemaining[i] = true
+	}
+	for i := range msgs {
+		if !isRemaining[i] && forwarded[i] {
+			results[i] = merge(results[i], remote[i])
+		}
+	}
+
+	// 本节点没有连接的接收者使用转发的结果，也不在其他节点在线时保存为离线消息。
+	p.parallel(len(remaining), func(j int) {
+		i := remaining[j]
+		switch {
+		case forwarded[i]:
+			results[i] = remote[i]
+		case Validate(msgs[i]) != nil:
+			results[i] = p.failed(msgs[i], Validate(msgs[i]))
+		default:
+			results[i] = p.saveOffline(ctx, msgs[i])
+		}
+	})
+	return results
+}
+
+// Broadcast 推送消息给同一业务下所有在线用户的连接，会忽略 msg 的 receiver_id。
+// 广播不等待 ack，也不保存离线消息。
+func (p *Pusher) Broadcast(ctx context.Context, msg *messagev1.PushMessage) (BroadcastResult, error) {
+	if msg.GetMessageId() == "" {
+		return BroadcastResult{}, fmt.Errorf("%w: empty message_id", ErrInvalidMessage)
+	}
+	if msg.GetBizId() == 0 {
+		return BroadcastResult{}, fmt.Errorf("%w: empty biz_id", ErrInvalidMessage)
+	}
+
+	res := BroadcastResult{Conns: p.broadcastLocal(msg), Nodes: 1}
+	if p.relay == nil {
+		return res, nil
+	}
+
+	payload, err := proto.Marshal(msg)
+	if err != nil {
+		return res, fmt.Errorf("failed to marshal push message: %w", err)
+	}
+
+	nodes, err := p.relay.Broadcast(ctx, &cluster.Envelope{
+		Kind:    KindBroadcast,
+		From:    p.nodeID,
+		BID:     msg.GetBizId(),
+		Payload: payload,
+	})
+	if err != nil {
+		return res, fmt.Errorf("failed to broadcast to other nodes: %w", err)
+	}
+
+	res.Nodes = max(nodes, 1)
+	return res, nil
+}
+
+// deliverLocal 投递给接收者在本节点上的连接，接收者在本节点没有连接时返回 false。
+func (p *Pusher) deliverLocal(ctx context.Context, msg *messagev1.PushMessage, ackTimeout time.Duration) (Result, bool) {
+	conns, ok := p.connManager.FindUserConn(session.User{BID: msg.GetBizId(), UID: msg.GetReceiverId()})
+	if !ok {
+		return Result{}, false
+	}
+
+	res := p.result(msg, StatusDelivered)
+	res.NodeID = p.nodeID
+	res.Conns = len(conns)
+
+	// 发送之前开始等待，避免 ack 先于等待到达。
+	var acked <-chan struct{}
+	if ackTimeout > 0 {
+		var cancel func()
+		acked, cancel = p.acks.watch(conns, msg.GetMessageId())
+		defer cancel()
+	}
+
+	if err := p.handler.OnReceiveFromBackend(conns, msg); err != nil {
+		slog.Error(
+			"[synp-pusher] failed to deliver push message",
+			"message_id", msg.GetMessageId(),
+			"biz_id", msg.GetBizId(),
+			"receiver_id", msg.GetReceiverId(),
+			"error", err,
+		)
+		res.Status, res.Error = StatusFailed, err.Error()
+		return res, true
+	}
+
+	if acked == nil {
+		return res, true
+	}
+
+	timer := time.NewTimer(ackTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-acked:
+		res.Status = StatusAcked
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	return res, true
+}
+
+// deliverLocalUsers 将消息一次投递给各个接收者在本节点上的所有连接，结果写入 results 中对应的位置，
+// 返回接收者在本节点没有连接 ( 或消息格式错误 ) 的消息下标。
+func (p *Pusher) deliverLocalUsers(
+	ctx context.Context,
+	msgs []*messagev1.PushMessage,
+	ackTimeout time.Duration,
+	results []Result,
+) []int {
+	var (
+		remaining []int
+		local     []int
+		conns     []synp.Conn
+		userConns [][]synp.Conn
+		acked     []<-chan struct{}
+		cancels   []func()
+	)
+	defer func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}()
+
+	for i, msg := range msgs {
+		var found []synp.Conn
+		if Validate(msg) == nil {
+			found, _ = p.connManager.FindUserConn(session.User{BID: msg.GetBizId(), UID: msg.GetReceiverId()})
+		}
+		if len(found) == 0 {
+			remaining = append(remaining, i)
+			continue
+		}
+
+		results[i] = p.result(msg, StatusDelivered)
+		results[i].NodeID = p.nodeID
+		results[i].Conns = len(found)
+
+		local = append(local, i)
+		conns = append(conns, found...)
+		userConns = append(userConns, found)
+	}
+	if len(local) == 0 {
+		return remaining
+	}
+
+	if message.Expired(msgs[local[0]], time.Now()) {
+		// 消息在投递之前过期，按接收者分别交给 handler 丢弃 ( 发布各自的过期回执 )。
+		for j, i := range local {
+			_ = p.handler.OnReceiveFromBackend(userConns[j], msgs[i])
+			results[i] = p.result(msgs[i], StatusExpired)
+			results[i].NodeID = p.nodeID
+		}
+		return remaining
+	}
+
+	// 发送之前开始等待，避免 ack 先于等待到达。
+	if ackTimeout > 0 {
+		for j, i := range local {
+			ch, cancel := p.acks.watch(userConns[j], msgs[i].GetMessageId())
+			acked = append(acked, ch)
+			cancels = append(cancels, cancel)
+		}
+	}
+
+	// 投递时只使用消息 id、body 及扩展字段，receiver_id 不影响发送的内容，
+	// 按接收者发布的回执 ( 如过期回执 ) 由 handler 根据连接的用户生成。
+	if err := p.handler.OnReceiveFromBackend(conns, msgs[local[0]]); err != nil {
+		slog.Error(
+			"[synp-pusher] failed to deliver push message to users",
+			"message_id", msgs[local[0]].GetMessageId(),
+			"biz_id", msgs[local[0]].GetBizId(),
+			"receivers", len(local),
+			"error", err,
+		)
+		for _, i := range local {
+			results[i].Status, results[i].Error = StatusFailed, err.Error()
+		}
+		return remaining
+	}
+
+	if ackTimeout <= 0 {
+		return remaining
+	}
+
+	timer := time.NewTimer(ackTimeout)
+	defer timer.Stop()
+
+	// 依次等待各个接收者的 ack，超时后只检查其余接收者是否已经 ack。
+	var timeout bool
+	for j, i := range local {
+		if !timeout {
+			select {
+			case <-acked[j]:
+			case <-timer.C:
+				timeout = true
+			case <-ctx.Done():
+				timeout = true
+			}
+		}
+
+		select {
+		case <-acked[j]:
+			results[i].Status = StatusAcked
+		default:
+		}
+	}
+	return remaining
+}
+
+// forward 转发到接收者所在的其他节点投递，接收者不在其他节点在线时返回 false。
+func (p *Pusher) forward(ctx context.Context, msg *messagev1.PushMessage, ackTimeout time.Duration) (Result, bool) {
+	if p.presenceStore == nil || p.relay == nil {
+		return Result{}, false
+	}
+
+	devices, err := p.presenceStore.Devices(ctx, msg.GetBizId(), msg.GetReceiverId())
+	if err != nil {
+		// 无法确定接收者是否在线，保存为离线消息可能导致重复投递，交给调用方重试。
+		return p.failed(msg, fmt.Errorf("failed to locate receiver: %w", err)), true
+	}
+
+	nodes := make([]string, 0, len(devices))
+	for _, state := range devices {
+		if state.NodeID != p.nodeID && !slices.Contains(nodes, state.NodeID) {
+			nodes = append(nodes, state.NodeID)
+		}
+	}
+	if len(nodes) == 0 {
+		return Result{}, false
+	}
+
+	payload, err := proto.Marshal(msg)
+	if err != nil {
+		return p.failed(msg, fmt.Errorf("failed to marshal push message: %w", err)), true
+	}
+
+	results := make([]Result, len(nodes))
+	var wg sync.WaitGroup
+	for i, nodeID := range nodes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = p.forwardTo(ctx, nodeID, msg, payload, ackTimeout)
+		}()
+	}
+	wg.Wait()
+
+	// 接收者在多个节点上有连接时，合并各个节点的结果。
+	best := merge(results...)
+	if best.Status == StatusUnknownReceiver {
+		// 在线状态已经过期 ( 如节点宕机 )，按不在线处理。
+		return Result{}, false
+	}
+	return best, true
+}
+
+func (p *Pusher) forwardTo(
+	ctx context.Context, nodeID string, msg *messagev1.PushMessage, payload []byte, ackTimeout time.Duration,
+) Result {
+	id := p.ids.Next()
+	ch := make(chan Result, 1)
+
+	p.mu.Lock()
+	p.pending[id] = ch
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.pending, id)
+		p.mu.Unlock()
+	}()
+
+	failed := func(err error) Result {
+		res := p.failed(msg, err)
+		res.NodeID = nodeID
+		return res
+	}
+
+	req, err := json.Marshal(&forwardRequest{
+		CorrelationID: id,
+		Message:       payload,
+		AckTimeout:    ackTimeout.Milliseconds(),
+	})
+	if err != nil {
+		return failed(fmt.Errorf("failed to marshal forward request: %w", err))
+	}
+
+	if err = p.relay.Publish(ctx, nodeID, &cluster.Envelope{
+		Kind:    KindPush,
+		From:    p.nodeID,
+		BID:     msg.GetBizId(),
+		UIDs:    []uint64{msg.GetReceiverId()},
+		Payload: req,
+	}); err != nil {
+		return failed(fmt.Errorf("failed to forward to node %s: %w", nodeID, err))
+	}
+
+	timer := time.NewTimer(ackTimeout + p.forwardTimeout)
+	defer timer.Stop()
+
+	select {
+	case res := <-ch:
+		return res
+	case <-timer.C:
+		return failed(fmt.Errorf("forward to node %s timeout", nodeID))
+	case <-ctx.Done():
+		return failed(ctx.Err())
+	}
+}
+
+// handleForward 处理其他节点转发的推送请求。
+// 投递 ( 及等待 ack ) 在单独的 goroutine 中进行，避免阻塞 relay 接收其他 envelope。
+func (p *Pusher) handleForward(ctx context.Context, env *cluster.Envelope) error {
+	req := &forwardRequest{}
+	if err := json.Unmarshal(env.Payload, req); err != nil {
+		return fmt.Errorf("failed to unmarshal forward request: %w", err)
+	}
+
+	msg := &messagev1.PushMessage{}
+	if err := proto.Unmarshal(req.Message, msg); err != nil {
+		return fmt.Errorf("failed to unmarshal push message: %w", err)
+	}
+
+	ackTimeout := min(time.Duration(req.AckTimeout)*time.Millisecond, p.maxAckTimeout)
+	go p.replyForward(ctx, env.From, req.CorrelationID, msg, ackTimeout)
+	return nil
+}
+
+func (p *Pusher) replyForward(ctx context.Context, from, correlationID string, msg *messagev1.PushMessage, ackTimeout time.Duration) {
+	res, ok := p.deliverLocal(ctx, msg, ackTimeout)
+	if !ok {
+		res = p.result(msg, StatusUnknownReceiver)
+		res.NodeID = p.nodeID
+	}
+
+	payload, err := json.Marshal(&forwardReply{CorrelationID: correlationID, Result: res})
+	if err != nil {
+		slog.Error("[synp-pusher] failed to marshal forward reply", "correlation_id", correlationID, "error", err)
+		return
+	}
+
+	publishCtx, cancel := context.WithTimeout(ctx, p.forwardTimeout)
+	defer cancel()
+
+	if err = p.relay.Publish(publishCtx, from, &cluster.Envelope{
+		Kind:    KindPushResult,
+		From:    p.nodeID,
+		BID:     msg.GetBizId(),
+		UIDs:    []uint64{msg.GetReceiverId()},
+		Payload: payload,
+	}); err != nil {
+		slog.Warn(
+			"[synp-pusher] failed to reply forward result",
+			"node_id", from,
+			"correlation_id", correlationID,
+			"error", err,
+		)
+	}
+}
+
+// handleForwardReply 处理其他节点回复的投递结果，等待已经超时的结果直接丢弃。
+func (p *Pusher) handleForwardReply(_ context.Context, env *cluster.Envelope) error {
+	reply := &forwardReply{}
+	if err := json.Unmarshal(env.Payload, reply); err != nil {
+		return fmt.Errorf("failed to unmarshal forward reply: %w", err)
+	}
+
+	p.mu.Lock()
+	ch, ok := p.pending[reply.CorrelationID]
+	p.mu.Unlock()
+
+	if ok {
+		select {
+		case ch <- reply.Result:
+		default:
+		}
+	}
+	return nil
+}
+
+// broadcastLocal 一次投递给本节点上同一业务下所有用户的连接 ( 消息只编码一次 )，返回投递的连接数。
+func (p *Pusher) broadcastLocal(msg *messagev1.PushMessage) int {
+	var conns []synp.Conn
+	p.connManager.RangeConn(func(conn synp.Conn) bool {
+		if conn.Session().User().BID == msg.GetBizId() {
+			conns = append(conns, conn)
+		}
+		return true
+	})
+	if len(conns) == 0 {
+		return 0
+	}
+
+	// 投递时只使用消息 id、body 及扩展字段，receiver_id 只需要通过校验。
+	receiver := withReceiver(msg, conns[0].Session().User().UID)
+	if err := p.handler.OnReceiveFromBackend(conns, receiver); err != nil {
+		slog.Warn(
+			"[synp-pusher] failed to deliver broadcast message",
+			"message_id", msg.GetMessageId(),
+			"biz_id", msg.GetBizId(),
+			"conns", len(conns),
+			"error", err,
+		)
+		return 0
+	}
+	return len(conns)
+}
+
+// handleBroadcast 处理其他节点的广播，本节点发起的广播已经投递过，直接忽略。
+func (p *Pusher) handleBroadcast(_ context.Context, env *cluster.Envelope) error {
+	if env.From == p.nodeID {
+		return nil
+	}
+
+	msg := &messagev1.PushMessage{}
+	if err := proto.Unmarshal(env.Payload, msg); err != nil {
+		return fmt.Errorf("failed to unmarshal push message: %w", err)
+	}
+
+	go p.broadcastLocal(msg)
+	return nil
+}
+
+func (p *Pusher) saveOffline(ctx context.Context, msg *messagev1.PushMessage) Result {
+	if p.offlineStore == nil {
+		return p.result(msg, StatusUnknownReceiver)
+	}
+
+	if err := p.offlineStore.Save(ctx, msg); err != nil {
+		return p.failed(msg, fmt.Errorf("failed to save offline message: %w", err))
+	}
+	return p.result(msg, StatusOfflineStored)
+}
+
+func (p *Pusher) result(msg *messagev1.PushMessage, status Status) Result {
+	return Result{
+		MessageID:  msg.GetMessageId(),
+		ReceiverID: msg.GetReceiverId(),
+		Status:     status,
+	}
+}
+
+func (p *Pusher) failed(msg *messagev1.PushMessage, err error) Result {
+	res := p.result(msg, StatusFailed)
+	res.Error = err.Error()
+	return res
+}
+
+// parallel 以 batchConcurrency 的并发数对 [0, n) 执行 fn，所有 fn 返回后返回。
+func (p *Pusher) parallel(n int, fn func(i int)) {
+	sem := make(chan struct{}, p.batchConcurrency)
+
+	var wg sync.WaitGroup
+	for i := range n {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i)
+		}()
+	}
+	wg.Wait()
+}
+
+// merge 合并同一条消息在多个节点上的投递结果：状态取优先级最高的结果，连接数为各个节点之和。
+func merge(results ...Result) Result {
+	best := results[0]
+	conns := best.Conns
+	for _, res := range results[1:] {
+		conns += res.Conns
+		if res.Status.rank() > best.Status.rank() {
+			best = res
+		}
+	}
+	best.Conns = conns
+	return best
+}
+
+// Validate 校验推送消息的必填字段。
+func Validate(msg *messagev1.PushMessage) error {
+	if msg.GetMessageId() == "" {
+		return fmt.Errorf("%w: empty message_id", ErrInvalidMessage)
+	}
+	if msg.GetBizId() == 0 {
+		return fmt.Errorf("%w: empty biz_id", ErrInvalidMessage)
+	}
+	if msg.GetReceiverId() == 0 {
+		return fmt.Errorf("%w: empty receiver_id", ErrInvalidMessage)
+	}
+	return nil
+}
+
+func withReceiver(msg *messagev1.PushMessage, uid uint64) *messagev1.PushMessage {
+	m, _ := proto.Clone(msg).(*messagev1.PushMessage)
+	m.ReceiverId = uid
+	return m
+}
+
+// PusherWithOfflineStore 设置离线消息存储，没有设置时接收者不在线返回 StatusUnknownReceiver。
+func PusherWithOfflineStore(store offline.Store) option.Opt[Pusher] {
+	return func(p *Pusher) {
+		p.offlineStore = store
+	}
+}
+
+// PusherWithCluster 设置在线状态存储及节点间转发，用于投递给其他节点上的连接。
+func PusherWithCluster(store presence.Store, relay cluster.Relay) option.Opt[Pusher] {
+	return func(p *Pusher) {
+		p.presenceStore = store
+		p.relay = relay
+	}
+}
+
+// PusherWithMaxAckTimeout 设置等待前端 ack 的最长时间。
+func PusherWithMaxAckTimeout(timeout time.Duration) option.Opt[Pusher] {
+	return func(p *Pusher) {
+		if timeout > 0 {
+			p.maxAckTimeout = timeout
+		}
+	}
+}
+
+// PusherWithForwardTimeout 设置转发到其他节点时 ( 在等待 ack 之外 ) 等待结果的时间。
+func PusherWithForwardTimeout(timeout time.Duration) option.Opt[Pusher] {
+	return func(p *Pusher) {
+		if timeout > 0 {
+			p.forwardTimeout = timeout
+		}
+	}
+}
+
+// PusherWithBatchConcurrency 设置批量推送的并发数。
+func PusherWithBatchConcurrency(concurrency int) option.Opt[Pusher] {
+	return func(p *Pusher) {
+		if concurrency > 0 {
+			p.batchConcurrency = concurrency
+		}
+	}
+}
+
+func NewPusher(
+	nodeID string,
+	connManager synp.ConnManager,
+	handler synp.Handler,
+	acks *AckTracker,
+	opts ...option.Opt[Pusher],
+) *Pusher {
+	p := &Pusher{
+		nodeID: nodeID,
+		ids:    correlation.NewGenerator(nodeID),
+
+		connManager: connManager,
+		handler:     handler,
+		acks:        acks,
+
+		maxAckTimeout:    DefaultMaxAckTimeout,
+		forwardTimeout:   DefaultForwardTimeout,
+		batchConcurrency: DefaultBatchConcurrency,
+
+		pending: make(map[string]chan Result),
+	}
+
+	option.Apply(p, opts...)
+
+	if p.relay != nil {
+		p.relay.Register(KindPush, p.handleForward)
+		p.relay.Register(KindPushResult, p.handleForwardReply)
+		p.relay.Register(KindBroadcast, p.handleBroadcast)
+	}
+	return p
+}
@@ -0,0 +1,364 @@
+package push
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jrmarcco/synp"
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/jrmarcco/synp/internal/pkg/cluster"
+	"github.com/jrmarcco/synp/internal/pkg/compression"
+	"github.com/jrmarcco/synp/internal/pkg/message"
+	"github.com/jrmarcco/synp/internal/pkg/presence"
+	"github.com/jrmarcco/synp/internal/pkg/session"
+	synpmock "github.com/jrmarcco/synp/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestPusher_Push(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	acks := NewAckTracker()
+	connManager := newTestConnManager(newTestConn(ctrl, 1, 1))
+	handler := &testHandler{acks: acks, autoAck: true}
+	offlineStore := &testOfflineStore{}
+	pusher := NewPusher("node-1", connManager, handler, acks, PusherWithOfflineStore(offlineStore))
+
+	// 不等待 ack。
+	res := pusher.Push(t.Context(), newPushMessage("m1", 1, 1), 0)
+	assert.Equal(t, StatusDelivered, res.Status)
+	assert.Equal(t, "node-1", res.NodeID)
+	assert.Equal(t, 1, res.Conns)
+
+	// 等待 ack。
+	res = pusher.Push(t.Context(), newPushMessage("m2", 1, 1), time.Second)
+	assert.Equal(t, StatusAcked, res.Status)
+
+	// 等待 ack 超时。
+	handler.autoAck = false
+	res = pusher.Push(t.Context(), newPushMessage("m3", 1, 1), 10*time.Millisecond)
+	assert.Equal(t, StatusDelivered, res.Status)
+	assert.Empty(t, acks.waiters)
+
+	// 接收者不在线，保存为离线消息。
+	res = pusher.Push(t.Context(), newPushMessage("m4", 1, 2), time.Second)
+	assert.Equal(t, StatusOfflineStored, res.Status)
+	assert.Equal(t, uint64(2), res.ReceiverID)
+	require.Len(t, offlineStore.msgs, 1)
+	assert.Equal(t, "m4", offlineStore.msgs[0].GetMessageId())
+
+	// 格式错误。
+	res = pusher.Push(t.Context(), newPushMessage("", 1, 1), 0)
+	assert.Equal(t, StatusFailed, res.Status)
+	assert.NotEmpty(t, res.Error)
+
+	// 批量推送，结果与消息一一对应。
+	results := pusher.PushUsers(t.Context(), newPushMessage("m5", 1, 0), []uint64{1, 2}, 0)
+	require.Len(t, results, 2)
+	assert.Equal(t, StatusDelivered, results[0].Status)
+	assert.Equal(t, StatusOfflineStored, results[1].Status)
+}
+
+func TestPusher_Forward(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	network := newTestNetwork()
+	presenceStore := &testPresenceStore{nodes: map[uint64][]string{2: {"node-2"}, 3: {"node-3"}}}
+
+	acks1 := NewAckTracker()
+	handler1 := &testHandler{acks: acks1}
+	pusher1 := NewPusher(
+		"node-1", newTestConnManager(newTestConn(ctrl, 1, 1)), handler1, acks1,
+		PusherWithCluster(presenceStore, network.relay("node-1")),
+		PusherWithForwardTimeout(50*time.Millisecond),
+		PusherWithOfflineStore(&testOfflineStore{}),
+	)
+
+	acks2 := NewAckTracker()
+	handler2 := &testHandler{acks: acks2, autoAck: true}
+	NewPusher(
+		"node-2", newTestConnManager(newTestConn(ctrl, 1, 2), newTestConn(ctrl, 2, 2)), handler2, acks2,
+		PusherWithCluster(presenceStore, network.relay("node-2")),
+	)
+
+	// 接收者在其他节点，转发并等待其回复结果。
+	res := pusher1.Push(t.Context(), newPushMessage("m1", 1, 2), time.Second)
+	assert.Equal(t, StatusAcked, res.Status)
+	assert.Equal(t, "node-2", res.NodeID)
+	assert.Len(t, handler2.received(), 1)
+	assert.Empty(t, pusher1.pending)
+
+	// 在线状态指向的节点没有回复 ( 已经宕机 )。
+	res = pusher1.Push(t.Context(), newPushMessage("m2", 1, 3), 0)
+	assert.Equal(t, StatusFailed, res.Status)
+	assert.Equal(t, "node-3", res.NodeID)
+
+	// 广播给所有节点上同一业务的连接。
+	bres, err := pusher1.Broadcast(t.Context(), newPushMessage("m3", 1, 0))
+	require.NoError(t, err)
+	assert.Equal(t, 1, bres.Conns)
+	assert.Equal(t, 2, bres.Nodes)
+	assert.Eventually(t, func() bool {
+		return len(handler2.received()) == 2
+	}, time.Second, 5*time.Millisecond)
+
+	msgs := handler2.received()
+	assert.Equal(t, "m3", msgs[1].GetMessageId())
+	assert.Equal(t, uint64(2), msgs[1].GetReceiverId())
+	assert.Len(t, handler1.received(), 1)
+}
+
+func TestPusher_LocalAndRemote(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	network := newTestNetwork()
+	// uid 1 在 node-1 和 node-2 上都有连接 ( 不同设备 )，uid 2 只在 node-2 上。
+	presenceStore := &testPresenceStore{nodes: map[uint64][]string{1: {"node-1", "node-2"}, 2: {"node-2"}}}
+
+	acks1 := NewAckTracker()
+	handler1 := &testHandler{acks: acks1}
+	offlineStore := &testOfflineStore{}
+	pusher1 := NewPusher(
+		"node-1", newTestConnManager(newTestConn(ctrl, 1, 1)), handler1, acks1,
+		PusherWithCluster(presenceStore, network.relay("node-1")),
+		PusherWithOfflineStore(offlineStore),
+	)
+
+	acks2 := NewAckTracker()
+	handler2 := &testHandler{acks: acks2, autoAck: true}
+	NewPusher(
+		"node-2", newTestConnManager(newTestConn(ctrl, 1, 1), newTestConn(ctrl, 1, 2)), handler2, acks2,
+		PusherWithCluster(presenceStore, network.relay("node-2")),
+	)
+
+	// 本节点投递后仍然转发到其他节点，合并两个节点的结果。
+	res := pusher1.Push(t.Context(), newPushMessage("m1", 1, 1), time.Second)
+	assert.Equal(t, StatusAcked, res.Status)
+	assert.Equal(t, 2, res.Conns)
+	assert.Len(t, handler1.received(), 1)
+	assert.Len(t, handler2.received(), 1)
+
+	// 批量推送时同样转发给本节点接收者在其他节点上的连接。
+	results := pusher1.PushUsers(t.Context(), newPushMessage("m2", 1, 0), []uint64{1, 2, 3}, 0)
+	require.Len(t, results, 3)
+	assert.Equal(t, StatusDelivered, results[0].Status)
+	assert.Equal(t, 2, results[0].Conns)
+	assert.Equal(t, StatusDelivered, results[1].Status)
+	assert.Equal(t, "node-2", results[1].NodeID)
+	assert.Equal(t, StatusOfflineStored, results[2].Status)
+	assert.Len(t, handler2.received(), 3)
+	require.Len(t, offlineStore.msgs, 1)
+	assert.Equal(t, uint64(3), offlineStore.msgs[0].GetReceiverId())
+}
+
+func TestPusher_DeliverLocalUsersExpired(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	acks := NewAckTracker()
+	handler := &testHandler{acks: acks}
+	pusher := NewPusher("node-1", newTestConnManager(newTestConn(ctrl, 1, 1), newTestConn(ctrl, 1, 2)), handler, acks)
+
+	// 消息在投递之前过期，每个接收者分别交给 handler 处理并返回过期结果。
+	msg := newPushMessage("m1", 1, 0)
+	message.SetExpireAt(msg, time.Now().Add(-time.Second))
+	msgs := []*messagev1.PushMessage{withReceiver(msg, 1), withReceiver(msg, 2)}
+
+	results := make([]Result, len(msgs))
+	remaining := pusher.deliverLocalUsers(t.Context(), msgs, 0, results)
+	assert.Empty(t, remaining)
+	for i, res := range results {
+		assert.Equal(t, StatusExpired, res.Status)
+		assert.Equal(t, msgs[i].GetReceiverId(), res.ReceiverID)
+	}
+
+	received := handler.received()
+	require.Len(t, received, 2)
+	assert.Equal(t, uint64(1), received[0].GetReceiverId())
+	assert.Equal(t, uint64(2), received[1].GetReceiverId())
+}
+
+func newPushMessage(id string, bid, uid uint64) *messagev1.PushMessage {
+	return &messagev1.PushMessage{MessageId: id, BizId: bid, ReceiverId: uid, Body: []byte("body")}
+}
+
+type testHandler struct {
+	acks    *AckTracker
+	autoAck bool
+
+	mu   sync.Mutex
+	msgs []*messagev1.PushMessage
+}
+
+func (h *testHandler) OnConnect(_ synp.Conn) error                       { return nil }
+func (h *testHandler) OnDisconnect(_ synp.Conn) error                    { return nil }
+func (h *testHandler) OnReceiveFromFrontend(_ synp.Conn, _ []byte) error { return nil }
+
+func (h *testHandler) OnReceiveFromBackend(conns []synp.Conn, msg *messagev1.PushMessage) error {
+	h.mu.Lock()
+	h.msgs = append(h.msgs, msg)
+	h.mu.Unlock()
+
+	if h.autoAck {
+		go h.acks.OnDownstreamAck(conns[0], msg.GetMessageId())
+	}
+	return nil
+}
+
+func (h *testHandler) received() []*messagev1.PushMessage {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]*messagev1.PushMessage(nil), h.msgs...)
+}
+
+type testOfflineStore struct {
+	mu   sync.Mutex
+	msgs []*messagev1.PushMessage
+}
+
+func (s *testOfflineStore) Save(_ context.Context, msg *messagev1.PushMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.msgs = append(s.msgs, msg)
+	return nil
+}
+
+func (s *testOfflineStore) Take(_ context.Context, _, _ uint64, _ int) ([]*messagev1.PushMessage, error) {
+	return nil, nil
+}
+
+type testPresenceStore struct {
+	nodes map[uint64][]string // uid -> 所在节点
+}
+
+func (s *testPresenceStore) Online(_ context.Context, _ session.User, _ presence.DeviceState) (bool, error) {
+	return false, nil
+}
+func (s *testPresenceStore) Refresh(_ context.Context, _ session.User) error { return nil }
+func (s *testPresenceStore) Offline(_ context.Context, _ session.User, _ string, _ time.Duration) (bool, error) {
+	return true, nil
+}
+func (s *testPresenceStore) Settle(_ context.Context, _ session.User, _ string) (bool, error) {
+	return true, nil
+}
+
+func (s *testPresenceStore) Devices(_ context.Context, _, uid uint64) ([]presence.DeviceState, error) {
+	states := make([]presence.DeviceState, 0, len(s.nodes[uid]))
+	for _, nodeID := range s.nodes[uid] {
+		states = append(states, presence.DeviceState{Device: session.DevicePC, NodeID: nodeID})
+	}
+	return states, nil
+}
+
+func (s *testPresenceStore) BatchQuery(_ context.Context, _ uint64, _ []uint64) ([]presence.Presence, error) {
+	return nil, nil
+}
+
+// testNetwork 模拟节点间转发，未注册的节点收不到 envelope。
+type testNetwork struct {
+	mu     sync.Mutex
+	relays map[string]*testRelay
+}
+
+func (n *testNetwork) relay(nodeID string) *testRelay {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	r := &testRelay{network: n, handlers: make(map[string]cluster.HandleFunc)}
+	n.relays[nodeID] = r
+	return r
+}
+
+func newTestNetwork() *testNetwork {
+	return &testNetwork{relays: make(map[string]*testRelay)}
+}
+
+type testRelay struct {
+	network  *testNetwork
+	handlers map[string]cluster.HandleFunc
+}
+
+func (r *testRelay) Publish(ctx context.Context, nodeID string, env *cluster.Envelope) error {
+	r.network.mu.Lock()
+	target, ok := r.network.relays[nodeID]
+	r.network.mu.Unlock()
+
+	if ok {
+		return target.handlers[env.Kind](ctx, env)
+	}
+	return nil
+}
+
+func (r *testRelay) Broadcast(ctx context.Context, env *cluster.Envelope) (int, error) {
+	r.network.mu.Lock()
+	relays := make([]*testRelay, 0, len(r.network.relays))
+	for _, relay := range r.network.relays {
+		relays = append(relays, relay)
+	}
+	r.network.mu.Unlock()
+
+	for _, relay := range relays {
+		if err := relay.handlers[env.Kind](ctx, env); err != nil {
+			return 0, err
+		}
+	}
+	return len(relays), nil
+}
+
+func (r *testRelay) Register(kind string, fn cluster.HandleFunc) { r.handlers[kind] = fn }
+func (r *testRelay) Start(_ context.Context) error               { return nil }
+func (r *testRelay) Close() error                                { return nil }
+
+type testConnManager struct {
+	conns []synp.Conn
+}
+
+func (m *testConnManager) NewConn(_ context.Context, _ net.Conn, _ session.Session, _ *compression.State) (synp.Conn, error) {
+	return nil, nil
+}
+func (m *testConnManager) RemoveConn(_ session.User) bool     { return false }
+func (m *testConnManager) RemoveUserConn(_ session.User) bool { return false }
+
+func (m *testConnManager) FindConn(user session.User) (synp.Conn, bool) {
+	for _, conn := range m.conns {
+		if conn.Session().User() == user {
+			return conn, true
+		}
+	}
+	return nil, false
+}
+
+func (m *testConnManager) FindUserConn(user session.User) ([]synp.Conn, bool) {
+	var conns []synp.Conn
+	for _, conn := range m.conns {
+		u := conn.Session().User()
+		if u.BID == user.BID && u.UID == user.UID {
+			conns = append(conns, conn)
+		}
+	}
+	return conns, len(conns) > 0
+}
+
+func (m *testConnManager) RangeConn(fn func(conn synp.Conn) bool) {
+	for _, conn := range m.conns {
+		if !fn(conn) {
+			return
+		}
+	}
+}
+
+func newTestConnManager(conns ...synp.Conn) *testConnManager {
+	return &testConnManager{conns: conns}
+}
+
+func newTestConn(ctrl *gomock.Controller, bid, uid uint64) synp.Conn {
+	return synpmock.NewUserConn(ctrl, session.User{BID: bid, UID: uid, Device: session.DevicePC})
+}
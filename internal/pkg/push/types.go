@@ -0,0 +1,51 @@
+// Package push 提供了后端 ( 业务服务端 ) 直接推送消息到前端的能力，
+// 作为通过消息队列推送 ( event.message.downstream ) 的低延迟替代方案，并同步返回投递结果。
+package push
+
+import "errors"
+
+var ErrInvalidMessage = errors.New("invalid push message")
+
+// Status 为消息的投递状态。
+type Status string
+
+const (
+	StatusAcked           Status = "acked"            // 已投递且在等待时间内收到前端 ack
+	StatusDelivered       Status = "delivered"        // 已投递 ( 没有等待 ack 或等待 ack 超时 )
+	StatusOfflineStored   Status = "offline_stored"   // 接收者不在线，已保存为离线消息
+	StatusUnknownReceiver Status = "unknown_receiver" // 接收者不在线且没有保存离线消息
+	StatusFailed          Status = "failed"           // 投递失败，可以重试
+	StatusExpired         Status = "expired"          // 消息已经过期，不再投递
+)
+
+// rank 返回状态的优先级，接收者在多个节点上有连接时取优先级最高的结果。
+func (s Status) rank() int {
+	switch s {
+	case StatusAcked:
+		return 4
+	case StatusDelivered:
+		return 3
+	case StatusFailed:
+		return 2
+	case StatusOfflineStored:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Result 为单条消息的投递结果。
+type Result struct {
+	MessageID  string `json:"messageId"`
+	ReceiverID uint64 `json:"receiverId"`
+	Status     Status `json:"status"`
+	NodeID     string `json:"nodeId,omitempty"` // 投递消息的网关节点
+	Conns      int    `json:"conns,omitempty"`  // 投递的连接数
+	Error      string `json:"error,omitempty"`
+}
+
+// BroadcastResult 为广播的结果。
+type BroadcastResult struct {
+	Conns int `json:"conns"` // 本节点投递的连接数
+	Nodes int `json:"nodes"` // 收到广播的节点数 ( 包括本节点 )
+}
@@ -0,0 +1,224 @@
+package receipt
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/jrmarcco/jit/bean/option"
+	"github.com/jrmarcco/synp"
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/jrmarcco/synp/internal/pkg/message"
+	"github.com/jrmarcco/synp/internal/pkg/offline"
+	"github.com/jrmarcco/synp/internal/pkg/session"
+	"github.com/jrmarcco/synp/internal/pkg/xmq"
+	"github.com/jrmarcco/synp/internal/pkg/xmq/produce"
+)
+
+const DefaultRequestTimeout = 3 * time.Second
+
+// Reporter 负责发布回执事件。
+//
+// 回执异步发布，不会阻塞连接的读写，发布失败只记录日志 ( 回执尽力而为 )。
+// 使用接收者的 ConnKey 作为消息 key，保证同一用户的回执有序。
+type Reporter struct {
+	producer produce.AsyncProducer
+
+	nodeID string
+	topic  string // 为空时不发布回执
+
+	requestTimeout time.Duration
+}
+
+// OnDownstreamAck 在前端确认收到 downstream 消息时发布 delivered 回执。
+func (r *Reporter) OnDownstreamAck(conn synp.Conn, messageID string) {
+	r.report(r.connReceipt(TypeDelivered, conn, messageID))
+}
+
+// OnRetransmitFailed 在重传达到上限 ( 或重传失败 ) 时发布 failed 回执。
+func (r *Reporter) OnRetransmitFailed(conn synp.Conn, msg *messagev1.Message, cause error) {
+	receipt := r.connReceipt(TypeFailed, conn, msg.GetMessageId())
+	receipt.Reason = cause.Error()
+	r.report(receipt)
+}
+
+// OfflineStored 发布 offline_stored 回执。
+func (r *Reporter) OfflineStored(msg *messagev1.PushMessage) {
+	r.report(&Receipt{
+		Type:       TypeOfflineStored,
+		MessageIDs: []string{msg.GetMessageId()},
+		BID:        msg.GetBizId(),
+		UID:        msg.GetReceiverId(),
+		NodeID:     r.nodeID,
+		Timestamp:  time.Now().UnixMilli(),
+	})
+}
+
+// Expired 在丢弃发送给连接的过期消息时发布 expired 回执 ( 按设备 )。
+func (r *Reporter) Expired(conn synp.Conn, msg *messagev1.Message, stage string) {
+	receipt := r.connReceipt(TypeExpired, conn, msg.GetMessageId())
+	receipt.Reason = stage
+	r.report(receipt)
+}
+
+// PushExpired 在丢弃还没有发送给连接的过期消息时发布 expired 回执。
+func (r *Reporter) PushExpired(msg *messagev1.PushMessage, stage string) {
+	r.report(&Receipt{
+		Type:       TypeExpired,
+		MessageIDs: []string{msg.GetMessageId()},
+		BID:        msg.GetBizId(),
+		UID:        msg.GetReceiverId(),
+		Reason:     stage,
+		NodeID:     r.nodeID,
+		Timestamp:  time.Now().UnixMilli(),
+	})
+}
+
+// Read 发布 read 回执。
+func (r *Reporter) Read(conn synp.Conn, messageIDs []string) {
+	r.report(r.connReceipt(TypeRead, conn, messageIDs...))
+}
+
+func (r *Reporter) connReceipt(typ Type, conn synp.Conn, messageIDs ...string) *Receipt {
+	user := conn.Session().User()
+	return &Receipt{
+		Type:       typ,
+		MessageIDs: messageIDs,
+		BID:        user.BID,
+		UID:        user.UID,
+		Device:     user.Device,
+		ConnID:     conn.ID(),
+		NodeID:     r.nodeID,
+		Timestamp:  time.Now().UnixMilli(),
+	}
+}
+
+func (r *Reporter) report(receipt *Receipt) {
+	if r.topic == "" {
+		return
+	}
+
+	val, err := json.Marshal(receipt)
+	if err != nil {
+		slog.Error("[synp-receipt-reporter] failed to marshal receipt", "type", receipt.Type, "error", err)
+		return
+	}
+
+	user := session.User{BID: receipt.BID, UID: receipt.UID}
+	ctx, cancel := context.WithTimeout(context.Background(), r.requestTimeout)
+
+	future, err := r.producer.ProduceAsync(ctx, &xmq.Message{
+		Topic: r.topic,
+		Key:   []byte(user.ConnKey()),
+		Val:   val,
+	})
+	if err != nil {
+		cancel()
+		r.logFailure(receipt, err)
+		return
+	}
+
+	go func() {
+		defer cancel()
+		if err := future.Wait(ctx); err != nil {
+			r.logFailure(receipt, err)
+		}
+	}()
+}
+
+func (r *Reporter) logFailure(receipt *Receipt, err error) {
+	slog.Warn(
+		"[synp-receipt-reporter] failed to publish receipt",
+		"type", receipt.Type,
+		"message_ids", receipt.MessageIDs,
+		"bid", receipt.BID,
+		"uid", receipt.UID,
+		"error", err,
+	)
+}
+
+// ReporterWithRequestTimeout 设置发布回执的超时时间。
+func ReporterWithRequestTimeout(timeout time.Duration) option.Opt[Reporter] {
+	return func(r *Reporter) {
+		if timeout > 0 {
+			r.requestTimeout = timeout
+		}
+	}
+}
+
+// NewReporter 创建回执发布者，回执会被发布到 topic，topic 为空时不发布。
+func NewReporter(producer produce.AsyncProducer, nodeID, topic string, opts ...option.Opt[Reporter]) *Reporter {
+	r := &Reporter{
+		producer:       producer,
+		nodeID:         nodeID,
+		topic:          topic,
+		requestTimeout: DefaultRequestTimeout,
+	}
+
+	option.Apply(r, opts...)
+	return r
+}
+
+var _ offline.Store = (*OfflineStore)(nil)
+
+// OfflineStore 在保存离线消息成功后发布 offline_stored 回执。
+// 已经过期的消息不会被保存，也不会被重新推送，丢弃时发布 expired 回执。
+type OfflineStore struct {
+	offline.Store
+
+	reporter *Reporter
+}
+
+func (s *OfflineStore) Save(ctx context.Context, msg *messagev1.PushMessage) error {
+	if message.Expired(msg, time.Now()) {
+		s.reporter.PushExpired(msg, StageOffline)
+		return nil
+	}
+
+	if err := s.Store.Save(ctx, msg); err != nil {
+		return err
+	}
+
+	s.reporter.OfflineStored(msg)
+	return nil
+}
+
+func (s *OfflineStore) Take(ctx context.Context, bid, uid uint64, limit int) ([]*messagev1.PushMessage, error) {
+	// 丢弃保存期间过期的消息，并继续取出离线消息补足 limit 条，
+	// 因为调用方根据返回的消息数是否小于 limit 判断是否还有离线消息。
+	var msgs []*messagev1.PushMessage
+	for len(msgs) < limit {
+		want := limit - len(msgs)
+		taken, err := s.Store.Take(ctx, bid, uid, want)
+		if err != nil {
+			if len(msgs) > 0 {
+				// 已经取出 ( 并删除 ) 的消息先返回，避免丢失。
+				return msgs, nil
+			}
+			return nil, err
+		}
+
+		now := time.Now()
+		for _, msg := range taken {
+			if message.Expired(msg, now) {
+				s.reporter.PushExpired(msg, StageOffline)
+				continue
+			}
+			msgs = append(msgs, msg)
+		}
+
+		if len(taken) < want {
+			break
+		}
+	}
+	return msgs, nil
+}
+
+// NewOfflineStore 包装离线消息存储，保存成功后发布 offline_stored 回执。
+func NewOfflineStore(store offline.Store, reporter *Reporter) *OfflineStore {
+	return &OfflineStore{
+		Store:    store,
+		reporter: reporter,
+	}
+}
@@ -0,0 +1,66 @@
+package receipt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	producemock "github.com/jrmarcco/synp/internal/pkg/xmq/produce/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestReporter(t *testing.T) {
+	t.Parallel()
+
+	producer, recorder := producemock.NewRecordingAsyncProducer(gomock.NewController(t))
+	reporter := NewReporter(producer, "node-1", "receipt")
+
+	reporter.OfflineStored(&messagev1.PushMessage{MessageId: "m1", BizId: 1, ReceiverId: 2})
+
+	msgs := recorder.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "receipt", msgs[0].Topic)
+	assert.Equal(t, "1:2", string(msgs[0].Key))
+
+	receipt := &Receipt{}
+	require.NoError(t, json.Unmarshal(msgs[0].Val, receipt))
+	assert.Equal(t, TypeOfflineStored, receipt.Type)
+	assert.Equal(t, []string{"m1"}, receipt.MessageIDs)
+	assert.Equal(t, uint64(1), receipt.BID)
+	assert.Equal(t, uint64(2), receipt.UID)
+	assert.Equal(t, "node-1", receipt.NodeID)
+
+	// topic 为空时不发布回执。
+	NewReporter(producer, "node-1", "").OfflineStored(&messagev1.PushMessage{MessageId: "m2"})
+	assert.Len(t, recorder.Messages(), 1)
+}
+
+func TestOfflineStore(t *testing.T) {
+	t.Parallel()
+
+	producer, recorder := producemock.NewRecordingAsyncProducer(gomock.NewController(t))
+	store := &testOfflineStore{}
+	offlineStore := NewOfflineStore(store, NewReporter(producer, "node-1", "receipt"))
+
+	require.NoError(t, offlineStore.Save(t.Context(), &messagev1.PushMessage{MessageId: "m1", BizId: 1, ReceiverId: 2}))
+	assert.Len(t, recorder.Messages(), 1)
+
+	// 保存失败时不发布回执。
+	store.err = errors.New("mock error")
+	require.Error(t, offlineStore.Save(t.Context(), &messagev1.PushMessage{MessageId: "m2", BizId: 1, ReceiverId: 2}))
+	assert.Len(t, recorder.Messages(), 1)
+}
+
+type testOfflineStore struct {
+	err error
+}
+
+func (s *testOfflineStore) Save(_ context.Context, _ *messagev1.PushMessage) error { return s.err }
+
+func (s *testOfflineStore) Take(_ context.Context, _, _ uint64, _ int) ([]*messagev1.PushMessage, error) {
+	return nil, nil
+}
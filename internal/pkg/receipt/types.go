@@ -0,0 +1,43 @@
+// Package receipt 负责将 downstream 消息的投递回执及已读回执发布到消息队列，供后端 ( 业务服务端 ) 订阅。
+package receipt
+
+import "github.com/jrmarcco/synp/internal/pkg/session"
+
+// Type 为回执类型。
+type Type string
+
+const (
+	TypeDelivered     Type = "delivered"      // 前端已确认收到 ( 按设备 )
+	TypeFailed        Type = "failed"         // 重传达到上限仍未确认 ( 按设备 )
+	TypeOfflineStored Type = "offline_stored" // 接收者不在线，已保存为离线消息
+	TypeRead          Type = "read"           // 前端上报已读
+	TypeExpired       Type = "expired"        // 消息已经过期，不再投递 ( reason 为丢弃消息的阶段 )
+)
+
+// 消息过期时被丢弃的阶段。
+const (
+	StageConsume    = "consume"    // 消费 ( 或直接推送 ) 时已经过期
+	StageSendQueue  = "send_queue" // 在连接的发送队列中过期
+	StageRetransmit = "retransmit" // 等待重传时过期
+	StageOffline    = "offline"    // 保存或重新推送离线消息时已经过期
+)
+
+// Receipt 为回执事件。
+type Receipt struct {
+	Type       Type     `json:"type"`
+	MessageIDs []string `json:"messageIds"`
+
+	BID    uint64         `json:"bid"`
+	UID    uint64         `json:"uid"`
+	Device session.Device `json:"device,omitempty"` // 离线回执没有设备
+	ConnID string         `json:"connId,omitempty"`
+
+	Reason    string `json:"reason,omitempty"` // 失败原因，过期回执为丢弃消息的阶段
+	NodeID    string `json:"nodeId"`
+	Timestamp int64  `json:"timestamp"` // 毫秒
+}
+
+// ReadRequest 为前端上报已读的请求 ( CommandTypeRead 消息的 body )。
+type ReadRequest struct {
+	MessageIDs []string `json:"messageIds"`
+}
@@ -0,0 +1,51 @@
+package retransmit
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	DefaultMaxRetryInterval = time.Minute
+	DefaultMultiplier       = 2.0
+	DefaultJitter           = 0.2
+)
+
+// Backoff 为重传的指数退避策略。
+// 第 n 次重传 ( 从 0 开始 ) 的间隔为 min(InitialInterval * Multiplier^n, MaxInterval)，
+// 并在 [1-Jitter, 1+Jitter] 范围内随机抖动，避免大量连接同时重传。
+type Backoff struct {
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+	Jitter          float64 // 抖动比例，取值范围 [0, 1)
+}
+
+// Next 返回第 attempt 次重传前的等待时间。
+func (b Backoff) Next(attempt int32) time.Duration {
+	interval := float64(b.InitialInterval) * math.Pow(b.Multiplier, float64(attempt))
+	interval = min(interval, float64(b.MaxInterval))
+
+	if b.Jitter > 0 {
+		interval *= 1 + b.Jitter*(2*rand.Float64()-1) //nolint:gosec // 抖动不需要安全的随机数。
+	}
+	return time.Duration(interval)
+}
+
+// normalize 修正无效的参数。
+func (b Backoff) normalize() Backoff {
+	if b.InitialInterval <= 0 {
+		b.InitialInterval = DefaultRetryInterval
+	}
+	if b.MaxInterval < b.InitialInterval {
+		b.MaxInterval = max(DefaultMaxRetryInterval, b.InitialInterval)
+	}
+	if b.Multiplier < 1 {
+		b.Multiplier = DefaultMultiplier
+	}
+	if b.Jitter < 0 || b.Jitter >= 1 {
+		b.Jitter = DefaultJitter
+	}
+	return b
+}
@@ -0,0 +1,126 @@
+package retransmit
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+
+	"github.com/jrmarcco/synp"
+)
+
+// connTasks 为同一连接 ID 上的重传任务索引。
+//
+// 任务按序号递增保存在 pending 中，累积 ack 只需要从头部开始停止任务；
+// 单独停止的任务只从 tasks 中删除，在 pending 中的位置延迟清理。
+type connTasks struct {
+	mu sync.Mutex
+
+	conn synp.Conn // 最近使用该索引的连接，该连接断开时删除索引
+	seq  uint64    // 最后分配的序号
+	dead bool      // 索引已经从 Manager 中删除，需要重新创建
+
+	tasks     map[string]*Task // messageId -> retransmit.Task
+	pending   []*Task          // 按序号递增排列，可能包含已经停止的任务
+	conflated map[string]*Task // conflationKey -> retransmit.Task
+}
+
+// add 添加任务，已经停止的任务占用的序号会被替换。
+func (c *connTasks) add(task *Task) {
+	c.tasks[task.msg.GetMessageId()] = task
+	if task.conflationKey != "" {
+		c.conflated[task.conflationKey] = task
+	}
+
+	if n := len(c.pending); n == 0 || c.pending[n-1].seq < task.seq {
+		c.pending = append(c.pending, task)
+		return
+	}
+
+	i, found := c.search(task.seq)
+	if found {
+		c.pending[i] = task
+		return
+	}
+	c.pending = slices.Insert(c.pending, i, task)
+}
+
+// remove 删除任务，返回任务是否在等待 ack。
+func (c *connTasks) remove(task *Task) bool {
+	if !c.unindex(task) {
+		return false
+	}
+
+	// 已经停止的任务过多时清理 pending。
+	if len(c.pending) > 2*len(c.tasks)+32 {
+		c.pending = slices.DeleteFunc(c.pending, func(t *Task) bool {
+			return !c.contains(t)
+		})
+	}
+	return true
+}
+
+// upTo 删除并返回序号不大于 seq 的任务。
+func (c *connTasks) upTo(seq uint64) []*Task {
+	var (
+		tasks []*Task
+		i     int
+	)
+	for ; i < len(c.pending) && c.pending[i].seq <= seq; i++ {
+		if task := c.pending[i]; c.unindex(task) {
+			tasks = append(tasks, task)
+		}
+	}
+
+	clear(c.pending[:i])
+	c.pending = c.pending[i:]
+	return tasks
+}
+
+// find 返回序号为 seq 且等待 ack 的任务。
+func (c *connTasks) find(seq uint64) (*Task, bool) {
+	i, found := c.search(seq)
+	if !found || !c.contains(c.pending[i]) {
+		return nil, false
+	}
+	return c.pending[i], true
+}
+
+func (c *connTasks) search(seq uint64) (int, bool) {
+	return slices.BinarySearchFunc(c.pending, seq, func(t *Task, seq uint64) int {
+		return cmp.Compare(t.seq, seq)
+	})
+}
+
+// byConn 返回属于指定连接的任务。
+func (c *connTasks) byConn(conn synp.Conn) []*Task {
+	var tasks []*Task
+	for _, task := range c.tasks {
+		if task.conn == conn {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks
+}
+
+func (c *connTasks) unindex(task *Task) bool {
+	if !c.contains(task) {
+		return false
+	}
+
+	delete(c.tasks, task.msg.GetMessageId())
+	if task.conflationKey != "" && c.conflated[task.conflationKey] == task {
+		delete(c.conflated, task.conflationKey)
+	}
+	return true
+}
+
+func (c *connTasks) contains(task *Task) bool {
+	return c.tasks[task.msg.GetMessageId()] == task
+}
+
+func newConnTasks() *connTasks {
+	return &connTasks{
+		tasks:     make(map[string]*Task),
+		conflated: make(map[string]*Task),
+	}
+}
@@ -1,69 +1,117 @@
 package retransmit
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"log/slog"
+	"maps"
+	"slices"
+	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/jrmarcco/jit/bean/option"
 	"github.com/jrmarcco/jit/xsync"
 	"github.com/jrmarcco/synp"
 	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
 	"github.com/jrmarcco/synp/internal/pkg/message"
+	"github.com/jrmarcco/synp/internal/pkg/timingwheel"
+	"google.golang.org/protobuf/proto"
 )
 
 const (
 	DefaultRetryInterval = 8 * time.Second
 	DefaultMaxRetryCnt   = 3
+	DefaultStoreTimeout  = time.Second
 )
 
+var ErrMaxRetransmitExceeded = errors.New("max retransmit count exceeded")
+
+// FailFunc 在放弃重传 ( 重传达到上限或重传失败 ) 时调用，不会在收到 ack 停止重传时调用。
+type FailFunc func(conn synp.Conn, msg *messagev1.Message, cause error)
+
+// ExpireFunc 在消息过期停止重传时调用。
+type ExpireFunc func(conn synp.Conn, msg *messagev1.Message)
+
 // Task 为重传任务。
 // 负责对 downstream 消息的失败重传。
 // 每个消息需要一个重传任务，一个重传任务只能对应一个消息。
 type Task struct {
-	key  string
 	conn synp.Conn
 	msg  *messagev1.Message
+	seq  uint64 // 消息在连接上的序号
+
+	conflationKey string    // 消息的合并 key，为空时不会被新消息取代
+	expireAt      time.Time // 消息的过期时间，过期后停止重传，零值表示永不过期
+	createdAt     time.Time // 开始等待 ack 的时间
 
-	timerPtr      atomic.Pointer[time.Timer] // 重传定时器
-	retransmitCnt atomic.Int32               // 重传次数
+	timerPtr      atomic.Pointer[timingwheel.Timer] // 重传定时器
+	retransmitCnt atomic.Int32                      // 重传次数
+
+	// 保存重传状态与停止任务互斥，避免停止 ( 删除状态 ) 之后又保存了状态。
+	mu      sync.Mutex
+	stopped bool
 
 	manager *Manager
 }
 
 func (t *Task) run() {
-	// 尝试加载任务，如果不存在说明已被停止。
-	if _, ok := t.manager.tasks.Load(t.key); !ok {
+	// 任务不在索引中 ( 或已经被新的任务替换 ) 说明已被停止。
+	if !t.manager.pending(t) {
 		return
 	}
 
-	t.retransmitCnt.Add(1)
+	// 连接已经断开，保留重传状态，等待用户重新连接时恢复。
+	if t.connClosed() {
+		t.manager.suspend(t)
+		return
+	}
+
+	// 消息已经过期，没有重传的意义。
+	if !t.expireAt.IsZero() && !time.Now().Before(t.expireAt) {
+		slog.Debug(
+			"[synp-retransmit-manager] message expired, stop retransmit task",
+			"conn_id", t.conn.ID(),
+			"message_id", t.msg.MessageId,
+			"expire_at", t.expireAt,
+		)
+		t.manager.expire(t)
+		return
+	}
+
+	cnt := t.retransmitCnt.Add(1)
 
 	// 检查重传次数。
-	if t.retransmitCnt.Load() >= t.manager.maxRetryCnt {
+	if cnt >= t.manager.maxRetryCnt {
 		slog.Warn(
 			"[synp-retransmit-manager] retransmit task reach max retry cnt",
 			"conn_id", t.conn.ID(),
 			"message_id", t.msg.MessageId,
-			"retransmit_count", t.retransmitCnt.Load(),
+			"retransmit_count", cnt,
 		)
-		_ = t.manager.stopAndDelete(t.key)
+		t.manager.giveUp(t, ErrMaxRetransmitExceeded)
 		return
 	}
 
 	// 重传。
 	err := t.manager.taskFunc(t.conn, t.msg)
 	if err != nil {
+		if t.connClosed() {
+			// 重传期间连接断开。
+			t.manager.suspend(t)
+			return
+		}
+
 		slog.Error(
 			"[synp-retransmit-manager] failed to retransmit message",
 			"conn_id", t.conn.ID(),
 			"message_id", t.msg.MessageId,
-			"retransmit_count", t.retransmitCnt.Load(),
+			"retransmit_count", cnt,
 			"error", err.Error(),
 		)
 
 		// 重传失败，直接停止重传任务。
-		_ = t.manager.stopAndDelete(t.key)
+		t.manager.giveUp(t, err)
 		return
 	}
 
@@ -72,138 +120,519 @@ func (t *Task) run() {
 		"[synp-retransmit-manager] successfully retransmit message",
 		"conn_id", t.conn.ID(),
 		"message_id", t.msg.MessageId,
-		"retransmit_count", t.retransmitCnt.Load(),
+		"retransmit_count", cnt,
 	)
 
-	// 更新定时器。
-	t.timerPtr.Store(time.AfterFunc(t.manager.retryInterval, t.run))
+	// 保存重传次数，恢复重传时继续计数。
+	t.persist()
+	t.schedule(t.manager.backoff.Next(cnt))
+}
+
+// schedule 在 d 之后重传。
+func (t *Task) schedule(d time.Duration) {
+	t.timerPtr.Store(t.manager.wheel.AfterFunc(d, t.run))
+}
+
+// persist 保存重传状态，任务已经被停止时不保存。
+func (t *Task) persist() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.stopped {
+		t.manager.save(t)
+	}
 }
 
 func (t *Task) stop() {
+	t.mu.Lock()
+	t.stopped = true
+	t.mu.Unlock()
+
 	if timer := t.timerPtr.Load(); timer != nil {
-		// Stop() 返回 false 表示 timer 已经过期或被停止。
-		// 此时需要排空 channel 防止 goroutine 泄露。
-		if !timer.Stop() {
-			// 尝试排空 channel。
-			select {
-			case <-timer.C:
-			default:
-			}
-		}
+		timer.Stop()
+	}
+}
+
+func (t *Task) connClosed() bool {
+	select {
+	case <-t.conn.Closed():
+		return true
+	default:
+		return false
 	}
 }
 
 // Manager 为重传管理器，负责管理重传任务。
-// 重传使用固定间隔重试，直到成功或达到最大重传次数。
+//
+// 重传按指数退避 ( 带随机抖动 ) 重试，直到成功、达到最大重传次数或消息过期。
+// 同一连接上合并 key 相同的消息只重传最新的一条，旧消息的重传任务会被停止。
+//
+// 重传任务按连接 ID 建立索引，每条消息在连接上分配递增的序号，
+// 前端可以批量 ack ( 消息 ID 列表 ) 或累积 ack ( 序号不大于 N 的所有消息 )，
+// 连接断开时也只需要处理该连接的任务。
+//
+// 所有重传定时器由同一个分层时间轮驱动，不会为每个任务创建 runtime timer。
+// 等待 ack 的消息同时保存在 Store 中，连接断开或节点关闭时只停止本地的重传任务，
+// 用户重新连接 ( 可能在其他节点 ) 时通过 Resume 恢复重传。
 type Manager struct {
-	tasks *xsync.Map[string, *Task] // key (connId:messageId) -> retransmit.Task
+	conns *xsync.Map[string, *connTasks] // connId -> 连接上的重传任务
+
+	totalTaskCnt atomic.Int64
+	backoff      Backoff // 重传间隔
+	maxRetryCnt  int32   // 最大重传次数
 
-	totalTaskCnt  atomic.Int64
-	retryInterval time.Duration // 重传间隔
-	maxRetryCnt   int32         // 最大重传次数
+	tick  time.Duration
+	wheel *timingwheel.TimingWheel
 
-	taskFunc message.PushFunc
-	closed   atomic.Bool
+	store        Store
+	storeTimeout time.Duration
+
+	taskFunc   message.PushFunc
+	failFunc   FailFunc
+	expireFunc ExpireFunc
+	closed     atomic.Bool
 }
 
-func (m *Manager) Start(conns []synp.Conn, msg *messagev1.Message) {
-	for _, conn := range conns {
-		m.start(conn, msg)
+// Start 为每个连接启动消息的重传任务，返回发送到各个连接的消息 ( 与 conns 一一对应 )。
+//
+// 发送到各个连接的消息是 msg 的副本，携带了在该连接上分配的序号 ( 见 message.Seq )。
+// 消息已经在连接上等待 ack 时返回等待 ack 的消息；管理器已经关闭或消息已经过期时返回 msg。
+func (m *Manager) Start(conns []synp.Conn, msg *messagev1.Message) []*messagev1.Message {
+	msgs := make([]*messagev1.Message, len(conns))
+	for i, conn := range conns {
+		msgs[i] = m.start(conn, msg)
 	}
+	return msgs
 }
 
-func (m *Manager) start(conn synp.Conn, msg *messagev1.Message) {
-	if m.closed.Load() {
-		return
+func (m *Manager) start(conn synp.Conn, msg *messagev1.Message) *messagev1.Message {
+	now := time.Now()
+	if m.closed.Load() || message.Expired(msg, now) {
+		return msg
 	}
 
-	task := &Task{
-		key:     m.taskKey(conn.ID(), msg.MessageId),
-		conn:    conn,
-		msg:     msg,
-		manager: m,
+	idx := m.lockIndex(conn)
+	if prev, ok := idx.tasks[msg.GetMessageId()]; ok {
+		idx.mu.Unlock()
+		return prev.msg
 	}
 
-	if _, ok := m.tasks.LoadOrStore(task.key, task); ok {
-		return
+	// 合并 key 相同的旧消息已经被新消息取代。
+	// 新消息总是分配新的序号，避免前端对旧序号的累积 ack 确认了还没有收到的新消息。
+	var superseded *Task
+	if key := message.ConflationKey(msg); key != "" {
+		if prev, ok := idx.conflated[key]; ok && idx.remove(prev) {
+			superseded = prev
+		}
 	}
 
-	task.timerPtr.Store(time.AfterFunc(m.retryInterval, task.run))
+	idx.seq++
+	downstreamMsg := proto.Clone(msg).(*messagev1.Message)
+	message.SetSeq(downstreamMsg, idx.seq)
+
+	task := m.newTask(conn, downstreamMsg, idx.seq, now)
+	idx.add(task)
+	idx.mu.Unlock()
+
 	m.totalTaskCnt.Add(1)
+	if superseded != nil {
+		m.stopTasks(superseded)
+		m.deleteState(conn.ID(), superseded.msg.GetMessageId())
+
+		slog.Debug(
+			"[synp-retransmit-manager] retransmit task superseded by newer message",
+			"conn_id", conn.ID(),
+			"conflation_key", task.conflationKey,
+			"message_id", task.msg.MessageId,
+		)
+	}
+
+	task.persist()
+	task.schedule(m.backoff.Next(0))
 
 	slog.Debug(
 		"[synp-retransmit-manager] successfully start retransmit task",
 		"conn_id", conn.ID(),
 		"message_id", msg.MessageId,
-		"retry_interval", m.retryInterval,
+		"seq", task.seq,
+		"init_retry_interval", m.backoff.InitialInterval,
 		"max_retry_cnt", m.maxRetryCnt,
 	)
+	return downstreamMsg
 }
 
-// Stop 停止指定消息的重传任务。
-func (m *Manager) Stop(connID, messageID string) {
-	key := m.taskKey(connID, messageID)
-	if task, ok := m.tasks.Load(key); ok {
-		_ = m.stopAndDelete(key)
+// Resume 恢复连接上等待 ack 的消息的重传 ( 连接建立时调用 )，恢复的消息在下一个 tick 重传。
+// 已经过期的消息不会恢复。
+//
+// 恢复的消息保持原来的序号，没有序号 ( 升级前保存 ) 或序号已经被占用的消息分配新的序号。
+func (m *Manager) Resume(conn synp.Conn) {
+	if m.closed.Load() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.storeTimeout)
+	entries, err := m.store.Load(ctx, conn.ID())
+	cancel()
+	if err != nil {
+		slog.Error(
+			"[synp-retransmit-manager] failed to load retransmit state",
+			"conn_id", conn.ID(),
+			"error", err,
+		)
+		return
+	}
+
+	// 按开始等待 ack 的顺序恢复，合并 key 相同时保留最新的消息。
+	slices.SortFunc(entries, func(a, b *Entry) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	var (
+		now = time.Now()
+
+		resumed  []*Task  // 恢复的任务
+		renumber []*Task  // 重新分配序号的任务，需要保存新的序号
+		replaced []*Task  // 旧连接上的同一消息的任务，只停止任务
+		dropped  []*Task  // 被新消息取代的任务，停止任务并删除状态
+		expired  []*Entry // 已经过期的消息
+		stale    []string // 已经被新消息取代的消息 ID
+	)
+
+	idx := m.lockIndex(conn)
+	for _, entry := range entries {
+		msg := entry.Message
+		if message.Expired(msg, now) {
+			expired = append(expired, entry)
+			continue
+		}
+
+		if prev, ok := idx.tasks[msg.GetMessageId()]; ok {
+			if prev.conn == conn {
+				continue
+			}
+			// 旧连接的重传任务还没有清除 ( 同一设备重新连接到本节点 )。
+			idx.remove(prev)
+			replaced = append(replaced, prev)
+		}
+
+		key := message.ConflationKey(msg)
+		if prev, ok := idx.conflated[key]; ok && key != "" {
+			if prev.createdAt.After(entry.CreatedAt) {
+				stale = append(stale, msg.GetMessageId())
+				continue
+			}
+			idx.remove(prev)
+			dropped = append(dropped, prev)
+		}
+
+		seq := message.Seq(msg)
+		_, taken := idx.find(seq)
+		if seq == 0 || taken {
+			idx.seq++
+			seq = idx.seq
+			msg = proto.Clone(msg).(*messagev1.Message)
+			message.SetSeq(msg, seq)
+		}
+		idx.seq = max(idx.seq, seq)
+
+		task := m.newTask(conn, msg, seq, entry.CreatedAt)
+		task.retransmitCnt.Store(entry.RetransmitCnt)
+		idx.add(task)
+
+		resumed = append(resumed, task)
+		if msg != entry.Message {
+			renumber = append(renumber, task)
+		}
+	}
+	idx.mu.Unlock()
+
+	m.stopTasks(replaced...)
+	m.stopTasks(dropped...)
+	for _, task := range dropped {
+		stale = append(stale, task.msg.GetMessageId())
+	}
+	for _, entry := range expired {
+		stale = append(stale, entry.Message.GetMessageId())
+	}
+	m.deleteState(conn.ID(), stale...)
+
+	if m.expireFunc != nil {
+		for _, entry := range expired {
+			m.expireFunc(conn, entry.Message)
+		}
+	}
+
+	m.totalTaskCnt.Add(int64(len(resumed)))
+	for _, task := range renumber {
+		task.persist()
+	}
+	for _, task := range resumed {
+		task.schedule(0)
+	}
+
+	if len(resumed) > 0 {
+		slog.Info(
+			"[synp-retransmit-manager] successfully resume retransmit tasks",
+			"conn_id", conn.ID(),
+			"task_resumed_cnt", len(resumed),
+		)
+	}
+}
+
+// Ack 停止连接上指定消息的重传任务并删除重传状态 ( 批量 ack )，返回其中等待 ack 的消息 ID。
+func (m *Manager) Ack(connID string, messageIDs ...string) []string {
+	if len(messageIDs) == 0 {
+		return nil
+	}
+
+	var tasks []*Task
+	if idx, ok := m.conns.Load(connID); ok {
+		idx.mu.Lock()
+		for _, id := range messageIDs {
+			if task, ok := idx.tasks[id]; ok && idx.remove(task) {
+				tasks = append(tasks, task)
+			}
+		}
+		idx.mu.Unlock()
+	}
+
+	m.stopTasks(tasks...)
+	// 任务可能还没有在本节点恢复，总是删除重传状态。
+	m.deleteState(connID, messageIDs...)
+	return m.acked(connID, tasks)
+}
+
+// AckUpTo 停止连接上序号不大于 seq 的所有消息的重传任务并删除重传状态 ( 累积 ack )，
+// 返回停止的消息 ID。
+//
+// 只会停止本节点上的任务，还没有在本节点恢复的消息需要通过 Ack 确认。
+func (m *Manager) AckUpTo(connID string, seq uint64) []string {
+	idx, ok := m.conns.Load(connID)
+	if !ok {
+		return nil
+	}
+
+	idx.mu.Lock()
+	tasks := idx.upTo(seq)
+	idx.mu.Unlock()
+
+	m.stopTasks(tasks...)
+	ids := m.acked(connID, tasks)
+	m.deleteState(connID, ids...)
+	return ids
+}
+
+func (m *Manager) acked(connID string, tasks []*Task) []string {
+	ids := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		ids = append(ids, task.msg.GetMessageId())
 
 		slog.Debug(
 			"[synp-retransmit-manager] successfully stop retransmit task",
 			"conn_id", connID,
-			"message_id", messageID,
+			"message_id", task.msg.MessageId,
+			"seq", task.seq,
 			"retransmit_count", task.retransmitCnt.Load(),
 		)
 	}
+	return ids
 }
 
-func (m *Manager) taskKey(connID, messageID string) string {
-	return fmt.Sprintf("%s:%s", connID, messageID)
+// Stop 停止指定消息的重传任务并删除重传状态。
+func (m *Manager) Stop(connID, messageID string) {
+	m.Ack(connID, messageID)
+}
+
+func (m *Manager) newTask(conn synp.Conn, msg *messagev1.Message, seq uint64, createdAt time.Time) *Task {
+	return &Task{
+		conn:    conn,
+		msg:     msg,
+		seq:     seq,
+		manager: m,
+
+		conflationKey: message.ConflationKey(msg),
+		expireAt:      message.ExpireAt(msg),
+		createdAt:     createdAt,
+	}
+}
+
+// lockIndex 返回连接 ID 对应的索引 ( 不存在时创建 ) 并加锁，同时将索引的连接更新为 conn。
+func (m *Manager) lockIndex(conn synp.Conn) *connTasks {
+	for {
+		idx, ok := m.conns.Load(conn.ID())
+		if !ok {
+			idx, _ = m.conns.LoadOrStore(conn.ID(), newConnTasks())
+		}
+
+		idx.mu.Lock()
+		if !idx.dead {
+			idx.conn = conn
+			return idx
+		}
+		// 索引已经被删除，重新加载。
+		idx.mu.Unlock()
+	}
+}
+
+// pending 判断任务是否在等待 ack。
+func (m *Manager) pending(task *Task) bool {
+	idx, ok := m.conns.Load(task.conn.ID())
+	if !ok {
+		return false
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	return !idx.dead && idx.contains(task)
+}
+
+// remove 从索引中删除任务，返回 false 表示任务已经被删除。
+func (m *Manager) remove(task *Task) bool {
+	idx, ok := m.conns.Load(task.conn.ID())
+	if !ok {
+		return false
+	}
+
+	idx.mu.Lock()
+	removed := !idx.dead && idx.remove(task)
+	idx.mu.Unlock()
+
+	if removed {
+		m.stopTasks(task)
+	}
+	return removed
+}
+
+// stopTasks 停止已经从索引中删除的任务，不会删除重传状态。
+func (m *Manager) stopTasks(tasks ...*Task) {
+	for _, task := range tasks {
+		task.stop()
+	}
+	m.totalTaskCnt.Add(-int64(len(tasks)))
 }
 
 func (m *Manager) TotalTaskCnt() int64 {
 	return m.totalTaskCnt.Load()
 }
 
-// ClearByConn 清除指定连接的重传任务。
-func (m *Manager) ClearByConn(connID string) {
-	var cnt int
-	m.tasks.Range(func(key string, task *Task) bool {
-		if task.conn.ID() == connID {
-			if m.stopAndDelete(key) {
-				cnt++
-			}
-		}
-		return true
-	})
+// ClearByConn 停止指定连接 ( 连接断开时 ) 的重传任务，保留重传状态，等待用户重新连接时恢复。
+// 只停止该连接的任务，不影响同一设备重新建立的连接。
+func (m *Manager) ClearByConn(conn synp.Conn) {
+	idx, ok := m.conns.Load(conn.ID())
+	if !ok {
+		return
+	}
+
+	idx.mu.Lock()
+	tasks := idx.byConn(conn)
+	for _, task := range tasks {
+		idx.remove(task)
+	}
+	// 同一设备没有建立新的连接时删除索引。
+	if idx.conn == conn && len(idx.tasks) == 0 && !idx.dead {
+		idx.dead = true
+		m.conns.Delete(conn.ID())
+	}
+	idx.mu.Unlock()
+
+	m.stopTasks(tasks...)
 
-	if cnt > 0 {
+	if len(tasks) > 0 {
 		slog.Info(
 			"[synp-retransmit-manager] successfully clear retransmit tasks by connection",
-			"conn_id", connID,
-			"task_cleared_cnt", cnt,
+			"conn_id", conn.ID(),
+			"task_cleared_cnt", len(tasks),
 		)
 	}
 }
 
-func (m *Manager) stopAndDelete(key string) bool {
-	if task, ok := m.tasks.LoadAndDelete(key); ok {
-		task.stop()
-		m.totalTaskCnt.Add(-1)
-		return true
+// suspend 停止连接已经断开的重传任务，保留重传状态。
+func (m *Manager) suspend(task *Task) {
+	if m.remove(task) {
+		slog.Debug(
+			"[synp-retransmit-manager] connection closed, suspend retransmit task",
+			"conn_id", task.conn.ID(),
+			"message_id", task.msg.MessageId,
+		)
+	}
+}
+
+// giveUp 放弃重传任务，任务已经被停止 ( 如同时收到 ack ) 时不调用 failFunc。
+func (m *Manager) giveUp(task *Task, cause error) {
+	if m.remove(task) {
+		m.deleteState(task.conn.ID(), task.msg.GetMessageId())
+		if m.failFunc != nil {
+			m.failFunc(task.conn, task.msg, cause)
+		}
+	}
+}
+
+// expire 停止已经过期的消息的重传任务，任务已经被停止时不调用 expireFunc。
+func (m *Manager) expire(task *Task) {
+	if m.remove(task) {
+		m.deleteState(task.conn.ID(), task.msg.GetMessageId())
+		if m.expireFunc != nil {
+			m.expireFunc(task.conn, task.msg)
+		}
+	}
+}
+
+func (m *Manager) save(task *Task) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.storeTimeout)
+	defer cancel()
+
+	err := m.store.Save(ctx, task.conn.ID(), &Entry{
+		Message:       task.msg,
+		RetransmitCnt: task.retransmitCnt.Load(),
+		CreatedAt:     task.createdAt,
+	})
+	if err != nil {
+		// 保存失败不影响本地重传，只是无法在重新连接时恢复。
+		slog.Warn(
+			"[synp-retransmit-manager] failed to save retransmit state",
+			"conn_id", task.conn.ID(),
+			"message_id", task.msg.MessageId,
+			"error", err,
+		)
+	}
+}
+
+func (m *Manager) deleteState(connID string, messageIDs ...string) {
+	if len(messageIDs) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.storeTimeout)
+	defer cancel()
+
+	if err := m.store.Delete(ctx, connID, messageIDs...); err != nil {
+		slog.Warn(
+			"[synp-retransmit-manager] failed to delete retransmit state",
+			"conn_id", connID,
+			"message_ids", messageIDs,
+			"error", err,
+		)
 	}
-	return false
 }
 
-// Close 关闭重传管理器。
+// Close 关闭重传管理器，停止所有本地的重传任务，保留重传状态 ( 节点重启后恢复 )。
 func (m *Manager) Close() {
 	if !m.closed.CompareAndSwap(false, true) {
 		return
 	}
 
+	m.wheel.Stop()
+
 	var cnt int
-	m.tasks.Range(func(key string, _ *Task) bool {
-		if m.stopAndDelete(key) {
-			cnt++
-		}
+	m.conns.Range(func(connID string, idx *connTasks) bool {
+		idx.mu.Lock()
+		tasks := slices.Collect(maps.Values(idx.tasks))
+		idx.dead = true
+		m.conns.Delete(connID)
+		idx.mu.Unlock()
+
+		m.stopTasks(tasks...)
+		cnt += len(tasks)
 		return true
 	})
 
@@ -213,7 +642,56 @@ func (m *Manager) Close() {
 	)
 }
 
-func NewManager(retryInterval time.Duration, maxRetryCnt int32, taskFunc message.PushFunc) *Manager {
+// ManagerWithFailFunc 设置放弃重传时的回调 ( 如发布投递失败回执 )。
+func ManagerWithFailFunc(fn FailFunc) option.Opt[Manager] {
+	return func(m *Manager) {
+		m.failFunc = fn
+	}
+}
+
+// ManagerWithExpireFunc 设置消息过期停止重传时的回调 ( 如发布过期回执 )。
+func ManagerWithExpireFunc(fn ExpireFunc) option.Opt[Manager] {
+	return func(m *Manager) {
+		m.expireFunc = fn
+	}
+}
+
+// ManagerWithBackoff 设置重传的退避策略，InitialInterval 为 0 时使用 NewManager 的 retryInterval。
+// 无效的参数使用默认值。
+func ManagerWithBackoff(backoff Backoff) option.Opt[Manager] {
+	return func(m *Manager) {
+		if backoff.InitialInterval <= 0 {
+			backoff.InitialInterval = m.backoff.InitialInterval
+		}
+		m.backoff = backoff
+	}
+}
+
+// ManagerWithStore 设置重传状态存储，默认使用进程内存储 ( 不能在节点间共享 )。
+func ManagerWithStore(store Store, timeout time.Duration) option.Opt[Manager] {
+	return func(m *Manager) {
+		m.store = store
+		if timeout > 0 {
+			m.storeTimeout = timeout
+		}
+	}
+}
+
+// ManagerWithTick 设置时间轮的 tick，重传时间精确到 tick。
+func ManagerWithTick(tick time.Duration) option.Opt[Manager] {
+	return func(m *Manager) {
+		if tick > 0 {
+			m.tick = tick
+		}
+	}
+}
+
+func NewManager(
+	retryInterval time.Duration,
+	maxRetryCnt int32,
+	taskFunc message.PushFunc,
+	opts ...option.Opt[Manager],
+) *Manager {
 	if retryInterval <= 0 {
 		retryInterval = DefaultRetryInterval
 	}
@@ -227,10 +705,18 @@ func NewManager(retryInterval time.Duration, maxRetryCnt int32, taskFunc message
 		}
 	}
 
-	return &Manager{
-		tasks:         &xsync.Map[string, *Task]{},
-		retryInterval: retryInterval,
-		maxRetryCnt:   maxRetryCnt,
-		taskFunc:      taskFunc,
+	m := &Manager{
+		conns:        &xsync.Map[string, *connTasks]{},
+		backoff:      Backoff{InitialInterval: retryInterval},
+		maxRetryCnt:  maxRetryCnt,
+		tick:         timingwheel.DefaultTick,
+		store:        NewMemoryStore(DefaultStoreTTL),
+		storeTimeout: DefaultStoreTimeout,
+		taskFunc:     taskFunc,
 	}
+
+	option.Apply(m, opts...)
+	m.backoff = m.backoff.normalize()
+	m.wheel = timingwheel.NewTimingWheel(m.tick, timingwheel.DefaultSlots, timingwheel.DefaultLevels)
+	return m
 }
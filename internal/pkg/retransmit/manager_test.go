@@ -0,0 +1,249 @@
+package retransmit
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jrmarcco/synp"
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/jrmarcco/synp/internal/pkg/message"
+	synpmock "github.com/jrmarcco/synp/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestManager_Conflation(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+
+	var (
+		mu   sync.Mutex
+		sent []string
+	)
+	m := NewManager(10*time.Millisecond, 100, func(conn synp.Conn, msg *messagev1.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, conn.ID()+":"+msg.GetMessageId())
+		return nil
+	}, ManagerWithTick(time.Millisecond))
+	defer m.Close()
+
+	conn1, conn2 := newTestConn(ctrl, "c1"), newTestConn(ctrl, "c2")
+	m.Start([]synp.Conn{conn1, conn2}, newConflatedMessage("m1", "ticker"))
+	msgs := m.Start([]synp.Conn{conn1}, newConflatedMessage("m2", "ticker"))
+	m.Start([]synp.Conn{conn1}, newConflatedMessage("m3", ""))
+
+	// conn1 上的 m1 被 m2 取代，conn2 上的 m1 不受影响。
+	// 取代旧消息的 m2 分配新的序号。
+	assert.Equal(t, int64(3), m.TotalTaskCnt())
+	assert.Equal(t, uint64(2), message.Seq(msgs[0]))
+	idx, ok := m.conns.Load("c1")
+	require.True(t, ok)
+	_, ok = idx.tasks["m1"]
+	assert.False(t, ok)
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(sent) >= 3
+	}, time.Second, 5*time.Millisecond)
+
+	// 收到 ack 后清理合并 key 索引。
+	m.Stop("c1", "m2")
+	m.Stop("c2", "m1")
+	m.Stop("c1", "m3")
+	assert.Zero(t, m.TotalTaskCnt())
+	assert.Empty(t, idx.conflated)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Contains(t, sent, "c2:m1")
+	assert.NotContains(t, sent, "c1:m1")
+}
+
+func TestManager_Expire(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+
+	var (
+		mu      sync.Mutex
+		expired []string
+	)
+	m := NewManager(
+		10*time.Millisecond, 100,
+		func(_ synp.Conn, _ *messagev1.Message) error { return nil },
+		ManagerWithFailFunc(func(_ synp.Conn, msg *messagev1.Message, _ error) {
+			t.Errorf("unexpected fail of message %s", msg.GetMessageId())
+		}),
+		ManagerWithExpireFunc(func(conn synp.Conn, msg *messagev1.Message) {
+			mu.Lock()
+			defer mu.Unlock()
+			expired = append(expired, conn.ID()+":"+msg.GetMessageId())
+		}),
+		ManagerWithTick(time.Millisecond),
+	)
+	defer m.Close()
+
+	conn := newTestConn(ctrl, "c1")
+
+	// 已经过期的消息不会开始重传。
+	msg := &messagev1.Message{MessageId: "m1"}
+	message.SetExpireAt(msg, time.Now().Add(-time.Second))
+	m.Start([]synp.Conn{conn}, msg)
+	assert.Zero(t, m.TotalTaskCnt())
+
+	// 等待重传时过期。
+	msg = &messagev1.Message{MessageId: "m2"}
+	message.SetExpireAt(msg, time.Now().Add(30*time.Millisecond))
+	m.Start([]synp.Conn{conn}, msg)
+	assert.Equal(t, int64(1), m.TotalTaskCnt())
+
+	assert.Eventually(t, func() bool {
+		return m.TotalTaskCnt() == 0
+	}, time.Second, 5*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"c1:m2"}, expired)
+}
+
+func TestManager_Resume(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+
+	var (
+		mu   sync.Mutex
+		sent []string
+	)
+	store := NewMemoryStore(time.Minute)
+	m := NewManager(time.Hour, 100, func(conn synp.Conn, msg *messagev1.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, conn.ID()+":"+msg.GetMessageId())
+		return nil
+	}, ManagerWithStore(store, 0), ManagerWithTick(time.Millisecond))
+	defer m.Close()
+
+	conn := newTestConn(ctrl, "c1")
+	m.Start([]synp.Conn{conn}, &messagev1.Message{MessageId: "m1"})
+	m.Start([]synp.Conn{conn}, &messagev1.Message{MessageId: "m2"})
+	m.Stop("c1", "m2")
+
+	// 连接断开后停止本地的重传任务，保留重传状态。
+	m.ClearByConn(conn)
+	assert.Zero(t, m.TotalTaskCnt())
+	entries, err := store.Load(t.Context(), "c1")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "m1", entries[0].Message.GetMessageId())
+
+	assert.Equal(t, uint64(1), message.Seq(entries[0].Message))
+
+	// 重新连接后立即重传没有 ack 的消息，恢复的消息保持原来的序号。
+	reconnected := newTestConn(ctrl, "c1")
+	m.Resume(reconnected)
+	assert.Equal(t, int64(1), m.TotalTaskCnt())
+	msgs := m.Start([]synp.Conn{reconnected}, &messagev1.Message{MessageId: "m3"})
+	assert.Equal(t, uint64(2), message.Seq(msgs[0]))
+	assert.Equal(t, []string{"m3"}, m.Ack("c1", "m3"))
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(sent) == 1
+	}, time.Second, time.Millisecond)
+
+	// 重传次数随重传状态保存。
+	assert.Eventually(t, func() bool {
+		entries, err = store.Load(t.Context(), "c1")
+		return err == nil && len(entries) == 1 && entries[0].RetransmitCnt == 1
+	}, time.Second, time.Millisecond)
+
+	m.Stop("c1", "m1")
+	entries, err = store.Load(t.Context(), "c1")
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestManager_Ack(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+
+	store := NewMemoryStore(time.Minute)
+	m := NewManager(time.Hour, 100, nil, ManagerWithStore(store, 0), ManagerWithTick(time.Millisecond))
+	defer m.Close()
+
+	conn1, conn2 := newTestConn(ctrl, "c1"), newTestConn(ctrl, "c2")
+	for _, id := range []string{"m1", "m2", "m3", "m4", "m5"} {
+		msg := &messagev1.Message{MessageId: id}
+		msgs := m.Start([]synp.Conn{conn1, conn2}, msg)
+
+		// 每个连接分别分配序号，不修改原消息。
+		require.Len(t, msgs, 2)
+		assert.Equal(t, message.Seq(msgs[0]), message.Seq(msgs[1]))
+		assert.Zero(t, message.Seq(msg))
+	}
+	assert.Equal(t, int64(10), m.TotalTaskCnt())
+
+	// 重复启动返回等待 ack 的消息。
+	msgs := m.Start([]synp.Conn{conn1}, &messagev1.Message{MessageId: "m3"})
+	assert.Equal(t, uint64(3), message.Seq(msgs[0]))
+	assert.Equal(t, int64(10), m.TotalTaskCnt())
+
+	// 批量 ack 只返回等待 ack 的消息。
+	assert.Equal(t, []string{"m2", "m4"}, m.Ack("c1", "m2", "m4", "unknown"))
+	assert.Empty(t, m.Ack("c1", "m2"))
+
+	// 累积 ack 跳过已经 ack 的消息。
+	assert.Equal(t, []string{"m1", "m3"}, m.AckUpTo("c1", 3))
+	assert.Empty(t, m.AckUpTo("c1", 3))
+	assert.Equal(t, int64(6), m.TotalTaskCnt())
+
+	entries, err := store.Load(t.Context(), "c1")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "m5", entries[0].Message.GetMessageId())
+
+	// 不影响其他连接。
+	assert.Equal(t, []string{"m1", "m2", "m3", "m4", "m5"}, m.AckUpTo("c2", 10))
+	assert.Equal(t, int64(1), m.TotalTaskCnt())
+
+	// 新的消息继续递增序号。
+	msgs = m.Start([]synp.Conn{conn1}, &messagev1.Message{MessageId: "m6"})
+	assert.Equal(t, uint64(6), message.Seq(msgs[0]))
+}
+
+func TestBackoff_Next(t *testing.T) {
+	t.Parallel()
+
+	b := Backoff{InitialInterval: time.Second, MaxInterval: 5 * time.Second, Multiplier: 2}
+	assert.Equal(t, time.Second, b.Next(0))
+	assert.Equal(t, 4*time.Second, b.Next(2))
+	assert.Equal(t, 5*time.Second, b.Next(10))
+
+	b.Jitter = 0.5
+	for range 100 {
+		d := b.Next(1)
+		assert.GreaterOrEqual(t, d, time.Second)
+		assert.LessOrEqual(t, d, 3*time.Second)
+	}
+}
+
+func newConflatedMessage(id, conflationKey string) *messagev1.Message {
+	msg := &messagev1.Message{MessageId: id}
+	message.SetConflationKey(msg, conflationKey)
+	return msg
+}
+
+func newTestConn(ctrl *gomock.Controller, id string) synp.Conn {
+	conn := synpmock.NewMockConn(ctrl)
+	conn.EXPECT().ID().Return(id).AnyTimes()
+	conn.EXPECT().Closed().Return(nil).AnyTimes()
+	conn.EXPECT().UpdateActivityTime().AnyTimes()
+	return conn
+}
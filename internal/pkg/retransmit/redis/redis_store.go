@@ -0,0 +1,108 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/jrmarcco/synp/internal/pkg/retransmit"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/protobuf/proto"
+)
+
+var _ retransmit.Store = (*Store)(nil)
+
+// Store 为重传状态存储的 Redis 实现，节点重启或用户重新连接到其他节点时恢复重传。
+// 每个连接等待 ack 的消息存储为一个 hash，每次保存时刷新过期时间：
+//
+//	synp:retransmit:{bid}:{uid}:{device} -> {message_id: entry ( json ), ...}
+type Store struct {
+	rdb redis.Cmdable
+	ttl time.Duration
+}
+
+// entry 为保存到 Redis 的重传状态，消息使用 protobuf 二进制编码，保留网关扩展字段。
+type entry struct {
+	Message       []byte `json:"message"`
+	RetransmitCnt int32  `json:"retransmitCnt"`
+	CreatedAt     int64  `json:"createdAt"` // 毫秒
+}
+
+func (s *Store) Save(ctx context.Context, connID string, e *retransmit.Entry) error {
+	msg, err := proto.Marshal(e.Message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal retransmit message: %w", err)
+	}
+
+	val, err := json.Marshal(&entry{
+		Message:       msg,
+		RetransmitCnt: e.RetransmitCnt,
+		CreatedAt:     e.CreatedAt.UnixMilli(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal retransmit entry: %w", err)
+	}
+
+	key := s.key(connID)
+	_, err = s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, e.Message.GetMessageId(), val)
+		pipe.Expire(ctx, key, s.ttl)
+		return nil
+	})
+	return err
+}
+
+func (s *Store) Delete(ctx context.Context, connID string, messageIDs ...string) error {
+	if len(messageIDs) == 0 {
+		return nil
+	}
+	return s.rdb.HDel(ctx, s.key(connID), messageIDs...).Err()
+}
+
+func (s *Store) Load(ctx context.Context, connID string) ([]*retransmit.Entry, error) {
+	key := s.key(connID)
+	vals, err := s.rdb.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*retransmit.Entry, 0, len(vals))
+	for messageID, val := range vals {
+		e := &entry{}
+		msg := &messagev1.Message{}
+		if err = json.Unmarshal([]byte(val), e); err == nil {
+			err = proto.Unmarshal(e.Message, msg)
+		}
+		if err != nil {
+			// 格式错误的消息无法重传，直接删除。
+			slog.Warn("[synp-retransmit-store] invalid retransmit entry", "key", key, "message_id", messageID, "error", err)
+			_ = s.rdb.HDel(ctx, key, messageID).Err()
+			continue
+		}
+
+		entries = append(entries, &retransmit.Entry{
+			Message:       msg,
+			RetransmitCnt: e.RetransmitCnt,
+			CreatedAt:     time.UnixMilli(e.CreatedAt),
+		})
+	}
+	return entries, nil
+}
+
+func (s *Store) key(connID string) string {
+	return "synp:retransmit:" + connID
+}
+
+// NewStore 创建重传状态存储，连接的重传状态在最后一次保存 ttl 时间后过期。
+func NewStore(rdb redis.Cmdable, ttl time.Duration) *Store {
+	if ttl <= 0 {
+		ttl = retransmit.DefaultStoreTTL
+	}
+	return &Store{
+		rdb: rdb,
+		ttl: ttl,
+	}
+}
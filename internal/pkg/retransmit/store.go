@@ -0,0 +1,127 @@
+package retransmit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+)
+
+// DefaultStoreTTL 为重传状态的默认保存时间，用户超过该时间没有重新连接时丢弃。
+const DefaultStoreTTL = 24 * time.Hour
+
+// Entry 为等待前端 ack 的 downstream 消息 ( 重传状态 )。
+type Entry struct {
+	Message       *messagev1.Message
+	RetransmitCnt int32     // 已经重传的次数
+	CreatedAt     time.Time // 开始等待 ack 的时间，恢复重传时按该时间排序
+}
+
+// Store 为重传状态存储。
+//
+// 重传状态按连接 ID ( bid:uid:device ) 保存，不依赖具体的连接，
+// 连接断开 ( 或节点重启 ) 后状态仍然保留，用户重新连接 ( 可能在其他节点 ) 时恢复重传。
+type Store interface {
+	// Save 保存 ( 或更新 ) 连接上等待 ack 的消息。
+	Save(ctx context.Context, connID string, entry *Entry) error
+	// Delete 删除连接上等待 ack 的消息 ( 收到 ack、放弃重传或消息过期 )，批量 ack 时一次删除多条。
+	Delete(ctx context.Context, connID string, messageIDs ...string) error
+	// Load 返回连接上所有等待 ack 的消息。
+	Load(ctx context.Context, connID string) ([]*Entry, error)
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// MemoryStore 为重传状态存储的进程内实现，状态在节点重启后丢失，也不能在节点间共享。
+// 单节点部署时使用，连接在同一节点上重新建立时恢复重传。
+type MemoryStore struct {
+	mu    sync.Mutex
+	conns map[string]*memoryConnEntries // connID -> 等待 ack 的消息
+
+	ttl       time.Duration
+	lastSweep time.Time
+}
+
+type memoryConnEntries struct {
+	entries  map[string]*Entry // messageID -> entry
+	expireAt time.Time
+}
+
+func (s *MemoryStore) Save(_ context.Context, connID string, entry *Entry) error {
+	// 保存 entry 的副本，消息发送后不会再被修改，直接共享。
+	saved := *entry
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	conn, ok := s.conns[connID]
+	if !ok {
+		conn = &memoryConnEntries{entries: make(map[string]*Entry)}
+		s.conns[connID] = conn
+	}
+	conn.entries[entry.Message.GetMessageId()] = &saved
+	conn.expireAt = now.Add(s.ttl)
+	return nil
+}
+
+func (s *MemoryStore) Delete(_ context.Context, connID string, messageIDs ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if conn, ok := s.conns[connID]; ok {
+		for _, messageID := range messageIDs {
+			delete(conn.entries, messageID)
+		}
+		if len(conn.entries) == 0 {
+			delete(s.conns, connID)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) Load(_ context.Context, connID string) ([]*Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conn, ok := s.conns[connID]
+	if !ok || !time.Now().Before(conn.expireAt) {
+		return nil, nil
+	}
+
+	entries := make([]*Entry, 0, len(conn.entries))
+	for _, entry := range conn.entries {
+		loaded := *entry
+		entries = append(entries, &loaded)
+	}
+	return entries, nil
+}
+
+// sweep 清除过期的重传状态 ( 用户长时间没有重新连接 )，最多每 ttl 清除一次，调用方需要持有锁。
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < s.ttl {
+		return
+	}
+
+	s.lastSweep = now
+	for connID, conn := range s.conns {
+		if !now.Before(conn.expireAt) {
+			delete(s.conns, connID)
+		}
+	}
+}
+
+// NewMemoryStore 创建进程内的重传状态存储，连接的重传状态在最后一次保存 ttl 时间后丢弃。
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	if ttl <= 0 {
+		ttl = DefaultStoreTTL
+	}
+	return &MemoryStore{
+		conns:     make(map[string]*memoryConnEntries),
+		ttl:       ttl,
+		lastSweep: time.Now(),
+	}
+}
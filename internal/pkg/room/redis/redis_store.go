@@ -0,0 +1,68 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/jrmarcco/synp/internal/pkg/room"
+	"github.com/redis/go-redis/v9"
+)
+
+var _ room.Store = (*Store)(nil)
+
+// Store 为房间成员存储的 Redis 实现，每个房间的成员存储为一个 set：
+//
+//	synp:room:{bid}:{room} -> {uid, ...}
+type Store struct {
+	rdb redis.Cmdable
+}
+
+func (s *Store) Join(ctx context.Context, bid uint64, room string, uids []uint64) error {
+	if len(uids) == 0 {
+		return nil
+	}
+	return s.rdb.SAdd(ctx, s.key(bid, room), s.members(uids)...).Err()
+}
+
+func (s *Store) Leave(ctx context.Context, bid uint64, room string, uids []uint64) error {
+	if len(uids) == 0 {
+		return nil
+	}
+	return s.rdb.SRem(ctx, s.key(bid, room), s.members(uids)...).Err()
+}
+
+func (s *Store) Members(ctx context.Context, bid uint64, room string) ([]uint64, error) {
+	vals, err := s.rdb.SMembers(ctx, s.key(bid, room)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	uids := make([]uint64, 0, len(vals))
+	for _, val := range vals {
+		uid, err := strconv.ParseUint(val, 10, 64)
+		if err != nil {
+			slog.Warn("[synp-room-store] invalid room member", "biz_id", bid, "room", room, "member", val)
+			continue
+		}
+		uids = append(uids, uid)
+	}
+	return uids, nil
+}
+
+func (s *Store) members(uids []uint64) []any {
+	members := make([]any, 0, len(uids))
+	for _, uid := range uids {
+		members = append(members, uid)
+	}
+	return members
+}
+
+func (s *Store) key(bid uint64, room string) string {
+	return fmt.Sprintf("synp:room:%d:%s", bid, room)
+}
+
+func NewStore(rdb redis.Cmdable) *Store {
+	return &Store{rdb: rdb}
+}
@@ -0,0 +1,16 @@
+package room
+
+import (
+	"context"
+)
+
+// Store 为房间成员存储。
+// 房间由后端 ( 业务服务端 ) 维护成员，网关按成员推送房间消息，与成员是否在线无关。
+type Store interface {
+	// Join 将用户加入房间。
+	Join(ctx context.Context, bid uint64, room string, uids []uint64) error
+	// Leave 将用户移出房间，房间没有成员时自动删除。
+	Leave(ctx context.Context, bid uint64, room string, uids []uint64) error
+	// Members 返回房间的所有成员。
+	Members(ctx context.Context, bid uint64, room string) ([]uint64, error)
+}
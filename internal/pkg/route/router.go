@@ -0,0 +1,274 @@
+package route
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	commonv1 "github.com/jrmarcco/synp-api/api/go/common/v1"
+)
+
+const defaultRuleName = "default"
+
+// rule 为校验后的路由规则。
+type rule struct {
+	name  string
+	match MatchConfig
+	topic string
+	key   string
+}
+
+// table 为不可变的路由表，重新加载时整体替换。
+type table struct {
+	conversationField string
+
+	rules      []*rule
+	def        *rule // 为空时表示没有默认路由
+	deadLetter *rule
+}
+
+// Router 根据路由表将上行消息路由到不同的 topic。
+// 路由表可以在运行时通过 Reload 整体替换，正在进行的路由不受影响。
+type Router struct {
+	table atomic.Pointer[table]
+}
+
+// Route 路由消息。
+// 没有匹配的路由或无法生成消息 key 时返回 ErrUnroutable，此时应该将消息发送到 DeadLetter。
+func (r *Router) Route(in *Input) (*Target, error) {
+	t := r.table.Load()
+
+	var body map[string]any
+	bodyParsed := false
+	lookupField := func(path string) (string, bool) {
+		if !bodyParsed {
+			bodyParsed = true
+			body = parseBody(in)
+		}
+		return lookup(body, path)
+	}
+
+	for _, rl := range t.rules {
+		if !rl.matches(in, lookupField) {
+			continue
+		}
+		return rl.target(t, in, lookupField)
+	}
+
+	if t.def == nil {
+		return nil, fmt.Errorf("%w: no route matched", ErrUnroutable)
+	}
+	return t.def.target(t, in, lookupField)
+}
+
+// DeadLetter 返回死信路由。
+func (r *Router) DeadLetter(in *Input) *Target {
+	dl := r.table.Load().deadLetter
+	return &Target{
+		Rule:  dl.name,
+		Topic: dl.topic,
+		Key:   []byte(in.Msg.GetMessageId()),
+	}
+}
+
+// Reload 校验并替换路由表，校验失败时保留原路由表。
+func (r *Router) Reload(cfg Config) error {
+	t, err := newTable(cfg)
+	if err != nil {
+		return err
+	}
+	r.table.Store(t)
+	return nil
+}
+
+func (rl *rule) matches(in *Input, lookupField func(string) (string, bool)) bool {
+	m := rl.match
+	if len(m.BIDs) != 0 && !slices.Contains(m.BIDs, in.User.BID) {
+		return false
+	}
+
+	var val string
+	var ok bool
+	switch {
+	case m.Field != "":
+		val, ok = lookupField(m.Field)
+	case m.Header != "":
+		val, ok = in.Headers[m.Header]
+	default:
+		// 只按 BID 匹配。
+		return true
+	}
+	if !ok {
+		return false
+	}
+
+	if m.Prefix != "" && strings.HasPrefix(val, m.Prefix) {
+		return true
+	}
+	return slices.Contains(m.Values, val)
+}
+
+func (rl *rule) target(t *table, in *Input, lookupField func(string) (string, bool)) (*Target, error) {
+	target := &Target{Rule: rl.name, Topic: rl.topic}
+
+	switch {
+	case rl.key == KeyNone:
+	case rl.key == KeyConnKey:
+		target.Key = []byte(in.User.ConnKey())
+	case rl.key == KeyConversation:
+		val, ok := lookupField(t.conversationField)
+		if !ok || val == "" {
+			return nil, fmt.Errorf("%w: rule %s missing conversation id %s", ErrUnroutable, rl.name, t.conversationField)
+		}
+		target.Key = []byte(val)
+	case rl.key == KeyMessageID:
+		target.Key = []byte(in.Msg.GetMessageId())
+	case rl.key == KeyBID:
+		target.Key = strconv.AppendUint(nil, in.User.BID, 10)
+	case rl.key == KeyUID:
+		target.Key = strconv.AppendUint(nil, in.User.UID, 10)
+	case strings.HasPrefix(rl.key, KeyFieldPrefix):
+		val, ok := lookupField(strings.TrimPrefix(rl.key, KeyFieldPrefix))
+		if !ok || val == "" {
+			return nil, fmt.Errorf("%w: rule %s missing key %s", ErrUnroutable, rl.name, rl.key)
+		}
+		target.Key = []byte(val)
+	case strings.HasPrefix(rl.key, KeyHeadPrefix):
+		val, ok := in.Headers[strings.TrimPrefix(rl.key, KeyHeadPrefix)]
+		if !ok || val == "" {
+			return nil, fmt.Errorf("%w: rule %s missing key %s", ErrUnroutable, rl.name, rl.key)
+		}
+		target.Key = []byte(val)
+	}
+	return target, nil
+}
+
+// parseBody 解析 json body，非 json body 或解析失败时返回 nil。
+func parseBody(in *Input) map[string]any {
+	switch in.Msg.GetSerializeType() {
+	case commonv1.SerializeType_SERIALIZE_TYPE_UNSPECIFIED, commonv1.SerializeType_SERIALIZE_TYPE_JSON:
+	default:
+		return nil
+	}
+
+	body := make(map[string]any)
+	if err := json.Unmarshal(in.Msg.GetBody(), &body); err != nil {
+		return nil
+	}
+	return body
+}
+
+// lookup 按 . 分隔的路径查找字段，字段值转换为字符串。
+func lookup(body map[string]any, path string) (string, bool) {
+	var cur any = body
+	for seg := range strings.SplitSeq(path, ".") {
+		obj, ok := cur.(map[string]any)
+		if !ok {
+			return "", false
+		}
+		if cur, ok = obj[seg]; !ok {
+			return "", false
+		}
+	}
+
+	switch v := cur.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	default:
+		return "", false
+	}
+}
+
+func newRule(cfg RuleConfig) (*rule, error) {
+	if cfg.Topic == "" {
+		return nil, errors.New("empty topic")
+	}
+
+	key := cfg.Key
+	if key == "" {
+		key = KeyConnKey
+	}
+	switch {
+	case key == KeyConnKey, key == KeyConversation, key == KeyMessageID, key == KeyBID, key == KeyUID, key == KeyNone:
+	case strings.HasPrefix(key, KeyFieldPrefix) && len(key) > len(KeyFieldPrefix):
+	case strings.HasPrefix(key, KeyHeadPrefix) && len(key) > len(KeyHeadPrefix):
+	default:
+		return nil, fmt.Errorf("invalid key %q", cfg.Key)
+	}
+
+	switch cfg.Partitioner {
+	case "", PartitionerHash:
+	case PartitionerRoundRobin:
+		// 没有 key 时 kafka.Hash 会退化为轮询。
+		key = KeyNone
+	default:
+		return nil, fmt.Errorf("invalid partitioner %q", cfg.Partitioner)
+	}
+
+	if cfg.Match.Field != "" && cfg.Match.Header != "" {
+		return nil, errors.New("match field and header are exclusive")
+	}
+	if (cfg.Match.Field != "" || cfg.Match.Header != "") && len(cfg.Match.Values) == 0 && cfg.Match.Prefix == "" {
+		return nil, errors.New("match values or prefix is required")
+	}
+
+	return &rule{
+		name:  cfg.Name,
+		match: cfg.Match,
+		topic: cfg.Topic,
+		key:   key,
+	}, nil
+}
+
+func newTable(cfg Config) (*table, error) {
+	t := &table{
+		conversationField: cfg.ConversationField,
+		rules:             make([]*rule, 0, len(cfg.Rules)),
+	}
+	if t.conversationField == "" {
+		t.conversationField = DefaultConversationField
+	}
+
+	for i, rc := range cfg.Rules {
+		if rc.Name == "" {
+			rc.Name = fmt.Sprintf("rule-%d", i)
+		}
+		rl, err := newRule(rc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid route rule %s: %w", rc.Name, err)
+		}
+		t.rules = append(t.rules, rl)
+	}
+
+	if cfg.Default.Topic != "" {
+		cfg.Default.Name = defaultRuleName
+		cfg.Default.Match = MatchConfig{}
+		def, err := newRule(cfg.Default)
+		if err != nil {
+			return nil, fmt.Errorf("invalid default route: %w", err)
+		}
+		t.def = def
+	}
+
+	if cfg.DeadLetter.Topic == "" {
+		return nil, errors.New("dead letter topic is required")
+	}
+	t.deadLetter = &rule{name: "dead_letter", topic: cfg.DeadLetter.Topic, key: KeyMessageID}
+	return t, nil
+}
+
+func NewRouter(cfg Config) (*Router, error) {
+	r := &Router{}
+	if err := r.Reload(cfg); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
@@ -0,0 +1,141 @@
+package route
+
+import (
+	"testing"
+
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/jrmarcco/synp/internal/pkg/session"
+	"github.com/jrmarcco/synp/internal/pkg/xmq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouter_Route(t *testing.T) {
+	t.Parallel()
+
+	router, err := NewRouter(Config{
+		Default:    RuleConfig{Topic: "upstream"},
+		DeadLetter: RuleConfig{Topic: "upstream.dlt"},
+		Rules: []RuleConfig{
+			{
+				Name:  "chat",
+				Match: MatchConfig{Field: "biz.type", Prefix: "chat."},
+				Topic: "upstream.chat",
+				Key:   KeyConversation,
+			},
+			{
+				Name:  "order",
+				Match: MatchConfig{Field: "biz.type", Values: []string{"order"}},
+				Topic: "upstream.order",
+				Key:   "field:orderId",
+			},
+			{
+				Name:  "tenant",
+				Match: MatchConfig{Header: "x-tenant", Values: []string{"vip"}},
+				Topic: "upstream.vip",
+				Key:   KeyUID,
+			},
+			{
+				Name:        "biz",
+				Match:       MatchConfig{BIDs: []uint64{2}},
+				Topic:       "upstream.biz2",
+				Partitioner: PartitionerRoundRobin,
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	tcs := []struct {
+		name      string
+		bid       uint64
+		body      string
+		headers   xmq.Headers
+		wantRule  string
+		wantTopic string
+		wantKey   string
+		wantErr   error
+	}{
+		{
+			name:      "field prefix",
+			bid:       1,
+			body:      `{"biz":{"type":"chat.text"},"conversationId":"c-1"}`,
+			wantRule:  "chat",
+			wantTopic: "upstream.chat",
+			wantKey:   "c-1",
+		}, {
+			name:    "missing conversation id",
+			bid:     1,
+			body:    `{"biz":{"type":"chat.text"}}`,
+			wantErr: ErrUnroutable,
+		}, {
+			name:      "field key",
+			bid:       1,
+			body:      `{"biz":{"type":"order"},"orderId":42}`,
+			wantRule:  "order",
+			wantTopic: "upstream.order",
+			wantKey:   "42",
+		}, {
+			name:      "header",
+			bid:       1,
+			body:      `{}`,
+			headers:   xmq.Headers{"x-tenant": "vip"},
+			wantRule:  "tenant",
+			wantTopic: "upstream.vip",
+			wantKey:   "100",
+		}, {
+			name:      "bid with round robin",
+			bid:       2,
+			body:      `not json`,
+			wantRule:  "biz",
+			wantTopic: "upstream.biz2",
+		}, {
+			name:      "default",
+			bid:       1,
+			body:      `{"biz":{"type":"other"}}`,
+			wantRule:  defaultRuleName,
+			wantTopic: "upstream",
+			wantKey:   "1:100",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			target, err := router.Route(&Input{
+				User:    session.User{BID: tc.bid, UID: 100},
+				Msg:     &messagev1.Message{MessageId: "msg-1", Body: []byte(tc.body)},
+				Headers: tc.headers,
+			})
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantRule, target.Rule)
+			assert.Equal(t, tc.wantTopic, target.Topic)
+			assert.Equal(t, tc.wantKey, string(target.Key))
+		})
+	}
+}
+
+func TestRouter_Reload(t *testing.T) {
+	t.Parallel()
+
+	router, err := NewRouter(Config{DeadLetter: RuleConfig{Topic: "upstream.dlt"}})
+	require.NoError(t, err)
+
+	in := &Input{Msg: &messagev1.Message{MessageId: "msg-1"}}
+	_, err = router.Route(in)
+	require.ErrorIs(t, err, ErrUnroutable)
+	assert.Equal(t, "upstream.dlt", router.DeadLetter(in).Topic)
+
+	// 无效配置不替换原路由表。
+	err = router.Reload(Config{Default: RuleConfig{Topic: "upstream", Key: "unknown"}, DeadLetter: RuleConfig{Topic: "upstream.dlt"}})
+	require.Error(t, err)
+
+	require.NoError(t, router.Reload(Config{Default: RuleConfig{Topic: "upstream"}, DeadLetter: RuleConfig{Topic: "upstream.dlt"}}))
+	target, err := router.Route(in)
+	require.NoError(t, err)
+	assert.Equal(t, "upstream", target.Topic)
+}
@@ -0,0 +1,83 @@
+package route
+
+import (
+	"errors"
+
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/jrmarcco/synp/internal/pkg/session"
+	"github.com/jrmarcco/synp/internal/pkg/xmq"
+)
+
+var ErrUnroutable = errors.New("unroutable message")
+
+// HeaderDeadLetterReason 为死信消息的 header，记录消息无法路由的原因。
+const HeaderDeadLetterReason = "x-synp-dead-letter-reason"
+
+// 消息 key 的取值方式。
+//
+// 相同 key 的消息落在同一分区，业务服务端按分区顺序消费即可保证这些消息的顺序。
+const (
+	KeyConnKey      = "conn"         // 连接 key ( bid:uid，默认 )，保证同一用户的消息有序
+	KeyConversation = "conversation" // 消息 body 中的会话 ID，保证同一会话的消息有序
+	KeyMessageID    = "message_id"   // 消息 ID，不保证顺序
+	KeyBID          = "bid"
+	KeyUID          = "uid"
+	KeyNone         = "none"    // 不设置 key
+	KeyFieldPrefix  = "field:"  // 消息 body 中的字段，如 field:conversationId
+	KeyHeadPrefix   = "header:" // 消息 header，如 header:x-tenant
+)
+
+// 分区策略。
+const (
+	PartitionerHash       = "hash"        // 按 key 哈希分区 ( 默认 )，相同 key 的消息落在同一分区
+	PartitionerRoundRobin = "round_robin" // 轮询分区，忽略 key
+)
+
+// MatchConfig 为路由规则的匹配条件，所有配置的条件都满足时才匹配。
+//
+// 匹配值取自 Field ( 消息 body 中的字段，仅支持 json body，多级字段用 . 分隔 ) 或 Header，
+// 匹配值等于 Values 中的任意一个，或以 Prefix 开头即可 ( 如业务类型 chat.text 匹配前缀 chat. )。
+type MatchConfig struct {
+	BIDs   []uint64 `mapstructure:"bids"`
+	Field  string   `mapstructure:"field"`
+	Header string   `mapstructure:"header"`
+	Values []string `mapstructure:"values"`
+	Prefix string   `mapstructure:"prefix"`
+}
+
+// RuleConfig 为路由规则。
+type RuleConfig struct {
+	Name        string      `mapstructure:"name"`
+	Match       MatchConfig `mapstructure:"match"`
+	Topic       string      `mapstructure:"topic"`
+	Key         string      `mapstructure:"key"`
+	Partitioner string      `mapstructure:"partitioner"`
+}
+
+// Config 为路由表配置。
+// 规则按顺序匹配，第一个匹配的规则生效；都不匹配时使用 Default。
+// 没有可用的路由或无法生成消息 key 时，消息会被发送到 DeadLetter。
+type Config struct {
+	Default    RuleConfig   `mapstructure:"default"`
+	DeadLetter RuleConfig   `mapstructure:"dead_letter"`
+	Rules      []RuleConfig `mapstructure:"rules"`
+
+	// 会话 ID 在消息 body 中的字段，为空时使用 DefaultConversationField。
+	ConversationField string `mapstructure:"conversation_field"`
+}
+
+const DefaultConversationField = "conversationId"
+
+// Input 为路由的输入。
+type Input struct {
+	User    session.User
+	Msg     *messagev1.Message
+	Headers xmq.Headers
+}
+
+// Target 为路由的结果。
+type Target struct {
+	Rule  string // 命中的规则名
+	Topic string
+	Key   []byte
+}
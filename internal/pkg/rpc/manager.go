@@ -0,0 +1,408 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jrmarcco/jit/bean/option"
+	"github.com/jrmarcco/synp"
+	commonv1 "github.com/jrmarcco/synp-api/api/go/common/v1"
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/jrmarcco/synp/internal/pkg/correlation"
+	"github.com/jrmarcco/synp/internal/pkg/message"
+	"github.com/jrmarcco/synp/internal/pkg/xmq"
+	"github.com/jrmarcco/synp/internal/pkg/xmq/produce"
+)
+
+const (
+	DefaultCallTimeout    = 10 * time.Second
+	DefaultMaxCallTimeout = 60 * time.Second
+	DefaultMaxPending     = 32
+	DefaultRequestTimeout = 3 * time.Second
+)
+
+var ErrCallNotFound = errors.New("rpc call not found")
+
+// call 为等待响应的 RPC 调用。
+type call struct {
+	id        string
+	conn      synp.Conn
+	messageID string
+	timer     *time.Timer
+}
+
+// Manager 负责管理前端 ( 业务客户端 ) 发起的 RPC 调用。
+//
+// 调用流程：
+//
+//	1、前端发送 CommandTypeRPCRequest 消息，网关生成 correlation id 并将请求转发到业务服务端。
+//	2、业务服务端处理完成后，通过 reply topic 或 HTTP 回调回复 Reply。
+//	3、网关根据 correlation id 找到发起调用的连接，回复 CommandTypeRPCResponse 消息。
+//
+// 超时或连接断开时，网关不再等待响应，并通知业务服务端取消调用 ( 尽力而为 )。
+//
+// 注：
+//
+//	correlation id 以节点 ID 为前缀，多个节点共享 reply topic 时可以快速跳过其他节点的响应。
+type Manager struct {
+	producer produce.Producer
+	pushFunc message.PushFunc
+
+	nodeID string
+	ids    *correlation.Generator
+
+	topic       string // 请求 topic
+	replyTopic  string
+	callbackURL string
+
+	defaultTimeout time.Duration
+	maxTimeout     time.Duration
+	maxPending     int
+	requestTimeout time.Duration
+
+	mu    sync.Mutex
+	calls map[string]*call
+	conns map[synp.Conn]map[string]struct{}
+
+	closed atomic.Bool
+}
+
+// Call 发起 RPC 调用。
+// 请求本身的错误 ( 格式错误、转发失败等 ) 会以错误码的形式回复给前端，只有回复失败时才返回 error。
+func (m *Manager) Call(conn synp.Conn, msg *messagev1.Message) error {
+	if m.closed.Load() {
+		return m.respond(conn, msg.GetMessageId(), &CallResponse{Code: CodeCanceled, Message: "gateway is closing"})
+	}
+
+	req := &CallRequest{}
+	if err := json.Unmarshal(msg.GetBody(), req); err != nil {
+		return m.respond(conn, msg.GetMessageId(), &CallResponse{
+			Code:    CodeInvalidRequest,
+			Message: fmt.Sprintf("invalid rpc request: %s", err),
+		})
+	}
+	if req.Method == "" {
+		return m.respond(conn, msg.GetMessageId(), &CallResponse{Code: CodeInvalidRequest, Message: "empty method"})
+	}
+
+	timeout := m.defaultTimeout
+	if req.Timeout > 0 {
+		timeout = min(time.Duration(req.Timeout)*time.Millisecond, m.maxTimeout)
+	}
+
+	c, ok := m.register(conn, msg.GetMessageId(), timeout)
+	if !ok {
+		return m.respond(conn, msg.GetMessageId(), &CallResponse{
+			Code:    CodeTooManyRequests,
+			Message: fmt.Sprintf("too many pending rpc calls, max %d", m.maxPending),
+		})
+	}
+
+	user := conn.Session().User()
+	err := m.produce(conn, &Request{
+		Type:          RequestTypeCall,
+		CorrelationID: c.id,
+		Method:        req.Method,
+		BID:           user.BID,
+		UID:           user.UID,
+		Device:        user.Device,
+		ConnID:        conn.ID(),
+		MessageID:     msg.GetMessageId(),
+		Data:          req.Data,
+		Deadline:      time.Now().Add(timeout).UnixMilli(),
+		ReplyTo: &ReplyTo{
+			NodeID:      m.nodeID,
+			Topic:       m.replyTopic,
+			CallbackURL: m.callbackURL,
+		},
+	})
+	if err != nil {
+		slog.Error(
+			"[synp-rpc-manager] failed to forward rpc request",
+			"conn_id", conn.ID(),
+			"correlation_id", c.id,
+			"method", req.Method,
+			"error", err,
+		)
+
+		if m.remove(c.id) == nil {
+			// 已经超时或被取消。
+			return nil
+		}
+		return m.respond(conn, msg.GetMessageId(), &CallResponse{Code: CodeUnavailable, Message: err.Error()})
+	}
+	return nil
+}
+
+// register 注册调用并启动超时计时器，连接上未完成的调用过多时返回 false。
+func (m *Manager) register(conn synp.Conn, messageID string, timeout time.Duration) (*call, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids, ok := m.conns[conn]
+	if !ok {
+		ids = make(map[string]struct{})
+		m.conns[conn] = ids
+	}
+	if len(ids) >= m.maxPending {
+		return nil, false
+	}
+
+	c := &call{
+		id:        m.ids.Next(),
+		conn:      conn,
+		messageID: messageID,
+	}
+	c.timer = time.AfterFunc(timeout, func() { m.expire(c.id) })
+
+	m.calls[c.id] = c
+	ids[c.id] = struct{}{}
+	return c, true
+}
+
+// remove 移除调用并停止计时器，调用不存在时返回 nil。
+func (m *Manager) remove(id string) *call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.calls[id]
+	if !ok {
+		return nil
+	}
+
+	c.timer.Stop()
+	delete(m.calls, id)
+	if ids, ok := m.conns[c.conn]; ok {
+		delete(ids, id)
+		if len(ids) == 0 {
+			delete(m.conns, c.conn)
+		}
+	}
+	return c
+}
+
+// expire 处理调用超时。
+func (m *Manager) expire(id string) {
+	c := m.remove(id)
+	if c == nil {
+		return
+	}
+
+	if err := m.respond(c.conn, c.messageID, &CallResponse{Code: CodeTimeout, Message: "rpc call timeout"}); err != nil {
+		slog.Warn(
+			"[synp-rpc-manager] failed to respond rpc timeout",
+			"conn_id", c.conn.ID(),
+			"correlation_id", id,
+			"error", err,
+		)
+	}
+	m.cancel(c)
+}
+
+// Resolve 处理业务服务端的响应，将响应回复给发起调用的连接。
+// 调用不存在 ( 已超时、已取消或不属于本节点 ) 时返回 ErrCallNotFound。
+func (m *Manager) Resolve(reply *Reply) error {
+	c := m.remove(reply.CorrelationID)
+	if c == nil {
+		return ErrCallNotFound
+	}
+
+	return m.respond(c.conn, c.messageID, &CallResponse{
+		Code:    reply.Code,
+		Message: reply.Message,
+		Data:    reply.Data,
+	})
+}
+
+// Consume 消费 reply topic 中的响应，跳过不属于本节点的响应。
+func (m *Manager) Consume(val []byte) error {
+	reply := &Reply{}
+	if err := json.Unmarshal(val, reply); err != nil {
+		return fmt.Errorf("failed to unmarshal rpc reply: %w", err)
+	}
+
+	if !m.ids.Owns(reply.CorrelationID) {
+		return nil
+	}
+
+	if err := m.Resolve(reply); err != nil {
+		if errors.Is(err, ErrCallNotFound) {
+			slog.Debug(
+				"[synp-rpc-manager] rpc call not found, maybe timeout or canceled",
+				"correlation_id", reply.CorrelationID,
+			)
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// CancelConn 取消连接上所有未完成的调用，在连接断开时调用。
+func (m *Manager) CancelConn(conn synp.Conn) {
+	m.mu.Lock()
+	ids := m.conns[conn]
+	calls := make([]*call, 0, len(ids))
+	for id := range ids {
+		if c, ok := m.calls[id]; ok {
+			c.timer.Stop()
+			delete(m.calls, id)
+			calls = append(calls, c)
+		}
+	}
+	delete(m.conns, conn)
+	m.mu.Unlock()
+
+	for _, c := range calls {
+		m.cancel(c)
+	}
+}
+
+// cancel 通知业务服务端取消调用。
+func (m *Manager) cancel(c *call) {
+	user := c.conn.Session().User()
+	if err := m.produce(c.conn, &Request{
+		Type:          RequestTypeCancel,
+		CorrelationID: c.id,
+		BID:           user.BID,
+		UID:           user.UID,
+		ConnID:        c.conn.ID(),
+		MessageID:     c.messageID,
+	}); err != nil {
+		slog.Warn(
+			"[synp-rpc-manager] failed to cancel rpc call",
+			"correlation_id", c.id,
+			"error", err,
+		)
+	}
+}
+
+// produce 发送请求到业务服务端，与上行消息一样附加网关元数据 header。
+func (m *Manager) produce(conn synp.Conn, req *Request) error {
+	val, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal rpc request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.requestTimeout)
+	defer cancel()
+
+	return m.producer.Produce(ctx, &xmq.Message{
+		Headers: message.NewGatewayHeaders(conn, m.nodeID, "", time.Now()),
+		Topic:   m.topic,
+		Key:     []byte(req.CorrelationID),
+		Val:     val,
+	})
+}
+
+func (m *Manager) respond(conn synp.Conn, messageID string, resp *CallResponse) error {
+	body, err := json.Marshal(resp)
+	if err != nil {
+		return fmt.Errorf("failed to marshal rpc response: %w", err)
+	}
+
+	return m.pushFunc(conn, &messagev1.Message{
+		MessageId:     messageID,
+		Cmd:           message.CommandTypeRPCResponse,
+		SerializeType: commonv1.SerializeType_SERIALIZE_TYPE_JSON,
+		Body:          body,
+	})
+}
+
+// Close 关闭 Manager，未完成的调用直接丢弃 ( 连接即将关闭 )。
+func (m *Manager) Close() {
+	if !m.closed.CompareAndSwap(false, true) {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, c := range m.calls {
+		c.timer.Stop()
+	}
+	m.calls = make(map[string]*call)
+	m.conns = make(map[synp.Conn]map[string]struct{})
+}
+
+func ManagerWithReplyTopic(topic string) option.Opt[Manager] {
+	return func(m *Manager) {
+		m.replyTopic = topic
+	}
+}
+
+// ManagerWithCallbackURL 设置 HTTP 回调地址，需要能够直接访问到本节点。
+func ManagerWithCallbackURL(url string) option.Opt[Manager] {
+	return func(m *Manager) {
+		m.callbackURL = url
+	}
+}
+
+func ManagerWithDefaultTimeout(timeout time.Duration) option.Opt[Manager] {
+	return func(m *Manager) {
+		if timeout > 0 {
+			m.defaultTimeout = timeout
+		}
+	}
+}
+
+func ManagerWithMaxTimeout(timeout time.Duration) option.Opt[Manager] {
+	return func(m *Manager) {
+		if timeout > 0 {
+			m.maxTimeout = timeout
+		}
+	}
+}
+
+// ManagerWithMaxPending 设置单个连接最多未完成的调用数。
+func ManagerWithMaxPending(maxPending int) option.Opt[Manager] {
+	return func(m *Manager) {
+		if maxPending > 0 {
+			m.maxPending = maxPending
+		}
+	}
+}
+
+func ManagerWithRequestTimeout(timeout time.Duration) option.Opt[Manager] {
+	return func(m *Manager) {
+		if timeout > 0 {
+			m.requestTimeout = timeout
+		}
+	}
+}
+
+// NewManager 创建 RPC 调用管理器，请求会被转发到 topic。
+func NewManager(
+	producer produce.Producer,
+	pushFunc message.PushFunc,
+	nodeID string,
+	topic string,
+	opts ...option.Opt[Manager],
+) *Manager {
+	m := &Manager{
+		producer: producer,
+		pushFunc: pushFunc,
+
+		nodeID: nodeID,
+		ids:    correlation.NewGenerator(nodeID),
+
+		topic: topic,
+
+		defaultTimeout: DefaultCallTimeout,
+		maxTimeout:     DefaultMaxCallTimeout,
+		maxPending:     DefaultMaxPending,
+		requestTimeout: DefaultRequestTimeout,
+
+		calls: make(map[string]*call),
+		conns: make(map[synp.Conn]map[string]struct{}),
+	}
+
+	option.Apply(m, opts...)
+	return m
+}
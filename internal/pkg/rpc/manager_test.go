@@ -0,0 +1,128 @@
+package rpc
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jrmarcco/synp"
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/jrmarcco/synp/internal/pkg/session"
+	producemock "github.com/jrmarcco/synp/internal/pkg/xmq/produce/mock"
+	synpmock "github.com/jrmarcco/synp/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestManager_Call(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	producer, recorder := producemock.NewRecordingProducer(ctrl)
+	pusher := &testPusher{}
+	m := NewManager(producer, pusher.push, "node-1", "rpc.request", ManagerWithMaxPending(1))
+	conn := synpmock.NewUserConn(ctrl, session.User{BID: 1, UID: 1, Device: session.DevicePC})
+
+	require.NoError(t, m.Call(conn, newCallMessage("m-1", `{"method":"echo","data":{"a":1}}`)))
+	reqs := requests(t, recorder)
+	require.Len(t, reqs, 1)
+	assert.Equal(t, RequestTypeCall, reqs[0].Type)
+	assert.Equal(t, "echo", reqs[0].Method)
+	assert.Equal(t, "node-1", reqs[0].ReplyTo.NodeID)
+
+	// 超过最大未完成调用数。
+	require.NoError(t, m.Call(conn, newCallMessage("m-2", `{"method":"echo"}`)))
+	assert.Equal(t, CodeTooManyRequests, pusher.last(t).Code)
+
+	// 其他节点的响应直接跳过。
+	require.NoError(t, m.Consume([]byte(`{"correlationId":"node-2-x-1","code":0}`)))
+	assert.Len(t, pusher.responses(), 1)
+
+	val, err := json.Marshal(&Reply{CorrelationID: reqs[0].CorrelationID, Data: json.RawMessage(`{"a":1}`)})
+	require.NoError(t, err)
+	require.NoError(t, m.Consume(val))
+	resp := pusher.last(t)
+	assert.Equal(t, CodeOK, resp.Code)
+	assert.JSONEq(t, `{"a":1}`, string(resp.Data))
+
+	assert.ErrorIs(t, m.Resolve(&Reply{CorrelationID: reqs[0].CorrelationID}), ErrCallNotFound)
+}
+
+func TestManager_TimeoutAndCancel(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	producer, recorder := producemock.NewRecordingProducer(ctrl)
+	pusher := &testPusher{}
+	m := NewManager(producer, pusher.push, "node-1", "rpc.request", ManagerWithMaxTimeout(20*time.Millisecond))
+	conn := synpmock.NewUserConn(ctrl, session.User{BID: 1, UID: 1, Device: session.DevicePC})
+
+	// 超时回复错误码并通知业务服务端取消。
+	require.NoError(t, m.Call(conn, newCallMessage("m-1", `{"method":"echo","timeout":1000}`)))
+	require.Eventually(t, func() bool { return len(pusher.responses()) == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, CodeTimeout, pusher.last(t).Code)
+	require.Eventually(t, func() bool { return len(requests(t, recorder)) == 2 }, time.Second, 10*time.Millisecond)
+	reqs := requests(t, recorder)
+	assert.Equal(t, RequestTypeCancel, reqs[1].Type)
+
+	// 连接断开取消调用，不再回复。
+	m = NewManager(producer, pusher.push, "node-1", "rpc.request")
+	require.NoError(t, m.Call(conn, newCallMessage("m-2", `{"method":"echo"}`)))
+	m.CancelConn(conn)
+	reqs = requests(t, recorder)
+	require.Len(t, reqs, 4)
+	assert.Equal(t, RequestTypeCancel, reqs[3].Type)
+	assert.Equal(t, reqs[2].CorrelationID, reqs[3].CorrelationID)
+	assert.Empty(t, m.calls)
+	assert.Empty(t, m.conns)
+}
+
+func newCallMessage(id, body string) *messagev1.Message {
+	return &messagev1.Message{MessageId: id, Body: []byte(body)}
+}
+
+// requests 解析已经发送的 rpc 请求。
+func requests(t *testing.T, recorder *producemock.Recorder) []Request {
+	t.Helper()
+
+	msgs := recorder.Messages()
+	reqs := make([]Request, 0, len(msgs))
+	for _, msg := range msgs {
+		req := Request{}
+		require.NoError(t, json.Unmarshal(msg.Val, &req))
+		reqs = append(reqs, req)
+	}
+	return reqs
+}
+
+type testPusher struct {
+	mu    sync.Mutex
+	resps []CallResponse
+}
+
+func (p *testPusher) push(_ synp.Conn, msg *messagev1.Message) error {
+	resp := CallResponse{}
+	if err := json.Unmarshal(msg.GetBody(), &resp); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.resps = append(p.resps, resp)
+	return nil
+}
+
+func (p *testPusher) responses() []CallResponse {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]CallResponse(nil), p.resps...)
+}
+
+func (p *testPusher) last(t *testing.T) CallResponse {
+	t.Helper()
+
+	resps := p.responses()
+	require.NotEmpty(t, resps)
+	return resps[len(resps)-1]
+}
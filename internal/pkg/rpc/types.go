@@ -0,0 +1,78 @@
+package rpc
+
+import (
+	"encoding/json"
+
+	"github.com/jrmarcco/synp/internal/pkg/session"
+)
+
+// Code 为 RPC 错误码。
+// 0 - 999 由网关保留，业务服务端自定义的错误码需要从 1000 开始。
+type Code int32
+
+const (
+	CodeOK              Code = 0
+	CodeInvalidRequest  Code = 1 // 请求格式错误
+	CodeTooManyRequests Code = 2 // 连接上未完成的请求过多
+	CodeUnavailable     Code = 3 // 请求转发到业务服务端失败
+	CodeTimeout         Code = 4 // 等待业务服务端响应超时
+	CodeCanceled        Code = 5 // 请求被取消 ( 如网关关闭 )
+	CodeInternal        Code = 6 // 网关内部错误
+)
+
+// CallRequest 为前端 ( 业务客户端 ) 发起的 RPC 请求，即 CommandTypeRPCRequest 上行消息的 body ( json )。
+type CallRequest struct {
+	Method  string          `json:"method"`
+	Timeout int64           `json:"timeout"` // 毫秒，小于等于 0 时使用默认超时时间
+	Data    json.RawMessage `json:"data"`
+}
+
+// CallResponse 为返回给前端的 RPC 响应，即 CommandTypeRPCResponse 下行消息的 body ( json )。
+// 下行消息的 message id 与请求的 message id 一致。
+type CallResponse struct {
+	Code    Code            `json:"code"`
+	Message string          `json:"message,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// RequestType 为转发给业务服务端的请求类型。
+type RequestType string
+
+const (
+	RequestTypeCall   RequestType = "call"
+	RequestTypeCancel RequestType = "cancel" // 调用方已放弃等待 ( 连接断开、超时 )，业务服务端可以停止处理
+)
+
+// ReplyTo 为业务服务端回复响应的方式，二选一：
+//
+//	Topic：将 Reply 发送到该 topic。
+//	CallbackURL：将 Reply POST 到该地址。
+type ReplyTo struct {
+	NodeID      string `json:"nodeId"`
+	Topic       string `json:"topic,omitempty"`
+	CallbackURL string `json:"callbackUrl,omitempty"`
+}
+
+// Request 为网关转发给业务服务端的 RPC 请求 ( json )。
+// 同一次调用的 call 和 cancel 使用 correlation id 作为消息 key，保证落在同一个分区。
+type Request struct {
+	Type          RequestType     `json:"type"`
+	CorrelationID string          `json:"correlationId"`
+	Method        string          `json:"method,omitempty"`
+	BID           uint64          `json:"bid"`
+	UID           uint64          `json:"uid"`
+	Device        session.Device  `json:"device,omitempty"`
+	ConnID        string          `json:"connId,omitempty"`
+	MessageID     string          `json:"messageId,omitempty"`
+	Data          json.RawMessage `json:"data,omitempty"`
+	Deadline      int64           `json:"deadline,omitempty"` // 毫秒时间戳，超过该时间网关不再等待响应
+	ReplyTo       *ReplyTo        `json:"replyTo,omitempty"`
+}
+
+// Reply 为业务服务端回复的 RPC 响应 ( json )。
+type Reply struct {
+	CorrelationID string          `json:"correlationId"`
+	Code          Code            `json:"code"`
+	Message       string          `json:"message,omitempty"`
+	Data          json.RawMessage `json:"data,omitempty"`
+}
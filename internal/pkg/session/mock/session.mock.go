@@ -0,0 +1,259 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: types.go
+//
+// Generated by this command:
+//
+//	mockgen -source=types.go -destination=mock/session.mock.go -package=sessionmock -typed Session
+//
+
+// Package sessionmock is a generated GoMock package.
+package sessionmock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	session "github.com/jrmarcco/synp/internal/pkg/session"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockSession is a mock of Session interface.
+type MockSession struct {
+	ctrl     *gomock.Controller
+	recorder *MockSessionMockRecorder
+	isgomock struct{}
+}
+
+// MockSessionMockRecorder is the mock recorder for MockSession.
+type MockSessionMockRecorder struct {
+	mock *MockSession
+}
+
+// NewMockSession creates a new mock instance.
+func NewMockSession(ctrl *gomock.Controller) *MockSession {
+	mock := &MockSession{ctrl: ctrl}
+	mock.recorder = &MockSessionMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSession) EXPECT() *MockSessionMockRecorder {
+	return m.recorder
+}
+
+// Destroy mocks base method.
+func (m *MockSession) Destroy(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Destroy", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Destroy indicates an expected call of Destroy.
+func (mr *MockSessionMockRecorder) Destroy(ctx any) *MockSessionDestroyCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Destroy", reflect.TypeOf((*MockSession)(nil).Destroy), ctx)
+	return &MockSessionDestroyCall{Call: call}
+}
+
+// MockSessionDestroyCall wrap *gomock.Call
+type MockSessionDestroyCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockSessionDestroyCall) Return(arg0 error) *MockSessionDestroyCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockSessionDestroyCall) Do(f func(context.Context) error) *MockSessionDestroyCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockSessionDestroyCall) DoAndReturn(f func(context.Context) error) *MockSessionDestroyCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// Get mocks base method.
+func (m *MockSession) Get(ctx context.Context, key string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, key)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockSessionMockRecorder) Get(ctx, key any) *MockSessionGetCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockSession)(nil).Get), ctx, key)
+	return &MockSessionGetCall{Call: call}
+}
+
+// MockSessionGetCall wrap *gomock.Call
+type MockSessionGetCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockSessionGetCall) Return(arg0 string, arg1 error) *MockSessionGetCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockSessionGetCall) Do(f func(context.Context, string) (string, error)) *MockSessionGetCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockSessionGetCall) DoAndReturn(f func(context.Context, string) (string, error)) *MockSessionGetCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// Set mocks base method.
+func (m *MockSession) Set(ctx context.Context, key, val string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Set", ctx, key, val)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Set indicates an expected call of Set.
+func (mr *MockSessionMockRecorder) Set(ctx, key, val any) *MockSessionSetCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockSession)(nil).Set), ctx, key, val)
+	return &MockSessionSetCall{Call: call}
+}
+
+// MockSessionSetCall wrap *gomock.Call
+type MockSessionSetCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockSessionSetCall) Return(arg0 error) *MockSessionSetCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockSessionSetCall) Do(f func(context.Context, string, string) error) *MockSessionSetCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockSessionSetCall) DoAndReturn(f func(context.Context, string, string) error) *MockSessionSetCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// User mocks base method.
+func (m *MockSession) User() session.User {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "User")
+	ret0, _ := ret[0].(session.User)
+	return ret0
+}
+
+// User indicates an expected call of User.
+func (mr *MockSessionMockRecorder) User() *MockSessionUserCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "User", reflect.TypeOf((*MockSession)(nil).User))
+	return &MockSessionUserCall{Call: call}
+}
+
+// MockSessionUserCall wrap *gomock.Call
+type MockSessionUserCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockSessionUserCall) Return(arg0 session.User) *MockSessionUserCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockSessionUserCall) Do(f func() session.User) *MockSessionUserCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockSessionUserCall) DoAndReturn(f func() session.User) *MockSessionUserCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// MockBuilder is a mock of Builder interface.
+type MockBuilder struct {
+	ctrl     *gomock.Controller
+	recorder *MockBuilderMockRecorder
+	isgomock struct{}
+}
+
+// MockBuilderMockRecorder is the mock recorder for MockBuilder.
+type MockBuilderMockRecorder struct {
+	mock *MockBuilder
+}
+
+// NewMockBuilder creates a new mock instance.
+func NewMockBuilder(ctrl *gomock.Controller) *MockBuilder {
+	mock := &MockBuilder{ctrl: ctrl}
+	mock.recorder = &MockBuilderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBuilder) EXPECT() *MockBuilderMockRecorder {
+	return m.recorder
+}
+
+// Build mocks base method.
+func (m *MockBuilder) Build(ctx context.Context, user session.User) (session.Session, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Build", ctx, user)
+	ret0, _ := ret[0].(session.Session)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Build indicates an expected call of Build.
+func (mr *MockBuilderMockRecorder) Build(ctx, user any) *MockBuilderBuildCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Build", reflect.TypeOf((*MockBuilder)(nil).Build), ctx, user)
+	return &MockBuilderBuildCall{Call: call}
+}
+
+// MockBuilderBuildCall wrap *gomock.Call
+type MockBuilderBuildCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockBuilderBuildCall) Return(arg0 session.Session, arg1 bool, arg2 error) *MockBuilderBuildCall {
+	c.Call = c.Call.Return(arg0, arg1, arg2)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockBuilderBuildCall) Do(f func(context.Context, session.User) (session.Session, bool, error)) *MockBuilderBuildCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockBuilderBuildCall) DoAndReturn(f func(context.Context, session.User) (session.Session, bool, error)) *MockBuilderBuildCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
@@ -33,12 +33,16 @@ const (
 	DeviceUnknown Device = "unknown"
 )
 
+// AllDevices 为所有设备类型。
+var AllDevices = []Device{DeviceMobile, DeviceTablet, DevicePC, DeviceUnknown}
+
 // User 为 Session 的用户信息。
 type User struct {
 	BID       uint64 `json:"bid"`
 	UID       uint64 `json:"uid"`
 	Device    Device `json:"device"`    // 设备类型：mobile/tablet/pc
 	AutoClose bool   `json:"autoClose"` // 空闲时是否自动关闭连接
+	ClientIP  string `json:"clientIp"`  // 客户端 IP
 }
 
 func (u *User) ConnID() string {
@@ -0,0 +1,203 @@
+// Package timingwheel 提供了分层时间轮，用于管理大量定时任务 ( 如每条 downstream 消息的重传定时器 )。
+//
+// 相比每个任务一个 runtime timer，时间轮只使用一个 ticker 驱动，
+// 添加和停止定时任务都是 O(1)，代价是触发时间精确到 tick。
+package timingwheel
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultTick   = 100 * time.Millisecond
+	DefaultSlots  = 64
+	DefaultLevels = 4
+)
+
+// Timer 为时间轮中的定时任务。
+type Timer struct {
+	deadline int64 // 触发时间 ( 时间轮启动后的 tick 数 )
+	fn       func()
+
+	wheel *TimingWheel
+	slot  *list.List
+	elem  *list.Element
+}
+
+// Stop 停止定时任务，返回 false 表示任务已经触发或已经被停止。
+func (t *Timer) Stop() bool {
+	tw := t.wheel
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if t.slot == nil {
+		return false
+	}
+
+	t.slot.Remove(t.elem)
+	t.slot, t.elem = nil, nil
+	return true
+}
+
+// TimingWheel 为分层时间轮。
+//
+// 第 l 层每个槽的跨度为 tick * slots^l，定时任务按剩余时间放入对应层的槽中，
+// 高层的槽到期时将其中的任务重新放入低层，只有第 0 层的槽到期时才触发任务。
+// 超出最高层范围的任务放在最高层最远的槽中，到期时重新计算位置。
+//
+// 例：tick 为 100ms，每层 64 个槽，4 层时间轮覆盖约 19 天。
+type TimingWheel struct {
+	tick  time.Duration
+	slots int64
+
+	mu      sync.Mutex
+	levels  [][]*list.List
+	current int64 // 已经推进的 tick 数
+	start   time.Time
+
+	stopOnce sync.Once
+	stopChan chan struct{}
+}
+
+// AfterFunc 在 d 之后 ( 向上取整到 tick ) 在单独的 goroutine 中执行 fn。
+func (tw *TimingWheel) AfterFunc(d time.Duration, fn func()) *Timer {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	ticks := int64((d + tw.tick - 1) / tw.tick)
+	t := &Timer{
+		deadline: tw.current + max(ticks, 1),
+		fn:       fn,
+		wheel:    tw,
+	}
+	tw.add(t)
+	return t
+}
+
+// add 将定时任务放入对应层的槽中，调用方需要持有锁。
+func (tw *TimingWheel) add(t *Timer) {
+	span := int64(1)
+	for l := range tw.levels {
+		// 与当前 tick 相差不到 slots 个槽时放入该层，保证槽在任务到期前不会被再次经过。
+		if t.deadline/span-tw.current/span < tw.slots || l == len(tw.levels)-1 {
+			idx := t.deadline / span
+			if l == len(tw.levels)-1 {
+				idx = min(idx, tw.current/span+tw.slots-1)
+			}
+
+			slot := tw.levels[l][idx%tw.slots]
+			t.slot, t.elem = slot, slot.PushBack(t)
+			return
+		}
+		span *= tw.slots
+	}
+}
+
+// advance 推进一个 tick，返回到期的定时任务，调用方需要持有锁。
+func (tw *TimingWheel) advance() []*Timer {
+	tw.current++
+
+	// 从高层到低层依次将到期的槽中的任务重新放入低层。
+	span := int64(1)
+	spans := make([]int64, len(tw.levels))
+	for l := range tw.levels {
+		spans[l] = span
+		span *= tw.slots
+	}
+	for l := len(tw.levels) - 1; l > 0; l-- {
+		if tw.current%spans[l] != 0 {
+			continue
+		}
+		slot := tw.levels[l][(tw.current/spans[l])%tw.slots]
+		for _, t := range tw.drain(slot) {
+			tw.add(t)
+		}
+	}
+
+	var expired []*Timer
+	for _, t := range tw.drain(tw.levels[0][tw.current%tw.slots]) {
+		if t.deadline > tw.current {
+			// 超出时间轮范围的任务，重新计算位置。
+			tw.add(t)
+			continue
+		}
+		expired = append(expired, t)
+	}
+	return expired
+}
+
+func (tw *TimingWheel) drain(slot *list.List) []*Timer {
+	timers := make([]*Timer, 0, slot.Len())
+	for e := slot.Front(); e != nil; e = e.Next() {
+		t := e.Value.(*Timer)
+		t.slot, t.elem = nil, nil
+		timers = append(timers, t)
+	}
+	slot.Init()
+	return timers
+}
+
+func (tw *TimingWheel) run() {
+	ticker := time.NewTicker(tw.tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-tw.stopChan:
+			return
+		case now := <-ticker.C:
+			// 按实际经过的时间推进，避免 ticker 被延迟时产生累积误差。
+			target := int64(now.Sub(tw.start) / tw.tick)
+
+			tw.mu.Lock()
+			var expired []*Timer
+			for tw.current < target {
+				expired = append(expired, tw.advance()...)
+			}
+			tw.mu.Unlock()
+
+			for _, t := range expired {
+				go t.fn()
+			}
+		}
+	}
+}
+
+// Stop 停止时间轮，没有触发的定时任务不会再触发。
+func (tw *TimingWheel) Stop() {
+	tw.stopOnce.Do(func() {
+		close(tw.stopChan)
+	})
+}
+
+// NewTimingWheel 创建并启动时间轮，每层 slots 个槽，共 levels 层。
+func NewTimingWheel(tick time.Duration, slots, levels int) *TimingWheel {
+	if tick <= 0 {
+		tick = DefaultTick
+	}
+	if slots <= 1 {
+		slots = DefaultSlots
+	}
+	if levels <= 0 {
+		levels = DefaultLevels
+	}
+
+	tw := &TimingWheel{
+		tick:     tick,
+		slots:    int64(slots),
+		levels:   make([][]*list.List, levels),
+		start:    time.Now(),
+		stopChan: make(chan struct{}),
+	}
+	for l := range tw.levels {
+		tw.levels[l] = make([]*list.List, slots)
+		for i := range tw.levels[l] {
+			tw.levels[l][i] = list.New()
+		}
+	}
+
+	go tw.run()
+	return tw
+}
@@ -0,0 +1,50 @@
+package timingwheel
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimingWheel_AfterFunc(t *testing.T) {
+	t.Parallel()
+
+	// 每层 4 个槽，共 2 层，覆盖 16 个 tick，超出范围的任务需要多次重新计算位置。
+	tw := NewTimingWheel(time.Millisecond, 4, 2)
+	defer tw.Stop()
+
+	var (
+		mu    sync.Mutex
+		fired []int
+	)
+	start := time.Now()
+	delays := []int{1, 3, 6, 15, 40}
+	for _, d := range delays {
+		tw.AfterFunc(time.Duration(d)*time.Millisecond, func() {
+			mu.Lock()
+			defer mu.Unlock()
+			// 不会提前触发。
+			assert.GreaterOrEqual(t, time.Since(start), time.Duration(d)*time.Millisecond)
+			fired = append(fired, d)
+		})
+	}
+
+	stopped := tw.AfterFunc(5*time.Millisecond, func() {
+		t.Error("stopped timer fired")
+	})
+	assert.True(t, stopped.Stop())
+	assert.False(t, stopped.Stop())
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(fired) == len(delays)
+	}, time.Second, time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.ElementsMatch(t, delays, fired)
+}
@@ -38,6 +38,7 @@ var _ Consumer = (*KafkaConsumer)(nil)
 
 // KafkaConsumer 是 kafka 消费者。
 // 负责从 kafka 中消费消息，并转换为 xmq.Message。
+// 分区由消费者组协调分配 ( 成员变化时自动重新平衡 )，offset 需要调用 Commit 手动提交。
 type KafkaConsumer struct {
 	topic   string
 	groupID string
@@ -84,7 +85,8 @@ func (c *KafkaConsumer) readMessage() {
 	}()
 
 	for {
-		kafkaMsg, err := c.reader.ReadMessage(c.ctx)
+		// 使用 FetchMessage 而不是 ReadMessage，由调用方在处理完成后手动提交 offset。
+		kafkaMsg, err := c.reader.FetchMessage(c.ctx)
 		if err != nil {
 			if errors.Is(err, io.ErrClosedPipe) || errors.Is(err, io.EOF) || errors.Is(err, context.Canceled) {
 				return
@@ -120,6 +122,22 @@ func (c *KafkaConsumer) convertMessage(kafkaMsg *kafka.Message) *xmq.Message {
 	}
 }
 
+func (c *KafkaConsumer) Commit(ctx context.Context, msgs ...*xmq.Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	kafkaMsgs := make([]kafka.Message, 0, len(msgs))
+	for _, msg := range msgs {
+		kafkaMsgs = append(kafkaMsgs, kafka.Message{
+			Topic:     msg.Topic,
+			Partition: msg.Partition,
+			Offset:    msg.Offset,
+		})
+	}
+	return c.reader.CommitMessages(ctx, kafkaMsgs...)
+}
+
 func (c *KafkaConsumer) Close() error {
 	var err error
 	c.closeOnce.Do(func() {
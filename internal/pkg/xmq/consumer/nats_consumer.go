@@ -0,0 +1,207 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jrmarcco/synp/internal/pkg/xmq"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+const (
+	DefaultNatsAckWait       = 30 * time.Second
+	DefaultNatsMaxAckPending = 10000
+
+	natsRequestTimeout = 5 * time.Second
+)
+
+// NatsConfig 为 NATS JetStream 消费者的配置。
+type NatsConfig struct {
+	Stream        string        // topic ( subject ) 所在的 stream
+	DeliverAll    bool          // 新建消费者时是否从最早的消息开始消费，默认只消费新消息
+	AckWait       time.Duration // 超过该时间没有确认的消息会被重新投递
+	MaxAckPending int           // 未确认消息的上限
+}
+
+var _ ConsumerFactory = (*NatsConsumerFactory)(nil)
+
+// NatsConsumerFactory 是 NATS JetStream 消费者工厂。
+// 消费者组对应一个 durable pull consumer，同一消费者组的成员共享该 consumer。
+type NatsConsumerFactory struct {
+	js  jetstream.JetStream
+	cfg NatsConfig
+}
+
+func (f *NatsConsumerFactory) NewConsumer(topic, groupID string) (Consumer, error) {
+	deliverPolicy := jetstream.DeliverNewPolicy
+	if f.cfg.DeliverAll {
+		deliverPolicy = jetstream.DeliverAllPolicy
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), natsRequestTimeout)
+	defer cancel()
+
+	cons, err := f.js.CreateOrUpdateConsumer(ctx, f.cfg.Stream, jetstream.ConsumerConfig{
+		Durable:       durableName(topic, groupID),
+		FilterSubject: topic,
+		DeliverPolicy: deliverPolicy,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       f.cfg.AckWait,
+		MaxAckPending: f.cfg.MaxAckPending,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create nats consumer: %w", err)
+	}
+
+	iter, err := cons.Messages()
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe nats consumer: %w", err)
+	}
+	return newNatsConsumer(topic, iter), nil
+}
+
+// durableName 生成 durable consumer 名称，名称中不能包含 . * > 等字符。
+func durableName(topic, groupID string) string {
+	return strings.NewReplacer(".", "_", "*", "_", ">", "_", "/", "_", "\\", "_", " ", "_").
+		Replace(groupID + "__" + topic)
+}
+
+func NewNatsConsumerFactory(js jetstream.JetStream, cfg NatsConfig) *NatsConsumerFactory {
+	if cfg.AckWait <= 0 {
+		cfg.AckWait = DefaultNatsAckWait
+	}
+	if cfg.MaxAckPending <= 0 {
+		cfg.MaxAckPending = DefaultNatsMaxAckPending
+	}
+
+	return &NatsConsumerFactory{
+		js:  js,
+		cfg: cfg,
+	}
+}
+
+var _ Consumer = (*NatsConsumer)(nil)
+
+// NatsConsumer 是 NATS JetStream 消费者组成员。
+//
+// JetStream 没有分区，消息的 Partition 固定为 0，Offset 为本地分配的递增序号，
+// Commit 时确认 ( Ack ) 该序号及之前的所有消息。超过 AckWait 没有确认的消息会被重新投递。
+type NatsConsumer struct {
+	topic string
+
+	iter        jetstream.MessagesContext
+	pending     *pendingAcks[jetstream.Msg]
+	messageChan chan *xmq.Message
+
+	ctx        context.Context
+	cancelFunc context.CancelFunc
+
+	closeOnce sync.Once
+}
+
+func (c *NatsConsumer) Consume(ctx context.Context) (*xmq.Message, error) {
+	select {
+	case <-c.ctx.Done():
+		return nil, xmq.ErrConsumerClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case msg, ok := <-c.messageChan:
+		if !ok {
+			return nil, xmq.ErrConsumerClosed
+		}
+		return msg, nil
+	}
+}
+
+func (c *NatsConsumer) ConsumeChan(ctx context.Context) (<-chan *xmq.Message, error) {
+	if c.ctx.Err() != nil {
+		return nil, xmq.ErrConsumerClosed
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return c.messageChan, nil
+}
+
+func (c *NatsConsumer) Commit(_ context.Context, msgs ...*xmq.Message) error {
+	var err error
+	for _, msg := range msgs {
+		for _, natsMsg := range c.pending.take(msg.Offset) {
+			err = errors.Join(err, natsMsg.Ack())
+		}
+	}
+	return err
+}
+
+func (c *NatsConsumer) readMessage() {
+	defer close(c.messageChan)
+
+	for {
+		natsMsg, err := c.iter.Next()
+		if err != nil {
+			if c.ctx.Err() != nil || errors.Is(err, jetstream.ErrMsgIteratorClosed) {
+				return
+			}
+			slog.Warn("[synp-xmq-consumer] failed to read message from nats", "topic", c.topic, "error", err)
+			continue
+		}
+
+		select {
+		case c.messageChan <- c.convertMessage(natsMsg):
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *NatsConsumer) convertMessage(natsMsg jetstream.Msg) *xmq.Message {
+	headers := xmq.Headers{}
+	var key []byte
+	for name, vals := range natsMsg.Headers() {
+		if len(vals) == 0 {
+			continue
+		}
+		if name == xmq.HeaderMessageKey {
+			key = []byte(vals[0])
+			continue
+		}
+		headers[name] = vals[0]
+	}
+
+	return &xmq.Message{
+		Headers: headers,
+		Topic:   c.topic,
+		Offset:  c.pending.add(natsMsg),
+		Key:     key,
+		Val:     natsMsg.Data(),
+	}
+}
+
+func (c *NatsConsumer) Close() error {
+	c.closeOnce.Do(func() {
+		c.cancelFunc()
+		c.iter.Stop()
+	})
+	return nil
+}
+
+func newNatsConsumer(topic string, iter jetstream.MessagesContext) *NatsConsumer {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	c := &NatsConsumer{
+		topic:       topic,
+		iter:        iter,
+		pending:     newPendingAcks[jetstream.Msg](),
+		messageChan: make(chan *xmq.Message, defaultMessageChanSize),
+		ctx:         ctx,
+		cancelFunc:  cancel,
+	}
+
+	go c.readMessage()
+	return c
+}
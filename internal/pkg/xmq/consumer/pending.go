@@ -0,0 +1,64 @@
+package consumer
+
+import "sync"
+
+// pendingAcks 记录已经投递但还没有确认的消息。
+// 用于逐条确认的消息队列 ( 如 Redis Streams、NATS JetStream ) 适配 Commit 的语义：
+// 投递时为消息分配递增的 offset，Commit 时确认该 offset 及之前的所有消息。
+type pendingAcks[T comparable] struct {
+	mu      sync.Mutex
+	nextSeq int64
+	seqs    []int64
+	vals    map[int64]T
+	index   map[T]struct{}
+}
+
+// add 记录待确认的消息，返回分配的 offset。
+func (p *pendingAcks[T]) add(val T) int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	seq := p.nextSeq
+	p.nextSeq++
+	p.seqs = append(p.seqs, seq)
+	p.vals[seq] = val
+	p.index[val] = struct{}{}
+	return seq
+}
+
+// contains 判断消息是否已经投递且还没有确认。
+func (p *pendingAcks[T]) contains(val T) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, ok := p.index[val]
+	return ok
+}
+
+// take 移除并返回 offset 及之前的所有待确认消息。
+func (p *pendingAcks[T]) take(offset int64) []T {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	idx := 0
+	for idx < len(p.seqs) && p.seqs[idx] <= offset {
+		idx++
+	}
+
+	res := make([]T, 0, idx)
+	for _, seq := range p.seqs[:idx] {
+		val := p.vals[seq]
+		res = append(res, val)
+		delete(p.vals, seq)
+		delete(p.index, val)
+	}
+	p.seqs = p.seqs[idx:]
+	return res
+}
+
+func newPendingAcks[T comparable]() *pendingAcks[T] {
+	return &pendingAcks[T]{
+		vals:  make(map[int64]T),
+		index: make(map[T]struct{}),
+	}
+}
@@ -0,0 +1,284 @@
+package consumer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jrmarcco/synp/internal/pkg/xmq"
+	"github.com/jrmarcco/synp/internal/pkg/xmq/produce"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	DefaultRedisStreamBlock        = time.Second
+	DefaultRedisStreamBatchSize    = 64
+	DefaultRedisStreamClaimMinIdle = 30 * time.Second
+
+	redisStreamRetryInterval = time.Second
+)
+
+// RedisStreamConfig 为 Redis Streams 消费者的配置。
+type RedisStreamConfig struct {
+	StreamPrefix string        // stream key 前缀，需要与生产者一致
+	StartID      string        // 新建消费者组时的起始位置，"$" 为最新消息 ( 默认 )，"0" 为最早消息
+	Block        time.Duration // XREADGROUP 阻塞等待时间
+	BatchSize    int64         // 每次读取的最大消息数
+	ClaimMinIdle time.Duration // 认领其他成员 ( 如已经宕机 ) 超过该时间没有确认的消息
+}
+
+var _ ConsumerFactory = (*RedisStreamConsumerFactory)(nil)
+
+// RedisStreamConsumerFactory 是 Redis Streams 消费者工厂。
+type RedisStreamConsumerFactory struct {
+	rdb redis.Cmdable
+	cfg RedisStreamConfig
+}
+
+func (f *RedisStreamConsumerFactory) NewConsumer(topic, groupID string) (Consumer, error) {
+	stream := f.cfg.StreamPrefix + topic
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisStreamRetryInterval*5)
+	defer cancel()
+
+	err := f.rdb.XGroupCreateMkStream(ctx, stream, groupID, f.cfg.StartID).Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return nil, fmt.Errorf("failed to create redis stream consumer group: %w", err)
+	}
+
+	return newRedisStreamConsumer(f.rdb, f.cfg, topic, stream, groupID), nil
+}
+
+func NewRedisStreamConsumerFactory(rdb redis.Cmdable, cfg RedisStreamConfig) *RedisStreamConsumerFactory {
+	if cfg.StartID == "" {
+		cfg.StartID = "$"
+	}
+	if cfg.Block <= 0 {
+		cfg.Block = DefaultRedisStreamBlock
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultRedisStreamBatchSize
+	}
+	if cfg.ClaimMinIdle <= 0 {
+		cfg.ClaimMinIdle = DefaultRedisStreamClaimMinIdle
+	}
+
+	return &RedisStreamConsumerFactory{
+		rdb: rdb,
+		cfg: cfg,
+	}
+}
+
+var _ Consumer = (*RedisStreamConsumer)(nil)
+
+// RedisStreamConsumer 是 Redis Streams 消费者组成员。
+//
+// Redis Streams 没有分区，消息的 Partition 固定为 0，Offset 为本地分配的递增序号，
+// Commit 时通过 XACK 确认该序号及之前的所有消息。
+// 成员宕机后未确认的消息会在 ClaimMinIdle 之后被其他成员通过 XAUTOCLAIM 认领并重新投递。
+type RedisStreamConsumer struct {
+	rdb redis.Cmdable
+	cfg RedisStreamConfig
+
+	topic   string
+	stream  string
+	groupID string
+	name    string
+
+	pending     *pendingAcks[string] // offset -> stream entry id
+	messageChan chan *xmq.Message
+
+	ctx        context.Context
+	cancelFunc context.CancelFunc
+
+	closeOnce sync.Once
+}
+
+func (c *RedisStreamConsumer) Consume(ctx context.Context) (*xmq.Message, error) {
+	select {
+	case <-c.ctx.Done():
+		return nil, xmq.ErrConsumerClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case msg, ok := <-c.messageChan:
+		if !ok {
+			return nil, xmq.ErrConsumerClosed
+		}
+		return msg, nil
+	}
+}
+
+func (c *RedisStreamConsumer) ConsumeChan(ctx context.Context) (<-chan *xmq.Message, error) {
+	if c.ctx.Err() != nil {
+		return nil, xmq.ErrConsumerClosed
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return c.messageChan, nil
+}
+
+func (c *RedisStreamConsumer) Commit(ctx context.Context, msgs ...*xmq.Message) error {
+	var ids []string
+	for _, msg := range msgs {
+		ids = append(ids, c.pending.take(msg.Offset)...)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return c.rdb.XAck(ctx, c.stream, c.groupID, ids...).Err()
+}
+
+func (c *RedisStreamConsumer) readMessage() {
+	defer close(c.messageChan)
+
+	lastClaim := time.Time{}
+	claimStart := "0-0"
+	for c.ctx.Err() == nil {
+		var entries []redis.XMessage
+
+		if time.Since(lastClaim) >= c.cfg.ClaimMinIdle {
+			claimed, next, err := c.rdb.XAutoClaim(c.ctx, &redis.XAutoClaimArgs{
+				Stream:   c.stream,
+				Group:    c.groupID,
+				Consumer: c.name,
+				MinIdle:  c.cfg.ClaimMinIdle,
+				Start:    claimStart,
+				Count:    c.cfg.BatchSize,
+			}).Result()
+			if err != nil && !c.isClosed(err) {
+				slog.Warn("[synp-xmq-consumer] failed to claim pending messages from redis stream", "stream", c.stream, "error", err)
+			}
+			entries = c.skipInflight(claimed)
+			lastClaim = time.Now()
+
+			// 下次从上次认领的位置继续，遍历完成后回到起点。
+			claimStart = next
+			if err != nil || claimStart == "" {
+				claimStart = "0-0"
+			}
+		}
+
+		if len(entries) == 0 {
+			streams, err := c.rdb.XReadGroup(c.ctx, &redis.XReadGroupArgs{
+				Group:    c.groupID,
+				Consumer: c.name,
+				Streams:  []string{c.stream, ">"},
+				Count:    c.cfg.BatchSize,
+				Block:    c.cfg.Block,
+			}).Result()
+			if err != nil {
+				if errors.Is(err, redis.Nil) || c.isClosed(err) {
+					continue
+				}
+				slog.Warn("[synp-xmq-consumer] failed to read message from redis stream", "stream", c.stream, "error", err)
+				c.sleep(redisStreamRetryInterval)
+				continue
+			}
+			for _, stream := range streams {
+				entries = append(entries, stream.Messages...)
+			}
+		}
+
+		for _, entry := range entries {
+			select {
+			case c.messageChan <- c.convertMessage(entry):
+			case <-c.ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// skipInflight 过滤掉本成员已经投递但还没有确认的消息。
+// 处理时间超过 ClaimMinIdle 的消息也会被 XAUTOCLAIM 认领 ( 认领者为本成员 )，重新投递会导致重复消费。
+func (c *RedisStreamConsumer) skipInflight(entries []redis.XMessage) []redis.XMessage {
+	res := entries[:0]
+	for _, entry := range entries {
+		if !c.pending.contains(entry.ID) {
+			res = append(res, entry)
+		}
+	}
+	return res
+}
+
+func (c *RedisStreamConsumer) convertMessage(entry redis.XMessage) *xmq.Message {
+	headers := xmq.Headers{}
+	if raw, ok := entry.Values[produce.RedisStreamFieldHeaders].(string); ok && raw != "" {
+		if err := json.Unmarshal([]byte(raw), &headers); err != nil {
+			slog.Warn("[synp-xmq-consumer] invalid redis stream message headers", "stream", c.stream, "id", entry.ID, "error", err)
+		}
+	}
+
+	key, _ := entry.Values[produce.RedisStreamFieldKey].(string)
+	val, _ := entry.Values[produce.RedisStreamFieldVal].(string)
+
+	msg := &xmq.Message{
+		Headers: headers,
+		Topic:   c.topic,
+		Val:     []byte(val),
+	}
+	if key != "" {
+		msg.Key = []byte(key)
+	}
+	msg.Offset = c.pending.add(entry.ID)
+	return msg
+}
+
+func (c *RedisStreamConsumer) isClosed(err error) bool {
+	return c.ctx.Err() != nil || errors.Is(err, context.Canceled) || errors.Is(err, redis.ErrClosed)
+}
+
+func (c *RedisStreamConsumer) sleep(d time.Duration) {
+	select {
+	case <-c.ctx.Done():
+	case <-time.After(d):
+	}
+}
+
+func (c *RedisStreamConsumer) Close() error {
+	c.closeOnce.Do(func() {
+		c.cancelFunc()
+	})
+	return nil
+}
+
+func newRedisStreamConsumer(rdb redis.Cmdable, cfg RedisStreamConfig, topic, stream, groupID string) *RedisStreamConsumer {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	c := &RedisStreamConsumer{
+		rdb:         rdb,
+		cfg:         cfg,
+		topic:       topic,
+		stream:      stream,
+		groupID:     groupID,
+		name:        consumerName(),
+		pending:     newPendingAcks[string](),
+		messageChan: make(chan *xmq.Message, defaultMessageChanSize),
+		ctx:         ctx,
+		cancelFunc:  cancel,
+	}
+
+	go c.readMessage()
+	return c
+}
+
+// consumerName 生成消费者组成员名称 ( 主机名 + 随机后缀 )。
+func consumerName() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "synp"
+	}
+
+	buf := make([]byte, 4)
+	_, _ = rand.Read(buf)
+	return hostname + "-" + hex.EncodeToString(buf)
+}
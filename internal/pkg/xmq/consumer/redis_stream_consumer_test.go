@@ -0,0 +1,88 @@
+package consumer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/jrmarcco/synp/internal/pkg/xmq"
+	"github.com/jrmarcco/synp/internal/pkg/xmq/produce"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisStreamConsumer_Claim(t *testing.T) {
+	t.Parallel()
+
+	const claimMinIdle = 50 * time.Millisecond
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	factory := NewRedisStreamConsumerFactory(rdb, RedisStreamConfig{
+		StartID:      "0",
+		Block:        10 * time.Millisecond,
+		ClaimMinIdle: claimMinIdle,
+	})
+
+	member1, err := factory.NewConsumer("test-topic", "test-group")
+	require.NoError(t, err)
+	defer member1.Close()
+
+	require.NoError(t, rdb.XAdd(t.Context(), &redis.XAddArgs{
+		Stream: "test-topic",
+		Values: []any{produce.RedisStreamFieldKey, "k1", produce.RedisStreamFieldVal, "v1"},
+	}).Err())
+
+	msg := receiveMessage(t, member1)
+	assert.Equal(t, "v1", string(msg.Val))
+
+	// 处理时间超过 ClaimMinIdle 时，本成员不会重新认领并投递自己未确认的消息。
+	time.Sleep(4 * claimMinIdle)
+	assertNoMessage(t, member1)
+
+	// 成员退出后未确认的消息由其他成员认领。
+	require.NoError(t, member1.Close())
+	member2, err := factory.NewConsumer("test-topic", "test-group")
+	require.NoError(t, err)
+	defer member2.Close()
+
+	msg = receiveMessage(t, member2)
+	assert.Equal(t, "v1", string(msg.Val))
+	require.NoError(t, member2.Commit(t.Context(), msg))
+
+	pending, err := rdb.XPending(t.Context(), "test-topic", "test-group").Result()
+	require.NoError(t, err)
+	assert.Zero(t, pending.Count)
+}
+
+func receiveMessage(t *testing.T, c Consumer) *xmq.Message {
+	t.Helper()
+
+	ch, err := c.ConsumeChan(t.Context())
+	require.NoError(t, err)
+
+	select {
+	case msg := <-ch:
+		require.NotNil(t, msg)
+		return msg
+	case <-time.After(time.Second):
+		require.FailNow(t, "message not received")
+		return nil
+	}
+}
+
+func assertNoMessage(t *testing.T, c Consumer) {
+	t.Helper()
+
+	ch, err := c.ConsumeChan(t.Context())
+	require.NoError(t, err)
+
+	select {
+	case msg := <-ch:
+		assert.Failf(t, "unexpected message", "offset=%d", msg.Offset)
+	default:
+	}
+}
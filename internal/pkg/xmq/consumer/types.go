@@ -14,5 +14,8 @@ type ConsumerFactory interface {
 type Consumer interface {
 	Consume(ctx context.Context) (*xmq.Message, error)
 	ConsumeChan(ctx context.Context) (<-chan *xmq.Message, error)
+	// Commit 提交消息的 offset，表示该消息及同一分区之前的消息都已处理完成。
+	// 消费者不会自动提交 offset，未提交的消息会在重启或分区重新分配后重新消费。
+	Commit(ctx context.Context, msgs ...*xmq.Message) error
 	Close() error
 }
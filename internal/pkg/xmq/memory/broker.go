@@ -0,0 +1,266 @@
+// Package memory 提供了进程内的消息队列实现，用于测试及单节点部署。
+package memory
+
+import (
+	"context"
+	"hash/fnv"
+	"maps"
+	"sync"
+
+	"github.com/jrmarcco/synp/internal/pkg/xmq"
+	pkgconsumer "github.com/jrmarcco/synp/internal/pkg/xmq/consumer"
+	"github.com/jrmarcco/synp/internal/pkg/xmq/produce"
+)
+
+const (
+	DefaultPartitions  = 4
+	DefaultMaxMessages = 10000
+
+	defaultMessageChanSize = 256
+)
+
+var (
+	_ produce.Producer            = (*Broker)(nil)
+	_ pkgconsumer.ConsumerFactory = (*Broker)(nil)
+)
+
+// Broker 为进程内的消息队列，同时实现了 produce.Producer 和 consumer.ConsumerFactory。
+//
+// 语义与 kafka 保持一致：
+//
+//	topic 按 key 分区 ( 没有 key 时轮询 )，同一分区内的消息有序。
+//	消费者组内每个分区同一时刻只属于一个成员，成员变化时重新平衡，每个成员最多拥有平均数个分区。
+//	offset 需要手动提交，成员关闭后未提交的消息会重新投递给认领该分区的成员。
+//	新的消费者组从保留的最早消息开始消费，每个分区最多保留 maxMessages 条消息。
+//
+// 消息只保存在内存中，进程退出后丢失。
+type Broker struct {
+	partitions  int
+	maxMessages int
+
+	mu     sync.Mutex
+	topics map[string]*topic
+}
+
+type topic struct {
+	partitions []*partitionLog
+	groups     map[string]*group
+	rr         int
+
+	// notify 在有新消息或分区被释放时关闭并替换，用于唤醒等待的消费者。
+	notify chan struct{}
+}
+
+type partitionLog struct {
+	base int64 // msgs[0] 的 offset
+	msgs []*xmq.Message
+}
+
+type group struct {
+	members   map[*Consumer]struct{}
+	owners    []*Consumer // 分区 -> 认领该分区的成员
+	next      []int64     // 分区 -> 下一条投递的 offset
+	committed []int64     // 分区 -> 下一条未提交的 offset
+}
+
+func (b *Broker) Produce(_ context.Context, msg *xmq.Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t := b.topic(msg.Topic)
+
+	partition := 0
+	if len(msg.Key) == 0 {
+		partition = t.rr % b.partitions
+		t.rr++
+	} else {
+		h := fnv.New32a()
+		_, _ = h.Write(msg.Key)
+		partition = int(h.Sum32() % uint32(b.partitions))
+	}
+
+	log := t.partitions[partition]
+	log.msgs = append(log.msgs, &xmq.Message{
+		Headers:   maps.Clone(msg.Headers),
+		Topic:     msg.Topic,
+		Partition: partition,
+		Offset:    log.base + int64(len(log.msgs)),
+		Key:       msg.Key,
+		Val:       msg.Val,
+	})
+	if overflow := len(log.msgs) - b.maxMessages; overflow > 0 {
+		log.msgs = log.msgs[overflow:]
+		log.base += int64(overflow)
+	}
+
+	t.wakeup()
+	return nil
+}
+
+func (b *Broker) NewConsumer(topicName, groupID string) (pkgconsumer.Consumer, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t := b.topic(topicName)
+	g, ok := t.groups[groupID]
+	if !ok {
+		g = &group{
+			members:   make(map[*Consumer]struct{}),
+			owners:    make([]*Consumer, b.partitions),
+			next:      make([]int64, b.partitions),
+			committed: make([]int64, b.partitions),
+		}
+		for i, log := range t.partitions {
+			g.next[i] = log.base
+			g.committed[i] = log.base
+		}
+		t.groups[groupID] = g
+	}
+
+	c := newConsumer(b, topicName, groupID)
+	g.members[c] = struct{}{}
+	// 唤醒其他成员释放超出平均数的分区。
+	t.wakeup()
+	go c.fetch()
+	return c, nil
+}
+
+// topic 返回 topic，不存在时创建，调用方需要持有锁。
+func (b *Broker) topic(name string) *topic {
+	t, ok := b.topics[name]
+	if ok {
+		return t
+	}
+
+	t = &topic{
+		partitions: make([]*partitionLog, b.partitions),
+		groups:     make(map[string]*group),
+		notify:     make(chan struct{}),
+	}
+	for i := range t.partitions {
+		t.partitions[i] = &partitionLog{}
+	}
+	b.topics[name] = t
+	return t
+}
+
+// next 返回消费者下一条可以投递的消息，没有消息时返回用于等待的 channel。
+func (b *Broker) next(c *Consumer) (*xmq.Message, <-chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t := b.topics[c.topic]
+	g := t.groups[c.groupID]
+	if _, ok := g.members[c]; !ok {
+		// 成员已经关闭。
+		return nil, t.notify
+	}
+	if g.claim(c) {
+		t.wakeup()
+	}
+
+	for i := range b.partitions {
+		// 从上次投递的下一个分区开始，避免某个分区独占消费者。
+		partition := (c.cursor + i) % b.partitions
+		if g.owners[partition] != c {
+			continue
+		}
+
+		log := t.partitions[partition]
+		g.next[partition] = max(g.next[partition], log.base)
+		idx := g.next[partition] - log.base
+		if idx >= int64(len(log.msgs)) {
+			continue
+		}
+
+		g.next[partition]++
+		c.cursor = partition + 1
+
+		msg := *log.msgs[idx]
+		return &msg, nil
+	}
+	return nil, t.notify
+}
+
+func (b *Broker) commit(c *Consumer, msgs ...*xmq.Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	g := b.topics[c.topic].groups[c.groupID]
+	for _, msg := range msgs {
+		if msg.Partition < 0 || msg.Partition >= b.partitions {
+			continue
+		}
+		g.committed[msg.Partition] = max(g.committed[msg.Partition], msg.Offset+1)
+	}
+}
+
+// leave 移除消费者组成员，释放其认领的分区，未提交的消息会重新投递。
+func (b *Broker) leave(c *Consumer) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t := b.topics[c.topic]
+	g := t.groups[c.groupID]
+	delete(g.members, c)
+	for partition, owner := range g.owners {
+		if owner == c {
+			g.owners[partition] = nil
+			g.next[partition] = g.committed[partition]
+		}
+	}
+	t.wakeup()
+}
+
+// claim 为成员重新分配分区，每个成员最多拥有平均数 ( 向上取整 ) 个分区。
+// 超出平均数的分区会被释放并从已提交的位置重新投递 ( 与 kafka 重新平衡一致，可能重复消费 )，
+// 之后认领没有归属的分区。返回 true 表示有分区被释放。
+func (g *group) claim(c *Consumer) bool {
+	limit := (len(g.owners) + len(g.members) - 1) / len(g.members)
+
+	owned := 0
+	released := false
+	for partition, owner := range g.owners {
+		if owner != c {
+			continue
+		}
+		if owned >= limit {
+			g.owners[partition] = nil
+			g.next[partition] = g.committed[partition]
+			released = true
+			continue
+		}
+		owned++
+	}
+	for partition, owner := range g.owners {
+		if owned >= limit {
+			break
+		}
+		if owner == nil {
+			g.owners[partition] = c
+			owned++
+		}
+	}
+	return released
+}
+
+func (t *topic) wakeup() {
+	close(t.notify)
+	t.notify = make(chan struct{})
+}
+
+// NewBroker 创建进程内消息队列，每个 topic 有 partitions 个分区，每个分区最多保留 maxMessages 条消息。
+func NewBroker(partitions, maxMessages int) *Broker {
+	if partitions <= 0 {
+		partitions = DefaultPartitions
+	}
+	if maxMessages <= 0 {
+		maxMessages = DefaultMaxMessages
+	}
+
+	return &Broker{
+		partitions:  partitions,
+		maxMessages: maxMessages,
+		topics:      make(map[string]*topic),
+	}
+}
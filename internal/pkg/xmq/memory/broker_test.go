@@ -0,0 +1,108 @@
+package memory
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jrmarcco/synp/internal/pkg/xmq"
+	pkgconsumer "github.com/jrmarcco/synp/internal/pkg/xmq/consumer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBroker(t *testing.T) {
+	t.Parallel()
+
+	broker := NewBroker(2, 0)
+	for i := range 4 {
+		err := broker.Produce(t.Context(), &xmq.Message{
+			Headers: xmq.Headers{xmq.HeaderBID: "1"},
+			Topic:   "test-topic",
+			Key:     []byte("key"),
+			Val:     []byte(strconv.Itoa(i)),
+		})
+		require.NoError(t, err)
+	}
+
+	consumer, err := broker.NewConsumer("test-topic", "test-group")
+	require.NoError(t, err)
+
+	// 相同 key 的消息在同一分区内按顺序投递。
+	msgs := consumeN(t, consumer, 4)
+	for i, msg := range msgs {
+		assert.Equal(t, strconv.Itoa(i), string(msg.Val))
+		assert.Equal(t, int64(i), msg.Offset)
+		assert.Equal(t, msgs[0].Partition, msg.Partition)
+		assert.Equal(t, "1", msg.Headers[xmq.HeaderBID])
+	}
+
+	// 只提交前两条消息，关闭后未提交的消息重新投递给新的成员。
+	require.NoError(t, consumer.Commit(t.Context(), msgs[1]))
+	require.NoError(t, consumer.Close())
+
+	_, err = consumer.Consume(t.Context())
+	require.ErrorIs(t, err, xmq.ErrConsumerClosed)
+
+	consumer, err = broker.NewConsumer("test-topic", "test-group")
+	require.NoError(t, err)
+	defer func() { _ = consumer.Close() }()
+
+	msgs = consumeN(t, consumer, 2)
+	assert.Equal(t, "2", string(msgs[0].Val))
+	assert.Equal(t, "3", string(msgs[1].Val))
+
+	// 不同的消费者组独立消费。
+	other, err := broker.NewConsumer("test-topic", "other-group")
+	require.NoError(t, err)
+	defer func() { _ = other.Close() }()
+
+	assert.Len(t, consumeN(t, other, 4), 4)
+}
+
+func TestBroker_GroupMembers(t *testing.T) {
+	t.Parallel()
+
+	broker := NewBroker(2, 2)
+
+	first, err := broker.NewConsumer("test-topic", "test-group")
+	require.NoError(t, err)
+	second, err := broker.NewConsumer("test-topic", "test-group")
+	require.NoError(t, err)
+	defer func() { _ = second.Close() }()
+
+	// 每个分区最多保留 2 条消息。
+	for i := range 6 {
+		err = broker.Produce(t.Context(), &xmq.Message{Topic: "test-topic", Val: []byte(strconv.Itoa(i))})
+		require.NoError(t, err)
+	}
+
+	// 两个成员各自认领一个分区。
+	firstMsgs := consumeN(t, first, 2)
+	secondMsgs := consumeN(t, second, 2)
+	assert.NotEqual(t, firstMsgs[0].Partition, secondMsgs[0].Partition)
+	assert.Equal(t, firstMsgs[0].Partition, firstMsgs[1].Partition)
+	assert.Equal(t, int64(1), firstMsgs[0].Offset)
+
+	// 成员关闭后，另一个成员认领其分区并从未提交的位置开始消费。
+	require.NoError(t, first.Close())
+	msgs := consumeN(t, second, 2)
+	assert.Equal(t, firstMsgs[0].Partition, msgs[0].Partition)
+	assert.Equal(t, firstMsgs[0].Offset, msgs[0].Offset)
+}
+
+func consumeN(t *testing.T, consumer pkgconsumer.Consumer, n int) []*xmq.Message {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+	defer cancel()
+
+	msgs := make([]*xmq.Message, 0, n)
+	for range n {
+		msg, err := consumer.Consume(ctx)
+		require.NoError(t, err)
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jrmarcco/synp/internal/pkg/xmq"
+	pkgconsumer "github.com/jrmarcco/synp/internal/pkg/xmq/consumer"
+)
+
+var _ pkgconsumer.Consumer = (*Consumer)(nil)
+
+// Consumer 为进程内消息队列的消费者组成员。
+type Consumer struct {
+	broker *Broker
+
+	topic   string
+	groupID string
+	cursor  int // 下一次投递开始的分区，由 broker 在持有锁时访问
+
+	messageChan chan *xmq.Message
+
+	ctx        context.Context
+	cancelFunc context.CancelFunc
+
+	closeOnce sync.Once
+}
+
+func (c *Consumer) Consume(ctx context.Context) (*xmq.Message, error) {
+	select {
+	case <-c.ctx.Done():
+		return nil, xmq.ErrConsumerClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case msg, ok := <-c.messageChan:
+		if !ok {
+			return nil, xmq.ErrConsumerClosed
+		}
+		return msg, nil
+	}
+}
+
+func (c *Consumer) ConsumeChan(ctx context.Context) (<-chan *xmq.Message, error) {
+	if c.ctx.Err() != nil {
+		return nil, xmq.ErrConsumerClosed
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return c.messageChan, nil
+}
+
+func (c *Consumer) Commit(_ context.Context, msgs ...*xmq.Message) error {
+	c.broker.commit(c, msgs...)
+	return nil
+}
+
+func (c *Consumer) fetch() {
+	defer close(c.messageChan)
+
+	for {
+		msg, wait := c.broker.next(c)
+		if msg == nil {
+			select {
+			case <-c.ctx.Done():
+				return
+			case <-wait:
+			}
+			continue
+		}
+
+		select {
+		case c.messageChan <- msg:
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *Consumer) Close() error {
+	c.closeOnce.Do(func() {
+		c.cancelFunc()
+		c.broker.leave(c)
+	})
+	return nil
+}
+
+func newConsumer(broker *Broker, topic, groupID string) *Consumer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Consumer{
+		broker:      broker,
+		topic:       topic,
+		groupID:     groupID,
+		messageChan: make(chan *xmq.Message, defaultMessageChanSize),
+		ctx:         ctx,
+		cancelFunc:  cancel,
+	}
+}
@@ -0,0 +1,98 @@
+package produce
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+
+	"github.com/jrmarcco/synp/internal/pkg/xmq"
+)
+
+const DefaultAsyncWorkers = 16
+
+var _ AsyncProducer = (*AsyncProducerAdapter)(nil)
+
+// AsyncProducerAdapter 将同步的 Producer 适配为 AsyncProducer，用于没有原生异步发送的消息队列。
+// 消息按 key 分配给固定数量的 worker 依次发送，保证相同 key 的消息保持发送顺序。
+type AsyncProducerAdapter struct {
+	producer Producer
+
+	inFlight chan struct{} // 未完成消息的信号量
+	queues   []chan *asyncTask
+
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+type asyncTask struct {
+	ctx    context.Context
+	msg    *xmq.Message
+	future *Future
+}
+
+func (p *AsyncProducerAdapter) ProduceAsync(ctx context.Context, msg *xmq.Message) (*Future, error) {
+	select {
+	case p.inFlight <- struct{}{}:
+	default:
+		return nil, ErrProducerBusy
+	}
+
+	task := &asyncTask{
+		// 消息在后台发送，不受调用方 ctx 取消的影响。
+		ctx:    context.WithoutCancel(ctx),
+		msg:    msg,
+		future: NewFuture(),
+	}
+	// 每个队列的容量都不小于 inFlight 的容量，这里不会阻塞。
+	p.queues[p.queueIndex(msg)] <- task
+	return task.future, nil
+}
+
+func (p *AsyncProducerAdapter) queueIndex(msg *xmq.Message) int {
+	if len(msg.Key) == 0 {
+		return 0
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write(msg.Key)
+	return int(h.Sum32() % uint32(len(p.queues)))
+}
+
+func (p *AsyncProducerAdapter) run(queue chan *asyncTask) {
+	defer p.wg.Done()
+
+	for task := range queue {
+		task.future.Complete(p.producer.Produce(task.ctx, task.msg))
+		<-p.inFlight
+	}
+}
+
+// Close 等待已经提交的消息发送完成，Close 之后不能再调用 ProduceAsync。
+func (p *AsyncProducerAdapter) Close() error {
+	p.closeOnce.Do(func() {
+		for _, queue := range p.queues {
+			close(queue)
+		}
+	})
+	p.wg.Wait()
+	return nil
+}
+
+// NewAsyncProducerAdapter 创建异步生产者适配器，maxInFlight 为未完成消息的上限。
+func NewAsyncProducerAdapter(producer Producer, maxInFlight int) *AsyncProducerAdapter {
+	if maxInFlight <= 0 {
+		maxInFlight = DefaultMaxInFlight
+	}
+
+	p := &AsyncProducerAdapter{
+		producer: producer,
+		inFlight: make(chan struct{}, maxInFlight),
+		queues:   make([]chan *asyncTask, DefaultAsyncWorkers),
+	}
+	for i := range p.queues {
+		p.queues[i] = make(chan *asyncTask, maxInFlight)
+		p.wg.Add(1)
+		go p.run(p.queues[i])
+	}
+	return p
+}
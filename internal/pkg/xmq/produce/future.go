@@ -0,0 +1,45 @@
+package produce
+
+import (
+	"context"
+	"sync"
+)
+
+// Future 为异步发送消息的结果。
+type Future struct {
+	once sync.Once
+	done chan struct{}
+	err  error
+}
+
+// Done 返回一个 channel，消息发送完成 ( 成功或失败 ) 时关闭。
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Err 返回发送结果，只有在 Done 关闭后调用才有意义。
+func (f *Future) Err() error {
+	return f.err
+}
+
+// Wait 等待发送完成并返回发送结果。
+func (f *Future) Wait(ctx context.Context) error {
+	select {
+	case <-f.done:
+		return f.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Complete 设置发送结果，只有第一次调用生效。
+func (f *Future) Complete(err error) {
+	f.once.Do(func() {
+		f.err = err
+		close(f.done)
+	})
+}
+
+func NewFuture() *Future {
+	return &Future{done: make(chan struct{})}
+}
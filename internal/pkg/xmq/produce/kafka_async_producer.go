@@ -0,0 +1,80 @@
+package produce
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/jrmarcco/synp/internal/pkg/xmq"
+	"github.com/segmentio/kafka-go"
+)
+
+const DefaultMaxInFlight = 10000
+
+var _ AsyncProducer = (*KafkaAsyncProducer)(nil)
+
+// KafkaAsyncProducer 为基于 kafka.Writer 异步模式的生产者。
+// 所有连接的消息共用一个 writer，由 writer 跨连接按分区批量发送。
+//
+// 注：
+//
+//	writer 必须开启 Async，并且不能设置 Completion ( 由 KafkaAsyncProducer 接管 )。
+//	writer 内部重试 ( MaxAttempts ) 时同一分区的消息保持原有顺序。
+type KafkaAsyncProducer struct {
+	writer   *kafka.Writer
+	inFlight chan struct{} // 未完成消息的信号量
+}
+
+func (p *KafkaAsyncProducer) ProduceAsync(ctx context.Context, msg *xmq.Message) (*Future, error) {
+	select {
+	case p.inFlight <- struct{}{}:
+	default:
+		return nil, ErrProducerBusy
+	}
+
+	future := NewFuture()
+	err := p.writer.WriteMessages(ctx, kafka.Message{
+		Topic:      msg.Topic,
+		Key:        msg.Key,
+		Value:      msg.Val,
+		Headers:    toKafkaHeaders(msg.Headers),
+		WriterData: future,
+	})
+	if err != nil {
+		<-p.inFlight
+		return nil, err
+	}
+	return future, nil
+}
+
+// complete 在 broker 确认 ( 或最终失败 ) 后由 writer 按批次回调。
+func (p *KafkaAsyncProducer) complete(msgs []kafka.Message, err error) {
+	if err != nil {
+		slog.Error(
+			"[synp-xmq-async-producer] failed to produce messages to kafka",
+			"message_cnt", len(msgs),
+			"error", err,
+		)
+	}
+
+	for i := range msgs {
+		if future, ok := msgs[i].WriterData.(*Future); ok {
+			future.Complete(err)
+			<-p.inFlight
+		}
+	}
+}
+
+// NewKafkaAsyncProducer 创建异步生产者，maxInFlight 为未完成消息的上限。
+func NewKafkaAsyncProducer(writer *kafka.Writer, maxInFlight int) *KafkaAsyncProducer {
+	if maxInFlight <= 0 {
+		maxInFlight = DefaultMaxInFlight
+	}
+
+	p := &KafkaAsyncProducer{
+		writer:   writer,
+		inFlight: make(chan struct{}, maxInFlight),
+	}
+	writer.Async = true
+	writer.Completion = p.complete
+	return p
+}
@@ -3,6 +3,8 @@ package produce
 import (
 	"context"
 	"log/slog"
+	"maps"
+	"slices"
 
 	"github.com/jrmarcco/synp/internal/pkg/xmq"
 	"github.com/segmentio/kafka-go"
@@ -16,9 +18,10 @@ type KafkaProducer struct {
 
 func (p *KafkaProducer) Produce(ctx context.Context, msg *xmq.Message) error {
 	err := p.writer.WriteMessages(ctx, kafka.Message{
-		Topic: msg.Topic,
-		Key:   msg.Key,
-		Value: msg.Val,
+		Topic:   msg.Topic,
+		Key:     msg.Key,
+		Value:   msg.Val,
+		Headers: toKafkaHeaders(msg.Headers),
 	})
 	if err != nil {
 		return err
@@ -33,6 +36,19 @@ func (p *KafkaProducer) Produce(ctx context.Context, msg *xmq.Message) error {
 	return nil
 }
 
+// toKafkaHeaders 转换消息 header，按 key 排序保证结果稳定。
+func toKafkaHeaders(headers xmq.Headers) []kafka.Header {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	res := make([]kafka.Header, 0, len(headers))
+	for _, key := range slices.Sorted(maps.Keys(headers)) {
+		res = append(res, kafka.Header{Key: key, Value: []byte(headers[key])})
+	}
+	return res
+}
+
 func NewKafkaProducer(writer *kafka.Writer) *KafkaProducer {
 	return &KafkaProducer{
 		writer: writer,
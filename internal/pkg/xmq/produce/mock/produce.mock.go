@@ -0,0 +1,144 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: types.go
+//
+// Generated by this command:
+//
+//	mockgen -source=types.go -destination=mock/produce.mock.go -package=producemock -typed Producer,AsyncProducer
+//
+
+// Package producemock is a generated GoMock package.
+package producemock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	xmq "github.com/jrmarcco/synp/internal/pkg/xmq"
+	produce "github.com/jrmarcco/synp/internal/pkg/xmq/produce"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockProducer is a mock of Producer interface.
+type MockProducer struct {
+	ctrl     *gomock.Controller
+	recorder *MockProducerMockRecorder
+	isgomock struct{}
+}
+
+// MockProducerMockRecorder is the mock recorder for MockProducer.
+type MockProducerMockRecorder struct {
+	mock *MockProducer
+}
+
+// NewMockProducer creates a new mock instance.
+func NewMockProducer(ctrl *gomock.Controller) *MockProducer {
+	mock := &MockProducer{ctrl: ctrl}
+	mock.recorder = &MockProducerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockProducer) EXPECT() *MockProducerMockRecorder {
+	return m.recorder
+}
+
+// Produce mocks base method.
+func (m *MockProducer) Produce(ctx context.Context, msg *xmq.Message) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Produce", ctx, msg)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Produce indicates an expected call of Produce.
+func (mr *MockProducerMockRecorder) Produce(ctx, msg any) *MockProducerProduceCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Produce", reflect.TypeOf((*MockProducer)(nil).Produce), ctx, msg)
+	return &MockProducerProduceCall{Call: call}
+}
+
+// MockProducerProduceCall wrap *gomock.Call
+type MockProducerProduceCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockProducerProduceCall) Return(arg0 error) *MockProducerProduceCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockProducerProduceCall) Do(f func(context.Context, *xmq.Message) error) *MockProducerProduceCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockProducerProduceCall) DoAndReturn(f func(context.Context, *xmq.Message) error) *MockProducerProduceCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// MockAsyncProducer is a mock of AsyncProducer interface.
+type MockAsyncProducer struct {
+	ctrl     *gomock.Controller
+	recorder *MockAsyncProducerMockRecorder
+	isgomock struct{}
+}
+
+// MockAsyncProducerMockRecorder is the mock recorder for MockAsyncProducer.
+type MockAsyncProducerMockRecorder struct {
+	mock *MockAsyncProducer
+}
+
+// NewMockAsyncProducer creates a new mock instance.
+func NewMockAsyncProducer(ctrl *gomock.Controller) *MockAsyncProducer {
+	mock := &MockAsyncProducer{ctrl: ctrl}
+	mock.recorder = &MockAsyncProducerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAsyncProducer) EXPECT() *MockAsyncProducerMockRecorder {
+	return m.recorder
+}
+
+// ProduceAsync mocks base method.
+func (m *MockAsyncProducer) ProduceAsync(ctx context.Context, msg *xmq.Message) (*produce.Future, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProduceAsync", ctx, msg)
+	ret0, _ := ret[0].(*produce.Future)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ProduceAsync indicates an expected call of ProduceAsync.
+func (mr *MockAsyncProducerMockRecorder) ProduceAsync(ctx, msg any) *MockAsyncProducerProduceAsyncCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProduceAsync", reflect.TypeOf((*MockAsyncProducer)(nil).ProduceAsync), ctx, msg)
+	return &MockAsyncProducerProduceAsyncCall{Call: call}
+}
+
+// MockAsyncProducerProduceAsyncCall wrap *gomock.Call
+type MockAsyncProducerProduceAsyncCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockAsyncProducerProduceAsyncCall) Return(arg0 *produce.Future, arg1 error) *MockAsyncProducerProduceAsyncCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockAsyncProducerProduceAsyncCall) Do(f func(context.Context, *xmq.Message) (*produce.Future, error)) *MockAsyncProducerProduceAsyncCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockAsyncProducerProduceAsyncCall) DoAndReturn(f func(context.Context, *xmq.Message) (*produce.Future, error)) *MockAsyncProducerProduceAsyncCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
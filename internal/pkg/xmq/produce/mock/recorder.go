@@ -0,0 +1,60 @@
+package producemock
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jrmarcco/synp/internal/pkg/xmq"
+	"github.com/jrmarcco/synp/internal/pkg/xmq/produce"
+	"go.uber.org/mock/gomock"
+)
+
+// Recorder 记录 mock 生产者发送的消息，并发安全。
+type Recorder struct {
+	mu   sync.Mutex
+	msgs []*xmq.Message
+}
+
+func (r *Recorder) record(msg *xmq.Message) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.msgs = append(r.msgs, msg)
+}
+
+// Messages 返回已经发送的消息。
+func (r *Recorder) Messages() []*xmq.Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*xmq.Message(nil), r.msgs...)
+}
+
+// NewRecordingProducer 创建记录所有消息的 MockProducer，Produce 可以调用任意次且总是成功。
+func NewRecordingProducer(ctrl *gomock.Controller) (*MockProducer, *Recorder) {
+	recorder := &Recorder{}
+
+	producer := NewMockProducer(ctrl)
+	producer.EXPECT().Produce(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, msg *xmq.Message) error {
+			recorder.record(msg)
+			return nil
+		},
+	).AnyTimes()
+	return producer, recorder
+}
+
+// NewRecordingAsyncProducer 创建记录所有消息的 MockAsyncProducer，ProduceAsync 可以调用任意次且返回已经成功的 Future。
+func NewRecordingAsyncProducer(ctrl *gomock.Controller) (*MockAsyncProducer, *Recorder) {
+	recorder := &Recorder{}
+
+	producer := NewMockAsyncProducer(ctrl)
+	producer.EXPECT().ProduceAsync(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, msg *xmq.Message) (*produce.Future, error) {
+			recorder.record(msg)
+
+			future := produce.NewFuture()
+			future.Complete(nil)
+			return future, nil
+		},
+	).AnyTimes()
+	return producer, recorder
+}
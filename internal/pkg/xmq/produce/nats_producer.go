@@ -0,0 +1,50 @@
+package produce
+
+import (
+	"context"
+
+	"github.com/jrmarcco/synp/internal/pkg/xmq"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+var _ Producer = (*NatsProducer)(nil)
+
+// NatsProducer 为基于 NATS JetStream 的生产者。
+// topic 即为 subject，需要被某个 stream 覆盖；消息 key 通过 xmq.HeaderMessageKey header 传递。
+type NatsProducer struct {
+	js jetstream.JetStream
+}
+
+func (p *NatsProducer) Produce(ctx context.Context, msg *xmq.Message) error {
+	natsMsg := &nats.Msg{
+		Subject: msg.Topic,
+		Data:    msg.Val,
+		Header:  toNatsHeader(msg),
+	}
+
+	_, err := p.js.PublishMsg(ctx, natsMsg)
+	return err
+}
+
+// toNatsHeader 转换消息 header，header 名称保持原有大小写。
+func toNatsHeader(msg *xmq.Message) nats.Header {
+	if len(msg.Headers) == 0 && len(msg.Key) == 0 {
+		return nil
+	}
+
+	header := make(nats.Header, len(msg.Headers)+1)
+	for key, val := range msg.Headers {
+		header[key] = []string{val}
+	}
+	if len(msg.Key) != 0 {
+		header[xmq.HeaderMessageKey] = []string{string(msg.Key)}
+	}
+	return header
+}
+
+func NewNatsProducer(js jetstream.JetStream) *NatsProducer {
+	return &NatsProducer{
+		js: js,
+	}
+}
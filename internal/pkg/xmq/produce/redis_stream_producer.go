@@ -0,0 +1,55 @@
+package produce
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jrmarcco/synp/internal/pkg/xmq"
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis Streams 消息的字段。
+const (
+	RedisStreamFieldKey     = "key"
+	RedisStreamFieldVal     = "val"
+	RedisStreamFieldHeaders = "headers" // json
+)
+
+var _ Producer = (*RedisStreamProducer)(nil)
+
+// RedisStreamProducer 为基于 Redis Streams 的生产者。
+// 每个 topic 对应一个 stream ( key 为 {streamPrefix}{topic} )，stream 长度近似限制为 maxLen。
+type RedisStreamProducer struct {
+	rdb redis.Cmdable
+
+	streamPrefix string
+	maxLen       int64
+}
+
+func (p *RedisStreamProducer) Produce(ctx context.Context, msg *xmq.Message) error {
+	headers, err := json.Marshal(msg.Headers)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message headers: %w", err)
+	}
+
+	return p.rdb.XAdd(ctx, &redis.XAddArgs{
+		Stream: p.streamPrefix + msg.Topic,
+		MaxLen: p.maxLen,
+		Approx: p.maxLen > 0,
+		Values: []any{
+			RedisStreamFieldKey, msg.Key,
+			RedisStreamFieldVal, msg.Val,
+			RedisStreamFieldHeaders, headers,
+		},
+	}).Err()
+}
+
+// NewRedisStreamProducer 创建 Redis Streams 生产者，maxLen 为 0 时不限制 stream 长度。
+func NewRedisStreamProducer(rdb redis.Cmdable, streamPrefix string, maxLen int64) *RedisStreamProducer {
+	return &RedisStreamProducer{
+		rdb:          rdb,
+		streamPrefix: streamPrefix,
+		maxLen:       maxLen,
+	}
+}
@@ -2,10 +2,23 @@ package produce
 
 import (
 	"context"
+	"errors"
 
 	"github.com/jrmarcco/synp/internal/pkg/xmq"
 )
 
+//go:generate mockgen -source=types.go -destination=mock/produce.mock.go -package=producemock -typed Producer,AsyncProducer
+
 type Producer interface {
 	Produce(ctx context.Context, msg *xmq.Message) error
 }
+
+var ErrProducerBusy = errors.New("producer busy")
+
+// AsyncProducer 为异步消息生产者。
+// 消息在后台批量发送，broker 确认 ( 或最终失败 ) 后完成返回的 Future。
+type AsyncProducer interface {
+	// ProduceAsync 异步发送消息。
+	// 未完成的消息达到上限时立即返回 ErrProducerBusy，调用方应该通知上游降低发送速率。
+	ProduceAsync(ctx context.Context, msg *xmq.Message) (*Future, error)
+}
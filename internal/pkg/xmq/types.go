@@ -6,6 +6,52 @@ var ErrConsumerClosed = errors.New("consumer closed")
 
 type Headers map[string]string
 
+// 网关附加到上行消息的元数据 header。
+// 身份信息来自连接建立时校验过的 token，业务服务端应该以这些 header 为准，
+// 不要信任消息 body 中由客户端填写的身份字段。
+const (
+	HeaderBID        = "x-synp-bid"
+	HeaderUID        = "x-synp-uid"
+	HeaderDevice     = "x-synp-device"
+	HeaderConnID     = "x-synp-conn-id"
+	HeaderNodeID     = "x-synp-node-id"
+	HeaderReceivedAt = "x-synp-received-at" // 网关收到消息的时间 ( 毫秒时间戳 )
+	HeaderClientIP   = "x-synp-client-ip"
+	HeaderCodec      = "x-synp-codec" // 网关与客户端之间使用的编解码器 ( json / proto )
+)
+
+// HeaderContentType 为后端 ( 业务服务端 ) 发送消息时声明消息体编码格式的 header，
+// 如 application/json、application/protobuf、application/cloudevents+json。
+const HeaderContentType = "content-type"
+
+// HeaderPriority 为后端 ( 业务服务端 ) 发送消息时指定发送优先级的 header，取值为 high 或 normal ( 默认 )。
+const HeaderPriority = "x-synp-priority"
+
+// HeaderConflationKey 为后端 ( 业务服务端 ) 发送消息时指定合并 key 的 header，
+// 同一连接上合并 key 相同的消息只投递最新的一条。
+const HeaderConflationKey = "x-synp-conflation-key"
+
+// HeaderExpireAt 为后端 ( 业务服务端 ) 发送消息时指定过期时间 ( 毫秒时间戳 ) 的 header，
+// 过期的消息不会再投递。
+// 注意：
+//
+//	这里只支持绝对时间，消息在重试 topic 中重新消费时过期时间不会改变。
+const HeaderExpireAt = "x-synp-expire-at"
+
+// HeaderMessageKey 用于在不支持消息 key 的消息队列 ( 如 NATS JetStream ) 中传递消息 key。
+const HeaderMessageKey = "x-synp-key"
+
+// 消费失败的消息转发到重试 topic 或死信 topic 时附加的 header，消息原有的 header 会保留。
+const (
+	HeaderError             = "x-synp-error"              // 最后一次处理失败的原因
+	HeaderRetryCount        = "x-synp-retry-count"        // 已经转发到重试 topic 的次数
+	HeaderRetryAt           = "x-synp-retry-at"           // 最早的重新处理时间 ( 毫秒时间戳 )
+	HeaderFailedAt          = "x-synp-failed-at"          // 最后一次处理失败的时间 ( 毫秒时间戳 )
+	HeaderOriginalTopic     = "x-synp-original-topic"     // 第一次消费失败时所在的 topic
+	HeaderOriginalPartition = "x-synp-original-partition" // 第一次消费失败时所在的分区
+	HeaderOriginalOffset    = "x-synp-original-offset"    // 第一次消费失败时的 offset
+)
+
 type Message struct {
 	Headers Headers
 
@@ -1,56 +1,170 @@
 package xws
 
 import (
+	"bytes"
 	"compress/flate"
+	"errors"
 	"io"
 	"net"
 
 	"github.com/gobwas/ws"
 	"github.com/gobwas/ws/wsflate"
 	"github.com/gobwas/ws/wsutil"
+	"github.com/jrmarcco/jit/bean/option"
+	"github.com/jrmarcco/synp/internal/pkg/bufpool"
+	"github.com/jrmarcco/synp/internal/pkg/compression"
 )
 
+// ErrMessageTooLarge 表示消息超过了最大长度，读取时已经向对端发送了 1009 ( message too big ) 关闭帧。
+var ErrMessageTooLarge = errors.New("message too large")
+
 // Reader 是对 gobwas/ws 的封装，用于读取 WebSocket 消息。
 type Reader struct {
-	conn net.Conn
+	conn  net.Conn
+	state ws.State
 
 	reader *wsutil.Reader
 
+	// 消息的最大长度 ( 压缩的消息为解压后的长度 )，为 0 时不限制。
+	// 在读取时限制，超过最大长度的消息不会被完整读入内存。
+	maxMessageSize int64
+
 	messageState *wsflate.MessageState
 	flateReader  *wsflate.Reader
+	zstd         *compression.Zstd // 协商了 zstd 共享字典扩展时，压缩的消息使用 zstd 解压
 
 	handlerFunc wsutil.FrameHandlerFunc
 }
 
+// Read 读取一条消息，返回的切片由调用方持有。
 func (r *Reader) Read() ([]byte, error) {
+	buf, err := r.ReadBuffer()
+	if err != nil {
+		return nil, err
+	}
+
+	payload := bytes.Clone(buf.Bytes())
+	buf.Release()
+	return payload, nil
+}
+
+// ReadBuffer 读取一条消息到池化的缓冲区，调用方处理完消息后需要调用 Buffer.Release。
+func (r *Reader) ReadBuffer() (*bufpool.Buffer, error) {
 	for {
-		header, err := r.reader.NextFrame()
-		if err != nil {
-			return nil, err
+		buf, err := r.ReadFrame()
+		if buf != nil || err != nil {
+			return buf, err
 		}
+	}
+}
 
-		if header.OpCode.IsControl() {
-			if err := r.handlerFunc(header, r.reader); err != nil {
-				return nil, err
-			}
-			continue
-		}
+// ReadFrame 读取下一帧，控制帧处理后返回 nil，数据帧读取完整的消息 ( 包括后续的分片 ) 到池化的缓冲区。
+// 用于事件驱动的读取，连接可读时只读取一帧，不会因为只收到控制帧而阻塞等待下一条消息。
+func (r *Reader) ReadFrame() (*bufpool.Buffer, error) {
+	header, err := r.reader.NextFrame()
+	if err != nil {
+		return nil, err
+	}
 
-		if r.messageState.IsCompressed() {
-			r.flateReader.Reset(r.reader)
-			return io.ReadAll(r.flateReader)
+	if header.OpCode.IsControl() {
+		return nil, r.handlerFunc(header, r.reader)
+	}
+
+	if r.zstd != nil && r.messageState.IsCompressed() {
+		return r.readZstd()
+	}
+
+	var src io.Reader = r.reader
+	if r.messageState.IsCompressed() {
+		// 解压器在第一次收到压缩消息时才创建，没有使用压缩的连接不需要占用解压器的内存。
+		if r.flateReader == nil {
+			r.flateReader = wsflate.NewReader(nil, func(r io.Reader) wsflate.Decompressor {
+				return flate.NewReader(r)
+			})
 		}
+		r.flateReader.Reset(r.reader)
+		src = r.flateReader
+	} else if r.maxMessageSize > 0 && header.Length > r.maxMessageSize {
+		// 未压缩的消息可以根据帧头提前判断 ( 分片消息仍需要在读取时判断 )。
+		return nil, r.tooLarge()
+	}
 
-		return io.ReadAll(r.reader)
+	if r.maxMessageSize > 0 {
+		src = io.LimitReader(src, r.maxMessageSize+1)
 	}
+
+	// 按第一帧的长度预留容量，多预留 1 字节避免读到 io.EOF 前扩容。
+	buf := bufpool.Get(int(min(header.Length, bufpool.MaxSize)) + 1)
+	if _, err = buf.ReadFrom(src); err != nil {
+		buf.Release()
+		return nil, err
+	}
+	if r.maxMessageSize > 0 && int64(buf.Len()) > r.maxMessageSize {
+		buf.Release()
+		return nil, r.tooLarge()
+	}
+	return buf, nil
 }
 
-func NewServerSideReader(conn net.Conn) *Reader {
+// readZstd 读取压缩的消息 ( 单个 zstd 帧 ) 并解压，解压前根据帧头中的长度检查消息长度。
+func (r *Reader) readZstd() (*bufpool.Buffer, error) {
+	var src io.Reader = r.reader
+	if r.maxMessageSize > 0 {
+		src = io.LimitReader(src, r.maxMessageSize+1)
+	}
+
+	compressed := bufpool.Get(0)
+	defer compressed.Release()
+	if _, err := compressed.ReadFrom(src); err != nil {
+		return nil, err
+	}
+
+	size, err := r.zstd.ContentSize(compressed.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if r.maxMessageSize > 0 && (size > uint64(r.maxMessageSize) || int64(compressed.Len()) > r.maxMessageSize) {
+		return nil, r.tooLarge()
+	}
+
+	buf := bufpool.Get(int(size))
+	if buf.B, err = r.zstd.Decode(buf.B, compressed.Bytes()); err != nil {
+		buf.Release()
+		return nil, err
+	}
+	return buf, nil
+}
+
+// tooLarge 向对端发送 1009 关闭帧，连接随后由调用方关闭。
+func (r *Reader) tooLarge() error {
+	body := ws.NewCloseFrameBody(ws.StatusMessageTooBig, ErrMessageTooLarge.Error())
+	if err := wsutil.WriteMessage(r.conn, r.state, ws.OpClose, body); err != nil {
+		return errors.Join(ErrMessageTooLarge, err)
+	}
+	return ErrMessageTooLarge
+}
+
+// ReaderWithMaxMessageSize 设置消息的最大长度 ( 字节 )，为 0 时不限制。
+func ReaderWithMaxMessageSize(size int64) option.Opt[Reader] {
+	return func(r *Reader) {
+		r.maxMessageSize = max(size, 0)
+	}
+}
+
+// ReaderWithZstd 设置协商的 zstd 共享字典编解码器，设置后压缩的消息使用 zstd 解压。
+func ReaderWithZstd(z *compression.Zstd) option.Opt[Reader] {
+	return func(r *Reader) {
+		r.zstd = z
+	}
+}
+
+func NewServerSideReader(conn net.Conn, opts ...option.Opt[Reader]) *Reader {
 	messageState := &wsflate.MessageState{}
 	handlerFunc := wsutil.ControlFrameHandler(conn, ws.StateServerSide)
 
-	return &Reader{
-		conn: conn,
+	r := &Reader{
+		conn:  conn,
+		state: ws.StateServerSide,
 		reader: &wsutil.Reader{
 			Source:         conn,
 			State:          ws.StateServerSide | ws.StateExtended,
@@ -58,19 +172,20 @@ func NewServerSideReader(conn net.Conn) *Reader {
 			OnIntermediate: handlerFunc,
 		},
 		messageState: messageState,
-		flateReader: wsflate.NewReader(nil, func(r io.Reader) wsflate.Decompressor {
-			return flate.NewReader(r)
-		}),
-		handlerFunc: handlerFunc,
+		handlerFunc:  handlerFunc,
 	}
+
+	option.Apply(r, opts...)
+	return r
 }
 
-func NewClientSideReader(conn net.Conn) *Reader {
+func NewClientSideReader(conn net.Conn, opts ...option.Opt[Reader]) *Reader {
 	messageState := &wsflate.MessageState{}
 	handlerFunc := wsutil.ControlFrameHandler(conn, ws.StateClientSide)
 
-	return &Reader{
-		conn: conn,
+	r := &Reader{
+		conn:  conn,
+		state: ws.StateClientSide,
 		reader: &wsutil.Reader{
 			Source:         conn,
 			State:          ws.StateClientSide | ws.StateExtended,
@@ -78,9 +193,9 @@ func NewClientSideReader(conn net.Conn) *Reader {
 			OnIntermediate: handlerFunc,
 		},
 		messageState: messageState,
-		flateReader: wsflate.NewReader(nil, func(r io.Reader) wsflate.Decompressor {
-			return flate.NewReader(r)
-		}),
-		handlerFunc: handlerFunc,
+		handlerFunc:  handlerFunc,
 	}
+
+	option.Apply(r, opts...)
+	return r
 }
@@ -0,0 +1,55 @@
+package xws
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReader_MaxMessageSize(t *testing.T) {
+	t.Parallel()
+
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	r := NewServerSideReader(server, ReaderWithMaxMessageSize(16))
+
+	// 客户端依次发送未超过限制的消息及超过限制的分片消息 ( 每个分片都不超过限制 )。
+	go func() {
+		_ = wsutil.WriteClientBinary(client, bytes.Repeat([]byte("a"), 16))
+
+		w := wsutil.NewWriterSize(client, ws.StateClientSide, ws.OpBinary, 8)
+		_, _ = w.Write(bytes.Repeat([]byte("b"), 32))
+		_ = w.Flush()
+	}()
+
+	payload, err := r.Read()
+	require.NoError(t, err)
+	assert.Len(t, payload, 16)
+
+	// 读取超过限制的消息时向客户端发送 1009 关闭帧。
+	closed := make(chan ws.StatusCode, 1)
+	go func() {
+		for {
+			frame, err := ws.ReadFrame(client)
+			if err != nil {
+				return
+			}
+			if frame.Header.OpCode == ws.OpClose {
+				code, _ := ws.ParseCloseFrameData(frame.Payload)
+				closed <- code
+				return
+			}
+		}
+	}()
+
+	_, err = r.Read()
+	require.ErrorIs(t, err, ErrMessageTooLarge)
+	assert.Equal(t, ws.StatusMessageTooBig, <-closed)
+}
@@ -3,74 +3,145 @@ package xws
 import (
 	"compress/flate"
 	"io"
+	"sync"
 
 	"github.com/gobwas/ws"
 	"github.com/gobwas/ws/wsflate"
 	"github.com/gobwas/ws/wsutil"
+	"github.com/jrmarcco/synp/internal/pkg/bufpool"
+	"github.com/jrmarcco/synp/internal/pkg/compression"
 )
 
+// flateWriters 按压缩级别 ( flate.HuffmanOnly - flate.BestCompression ) 复用 flate 压缩器，
+// 压缩器只在压缩消息期间使用，连接不需要持有压缩器 ( 约 1MB )。
+var flateWriters [flate.BestCompression - flate.HuffmanOnly + 1]sync.Pool
+
 // Writer 是对 gobwas/ws 的封装，用于写入 WebSocket 消息。
 type Writer struct {
 	writer *wsutil.Writer
+	state  ws.State
 
+	// 每条消息单独决定是否压缩 ( RSV1 标记 )，见 compression.State.Compress。
 	messageState *wsflate.MessageState
-	flateWriter  *wsflate.Writer
+	compression  *compression.State
 }
 
 func (w *Writer) Write(payload []byte) (int, error) {
-	if w.messageState.IsCompressed() {
-		return w.writeCompressed(payload)
-	}
+	return w.WriteMessage(payload)
+}
 
-	return w.writeUncompressed(payload)
+// Reset 将 Writer 重置为写入 dst，使用 cs 的压缩设置，用于在多个连接之间复用 Writer。
+func (w *Writer) Reset(dst io.Writer, cs *compression.State) {
+	w.writer.Reset(dst, w.state, ws.OpBinary)
+	w.writer.SetExtensions(w.messageState)
+	w.compression = cs
 }
 
-func (w *Writer) writeCompressed(payload []byte) (int, error) {
-	w.flateWriter.Reset(w.writer)
+// WriteMessage 将 parts 依次写入为一条消息，用于发送共享的编码结果及连接相关的后缀 ( 如序号 )，不需要拼接复制。
+// 消息长度小于压缩的最小长度时不压缩。
+func (w *Writer) WriteMessage(parts ...[]byte) (int, error) {
+	var size int
+	for _, part := range parts {
+		size += len(part)
+	}
 
-	n, err := w.flateWriter.Write(payload)
+	compressed := w.compression.Compress(size)
+	w.messageState.SetCompressed(compressed)
+
+	var err error
+	switch {
+	case !compressed:
+		err = writeParts(w.writer, parts)
+	case w.compression.Zstd != nil:
+		err = w.writeZstd(size, parts)
+	default:
+		err = w.writeFlate(parts)
+	}
 	if err != nil {
 		return 0, err
 	}
+	return size, w.writer.Flush()
+}
+
+func (w *Writer) writeFlate(parts [][]byte) error {
+	fw := getFlateWriter(w.compression.Level)
+	defer putFlateWriter(fw, w.compression.Level)
+
+	fw.Reset(w.writer)
+	if err := writeParts(fw, parts); err != nil {
+		return err
+	}
+	// 以 sync flush 结束消息，写入尾标记 ( 0x00 0x00 0xff 0xff，发送时去掉 )。
+	// 不能使用 Close，flate 的结束块不是以尾标记结尾的。压缩器每条消息都会重置，所以不需要结束块。
+	return fw.Flush()
+}
 
-	// 完成 flate wirter 压缩，即写入尾标记。
-	err = w.flateWriter.Close()
-	if err != nil {
-		return 0, err
+// writeZstd 将消息压缩为单个 zstd 帧 ( 包含解压后的长度 )。
+func (w *Writer) writeZstd(size int, parts [][]byte) error {
+	src := parts[0]
+	if len(parts) > 1 {
+		buf := bufpool.Get(size)
+		defer buf.Release()
+		for _, part := range parts {
+			buf.B = append(buf.B, part...)
+		}
+		src = buf.B
 	}
 
-	return n, w.writer.Flush()
+	dst := bufpool.Get(size)
+	defer dst.Release()
+	dst.B = w.compression.Zstd.Encode(dst.B, src)
+
+	_, err := w.writer.Write(dst.B)
+	return err
 }
 
-func (w *Writer) writeUncompressed(payload []byte) (int, error) {
-	n, err := w.writer.Write(payload)
-	if err != nil {
-		return 0, err
+func writeParts(dst io.Writer, parts [][]byte) error {
+	for _, part := range parts {
+		if _, err := dst.Write(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getFlateWriter(level int) *wsflate.Writer {
+	level = flateLevel(level)
+	if fw, ok := flateWriters[level-flate.HuffmanOnly].Get().(*wsflate.Writer); ok {
+		return fw
 	}
+	return wsflate.NewWriter(nil, func(w io.Writer) wsflate.Compressor {
+		// 级别已经校验过，不会返回错误。
+		fw, _ := flate.NewWriter(w, level)
+		return fw
+	})
+}
 
-	return n, w.writer.Flush()
+func putFlateWriter(fw *wsflate.Writer, level int) {
+	// 不再引用连接。
+	fw.Reset(nil)
+	flateWriters[flateLevel(level)-flate.HuffmanOnly].Put(fw)
 }
 
-func NewServerSideWriter(dst io.Writer, compressed bool) *Writer {
-	messageState := &wsflate.MessageState{}
-	messageState.SetCompressed(compressed)
+// flateLevel 校验压缩级别，无效的级别使用 flate.DefaultCompression。
+func flateLevel(level int) int {
+	if level < flate.HuffmanOnly || level > flate.BestCompression {
+		return flate.DefaultCompression
+	}
+	return level
+}
 
+// NewServerSideWriter 创建服务端的 Writer，cs 为 nil 或没有启用压缩时不压缩。
+func NewServerSideWriter(dst io.Writer, cs *compression.State) *Writer {
 	state := ws.StateServerSide | ws.StateExtended
-	opCode := ws.OpBinary
 
 	rtn := &Writer{
-		writer:       wsutil.NewWriter(dst, state, opCode),
-		messageState: messageState,
+		writer:       wsutil.NewWriter(dst, state, ws.OpBinary),
+		state:        state,
+		messageState: &wsflate.MessageState{},
+		compression:  cs,
 	}
-
-	if compressed {
-		rtn.flateWriter = wsflate.NewWriter(nil, func(w io.Writer) wsflate.Compressor {
-			fw, _ := flate.NewWriter(w, flate.DefaultCompression)
-			return fw
-		})
-	}
-
-	rtn.writer.SetExtensions(messageState)
+	rtn.writer.SetExtensions(rtn.messageState)
 
 	return rtn
 }
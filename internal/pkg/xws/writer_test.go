@@ -0,0 +1,53 @@
+package xws
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsflate"
+	"github.com/jrmarcco/synp/internal/pkg/compression"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriter_CompressionMinSize(t *testing.T) {
+	t.Parallel()
+
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+
+	w := NewServerSideWriter(server, &compression.State{
+		Enabled: true,
+		Level:   9,
+		MinSize: 64,
+	})
+
+	small := bytes.Repeat([]byte("a"), 16)
+	large := bytes.Repeat([]byte("b"), 256)
+	go func() {
+		_, _ = w.WriteMessage(small)
+		_, _ = w.WriteMessage(large[:128], large[128:])
+	}()
+
+	// 小于最小长度的消息不压缩。
+	frame, err := ws.ReadFrame(client)
+	require.NoError(t, err)
+	compressed, err := wsflate.IsCompressed(frame.Header)
+	require.NoError(t, err)
+	assert.False(t, compressed)
+	assert.Equal(t, small, frame.Payload)
+
+	frame, err = ws.ReadFrame(client)
+	require.NoError(t, err)
+	compressed, err = wsflate.IsCompressed(frame.Header)
+	require.NoError(t, err)
+	assert.True(t, compressed)
+	assert.Less(t, len(frame.Payload), len(large))
+
+	payload, err := wsflate.DecompressFrame(frame)
+	require.NoError(t, err)
+	assert.Equal(t, large, payload.Payload)
+}
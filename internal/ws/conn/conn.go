@@ -5,6 +5,7 @@ import (
 	"errors"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gobwas/ws"
@@ -12,7 +13,9 @@ import (
 	"github.com/jrmarcco/jit/bean/option"
 	"github.com/jrmarcco/jit/retry"
 	"github.com/jrmarcco/synp"
+	"github.com/jrmarcco/synp/internal/pkg/bufpool"
 	"github.com/jrmarcco/synp/internal/pkg/compression"
+	"github.com/jrmarcco/synp/internal/pkg/netpoll"
 	"github.com/jrmarcco/synp/internal/pkg/session"
 	"github.com/jrmarcco/synp/internal/pkg/xws"
 	"go.uber.org/multierr"
@@ -32,8 +35,9 @@ type Conn struct {
 
 	netConn net.Conn
 
-	reader      *xws.Reader
-	readTimeout time.Duration
+	reader         *xws.Reader
+	readTimeout    time.Duration
+	maxMessageSize int64 // 前端消息的最大长度，超过时以 1009 关闭连接
 
 	writer       *xws.Writer
 	writeTimeout time.Duration
@@ -46,8 +50,27 @@ type Conn struct {
 	maxRetryCount     int32
 
 	// 通信通道
-	sendChan    chan []byte
-	receiveChan chan []byte
+	//
+	// 	每个优先级一个发送队列，sendLoop 总是先发送控制帧，
+	// 	其余优先级按 sendWeights 加权轮询，每轮最多发送 weight 条消息。
+	sendQueue      *sendQueue
+	sendBufferSize int
+	sendWeights    [synp.PriorityCount]int
+	receiveChan    chan *bufpool.Buffer
+	receiveSize    int
+
+	// 慢消费者处理:
+	//
+	// 	发送队列已满时最多等待 sendTimeout ( 为 0 时不等待 )，
+	// 	仍然没有空间时按 overflowPolicy 处理，不会无限阻塞发送方 ( 如消息队列的消费者 )。
+	sendTimeout     time.Duration
+	overflowPolicy  OverflowPolicy
+	disconnectAfter time.Duration // 溢出策略为 disconnect 时，持续溢出超过该时间后关闭连接
+	overflowSince   atomic.Int64  // 开始持续溢出的时间 ( 纳秒时间戳 )，0 表示没有溢出
+	spillFunc       SpillFunc
+
+	// 已经过期的消息不会入队，在发送队列中过期的消息在发送前丢弃。
+	expireFunc ExpireFunc
 
 	// 空闲连接管理
 	mu           sync.RWMutex
@@ -58,9 +81,10 @@ type Conn struct {
 	//
 	// 	这里使用 uber 的 ratelimit 库，是一个基于漏桶算法（Leaky Bucket）的限流器。
 	// 	WebSocket 消息处理需要平滑，避免突发消息阻塞 receiveChan。
-	// 	同时达到限流时优先考虑阻塞等待。
-	limitRate int
-	limiter   ratelimit.Limiter
+	// 	同时达到限流时优先考虑阻塞等待 ( 事件驱动模式下不阻塞 worker，见 reserveRead )。
+	limitRate  int
+	limiter    ratelimit.Limiter
+	nextReadAt time.Time // 事件驱动模式下一次允许读取的时间，只在读任务中访问
 
 	ctx        context.Context
 	cancelFunc context.CancelFunc
@@ -68,6 +92,22 @@ type Conn struct {
 	closeOnce sync.Once
 	closeErr  error
 
+	// 事件驱动模式 ( eventLoop 不为 nil ) 下没有收发 goroutine，见 EventLoop。
+	eventLoop *EventLoop
+	pollMu    sync.Mutex // 保护 desc，保证连接关闭后不会再注册到 poller
+	desc      *netpoll.Desc
+	onReceive func(payload []byte)
+	flushing  atomic.Bool // 是否已经提交了发送任务
+
+	// 事件驱动模式下的接收队列：
+	//
+	// 	读 worker 只负责读取，消息由连接单独的处理 goroutine 交给 onReceive ( 队列为空时退出 )，
+	// 	处理阻塞 ( 如等待后端 ) 只影响当前连接。队列达到 receiveSize 时暂停读取，队列清空后恢复。
+	inboxMu    sync.Mutex
+	inbox      []*bufpool.Buffer
+	handling   bool // 是否有处理 goroutine 在运行
+	readPaused bool // 是否因为接收队列已满暂停读取
+
 	logger *zap.Logger
 }
 
@@ -80,18 +120,63 @@ func (c *Conn) Session() session.Session {
 }
 
 func (c *Conn) Send(payload []byte) error {
-	select {
-	case <-c.ctx.Done():
+	return c.SendFrame(synp.Frame{Payload: payload, Priority: synp.PriorityNormal})
+}
+
+// SendFrame 将消息放入对应优先级的发送队列，未知的优先级按 synp.PriorityNormal 处理。
+// 队列中有合并 key 相同的旧消息时直接替换旧消息。
+// 队列已满时最多等待 sendTimeout，仍然没有空间时按溢出策略处理。
+// 已经过期的消息直接丢弃。
+// frame.Buffer 在消息发送完成或被丢弃 ( 包括返回错误 ) 后释放。
+func (c *Conn) SendFrame(frame synp.Frame) error {
+	if c.ctx.Err() != nil {
+		frame.Release()
 		return ErrConnClosed
-	case c.sendChan <- payload:
-		if c.ctx.Err() != nil {
-			return ErrConnClosed
-		}
+	}
+	if c.dropExpired(&frame, time.Now()) {
+		frame.Release()
 		return nil
 	}
+	defer c.wakeWriter()
+
+	if int(frame.Priority) >= synp.PriorityCount {
+		frame.Priority = synp.PriorityNormal
+	}
+
+	res, space := c.sendQueue.push(&frame)
+	if res == pushFull && c.sendTimeout > 0 {
+		timer := time.NewTimer(c.sendTimeout)
+		defer timer.Stop()
+
+	wait:
+		for res == pushFull {
+			select {
+			case <-c.ctx.Done():
+				frame.Release()
+				return ErrConnClosed
+			case <-timer.C:
+				break wait
+			case <-space:
+				res, space = c.sendQueue.push(&frame)
+			}
+		}
+	}
+
+	switch res {
+	case pushFull:
+		return c.overflow(&frame)
+	case pushConflated:
+		conflatedTotal.Inc()
+	case pushQueued:
+	}
+
+	if c.overflowSince.Load() != 0 {
+		c.overflowSince.Store(0)
+	}
+	return nil
 }
 
-func (c *Conn) Receive() <-chan []byte {
+func (c *Conn) Receive() <-chan *bufpool.Buffer {
 	return c.receiveChan
 }
 
@@ -111,8 +196,8 @@ func (c *Conn) Closed() <-chan struct{} {
 func (c *Conn) Close() error {
 	// 注意:
 	//
-	// 不要关闭 c.sendChan，
-	// 这会导致 Send 方法中的 c.sendChan <- payload 分支发生 panic：send on closed channel。
+	// 不要关闭 c.sendQueue.ready，
+	// SendFrame 可能与 Close 并发执行，入队时向已关闭的 channel 发送通知会发生 panic：send on closed channel。
 	c.closeOnce.Do(func() {
 		// 尝试发送 WebSocket 关闭帧。
 		_ = c.netConn.SetWriteDeadline(time.Now().Add(DefaultCloseTimeout))
@@ -121,6 +206,13 @@ func (c *Conn) Close() error {
 		// 取消 context。
 		c.cancelFunc()
 
+		// 从 poller 中删除，之后底层连接的文件描述符可能被新连接复用。
+		c.pollMu.Lock()
+		if c.desc != nil {
+			_ = c.eventLoop.poller.Remove(c.desc)
+		}
+		c.pollMu.Unlock()
+
 		// 关闭底层连接 ( net.Conn )。
 		c.closeErr = c.netConn.Close()
 
@@ -141,28 +233,39 @@ func (c *Conn) sendLoop() {
 	}()
 
 	for {
-		select {
-		case <-c.ctx.Done():
-			return
-		case payload, ok := <-c.sendChan:
-			if !ok {
+		frame := c.sendQueue.pop()
+		if frame == nil {
+			// 队列为空，等待新消息。
+			select {
+			case <-c.ctx.Done():
 				return
+			case <-c.sendQueue.ready:
+				continue
 			}
+		}
 
-			if !c.trySend(payload) {
-				// 发送失败，关闭连接。
-				return
-			}
+		if c.dropExpired(frame, time.Now()) {
+			frame.Release()
+			continue
+		}
+
+		ok := c.trySend(c.writer, frame.Bytes(), frame.Suffix)
+		frame.Release()
+		if !ok {
+			// 发送失败，关闭连接。
+			return
 		}
 	}
 }
 
 // trySend 是实际发送消息给客户端的逻辑。
 // 在发送失败时，会根据配置使用指数退避策略进行重试，最终重试失败才会返回 false。
+// suffix 不为空时追加在 payload 之后作为同一条消息发送。
+// w 为连接的 writer，事件驱动模式下为从 EventLoop 获取的池化 writer。
 // 注意：
 //
 //	只允许在发生超时时进行重试。
-func (c *Conn) trySend(payload []byte) bool {
+func (c *Conn) trySend(w *xws.Writer, payload, suffix []byte) bool {
 	// 这里可以忽略 error。
 	// 创建 ws.Conn 的时候就应该确保重试策略的参数正确。
 	retryStrategy, _ := retry.NewExponentialBackoffStrategy(
@@ -179,7 +282,7 @@ func (c *Conn) trySend(payload []byte) bool {
 		// 设置写超时。
 		_ = c.netConn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
 
-		_, err := c.writer.Write(payload)
+		_, err := w.WriteMessage(payload, suffix)
 		if err == nil {
 			return true
 		}
@@ -187,7 +290,7 @@ func (c *Conn) trySend(payload []byte) bool {
 		c.logger.Error(
 			"[synp-conn] failed to send message to client",
 			zap.String("conn_id", c.id),
-			zap.Int("payload_len", len(payload)),
+			zap.Int("payload_len", len(payload)+len(suffix)),
 			zap.Any("compression_state", c.compressionState),
 			zap.Any("user", c.sess.User()),
 			zap.Error(err),
@@ -242,50 +345,76 @@ func (c *Conn) receiveLoop() {
 		// 设置读超时。
 		_ = c.netConn.SetReadDeadline(time.Now().Add(c.readTimeout))
 
-		payload, err := c.reader.Read()
+		payload, err := c.reader.ReadBuffer()
 		if err != nil {
-			var netErr net.Error
-			if errors.As(err, &netErr) && netErr.Timeout() {
+			if c.readRetryable(err) {
 				continue
 			}
-
-			var wsErr wsutil.ClosedError
-			if errors.As(err, &wsErr) && (wsErr.Code == ws.StatusNoStatusRcvd || wsErr.Code == ws.StatusGoingAway) {
-				// 客户端关闭连接，记录日志直接返回。
-				c.logger.Debug(
-					"[synp-conn] client closed connection",
-					zap.String("conn_id", c.id),
-					zap.Any("compression_state", c.compressionState),
-					zap.Any("user", c.sess.User()),
-				)
-				return
-			}
-
-			// 其他错误，直接返回。
-			c.logger.Error(
-				"[synp-conn] failed to read message from client",
-				zap.String("conn_id", c.id),
-				zap.Any("compression_state", c.compressionState),
-				zap.Any("user", c.sess.User()),
-				zap.Error(err),
-			)
 			return
 		}
 
 		select {
 		case <-c.ctx.Done():
+			payload.Release()
 			return
 		case c.receiveChan <- payload:
 		}
 	}
 }
 
+// readRetryable 处理读取消息的错误并记录日志，返回是否可以继续读取 ( 只有读超时可以继续 )。
+func (c *Conn) readRetryable(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	if errors.Is(err, xws.ErrMessageTooLarge) {
+		c.logger.Warn(
+			"[synp-conn] message from client too large, close connection",
+			zap.String("conn_id", c.id),
+			zap.Int64("max_message_size", c.maxMessageSize),
+			zap.Any("user", c.sess.User()),
+		)
+		return false
+	}
+
+	var wsErr wsutil.ClosedError
+	if errors.As(err, &wsErr) && (wsErr.Code == ws.StatusNoStatusRcvd || wsErr.Code == ws.StatusGoingAway) {
+		// 客户端关闭连接，记录日志直接返回。
+		c.logger.Debug(
+			"[synp-conn] client closed connection",
+			zap.String("conn_id", c.id),
+			zap.Any("compression_state", c.compressionState),
+			zap.Any("user", c.sess.User()),
+		)
+		return false
+	}
+
+	// 其他错误，直接返回。
+	c.logger.Error(
+		"[synp-conn] failed to read message from client",
+		zap.String("conn_id", c.id),
+		zap.Any("compression_state", c.compressionState),
+		zap.Any("user", c.sess.User()),
+		zap.Error(err),
+	)
+	return false
+}
+
 func ConnWithReadTimeout(readTimeout time.Duration) option.Opt[Conn] {
 	return func(c *Conn) {
 		c.readTimeout = readTimeout
 	}
 }
 
+// ConnWithMaxMessageSize 设置前端消息的最大长度 ( 字节，压缩的消息为解压后的长度 )。
+func ConnWithMaxMessageSize(size int64) option.Opt[Conn] {
+	return func(c *Conn) {
+		c.maxMessageSize = size
+	}
+}
+
 func ConnWithWriteTimeout(writeTimeout time.Duration) option.Opt[Conn] {
 	return func(c *Conn) {
 		c.writeTimeout = writeTimeout
@@ -306,15 +435,63 @@ func ConnWithRetry(initRetryInterval, maxRetryInterval time.Duration, maxRetryCo
 	}
 }
 
+// ConnWithReadBuffer 设置接收队列的大小 ( 已经读取、等待处理的消息数 )。
 func ConnWithReadBuffer(receiveBufferSize int) option.Opt[Conn] {
 	return func(c *Conn) {
-		c.receiveChan = make(chan []byte, receiveBufferSize)
+		c.receiveSize = receiveBufferSize
 	}
 }
 
+// ConnWithWriteBuffer 设置每个优先级发送队列的大小。
 func ConnWithWriteBuffer(sendBufferSize int) option.Opt[Conn] {
 	return func(c *Conn) {
-		c.sendChan = make(chan []byte, sendBufferSize)
+		c.sendBufferSize = sendBufferSize
+	}
+}
+
+// ConnWithSendTimeout 设置发送队列已满时等待队列空间的时间，为 0 时不等待直接按溢出策略处理。
+func ConnWithSendTimeout(sendTimeout time.Duration) option.Opt[Conn] {
+	return func(c *Conn) {
+		c.sendTimeout = max(sendTimeout, 0)
+	}
+}
+
+// ConnWithOverflowPolicy 设置发送队列已满时的处理策略。
+// disconnectAfter 只对 OverflowDisconnect 有效，为 0 时第一次溢出就关闭连接。
+func ConnWithOverflowPolicy(policy OverflowPolicy, disconnectAfter time.Duration) option.Opt[Conn] {
+	return func(c *Conn) {
+		c.overflowPolicy = policy
+		c.disconnectAfter = max(disconnectAfter, 0)
+	}
+}
+
+// ConnWithSpillFunc 设置溢出策略为 OverflowSpillOffline 时保存消息的函数。
+func ConnWithSpillFunc(fn SpillFunc) option.Opt[Conn] {
+	return func(c *Conn) {
+		c.spillFunc = fn
+	}
+}
+
+// ConnWithExpireFunc 设置丢弃过期消息时的回调。
+func ConnWithExpireFunc(fn ExpireFunc) option.Opt[Conn] {
+	return func(c *Conn) {
+		c.expireFunc = fn
+	}
+}
+
+// ConnWithSendWeights 设置 ack、high、normal 优先级每轮调度最多发送的消息数，控制帧不受权重限制。
+// 小于 1 的权重会被忽略。
+func ConnWithSendWeights(ack, high, normal int) option.Opt[Conn] {
+	return func(c *Conn) {
+		for priority, weight := range map[synp.Priority]int{
+			synp.PriorityAck:    ack,
+			synp.PriorityHigh:   high,
+			synp.PriorityNormal: normal,
+		} {
+			if weight > 0 {
+				c.sendWeights[priority] = weight
+			}
+		}
 	}
 }
 
@@ -350,15 +527,20 @@ func NewConn(
 		sess:    sess,
 		netConn: netConn,
 
-		readTimeout:  DefaultReadTiemout,
-		writeTimeout: DefaultWriteTiemout,
+		readTimeout:    DefaultReadTiemout,
+		writeTimeout:   DefaultWriteTiemout,
+		maxMessageSize: DefaultMaxMessageSize,
 
 		initRetryInterval: DefaultInitRetryInterval,
 		maxRetryInterval:  DefaultMaxRetryInterval,
 		maxRetryCount:     DefaultMaxRetryCount,
 
-		sendChan:    make(chan []byte, DefaultSendBufferSize),
-		receiveChan: make(chan []byte, DefaultReceiveBufferSize),
+		sendBufferSize: DefaultSendBufferSize,
+		sendWeights:    DefaultSendWeights,
+		receiveSize:    DefaultReceiveBufferSize,
+
+		overflowPolicy:  DefaultOverflowPolicy,
+		disconnectAfter: DefaultDisconnectAfter,
 
 		activityTime: time.Now(),
 
@@ -369,15 +551,28 @@ func NewConn(
 	}
 
 	option.Apply(c, opts...)
+	c.sendQueue = newSendQueue(c.sendBufferSize, c.sendWeights)
 
 	// 在 option 应用之后才能确定 compressionState。
 	// 所以只能在这里初始化 writer 和 reader。
-	var compressionEnabled bool
-	if c.compressionState != nil {
-		compressionEnabled = c.compressionState.Enabled
+	readerOpts := []option.Opt[xws.Reader]{xws.ReaderWithMaxMessageSize(c.maxMessageSize)}
+	if c.compressionState != nil && c.compressionState.Zstd != nil {
+		readerOpts = append(readerOpts, xws.ReaderWithZstd(c.compressionState.Zstd))
 	}
-	c.reader = xws.NewServerSideReader(netConn)
-	c.writer = xws.NewServerSideWriter(netConn, compressionEnabled)
+	c.reader = xws.NewServerSideReader(netConn, readerOpts...)
+
+	if c.eventLoop != nil {
+		// 事件驱动模式下消息由 Serve 注册的处理函数接收，只在降级为 receiveLoop 时使用 receiveChan。
+		c.receiveChan = make(chan *bufpool.Buffer)
+		// 没有 sendLoop 监听 context，context 取消 ( 如服务器关闭 ) 时需要主动关闭连接。
+		context.AfterFunc(ctx, func() {
+			_ = c.Close()
+		})
+		return c
+	}
+
+	c.receiveChan = make(chan *bufpool.Buffer, c.receiveSize)
+	c.writer = xws.NewServerSideWriter(netConn, c.compressionState)
 
 	// 启动收发数据的 goroutine。
 	//nolint:contextcheck // sendLoop 内部使用 c.ctx。
@@ -20,7 +20,7 @@ const (
 	DefaultReadTiemout  = 15 * time.Second
 	DefaultWriteTiemout = 10 * time.Second
 
-	// 默认缓冲大小
+	// 默认缓冲大小 ( 每个优先级的发送队列大小均为 DefaultSendBufferSize )
 	DefaultSendBufferSize    = 256
 	DefaultReceiveBufferSize = 256
 
@@ -31,8 +31,23 @@ const (
 
 	DefaultCloseTimeout = time.Second
 	DefaultRateLimit    = 10
+
+	// 默认的消息最大长度，更大的消息需要分片传输 ( 见 chunk 包 )
+	DefaultMaxMessageSize = 1 << 20
+
+	// 默认溢出策略
+	DefaultOverflowPolicy  = OverflowDropOldest
+	DefaultDisconnectAfter = 10 * time.Second
+	DefaultSpillTimeout    = time.Second
 )
 
+// DefaultSendWeights 为各优先级每轮调度默认最多发送的消息数 ( 控制帧不受权重限制 )。
+var DefaultSendWeights = [synp.PriorityCount]int{
+	synp.PriorityAck:    8,
+	synp.PriorityHigh:   4,
+	synp.PriorityNormal: 1,
+}
+
 // DeviceConns 管理单个用户的多设备连接。
 // 这里不直接使用 sync.Map 是因为一个用户最多只会有 3 个设备连接。
 // 相比起直接使用 sync.Map 性能更好且内存占用更低。
@@ -106,8 +121,22 @@ type ConnConfig struct {
 	SendBufferSize    int
 	ReceiveBufferSize int
 
+	// 各优先级的发送权重，小于 1 时使用默认值。
+	AckSendWeight    int
+	HighSendWeight   int
+	NormalSendWeight int
+
+	// 慢消费者处理。
+	SendTimeout     time.Duration
+	OverflowPolicy  OverflowPolicy
+	BizOverflow     map[uint64]OverflowPolicy // 按业务覆盖溢出策略
+	DisconnectAfter time.Duration
+
 	CloseTimeout time.Duration
 	RateLimit    int
+
+	// 前端消息的最大长度 ( 字节 )，超过时以 1009 关闭连接。
+	MaxMessageSize int64
 }
 
 var _ synp.ConnManager = (*ConnManager)(nil)
@@ -119,6 +148,11 @@ type ConnManager struct {
 	connCnt atomic.Int64
 	userCnt atomic.Int64
 
+	spillFunc  SpillFunc
+	expireFunc ExpireFunc
+
+	eventLoop *EventLoop // 不为 nil 时新连接使用事件驱动模式
+
 	logger *zap.Logger
 }
 
@@ -200,6 +234,31 @@ func (m *ConnManager) convertToConnOpts(user session.User, compressionState *com
 	if m.cfg.ReceiveBufferSize > 0 {
 		opts = append(opts, ConnWithReadBuffer(m.cfg.ReceiveBufferSize))
 	}
+	opts = append(opts, ConnWithSendWeights(m.cfg.AckSendWeight, m.cfg.HighSendWeight, m.cfg.NormalSendWeight))
+
+	if m.cfg.SendTimeout > 0 {
+		opts = append(opts, ConnWithSendTimeout(m.cfg.SendTimeout))
+	}
+	if policy := m.overflowPolicy(user); policy != "" {
+		disconnectAfter := m.cfg.DisconnectAfter
+		if disconnectAfter <= 0 {
+			disconnectAfter = DefaultDisconnectAfter
+		}
+		opts = append(opts, ConnWithOverflowPolicy(policy, disconnectAfter))
+	}
+	if m.spillFunc != nil {
+		opts = append(opts, ConnWithSpillFunc(m.spillFunc))
+	}
+	if m.expireFunc != nil {
+		opts = append(opts, ConnWithExpireFunc(m.expireFunc))
+	}
+	if m.eventLoop != nil {
+		opts = append(opts, ConnWithEventLoop(m.eventLoop))
+	}
+
+	if m.cfg.MaxMessageSize > 0 {
+		opts = append(opts, ConnWithMaxMessageSize(m.cfg.MaxMessageSize))
+	}
 
 	if m.cfg.InitRetryInterval > 0 && m.cfg.MaxRetryInterval > 0 && m.cfg.MaxRetryCount > 0 {
 		opts = append(opts, ConnWithRetry(m.cfg.InitRetryInterval, m.cfg.MaxRetryInterval, m.cfg.MaxRetryCount))
@@ -214,6 +273,14 @@ func (m *ConnManager) convertToConnOpts(user session.User, compressionState *com
 	return opts
 }
 
+// overflowPolicy 返回连接的溢出策略，业务单独配置的策略优先。
+func (m *ConnManager) overflowPolicy(user session.User) OverflowPolicy {
+	if policy, ok := m.cfg.BizOverflow[user.BID]; ok {
+		return policy
+	}
+	return m.cfg.OverflowPolicy
+}
+
 func (m *ConnManager) RemoveConn(user session.User) bool {
 	connKey := user.ConnKey()
 
@@ -282,12 +349,45 @@ func (m *ConnManager) FindUserConn(user session.User) ([]synp.Conn, bool) {
 	return dc.findAll()
 }
 
+func (m *ConnManager) RangeConn(fn func(conn synp.Conn) bool) {
+	m.conns.Range(func(_ string, dc *DeviceConns) bool {
+		conns, _ := dc.findAll()
+		for _, conn := range conns {
+			if !fn(conn) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
 func ConnManagerWithConfig(cfg *ConnConfig) option.Opt[ConnManager] {
 	return func(m *ConnManager) {
 		m.cfg = cfg
 	}
 }
 
+// ConnManagerWithSpillFunc 设置溢出策略为 OverflowSpillOffline 时保存消息的函数。
+func ConnManagerWithSpillFunc(fn SpillFunc) option.Opt[ConnManager] {
+	return func(m *ConnManager) {
+		m.spillFunc = fn
+	}
+}
+
+// ConnManagerWithExpireFunc 设置丢弃过期消息时的回调。
+func ConnManagerWithExpireFunc(fn ExpireFunc) option.Opt[ConnManager] {
+	return func(m *ConnManager) {
+		m.expireFunc = fn
+	}
+}
+
+// ConnManagerWithEventLoop 设置新连接使用事件驱动模式，见 EventLoop。
+func ConnManagerWithEventLoop(loop *EventLoop) option.Opt[ConnManager] {
+	return func(m *ConnManager) {
+		m.eventLoop = loop
+	}
+}
+
 func NewConnManager(logger *zap.Logger, opts ...option.Opt[ConnManager]) *ConnManager {
 	cfg := &ConnConfig{
 		ReadTimeout:       DefaultReadTiemout,
@@ -299,6 +399,9 @@ func NewConnManager(logger *zap.Logger, opts ...option.Opt[ConnManager]) *ConnMa
 		ReceiveBufferSize: DefaultReceiveBufferSize,
 		CloseTimeout:      DefaultCloseTimeout,
 		RateLimit:         DefaultRateLimit,
+		OverflowPolicy:    DefaultOverflowPolicy,
+		DisconnectAfter:   DefaultDisconnectAfter,
+		MaxMessageSize:    DefaultMaxMessageSize,
 	}
 
 	cm := &ConnManager{
@@ -1 +1,197 @@
 package conn
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jrmarcco/jit/bean/option"
+	"github.com/jrmarcco/synp"
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/jrmarcco/synp/internal/pkg/session"
+	sessionmock "github.com/jrmarcco/synp/internal/pkg/session/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func TestConn_SendFrame(t *testing.T) {
+	t.Parallel()
+
+	tcs := []struct {
+		name      string
+		policy    OverflowPolicy
+		spillFunc SpillFunc
+		wantQueue []string
+		wantSpill []string
+	}{
+		{
+			name:      "drop oldest",
+			policy:    OverflowDropOldest,
+			wantQueue: []string{"m3", "m4"},
+		}, {
+			name:      "drop newest",
+			policy:    OverflowDropNewest,
+			wantQueue: []string{"m1", "m2"},
+		}, {
+			name:      "coalesce",
+			policy:    OverflowCoalesce,
+			wantQueue: []string{"m1", "m2'"},
+		}, {
+			name:   "spill offline",
+			policy: OverflowSpillOffline,
+			spillFunc: func(_ synp.Conn, msg *messagev1.Message) error {
+				if msg.GetMessageId() == "m4" {
+					return errors.New("mock error")
+				}
+				return nil
+			},
+			wantQueue: []string{"m1", "m2"},
+			wantSpill: []string{"m2'", "m3", "m4"},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var spilled []string
+			c := newTestConn(t, ConnWithWriteBuffer(2), ConnWithOverflowPolicy(tc.policy, 0))
+			if tc.spillFunc != nil {
+				c.spillFunc = func(conn synp.Conn, msg *messagev1.Message) error {
+					spilled = append(spilled, string(msg.GetBody()))
+					return tc.spillFunc(conn, msg)
+				}
+			}
+
+			for _, payload := range []string{"m1", "m2", "m2'", "m3", "m4"} {
+				require.NoError(t, c.SendFrame(newTestFrame(payload)))
+			}
+			assert.Equal(t, tc.wantQueue, drain(c))
+			assert.Equal(t, tc.wantSpill, spilled)
+		})
+	}
+}
+
+func TestConn_SendFrameDisconnect(t *testing.T) {
+	t.Parallel()
+
+	c := newTestConn(t, ConnWithWriteBuffer(1), ConnWithOverflowPolicy(OverflowDisconnect, 20*time.Millisecond))
+	require.NoError(t, c.SendFrame(newTestFrame("m1")))
+
+	// 溢出未超过 disconnectAfter 时丢弃新消息。
+	require.NoError(t, c.SendFrame(newTestFrame("m2")))
+
+	// 入队成功后重新计算溢出时间。
+	assert.Equal(t, []string{"m1"}, drain(c))
+	require.NoError(t, c.SendFrame(newTestFrame("m3")))
+	require.NoError(t, c.SendFrame(newTestFrame("m4")))
+
+	time.Sleep(30 * time.Millisecond)
+	require.ErrorIs(t, c.SendFrame(newTestFrame("m5")), ErrConnClosed)
+	assert.Error(t, c.ctx.Err())
+}
+
+func TestConn_SendFrameTimeout(t *testing.T) {
+	t.Parallel()
+
+	c := newTestConn(
+		t,
+		ConnWithWriteBuffer(1),
+		ConnWithSendTimeout(time.Second),
+		ConnWithOverflowPolicy(OverflowDropNewest, 0),
+	)
+	require.NoError(t, c.SendFrame(newTestFrame("m1")))
+
+	// 等待期间有消息出队。
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		c.sendQueue.pop()
+	}()
+	require.NoError(t, c.SendFrame(newTestFrame("m2")))
+	assert.Equal(t, []string{"m2"}, drain(c))
+}
+
+func TestConn_SendFrameExpired(t *testing.T) {
+	t.Parallel()
+
+	var expired []string
+	c := newTestConn(t, ConnWithExpireFunc(func(_ synp.Conn, msg *messagev1.Message) {
+		expired = append(expired, msg.GetMessageId())
+	}))
+
+	// 已经过期的消息不会入队。
+	frame := newTestFrame("m1")
+	frame.ExpireAt = time.Now().Add(-time.Second)
+	require.NoError(t, c.SendFrame(frame))
+	assert.Zero(t, c.sendQueue.len())
+
+	// 在发送队列中过期的消息在发送前丢弃。
+	frame = newTestFrame("m2")
+	frame.ExpireAt = time.Now().Add(time.Minute)
+	require.NoError(t, c.SendFrame(frame))
+	require.NoError(t, c.SendFrame(newTestFrame("m3")))
+
+	now := time.Now().Add(time.Minute)
+	assert.True(t, c.dropExpired(c.sendQueue.pop(), now))
+	assert.False(t, c.dropExpired(c.sendQueue.pop(), now))
+	assert.Equal(t, []string{"m1", "m2"}, expired)
+}
+
+// newTestConn 创建不启动收发 goroutine 的连接。
+func newTestConn(t *testing.T, opts ...option.Opt[Conn]) *Conn {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	t.Cleanup(cancel)
+
+	c := &Conn{
+		id:             "test",
+		sess:           newTestSession(gomock.NewController(t)),
+		netConn:        &testNetConn{},
+		sendBufferSize: DefaultSendBufferSize,
+		sendWeights:    DefaultSendWeights,
+		overflowPolicy: DefaultOverflowPolicy,
+		ctx:            ctx,
+		cancelFunc:     cancel,
+		logger:         zap.NewNop(),
+	}
+	option.Apply(c, opts...)
+	c.sendQueue = newSendQueue(c.sendBufferSize, c.sendWeights)
+	return c
+}
+
+func newTestFrame(payload string) synp.Frame {
+	return synp.Frame{
+		Payload: []byte(payload),
+		Key:     payload[:2],
+		Message: &messagev1.Message{MessageId: payload, Body: []byte(payload)},
+	}
+}
+
+func drain(c *Conn) []string {
+	var payloads []string
+	for frame := c.sendQueue.pop(); frame != nil; frame = c.sendQueue.pop() {
+		payloads = append(payloads, string(frame.Payload))
+	}
+	return payloads
+}
+
+func newTestSession(ctrl *gomock.Controller) session.Session {
+	sess := sessionmock.NewMockSession(ctrl)
+	sess.EXPECT().User().Return(session.User{BID: 1, UID: 1}).AnyTimes()
+	sess.EXPECT().Destroy(gomock.Any()).Return(nil).AnyTimes()
+	return sess
+}
+
+// testNetConn 只支持关闭连接时用到的方法。
+type testNetConn struct {
+	net.Conn
+}
+
+func (c *testNetConn) Write(b []byte) (int, error)        { return len(b), nil }
+func (c *testNetConn) SetWriteDeadline(_ time.Time) error { return nil }
+func (c *testNetConn) Close() error                       { return nil }
@@ -0,0 +1,314 @@
+package conn
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/jrmarcco/jit/bean/option"
+	"github.com/jrmarcco/synp/internal/pkg/bufpool"
+	"github.com/jrmarcco/synp/internal/pkg/netpoll"
+	"github.com/jrmarcco/synp/internal/pkg/xws"
+	"go.uber.org/zap"
+)
+
+const (
+	// 默认每个 CPU 的读写 worker 数量，读写超时期间 worker 会被占用，所以需要多于 CPU 数量。
+	DefaultEventLoopWorkersPerCPU = 8
+	DefaultEventLoopQueueSize     = 4096
+
+	// readSlack 为事件驱动模式下限流允许的突发读取次数。
+	readSlack = 10
+)
+
+// EventLoop 为事件驱动模式下所有连接共享的事件循环 ( 仅支持 Linux )，连接不再需要常驻的收发 goroutine：
+//
+//  1. epoll 通知连接可读后，在读 worker 池中读取一帧，数据帧放入连接的接收队列，
+//     由连接的处理 goroutine 交给处理函数 ( 见 Conn.Serve )，处理函数阻塞不会占用读 worker；
+//  2. 消息入队后，在写 worker 池中发送连接发送队列中的消息，发送时使用池化的 xws.Writer，
+//     空闲连接不需要持有写缓冲区及压缩器。
+//
+// 读写在 worker 中同步完成，一帧没有读完 ( 或发送重试 ) 时会占用 worker 直到读写超时，
+// worker 数量需要按读写超时及并发量配置。
+// 接收队列已满或达到限流时不再注册可读事件 ( 由 TCP 流控传递给客户端 )，不会占用 worker 等待。
+type EventLoop struct {
+	poller  *netpoll.Poller
+	readers *netpoll.Pool
+	writers *netpoll.Pool
+
+	writerPool sync.Pool // *xws.Writer，压缩设置在 Reset 时指定
+}
+
+// Close 停止事件通知，等待已经提交的读写任务完成。
+// 应该在所有连接关闭后调用，之后仍然使用 EventLoop 的连接会被关闭。
+func (l *EventLoop) Close() error {
+	err := l.poller.Close()
+	l.readers.Close()
+	l.writers.Close()
+	return err
+}
+
+func (l *EventLoop) getWriter() *xws.Writer {
+	if w, ok := l.writerPool.Get().(*xws.Writer); ok {
+		return w
+	}
+	return xws.NewServerSideWriter(nil, nil)
+}
+
+func (l *EventLoop) putWriter(w *xws.Writer) {
+	// 不再引用连接。
+	w.Reset(nil, nil)
+	l.writerPool.Put(w)
+}
+
+// EventLoopConfig 为事件循环的配置，小于 1 的值使用默认值。
+type EventLoopConfig struct {
+	ReadWorkers  int
+	WriteWorkers int
+	QueueSize    int // 读写 worker 池等待执行的任务队列大小
+}
+
+// NewEventLoop 创建事件循环，当前平台不支持 epoll 时返回 netpoll.ErrNotSupported。
+func NewEventLoop(cfg EventLoopConfig) (*EventLoop, error) {
+	defaultWorkers := DefaultEventLoopWorkersPerCPU * runtime.GOMAXPROCS(0)
+	if cfg.ReadWorkers < 1 {
+		cfg.ReadWorkers = defaultWorkers
+	}
+	if cfg.WriteWorkers < 1 {
+		cfg.WriteWorkers = defaultWorkers
+	}
+	if cfg.QueueSize < 1 {
+		cfg.QueueSize = DefaultEventLoopQueueSize
+	}
+
+	poller, err := netpoll.New()
+	if err != nil {
+		return nil, err
+	}
+
+	return &EventLoop{
+		poller:  poller,
+		readers: netpoll.NewPool(cfg.ReadWorkers, cfg.QueueSize),
+		writers: netpoll.NewPool(cfg.WriteWorkers, cfg.QueueSize),
+	}, nil
+}
+
+// Serve 在事件驱动模式下注册连接的可读事件，收到的消息交给 fn 处理 ( payload 只在 fn 返回前有效 )，
+// 连接关闭后调用 onClose。fn 在连接的处理 goroutine 中按接收顺序串行调用，可以阻塞。
+// 连接没有使用事件驱动模式 ( 或连接不支持 epoll，如 TLS 连接 ) 时返回 false，调用方需要通过 Receive 读取消息。
+func (c *Conn) Serve(fn func(payload []byte), onClose func()) bool {
+	if c.eventLoop == nil {
+		return false
+	}
+
+	c.pollMu.Lock()
+	defer c.pollMu.Unlock()
+
+	// 持有 pollMu 时连接不会被关闭，避免注册已经关闭 ( 文件描述符可能已经被复用 ) 的连接。
+	if c.ctx.Err() == nil {
+		c.onReceive = fn
+		desc, err := c.eventLoop.poller.Add(c.netConn, c.onReadable)
+		if err != nil {
+			c.logger.Warn(
+				"[synp-conn] failed to register connection to event loop, fallback to receive goroutine",
+				zap.String("conn_id", c.id),
+				zap.Any("user", c.sess.User()),
+				zap.Error(err),
+			)
+			//nolint:contextcheck // receiveLoop 内部使用 c.ctx。
+			go c.receiveLoop()
+			return false
+		}
+		c.desc = desc
+	}
+
+	context.AfterFunc(c.ctx, func() {
+		close(c.receiveChan)
+		onClose()
+	})
+	return true
+}
+
+// onReadable 在 poller 的事件 goroutine 中执行，只负责提交读任务。
+func (c *Conn) onReadable(netpoll.Event) {
+	c.submitRead()
+}
+
+func (c *Conn) submitRead() {
+	if err := c.eventLoop.readers.Submit(c.handleRead); err != nil {
+		_ = c.Close()
+	}
+}
+
+// handleRead 读取一帧，数据帧放入接收队列，接收队列没有满时重新注册可读事件。
+// 读取失败 ( 包括对端关闭 ) 时关闭连接。
+func (c *Conn) handleRead() {
+	if c.ctx.Err() != nil {
+		return
+	}
+
+	if wait := c.reserveRead(time.Now()); wait > 0 {
+		// 达到限流，稍后重新提交读任务，不占用 worker 等待。
+		time.AfterFunc(wait, c.submitRead)
+		return
+	}
+
+	// 设置读超时，避免只收到部分帧时一直占用 worker。
+	_ = c.netConn.SetReadDeadline(time.Now().Add(c.readTimeout))
+
+	payload, err := c.reader.ReadFrame()
+	if err != nil && !c.readRetryable(err) {
+		_ = c.Close()
+		return
+	}
+
+	if payload != nil && !c.enqueueReceived(payload) {
+		// 接收队列已满，队列清空后由处理 goroutine 恢复读取。
+		return
+	}
+	c.resumeRead()
+}
+
+// reserveRead 按限流速率预留一次读取，返回需要等待的时间，为 0 时可以立即读取。
+// 与 ratelimit.Limiter 的默认设置一样，空闲后允许最多 readSlack 次的突发读取。
+func (c *Conn) reserveRead(now time.Time) time.Duration {
+	if c.limitRate <= 0 {
+		return 0
+	}
+	if now.Before(c.nextReadAt) {
+		return c.nextReadAt.Sub(now)
+	}
+
+	interval := time.Second / time.Duration(c.limitRate)
+	if earliest := now.Add(-readSlack * interval); c.nextReadAt.Before(earliest) {
+		c.nextReadAt = earliest
+	}
+	c.nextReadAt = c.nextReadAt.Add(interval)
+	return 0
+}
+
+// enqueueReceived 将消息放入接收队列，没有处理 goroutine 时启动一个。
+// 接收队列已满时暂停读取并返回 false。
+func (c *Conn) enqueueReceived(payload *bufpool.Buffer) bool {
+	c.inboxMu.Lock()
+	defer c.inboxMu.Unlock()
+
+	c.inbox = append(c.inbox, payload)
+	if !c.handling {
+		c.handling = true
+		go c.handleReceived()
+	}
+
+	if len(c.inbox) >= c.receiveSize {
+		c.readPaused = true
+		return false
+	}
+	return true
+}
+
+// handleReceived 依次处理接收队列中的消息 ( 连接关闭后只释放 )，队列为空时退出。
+// 读取因为接收队列已满而暂停时，队列清空后恢复读取。
+func (c *Conn) handleReceived() {
+	for {
+		c.inboxMu.Lock()
+		if len(c.inbox) == 0 {
+			// 释放队列占用的内存，空闲连接不需要持有。
+			c.inbox = nil
+			c.handling = false
+			paused := c.readPaused
+			c.readPaused = false
+			c.inboxMu.Unlock()
+
+			if paused {
+				c.resumeRead()
+			}
+			return
+		}
+		payload := c.inbox[0]
+		c.inbox[0] = nil
+		c.inbox = c.inbox[1:]
+		c.inboxMu.Unlock()
+
+		if c.ctx.Err() == nil {
+			c.onReceive(payload.Bytes())
+		}
+		payload.Release()
+	}
+}
+
+// resumeRead 重新注册可读事件，失败时关闭连接。
+func (c *Conn) resumeRead() {
+	if c.ctx.Err() != nil {
+		return
+	}
+
+	// 注册完成前 desc 还没有保存，需要等待 Serve 返回。
+	c.pollMu.Lock()
+	desc := c.desc
+	c.pollMu.Unlock()
+
+	if err := c.eventLoop.poller.Resume(desc); err != nil {
+		if c.ctx.Err() == nil && !errors.Is(err, netpoll.ErrClosed) {
+			c.logger.Error(
+				"[synp-conn] failed to resume connection in event loop",
+				zap.String("conn_id", c.id),
+				zap.Any("user", c.sess.User()),
+				zap.Error(err),
+			)
+		}
+		_ = c.Close()
+	}
+}
+
+// wakeWriter 在事件驱动模式下提交发送任务，每个连接同一时间最多只有一个发送任务。
+func (c *Conn) wakeWriter() {
+	if c.eventLoop == nil || c.sendQueue.len() == 0 || !c.flushing.CompareAndSwap(false, true) {
+		return
+	}
+
+	if err := c.eventLoop.writers.Submit(c.flush); err != nil {
+		c.flushing.Store(false)
+		_ = c.Close()
+	}
+}
+
+// flush 按调度规则发送队列中的所有消息，发送失败时关闭连接。
+func (c *Conn) flush() {
+	w := c.eventLoop.getWriter()
+	w.Reset(c.netConn, c.compressionState)
+	defer c.eventLoop.putWriter(w)
+
+	for {
+		frame := c.sendQueue.pop()
+		if frame == nil {
+			// 先清除标记再检查队列，避免与并发入队的 wakeWriter 错过消息。
+			c.flushing.Store(false)
+			if c.sendQueue.len() == 0 || !c.flushing.CompareAndSwap(false, true) {
+				return
+			}
+			continue
+		}
+
+		if c.dropExpired(frame, time.Now()) {
+			frame.Release()
+			continue
+		}
+
+		ok := c.trySend(w, frame.Bytes(), frame.Suffix)
+		frame.Release()
+		if !ok {
+			// 发送失败，关闭连接 ( 不清除标记，之后不会再提交发送任务 )。
+			_ = c.Close()
+			return
+		}
+	}
+}
+
+// ConnWithEventLoop 使用事件驱动模式，连接的收发由 loop 完成，见 EventLoop。
+func ConnWithEventLoop(loop *EventLoop) option.Opt[Conn] {
+	return func(c *Conn) {
+		c.eventLoop = loop
+	}
+}
@@ -0,0 +1,213 @@
+package conn
+
+import (
+	"errors"
+	"net"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsutil"
+	"github.com/jrmarcco/jit/bean/option"
+	"github.com/jrmarcco/synp/internal/pkg/netpoll"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func TestConn_Serve(t *testing.T) {
+	t.Parallel()
+
+	loop := newTestEventLoop(t, EventLoopConfig{})
+	client, server := tcpPair(t)
+
+	c := NewConn(t.Context(), "test", newTestSession(gomock.NewController(t)), server, zap.NewNop(), ConnWithEventLoop(loop))
+
+	received := make(chan string, 1)
+	closed := make(chan struct{})
+	require.True(t, c.Serve(func(payload []byte) {
+		received <- string(payload)
+	}, func() {
+		close(closed)
+	}))
+
+	// 只收到控制帧时不会阻塞 worker。
+	require.NoError(t, wsutil.WriteClientMessage(client, ws.OpPing, []byte("ping")))
+	frame, err := ws.ReadFrame(client)
+	require.NoError(t, err)
+	assert.Equal(t, ws.OpPong, frame.Header.OpCode)
+	assert.Equal(t, "ping", string(frame.Payload))
+
+	require.NoError(t, wsutil.WriteClientBinary(client, []byte("m1")))
+	assert.Equal(t, "m1", <-received)
+
+	// 消息由写 worker 发送。
+	require.NoError(t, c.Send([]byte("m2")))
+	payload, err := wsutil.ReadServerBinary(client)
+	require.NoError(t, err)
+	assert.Equal(t, "m2", string(payload))
+
+	// 对端关闭后关闭连接。
+	require.NoError(t, client.Close())
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		require.Fail(t, "connection not closed")
+	}
+	require.ErrorIs(t, c.Send([]byte("m3")), ErrConnClosed)
+
+	_, ok := <-c.Receive()
+	assert.False(t, ok)
+}
+
+func TestConn_ServeIsolation(t *testing.T) {
+	t.Parallel()
+
+	// 只有一个读 worker，处理函数阻塞或限流等待时占用 worker 会导致其他连接无法读取。
+	loop := newTestEventLoop(t, EventLoopConfig{ReadWorkers: 1})
+	ctrl := gomock.NewController(t)
+
+	serve := func(id string, handle func(payload []byte), opts ...option.Opt[Conn]) net.Conn {
+		client, server := tcpPair(t)
+		opts = append(opts, ConnWithEventLoop(loop))
+		c := NewConn(t.Context(), id, newTestSession(ctrl), server, zap.NewNop(), opts...)
+		require.True(t, c.Serve(handle, func() {}))
+		return client
+	}
+	write := func(client net.Conn, prefix string, n int) {
+		for i := range n {
+			require.NoError(t, wsutil.WriteClientBinary(client, []byte(prefix+strconv.Itoa(i))))
+		}
+	}
+	receive := func(ch <-chan string) string {
+		select {
+		case payload := <-ch:
+			return payload
+		case <-time.After(time.Second):
+			require.Fail(t, "message not received")
+			return ""
+		}
+	}
+
+	// 连接 a 的处理函数阻塞，接收队列满后暂停读取。
+	unblock := make(chan struct{})
+	release := sync.OnceFunc(func() { close(unblock) })
+	t.Cleanup(release)
+	receivedA := make(chan string, 5)
+	clientA := serve("a", func(payload []byte) {
+		<-unblock
+		receivedA <- string(payload)
+	}, ConnWithReadBuffer(2))
+	write(clientA, "a", 5)
+
+	// 连接 b 达到限流。
+	receivedB := make(chan string, readSlack+5)
+	clientB := serve("b", func(payload []byte) {
+		receivedB <- string(payload)
+	}, ConnWithRateLimit(1))
+	write(clientB, "b", readSlack+5)
+
+	// 连接 c 的消息不受影响。
+	receivedC := make(chan string, 1)
+	clientC := serve("c", func(payload []byte) {
+		receivedC <- string(payload)
+	})
+	write(clientC, "c", 1)
+	assert.Equal(t, "c0", receive(receivedC))
+
+	// 处理函数恢复后按顺序处理连接 a 的所有消息。
+	release()
+	for i := range 5 {
+		assert.Equal(t, "a"+strconv.Itoa(i), receive(receivedA))
+	}
+	for i := range readSlack {
+		assert.Equal(t, "b"+strconv.Itoa(i), receive(receivedB))
+	}
+}
+
+// BenchmarkIdleConn 对比空闲连接 ( 完成 upgrade 后没有收发消息 ) 的内存占用 ( 包括 goroutine 栈 )。
+func BenchmarkIdleConn(b *testing.B) {
+	const connCount = 1000
+
+	loop := newTestEventLoop(b, EventLoopConfig{})
+
+	for _, bc := range []struct {
+		name string
+		opts []option.Opt[Conn]
+	}{
+		{name: "goroutine"},
+		{name: "event_loop", opts: []option.Opt[Conn]{ConnWithEventLoop(loop)}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			var bytesPerConn float64
+			for b.Loop() {
+				servers := make([]net.Conn, connCount)
+				for i := range servers {
+					_, servers[i] = tcpPair(b)
+				}
+
+				sess := newTestSession(gomock.NewController(b))
+				before := memInUse()
+				conns := make([]*Conn, connCount)
+				for i, server := range servers {
+					conns[i] = NewConn(b.Context(), strconv.Itoa(i), sess, server, zap.NewNop(), bc.opts...)
+					conns[i].Serve(func([]byte) {}, func() {})
+				}
+				bytesPerConn = float64(memInUse()-before) / connCount
+
+				for _, c := range conns {
+					_ = c.Close()
+				}
+			}
+			b.ReportMetric(bytesPerConn, "B/conn")
+		})
+	}
+}
+
+func memInUse() uint64 {
+	// 等待连接的 goroutine 启动。
+	time.Sleep(100 * time.Millisecond)
+	runtime.GC()
+
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapInuse + stats.StackInuse
+}
+
+func newTestEventLoop(tb testing.TB, cfg EventLoopConfig) *EventLoop {
+	tb.Helper()
+
+	loop, err := NewEventLoop(cfg)
+	if errors.Is(err, netpoll.ErrNotSupported) {
+		tb.Skip("event loop is not supported on this platform")
+	}
+	require.NoError(tb, err)
+
+	tb.Cleanup(func() {
+		_ = loop.Close()
+	})
+	return loop
+}
+
+func tcpPair(tb testing.TB) (net.Conn, net.Conn) {
+	tb.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(tb, err)
+	defer ln.Close()
+
+	client, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(tb, err)
+	server, err := ln.Accept()
+	require.NoError(tb, err)
+
+	tb.Cleanup(func() {
+		_ = client.Close()
+		_ = server.Close()
+	})
+	return client, server
+}
@@ -0,0 +1,44 @@
+package conn
+
+import (
+	"time"
+
+	"github.com/jrmarcco/synp"
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// ExpireFunc 在丢弃发送队列中已经过期的消息时调用 ( 如停止重传、发布过期回执 )。
+type ExpireFunc func(conn synp.Conn, msg *messagev1.Message)
+
+var expiredTotal = promauto.NewCounter(
+	prometheus.CounterOpts{
+		Namespace: "synp",
+		Subsystem: "conn",
+		Name:      "send_expired_total",
+		Help:      "Number of frames dropped because they expired before being written to the connection.",
+	},
+)
+
+// dropExpired 丢弃已经过期的消息，返回消息是否已经过期。
+func (c *Conn) dropExpired(frame *synp.Frame, now time.Time) bool {
+	if frame.ExpireAt.IsZero() || now.Before(frame.ExpireAt) {
+		return false
+	}
+
+	expiredTotal.Inc()
+	c.logger.Debug(
+		"[synp-conn] drop expired frame",
+		zap.String("conn_id", c.id),
+		zap.String("key", frame.Key),
+		zap.Time("expire_at", frame.ExpireAt),
+		zap.Any("user", c.sess.User()),
+	)
+
+	if c.expireFunc != nil && frame.Message != nil {
+		c.expireFunc(c, frame.Message)
+	}
+	return true
+}
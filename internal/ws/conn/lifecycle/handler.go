@@ -2,18 +2,23 @@ package lifecycle
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
 
+	"github.com/jrmarcco/jit/bean/option"
 	"github.com/jrmarcco/synp"
 	commonv1 "github.com/jrmarcco/synp-api/api/go/common/v1"
 	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/jrmarcco/synp/internal/pkg/chunk"
 	"github.com/jrmarcco/synp/internal/pkg/codec"
+	"github.com/jrmarcco/synp/internal/pkg/dedup"
+	"github.com/jrmarcco/synp/internal/pkg/message"
 	"github.com/jrmarcco/synp/internal/pkg/message/downstream"
 	"github.com/jrmarcco/synp/internal/pkg/message/upstream"
-	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
 )
 
 var (
@@ -29,16 +34,19 @@ var _ synp.Handler = (*Handler)(nil)
 
 // Handler 是连接事件的处理器。
 type Handler struct {
-	rdb redis.Cmdable
+	deduper dedup.Deduper
 
 	cacheRequestTimeout time.Duration
-	cacheExpiration     time.Duration
 
 	codec codec.Codec
 
 	uMsgHandlers map[commonv1.CommandType]upstream.UMsgHandler
 	dMsgHandler  downstream.DMsgHandler
 
+	// 分片传输，为 nil 时不支持分片消息。
+	assembler *chunk.Assembler
+	pushFunc  message.PushFunc
+
 	logger *zap.Logger
 }
 
@@ -55,6 +63,14 @@ func (h *Handler) OnDisconnect(conn synp.Conn) error {
 		"[synp-conn-lifecycle-handler] connection disconnected",
 		zap.String("conn_id", conn.ID()),
 	)
+	if h.assembler != nil {
+		h.assembler.ClearByConn(conn.ID())
+	}
+	for _, uMsgHandler := range h.uMsgHandlers {
+		if cleaner, ok := uMsgHandler.(upstream.ConnCleaner); ok {
+			cleaner.ClearByConn(conn)
+		}
+	}
 	return conn.Close()
 }
 
@@ -71,9 +87,15 @@ func (h *Handler) OnReceiveFromFrontend(conn synp.Conn, payload []byte) error {
 		return err
 	}
 
+	// 还原分片消息，收到所有分片之前不处理。
+	if msg.GetCmd() == message.CommandTypeChunk {
+		if msg, err = h.assemble(conn, msg); err != nil || msg == nil {
+			return err
+		}
+	}
+
 	// 消息去重（幂等）。
-	user := conn.Session().User()
-	ok, err := h.cacheMessage(user.BID, msg)
+	ok, err := h.cacheMessage(conn, msg)
 	if err != nil {
 		h.logger.Error(
 			"[synp-conn-lifecycle-handler] failed to cache message",
@@ -115,7 +137,7 @@ func (h *Handler) OnReceiveFromFrontend(conn synp.Conn, payload []byte) error {
 	if err = uMsgHandler.Handle(conn, msg); err != nil {
 		// 删除消息缓存。
 		if h.needUncacheMessage(err) {
-			uncacheErr := h.uncacheMessage(user.BID, msg)
+			uncacheErr := h.uncacheMessage(conn, msg)
 			if uncacheErr != nil {
 				h.logger.Error(
 					"[synp-conn-lifecycle-handler] failed to uncache message",
@@ -139,48 +161,95 @@ func (h *Handler) decodePayload(payload []byte) (*messagev1.Message, error) {
 		return nil, fmt.Errorf("%w: unknown message type", ErrInvalidMessage)
 	}
 
-	if msg.GetCmd() != commonv1.CommandType_COMMAND_TYPE_HEARTBEAT && msg.GetMessageId() == "" {
-		// 非心跳消息，message_id 不能为空。
+	if message.NeedDedup(msg.GetCmd()) && msg.GetMessageId() == "" {
+		// 需要去重的消息，message_id 不能为空。
 		return nil, fmt.Errorf("%w: empty message_id", ErrInvalidMessage)
 	}
 
 	return msg, nil
 }
 
-func (h *Handler) cacheMessage(bizID uint64, msg *messagev1.Message) (bool, error) {
-	if msg.GetCmd() == commonv1.CommandType_COMMAND_TYPE_HEARTBEAT {
+// assemble 添加分片并回复分片的 ack，收到所有分片后返回还原的原消息，否则返回 nil。
+func (h *Handler) assemble(conn synp.Conn, msg *messagev1.Message) (*messagev1.Message, error) {
+	if h.assembler == nil {
+		return nil, ErrUnknownMessageType
+	}
+
+	c := &chunk.Chunk{}
+	var assembled *messagev1.Message
+	err := json.Unmarshal(msg.GetBody(), c)
+	if err != nil {
+		err = fmt.Errorf("%w: %w", chunk.ErrInvalidChunk, err)
+	} else {
+		assembled, err = h.assembler.Add(conn.ID(), c)
+	}
+
+	ackPayload := &messagev1.AckPayload{
+		Success:   err == nil,
+		Timestamp: time.Now().UnixMilli(),
+	}
+	if err != nil {
+		h.logger.Warn(
+			"[synp-conn-lifecycle-handler] failed to add chunk",
+			zap.String("conn_id", conn.ID()),
+			zap.String("message_id", msg.GetMessageId()),
+			zap.String("upload_id", c.UploadID),
+			zap.Int64("offset", c.Offset),
+			zap.Error(err),
+		)
+		ackPayload.ErrorMessage = err.Error()
+	}
+
+	body, marshalErr := protojson.Marshal(ackPayload)
+	if marshalErr != nil {
+		return nil, fmt.Errorf("failed to marshal ack payload: %w", marshalErr)
+	}
+	if pushErr := h.pushFunc(conn, &messagev1.Message{
+		MessageId: msg.GetMessageId(),
+		Cmd:       commonv1.CommandType_COMMAND_TYPE_UPSTREAM_ACK,
+		Body:      body,
+	}); pushErr != nil {
+		return nil, pushErr
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidMessage, err)
+	}
+	return assembled, nil
+}
+
+func (h *Handler) cacheMessage(conn synp.Conn, msg *messagev1.Message) (bool, error) {
+	if !message.NeedDedup(msg.GetCmd()) {
 		return true, nil
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), h.cacheRequestTimeout)
 	defer cancel()
 
-	return h.rdb.SetNX(ctx, h.cacheKey(bizID, msg.GetMessageId()), msg.GetMessageId(), h.cacheExpiration).Result()
+	return h.deduper.Mark(ctx, dedup.NewKey(conn, msg.GetMessageId()))
 }
 
 func (h *Handler) needUncacheMessage(err error) bool {
-	return errors.Is(err, ErrUnknownMessageType) || errors.Is(err, ErrMaxRetryExceeded)
+	return errors.Is(err, ErrUnknownMessageType) ||
+		errors.Is(err, ErrMaxRetryExceeded) ||
+		errors.Is(err, synp.ErrRateLimited)
 }
 
-func (h *Handler) uncacheMessage(bizID uint64, msg *messagev1.Message) error {
-	if msg.GetCmd() == commonv1.CommandType_COMMAND_TYPE_HEARTBEAT {
+func (h *Handler) uncacheMessage(conn synp.Conn, msg *messagev1.Message) error {
+	if !message.NeedDedup(msg.GetCmd()) {
 		return nil
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), h.cacheRequestTimeout)
 	defer cancel()
 
-	if _, err := h.rdb.Del(ctx, h.cacheKey(bizID, msg.MessageId)).Result(); err != nil {
+	if err := h.deduper.Unmark(ctx, dedup.NewKey(conn, msg.GetMessageId())); err != nil {
 		return fmt.Errorf("%w: %w", ErrUncacheMessage, err)
 	}
 
 	return nil
 }
 
-func (h *Handler) cacheKey(bizID uint64, messageID string) string {
-	return fmt.Sprintf("%d:%s", bizID, messageID)
-}
-
 func (h *Handler) OnReceiveFromBackend(conns []synp.Conn, msg *messagev1.PushMessage) error {
 	if msg.GetMessageId() == "" {
 		return fmt.Errorf("%w: empty message_id", ErrInvalidMessage)
@@ -196,26 +265,36 @@ func (h *Handler) OnReceiveFromBackend(conns []synp.Conn, msg *messagev1.PushMes
 }
 
 func NewHandler(
-	rdb redis.Cmdable,
+	deduper dedup.Deduper,
 	cacheRequestTimeout time.Duration,
-	cacheExpiration time.Duration,
 	codec codec.Codec,
 	uMsgHandlers []upstream.UMsgHandler,
 	dMsgHandler downstream.DMsgHandler,
 	logger *zap.Logger,
+	opts ...option.Opt[Handler],
 ) *Handler {
 	m := make(map[commonv1.CommandType]upstream.UMsgHandler)
 	for _, handler := range uMsgHandlers {
 		m[handler.CmdType()] = handler
 	}
 
-	return &Handler{
-		rdb:                 rdb,
+	h := &Handler{
+		deduper:             deduper,
 		cacheRequestTimeout: cacheRequestTimeout,
-		cacheExpiration:     cacheExpiration,
 		codec:               codec,
 		uMsgHandlers:        m,
 		dMsgHandler:         dMsgHandler,
 		logger:              logger,
 	}
+
+	option.Apply(h, opts...)
+	return h
+}
+
+// HandlerWithChunkAssembler 开启分片传输，pushFunc 用于回复分片的 ack。
+func HandlerWithChunkAssembler(assembler *chunk.Assembler, pushFunc message.PushFunc) option.Opt[Handler] {
+	return func(h *Handler) {
+		h.assembler = assembler
+		h.pushFunc = pushFunc
+	}
 }
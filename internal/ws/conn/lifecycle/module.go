@@ -3,11 +3,14 @@ package lifecycle
 import (
 	"time"
 
+	"github.com/jrmarcco/jit/bean/option"
 	"github.com/jrmarcco/synp"
+	"github.com/jrmarcco/synp/internal/pkg/chunk"
 	"github.com/jrmarcco/synp/internal/pkg/codec"
+	"github.com/jrmarcco/synp/internal/pkg/dedup"
+	"github.com/jrmarcco/synp/internal/pkg/message"
 	"github.com/jrmarcco/synp/internal/pkg/message/downstream"
 	"github.com/jrmarcco/synp/internal/pkg/message/upstream"
-	"github.com/redis/go-redis/v9"
 	"github.com/spf13/viper"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
@@ -16,29 +19,48 @@ import (
 var ConnLcHandlerFxModule = fx.Module(
 	"ws-conn-lifecycle-handler",
 	fx.Provide(
+		newConnLcHandler,
+		NewPresenceHandler,
+		NewRPCHandler,
+		NewOfflineHandler,
+		NewRetransmitHandler,
 		fx.Annotate(
-			newConnLcHandler,
+			newHandlerWrapper,
 			fx.As(new(synp.Handler)),
 		),
 	),
 )
 
+// newHandlerWrapper 组合所有连接事件处理器。
+func newHandlerWrapper(
+	handler *Handler,
+	presenceHandler *PresenceHandler,
+	rpcHandler *RPCHandler,
+	offlineHandler *OfflineHandler,
+	retransmitHandler *RetransmitHandler,
+) *synp.HandlerWrapper {
+	return synp.NewHandlerWrapper(handler, presenceHandler, rpcHandler, offlineHandler, retransmitHandler)
+}
+
 type connHandlerFxParams struct {
 	fx.In
 
-	Rdb   redis.Cmdable
-	Codec codec.Codec
+	Deduper dedup.Deduper
+	Codec   codec.Codec
 
 	UMsgHandlers []upstream.UMsgHandler `group:"upstream-message-handler"`
 	DMsgHandler  downstream.DMsgHandler
 
+	// 没有开启上行分片时为 nil。
+	Assembler *chunk.Assembler
+	PushFunc  message.PushFunc
+
 	Logger *zap.Logger
 }
 
 func newConnLcHandler(params connHandlerFxParams) (*Handler, error) {
 	type config struct {
 		CacheRequestTimeout time.Duration `mapstructure:"cache_request_timeout"`
-		CacheExpiration     time.Duration `mapstructure:"cache_expiration"`
 	}
 
 	cfg := config{}
@@ -46,13 +68,18 @@ func newConnLcHandler(params connHandlerFxParams) (*Handler, error) {
 		return nil, err
 	}
 
+	var opts []option.Opt[Handler]
+	if params.Assembler != nil {
+		opts = append(opts, HandlerWithChunkAssembler(params.Assembler, params.PushFunc))
+	}
+
 	return NewHandler(
-		params.Rdb,
+		params.Deduper,
 		cfg.CacheRequestTimeout,
-		cfg.CacheExpiration,
 		params.Codec,
 		params.UMsgHandlers,
 		params.DMsgHandler,
 		params.Logger,
+		opts...,
 	), nil
 }
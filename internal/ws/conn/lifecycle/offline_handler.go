@@ -0,0 +1,121 @@
+package lifecycle
+
+import (
+	"context"
+	"time"
+
+	"github.com/jrmarcco/synp"
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/jrmarcco/synp/internal/pkg/message/downstream"
+	"github.com/jrmarcco/synp/internal/pkg/offline"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultOfflineReplayBatchSize = 100
+	DefaultOfflineRequestTimeout  = 3 * time.Second
+)
+
+var _ synp.Handler = (*OfflineHandler)(nil)
+
+// OfflineHandler 在连接建立后重新推送用户的离线消息。
+// 需要通过 synp.HandlerWrapper 与 Handler 组合使用。
+//
+// 注：
+//
+//	离线消息在后台推送，不阻塞连接建立。
+//	推送失败 ( 如连接已经断开 ) 时剩余的消息会被重新保存，等待下次连接。
+type OfflineHandler struct {
+	store       offline.Store
+	dMsgHandler downstream.DMsgHandler
+
+	batchSize      int
+	requestTimeout time.Duration
+
+	logger *zap.Logger
+}
+
+func (h *OfflineHandler) OnConnect(conn synp.Conn) error {
+	go h.replay(conn)
+	return nil
+}
+
+func (h *OfflineHandler) replay(conn synp.Conn) {
+	user := conn.Session().User()
+
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), h.requestTimeout)
+		msgs, err := h.store.Take(ctx, user.BID, user.UID, h.batchSize)
+		cancel()
+		if err != nil {
+			h.logger.Error(
+				"[synp-conn-offline-handler] failed to take offline messages",
+				zap.String("conn_id", conn.ID()),
+				zap.Error(err),
+			)
+			return
+		}
+
+		for i, msg := range msgs {
+			if err := h.dMsgHandler.Handle([]synp.Conn{conn}, msg); err != nil {
+				h.logger.Warn(
+					"[synp-conn-offline-handler] failed to replay offline message",
+					zap.String("conn_id", conn.ID()),
+					zap.String("message_id", msg.GetMessageId()),
+					zap.Error(err),
+				)
+				h.restore(msgs[i:])
+				return
+			}
+		}
+
+		if len(msgs) < h.batchSize {
+			return
+		}
+
+		select {
+		case <-conn.Closed():
+			return
+		default:
+		}
+	}
+}
+
+// restore 重新保存没有推送成功的离线消息。
+func (h *OfflineHandler) restore(msgs []*messagev1.PushMessage) {
+	ctx, cancel := context.WithTimeout(context.Background(), h.requestTimeout)
+	defer cancel()
+
+	for _, msg := range msgs {
+		if err := h.store.Save(ctx, msg); err != nil {
+			h.logger.Error(
+				"[synp-conn-offline-handler] failed to restore offline message",
+				zap.String("message_id", msg.GetMessageId()),
+				zap.Error(err),
+			)
+			return
+		}
+	}
+}
+
+func (h *OfflineHandler) OnDisconnect(_ synp.Conn) error {
+	return nil
+}
+
+func (h *OfflineHandler) OnReceiveFromFrontend(_ synp.Conn, _ []byte) error {
+	return nil
+}
+
+func (h *OfflineHandler) OnReceiveFromBackend(_ []synp.Conn, _ *messagev1.PushMessage) error {
+	return nil
+}
+
+func NewOfflineHandler(store offline.Store, dMsgHandler downstream.DMsgHandler, logger *zap.Logger) *OfflineHandler {
+	return &OfflineHandler{
+		store:          store,
+		dMsgHandler:    dMsgHandler,
+		batchSize:      DefaultOfflineReplayBatchSize,
+		requestTimeout: DefaultOfflineRequestTimeout,
+		logger:         logger,
+	}
+}
@@ -0,0 +1,71 @@
+package lifecycle
+
+import (
+	"github.com/jrmarcco/synp"
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/jrmarcco/synp/internal/pkg/presence"
+	"go.uber.org/zap"
+)
+
+var _ synp.Handler = (*PresenceHandler)(nil)
+
+// PresenceHandler 是维护用户在线状态及在线状态订阅的连接事件处理器。
+// 需要通过 synp.HandlerWrapper 与 Handler 组合使用。
+//
+// 注：
+//
+//	在线状态属于旁路功能，处理失败只记录日志，不影响连接本身。
+type PresenceHandler struct {
+	tracker *presence.Tracker
+	hub     *presence.Hub
+
+	logger *zap.Logger
+}
+
+func (h *PresenceHandler) OnConnect(conn synp.Conn) error {
+	if err := h.tracker.Connect(conn); err != nil {
+		h.logger.Error(
+			"[synp-conn-presence-handler] failed to track connection online",
+			zap.String("conn_id", conn.ID()),
+			zap.Error(err),
+		)
+	}
+	return nil
+}
+
+func (h *PresenceHandler) OnDisconnect(conn synp.Conn) error {
+	h.hub.Unsubscribe(conn)
+
+	if err := h.tracker.Disconnect(conn); err != nil {
+		h.logger.Error(
+			"[synp-conn-presence-handler] failed to track connection offline",
+			zap.String("conn_id", conn.ID()),
+			zap.Error(err),
+		)
+	}
+	return nil
+}
+
+func (h *PresenceHandler) OnReceiveFromFrontend(conn synp.Conn, _ []byte) error {
+	// 心跳及其他前端消息都代表连接存活，为在线状态续期。
+	if err := h.tracker.Refresh(conn); err != nil {
+		h.logger.Warn(
+			"[synp-conn-presence-handler] failed to refresh presence",
+			zap.String("conn_id", conn.ID()),
+			zap.Error(err),
+		)
+	}
+	return nil
+}
+
+func (h *PresenceHandler) OnReceiveFromBackend(_ []synp.Conn, _ *messagev1.PushMessage) error {
+	return nil
+}
+
+func NewPresenceHandler(tracker *presence.Tracker, hub *presence.Hub, logger *zap.Logger) *PresenceHandler {
+	return &PresenceHandler{
+		tracker: tracker,
+		hub:     hub,
+		logger:  logger,
+	}
+}
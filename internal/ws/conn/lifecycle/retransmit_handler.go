@@ -0,0 +1,44 @@
+package lifecycle
+
+import (
+	"github.com/jrmarcco/synp"
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/jrmarcco/synp/internal/pkg/retransmit"
+)
+
+var _ synp.Handler = (*RetransmitHandler)(nil)
+
+// RetransmitHandler 在连接建立时恢复等待 ack 的消息的重传 ( 包括在其他节点上没有确认的消息 )，
+// 在连接断开时停止连接上的重传任务 ( 保留重传状态 )。
+// 需要通过 synp.HandlerWrapper 与 Handler 组合使用。
+//
+// 注：
+//
+//	重传状态在后台恢复，不阻塞连接建立。
+type RetransmitHandler struct {
+	manager *retransmit.Manager
+}
+
+func (h *RetransmitHandler) OnConnect(conn synp.Conn) error {
+	go h.manager.Resume(conn)
+	return nil
+}
+
+func (h *RetransmitHandler) OnDisconnect(conn synp.Conn) error {
+	h.manager.ClearByConn(conn)
+	return nil
+}
+
+func (h *RetransmitHandler) OnReceiveFromFrontend(_ synp.Conn, _ []byte) error {
+	return nil
+}
+
+func (h *RetransmitHandler) OnReceiveFromBackend(_ []synp.Conn, _ *messagev1.PushMessage) error {
+	return nil
+}
+
+func NewRetransmitHandler(manager *retransmit.Manager) *RetransmitHandler {
+	return &RetransmitHandler{
+		manager: manager,
+	}
+}
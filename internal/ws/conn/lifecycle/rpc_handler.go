@@ -0,0 +1,38 @@
+package lifecycle
+
+import (
+	"github.com/jrmarcco/synp"
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/jrmarcco/synp/internal/pkg/rpc"
+)
+
+var _ synp.Handler = (*RPCHandler)(nil)
+
+// RPCHandler 在连接断开时取消连接上所有未完成的 RPC 调用。
+// 需要通过 synp.HandlerWrapper 与 Handler 组合使用。
+type RPCHandler struct {
+	manager *rpc.Manager
+}
+
+func (h *RPCHandler) OnConnect(_ synp.Conn) error {
+	return nil
+}
+
+func (h *RPCHandler) OnDisconnect(conn synp.Conn) error {
+	h.manager.CancelConn(conn)
+	return nil
+}
+
+func (h *RPCHandler) OnReceiveFromFrontend(_ synp.Conn, _ []byte) error {
+	return nil
+}
+
+func (h *RPCHandler) OnReceiveFromBackend(_ []synp.Conn, _ *messagev1.PushMessage) error {
+	return nil
+}
+
+func NewRPCHandler(manager *rpc.Manager) *RPCHandler {
+	return &RPCHandler{
+		manager: manager,
+	}
+}
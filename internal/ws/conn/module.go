@@ -1,9 +1,20 @@
 package conn
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"time"
 
+	"github.com/jrmarcco/jit/bean/option"
 	"github.com/jrmarcco/synp"
+	commonv1 "github.com/jrmarcco/synp-api/api/go/common/v1"
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/jrmarcco/synp/internal/pkg/message"
+	"github.com/jrmarcco/synp/internal/pkg/netpoll"
+	"github.com/jrmarcco/synp/internal/pkg/offline"
+	"github.com/jrmarcco/synp/internal/pkg/receipt"
+	"github.com/jrmarcco/synp/internal/pkg/retransmit"
 	"github.com/spf13/viper"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
@@ -19,7 +30,13 @@ var ConnManagerFxModule = fx.Module(
 	),
 )
 
-func newConnManager(zapLogger *zap.Logger) (*ConnManager, error) {
+func newConnManager(
+	offlineStore offline.Store,
+	retransmitManager *retransmit.Manager,
+	reporter *receipt.Reporter,
+	zapLogger *zap.Logger,
+	lifecycle fx.Lifecycle,
+) (*ConnManager, error) {
 	type config = struct {
 		ReadTimeout  time.Duration `mapstructure:"read_timeout"`
 		WriteTimeout time.Duration `mapstructure:"write_timeout"`
@@ -31,8 +48,32 @@ func newConnManager(zapLogger *zap.Logger) (*ConnManager, error) {
 		SendBufferSize    int `mapstructure:"send_buffer_size"`
 		ReceiveBufferSize int `mapstructure:"receive_buffer_size"`
 
-		CloseTimeout time.Duration `mapstructure:"close_timeout"`
-		RateLimit    int           `mapstructure:"rate_limit"`
+		SendWeights struct {
+			Ack    int `mapstructure:"ack"`
+			High   int `mapstructure:"high"`
+			Normal int `mapstructure:"normal"`
+		} `mapstructure:"send_weights"`
+
+		SendTimeout time.Duration `mapstructure:"send_timeout"`
+		Overflow    struct {
+			Policy          string        `mapstructure:"policy"`
+			DisconnectAfter time.Duration `mapstructure:"disconnect_after"`
+			BizPolicies     []struct {
+				BID    uint64 `mapstructure:"bid"`
+				Policy string `mapstructure:"policy"`
+			} `mapstructure:"biz_policies"`
+		} `mapstructure:"overflow"`
+
+		CloseTimeout   time.Duration `mapstructure:"close_timeout"`
+		RateLimit      int           `mapstructure:"rate_limit"`
+		MaxMessageSize int64         `mapstructure:"max_message_size"`
+
+		EventLoop struct {
+			Enabled      bool `mapstructure:"enabled"`
+			ReadWorkers  int  `mapstructure:"read_workers"`
+			WriteWorkers int  `mapstructure:"write_workers"`
+			QueueSize    int  `mapstructure:"queue_size"`
+		} `mapstructure:"event_loop"`
 	}
 
 	cfg := config{}
@@ -40,15 +81,101 @@ func newConnManager(zapLogger *zap.Logger) (*ConnManager, error) {
 		return nil, err
 	}
 
-	return NewConnManager(zapLogger, ConnManagerWithConfig(&ConnConfig{
-		ReadTimeout:       cfg.ReadTimeout,
-		WriteTimeout:      cfg.WriteTimeout,
-		InitRetryInterval: cfg.InitRetryInterval,
-		MaxRetryInterval:  cfg.MaxRetryInterval,
-		MaxRetryCount:     cfg.MaxRetryCount,
-		SendBufferSize:    cfg.SendBufferSize,
-		ReceiveBufferSize: cfg.ReceiveBufferSize,
-		CloseTimeout:      cfg.CloseTimeout,
-		RateLimit:         cfg.RateLimit,
-	})), nil
+	policy, err := ParseOverflowPolicy(cfg.Overflow.Policy)
+	if err != nil {
+		return nil, err
+	}
+	bizOverflow := make(map[uint64]OverflowPolicy, len(cfg.Overflow.BizPolicies))
+	for _, biz := range cfg.Overflow.BizPolicies {
+		if bizOverflow[biz.BID], err = ParseOverflowPolicy(biz.Policy); err != nil {
+			return nil, fmt.Errorf("biz %d: %w", biz.BID, err)
+		}
+	}
+
+	opts := []option.Opt[ConnManager]{
+		ConnManagerWithConfig(&ConnConfig{
+			ReadTimeout:       cfg.ReadTimeout,
+			WriteTimeout:      cfg.WriteTimeout,
+			InitRetryInterval: cfg.InitRetryInterval,
+			MaxRetryInterval:  cfg.MaxRetryInterval,
+			MaxRetryCount:     cfg.MaxRetryCount,
+			SendBufferSize:    cfg.SendBufferSize,
+			ReceiveBufferSize: cfg.ReceiveBufferSize,
+			AckSendWeight:     cfg.SendWeights.Ack,
+			HighSendWeight:    cfg.SendWeights.High,
+			NormalSendWeight:  cfg.SendWeights.Normal,
+			SendTimeout:       cfg.SendTimeout,
+			OverflowPolicy:    policy,
+			BizOverflow:       bizOverflow,
+			DisconnectAfter:   cfg.Overflow.DisconnectAfter,
+			CloseTimeout:      cfg.CloseTimeout,
+			RateLimit:         cfg.RateLimit,
+			MaxMessageSize:    cfg.MaxMessageSize,
+		}),
+		ConnManagerWithSpillFunc(newSpillFunc(offlineStore, retransmitManager)),
+		ConnManagerWithExpireFunc(newExpireFunc(retransmitManager, reporter)),
+	}
+	if cfg.EventLoop.Enabled {
+		loop, err := NewEventLoop(EventLoopConfig{
+			ReadWorkers:  cfg.EventLoop.ReadWorkers,
+			WriteWorkers: cfg.EventLoop.WriteWorkers,
+			QueueSize:    cfg.EventLoop.QueueSize,
+		})
+		switch {
+		case errors.Is(err, netpoll.ErrNotSupported):
+			zapLogger.Warn("[synp-conn-manager] event loop is not supported on this platform, fallback to goroutine per connection")
+		case err != nil:
+			return nil, err
+		default:
+			opts = append(opts, ConnManagerWithEventLoop(loop))
+			// 在 app 关闭 ( 连接关闭 ) 之后执行。
+			lifecycle.Append(fx.Hook{
+				OnStop: func(_ context.Context) error {
+					return loop.Close()
+				},
+			})
+		}
+	}
+
+	return NewConnManager(zapLogger, opts...), nil
+}
+
+// newExpireFunc 创建丢弃过期消息时的 ExpireFunc，停止消息在该连接上的重传并发布过期回执。
+func newExpireFunc(retransmitManager *retransmit.Manager, reporter *receipt.Reporter) ExpireFunc {
+	return func(conn synp.Conn, msg *messagev1.Message) {
+		retransmitManager.Stop(conn.ID(), msg.GetMessageId())
+		reporter.Expired(conn, msg, receipt.StageSendQueue)
+	}
+}
+
+// newSpillFunc 创建将 downstream 消息保存为离线消息的 SpillFunc。
+// 保存成功后停止消息在该连接上的重传，避免重传时再次溢出而重复保存。
+func newSpillFunc(store offline.Store, retransmitManager *retransmit.Manager) SpillFunc {
+	return func(conn synp.Conn, msg *messagev1.Message) error {
+		if msg.GetCmd() != commonv1.CommandType_COMMAND_TYPE_DOWNSTREAM {
+			return ErrSpillNotSupported
+		}
+
+		user := conn.Session().User()
+		ctx, cancel := context.WithTimeout(context.Background(), DefaultSpillTimeout)
+		defer cancel()
+
+		pushMsg := &messagev1.PushMessage{
+			MessageId:     msg.GetMessageId(),
+			BizId:         user.BID,
+			ReceiverId:    user.UID,
+			SerializeType: msg.GetSerializeType(),
+			Body:          msg.GetBody(),
+		}
+		message.SetPushPriority(pushMsg, message.PushPriority(msg))
+		message.SetConflationKey(pushMsg, message.ConflationKey(msg))
+		message.SetExpireAt(pushMsg, message.ExpireAt(msg))
+
+		if err := store.Save(ctx, pushMsg); err != nil {
+			return err
+		}
+
+		retransmitManager.Stop(conn.ID(), msg.GetMessageId())
+		return nil
+	}
 }
@@ -0,0 +1,150 @@
+package conn
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jrmarcco/synp"
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// OverflowPolicy 为发送队列已满 ( 慢消费者 ) 时的处理策略。
+//
+// 除 disconnect 外，被丢弃的 downstream 消息仍然由 retransmit.Manager 负责重传，
+// 所以溢出不会返回错误，避免阻塞或重试整条消息 ( 影响同一分区的其他用户 )。
+type OverflowPolicy string
+
+const (
+	// OverflowDropOldest 丢弃同一优先级队列中最早的消息，新消息入队。
+	OverflowDropOldest OverflowPolicy = "drop_oldest"
+	// OverflowDropNewest 丢弃新消息。
+	OverflowDropNewest OverflowPolicy = "drop_newest"
+	// OverflowCoalesce 用新消息替换队列中 key 相同的消息 ( 如同一条消息的重传 )，没有相同 key 时丢弃新消息。
+	OverflowCoalesce OverflowPolicy = "coalesce"
+	// OverflowSpillOffline 将 downstream 消息保存为离线消息，无法保存时丢弃新消息。
+	OverflowSpillOffline OverflowPolicy = "spill_offline"
+	// OverflowDisconnect 丢弃新消息，持续溢出超过 disconnectAfter 时关闭连接 ( 客户端重连后补发离线消息 )。
+	OverflowDisconnect OverflowPolicy = "disconnect"
+)
+
+// ParseOverflowPolicy 解析溢出策略，为空时返回 DefaultOverflowPolicy。
+func ParseOverflowPolicy(s string) (OverflowPolicy, error) {
+	switch policy := OverflowPolicy(s); policy {
+	case "":
+		return DefaultOverflowPolicy, nil
+	case OverflowDropOldest, OverflowDropNewest, OverflowCoalesce, OverflowSpillOffline, OverflowDisconnect:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid overflow policy %q", s)
+	}
+}
+
+// ErrSpillNotSupported 表示消息不支持保存为离线消息 ( 如控制帧、ack )。
+var ErrSpillNotSupported = errors.New("message can not be spilled to offline store")
+
+// SpillFunc 在溢出策略为 OverflowSpillOffline 时保存无法入队的消息。
+type SpillFunc func(conn synp.Conn, msg *messagev1.Message) error
+
+// 溢出的处理结果。
+const (
+	overflowDecisionDropOldest  = "drop_oldest"
+	overflowDecisionDropNewest  = "drop_newest"
+	overflowDecisionCoalesce    = "coalesce"
+	overflowDecisionSpill       = "spill"
+	overflowDecisionDisconnect  = "disconnect"
+	overflowDecisionSpillFailed = "spill_failed"
+)
+
+var overflowTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "synp",
+		Subsystem: "conn",
+		Name:      "send_overflow_total",
+		Help:      "Number of frames that could not be queued because the connection send queue was full, by overflow policy and decision.",
+	},
+	[]string{"policy", "decision"},
+)
+
+var conflatedTotal = promauto.NewCounter(
+	prometheus.CounterOpts{
+		Namespace: "synp",
+		Subsystem: "conn",
+		Name:      "send_conflated_total",
+		Help:      "Number of queued frames replaced by a newer frame with the same conflation key.",
+	},
+)
+
+// overflow 按溢出策略处理无法入队的消息，无法按策略处理时丢弃新消息，没有入队的消息会被释放。
+func (c *Conn) overflow(frame *synp.Frame) error {
+	switch c.overflowPolicy {
+	case OverflowDropOldest:
+		if dropped := c.sendQueue.pushDropOldest(frame); dropped != nil {
+			dropped.Release()
+		}
+		c.countOverflow(overflowDecisionDropOldest)
+		return nil
+	case OverflowCoalesce:
+		if c.sendQueue.replace(frame) {
+			c.countOverflow(overflowDecisionCoalesce)
+			return nil
+		}
+	case OverflowSpillOffline:
+		if c.spill(frame) {
+			frame.Release()
+			c.countOverflow(overflowDecisionSpill)
+			return nil
+		}
+	case OverflowDisconnect:
+		now := time.Now().UnixNano()
+		c.overflowSince.CompareAndSwap(0, now)
+		if time.Duration(now-c.overflowSince.Load()) >= c.disconnectAfter {
+			c.countOverflow(overflowDecisionDisconnect)
+			c.logger.Warn(
+				"[synp-conn] send queue overflowed for too long, close connection",
+				zap.String("conn_id", c.id),
+				zap.Duration("disconnect_after", c.disconnectAfter),
+				zap.Any("user", c.sess.User()),
+			)
+			_ = c.Close()
+			frame.Release()
+			return ErrConnClosed
+		}
+	case OverflowDropNewest:
+	}
+
+	frame.Release()
+	c.countOverflow(overflowDecisionDropNewest)
+	return nil
+}
+
+// spill 将消息保存为离线消息，返回是否保存成功。
+func (c *Conn) spill(frame *synp.Frame) bool {
+	if c.spillFunc == nil || frame.Message == nil {
+		return false
+	}
+
+	err := c.spillFunc(c, frame.Message)
+	if err == nil {
+		return true
+	}
+
+	if !errors.Is(err, ErrSpillNotSupported) {
+		c.countOverflow(overflowDecisionSpillFailed)
+		c.logger.Error(
+			"[synp-conn] failed to spill message to offline store",
+			zap.String("conn_id", c.id),
+			zap.String("message_id", frame.Message.GetMessageId()),
+			zap.Any("user", c.sess.User()),
+			zap.Error(err),
+		)
+	}
+	return false
+}
+
+func (c *Conn) countOverflow(decision string) {
+	overflowTotal.WithLabelValues(string(c.overflowPolicy), decision).Inc()
+}
@@ -0,0 +1,169 @@
+package conn
+
+import (
+	"sync"
+
+	"github.com/jrmarcco/synp"
+)
+
+// sendQueue 为连接的发送队列，每个优先级一个有界的 FIFO 队列。
+//
+// 调度规则：
+//
+//  1. 控制帧严格优先，只要有控制帧就先发送；
+//  2. 其余优先级加权轮询，优先级高的先发送，每条消息消耗一次本轮的发送次数，
+//     本轮所有有消息的队列都没有剩余次数时开始新一轮，
+//     这样高优先级的消息总是先发送，普通消息也不会被饿死。
+type sendQueue struct {
+	mu sync.Mutex
+
+	frames   [synp.PriorityCount][]*synp.Frame
+	capacity int // 每个优先级队列的容量
+
+	weights [synp.PriorityCount]int // 每轮最多发送的消息数，控制帧不受权重限制
+	credits [synp.PriorityCount]int // 本轮剩余的发送次数
+
+	ready   chan struct{} // 有消息入队时通知发送方 ( 容量为 1 )
+	space   chan struct{} // 有消息出队时关闭并替换，用于等待队列空间
+	waiting bool          // 是否有等待队列空间的发送方
+}
+
+// pushResult 为消息入队的结果。
+type pushResult uint8
+
+const (
+	pushQueued    pushResult = iota // 入队成功
+	pushConflated                   // 替换了合并 key 相同的旧消息
+	pushFull                        // 队列已满
+)
+
+// push 将消息入队，同一优先级队列中有合并 key 相同的旧消息时直接替换 ( 保留原来的位置 ) 并释放旧消息。
+// 队列已满时返回 pushFull 以及一个在有消息出队时关闭的 channel。
+func (q *sendQueue) push(frame *synp.Frame) (pushResult, <-chan struct{}) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if frame.ConflationKey != "" {
+		for i, queued := range q.frames[frame.Priority] {
+			if queued.ConflationKey == frame.ConflationKey {
+				q.frames[frame.Priority][i] = frame
+				queued.Release()
+				return pushConflated, nil
+			}
+		}
+	}
+
+	if len(q.frames[frame.Priority]) >= q.capacity {
+		q.waiting = true
+		return pushFull, q.space
+	}
+
+	q.frames[frame.Priority] = append(q.frames[frame.Priority], frame)
+	q.notify()
+	return pushQueued, nil
+}
+
+// pushDropOldest 丢弃同一优先级队列中最早的消息后入队，返回被丢弃的消息。
+func (q *sendQueue) pushDropOldest(frame *synp.Frame) *synp.Frame {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var dropped *synp.Frame
+	if frames := q.frames[frame.Priority]; len(frames) >= q.capacity && len(frames) > 0 {
+		dropped = q.removeAt(frame.Priority, 0)
+	}
+
+	q.frames[frame.Priority] = append(q.frames[frame.Priority], frame)
+	q.notify()
+	return dropped
+}
+
+// replace 用新消息替换同一优先级队列中 key 相同的消息 ( 保留原来的位置 ) 并释放旧消息，返回是否替换成功。
+func (q *sendQueue) replace(frame *synp.Frame) bool {
+	if frame.Key == "" {
+		return false
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i, queued := range q.frames[frame.Priority] {
+		if queued.Key == frame.Key {
+			q.frames[frame.Priority][i] = frame
+			queued.Release()
+			return true
+		}
+	}
+	return false
+}
+
+// pop 按调度规则取出下一条消息，队列为空时返回 nil。
+func (q *sendQueue) pop() *synp.Frame {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.frames[synp.PriorityControl]) > 0 {
+		return q.removeAt(synp.PriorityControl, 0)
+	}
+
+	for range 2 {
+		for i := synp.PriorityAck; ; i-- {
+			if q.credits[i] > 0 && len(q.frames[i]) > 0 {
+				q.credits[i]--
+				return q.removeAt(i, 0)
+			}
+			if i == synp.PriorityNormal {
+				break
+			}
+		}
+		// 本轮没有可以发送的消息，开始新一轮。
+		q.credits = q.weights
+	}
+	return nil
+}
+
+// len 返回队列中的消息总数。
+func (q *sendQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var n int
+	for _, frames := range q.frames {
+		n += len(frames)
+	}
+	return n
+}
+
+func (q *sendQueue) removeAt(priority synp.Priority, i int) *synp.Frame {
+	frames := q.frames[priority]
+	frame := frames[i]
+
+	copy(frames[i:], frames[i+1:])
+	frames[len(frames)-1] = nil
+	q.frames[priority] = frames[:len(frames)-1]
+
+	// 唤醒等待队列空间的发送方。
+	if q.waiting {
+		close(q.space)
+		q.space = make(chan struct{})
+		q.waiting = false
+	}
+	return frame
+}
+
+func (q *sendQueue) notify() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+func newSendQueue(capacity int, weights [synp.PriorityCount]int) *sendQueue {
+	return &sendQueue{
+		capacity: max(capacity, 1),
+		weights:  weights,
+		credits:  weights,
+		ready:    make(chan struct{}, 1),
+		space:    make(chan struct{}),
+	}
+}
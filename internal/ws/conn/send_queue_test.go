@@ -0,0 +1,102 @@
+package conn
+
+import (
+	"testing"
+
+	"github.com/jrmarcco/synp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendQueue_Pop(t *testing.T) {
+	t.Parallel()
+
+	q := newSendQueue(16, [synp.PriorityCount]int{synp.PriorityAck: 2, synp.PriorityHigh: 2, synp.PriorityNormal: 1})
+	enqueue := func(priority synp.Priority, payloads ...string) {
+		for _, payload := range payloads {
+			res, _ := q.push(&synp.Frame{Payload: []byte(payload), Priority: priority})
+			assert.Equal(t, pushQueued, res)
+		}
+	}
+	enqueue(synp.PriorityNormal, "n1", "n2", "n3")
+	enqueue(synp.PriorityHigh, "h1", "h2", "h3")
+	enqueue(synp.PriorityAck, "a1")
+	enqueue(synp.PriorityControl, "c1")
+
+	var got []string
+	for frame := q.pop(); frame != nil; frame = q.pop() {
+		got = append(got, string(frame.Payload))
+
+		if len(got) == 3 {
+			// 调度过程中到达的控制帧立即发送。
+			enqueue(synp.PriorityControl, "c2")
+		}
+	}
+
+	// 每轮 high 最多发送 2 条，normal 最多 1 条，之后开始新一轮。
+	assert.Equal(t, []string{"c1", "a1", "h1", "c2", "h2", "n1", "h3", "n2", "n3"}, got)
+	assert.Zero(t, q.len())
+}
+
+func TestSendQueue_Overflow(t *testing.T) {
+	t.Parallel()
+
+	q := newSendQueue(2, DefaultSendWeights)
+	res, _ := q.push(&synp.Frame{Payload: []byte("m1"), Key: "m1"})
+	assert.Equal(t, pushQueued, res)
+	res, _ = q.push(&synp.Frame{Payload: []byte("m2"), Key: "m2"})
+	assert.Equal(t, pushQueued, res)
+
+	// 队列已满，出队后通知等待方。
+	res, space := q.push(&synp.Frame{Payload: []byte("m3"), Key: "m3"})
+	assert.Equal(t, pushFull, res)
+	select {
+	case <-space:
+		t.Fatal("space should not be ready")
+	default:
+	}
+
+	// 其他优先级的队列不受影响。
+	res, _ = q.push(&synp.Frame{Payload: []byte("c1"), Priority: synp.PriorityControl})
+	assert.Equal(t, pushQueued, res)
+	assert.Equal(t, "c1", string(q.pop().Payload))
+	<-space
+
+	res, _ = q.push(&synp.Frame{Payload: []byte("m3"), Key: "m3"})
+	assert.Equal(t, pushFull, res)
+
+	// 替换相同 key 的消息。
+	assert.True(t, q.replace(&synp.Frame{Payload: []byte("m2'"), Key: "m2"}))
+	assert.False(t, q.replace(&synp.Frame{Payload: []byte("m3"), Key: "m3"}))
+
+	// 丢弃最早的消息。
+	dropped := q.pushDropOldest(&synp.Frame{Payload: []byte("m3"), Key: "m3"})
+	assert.Equal(t, "m1", string(dropped.Payload))
+
+	assert.Equal(t, "m2'", string(q.pop().Payload))
+	assert.Equal(t, "m3", string(q.pop().Payload))
+	assert.Nil(t, q.pop())
+}
+
+func TestSendQueue_Conflation(t *testing.T) {
+	t.Parallel()
+
+	q := newSendQueue(2, DefaultSendWeights)
+	push := func(payload, conflationKey string) pushResult {
+		res, _ := q.push(&synp.Frame{Payload: []byte(payload), ConflationKey: conflationKey})
+		return res
+	}
+
+	assert.Equal(t, pushQueued, push("t1", "ticker"))
+	assert.Equal(t, pushQueued, push("s1", "score"))
+
+	// 队列已满时仍然可以替换合并 key 相同的旧消息 ( 保留原来的位置 )。
+	assert.Equal(t, pushConflated, push("t2", "ticker"))
+	assert.Equal(t, pushFull, push("n1", ""))
+
+	assert.Equal(t, "t2", string(q.pop().Payload))
+	assert.Equal(t, "s1", string(q.pop().Payload))
+
+	// 已经出队的消息不会被替换。
+	assert.Equal(t, pushQueued, push("t3", "ticker"))
+	assert.Equal(t, 1, q.len())
+}
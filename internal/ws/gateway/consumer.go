@@ -2,20 +2,63 @@ package gateway
 
 import (
 	"context"
+	"errors"
+	"sync"
+	"time"
 
+	"github.com/jrmarcco/jit/bean/option"
+	"github.com/jrmarcco/jit/retry"
 	"github.com/jrmarcco/synp/internal/pkg/xmq"
 	pkgconsumer "github.com/jrmarcco/synp/internal/pkg/xmq/consumer"
+	"go.uber.org/multierr"
 	"go.uber.org/zap"
 )
 
+const (
+	DefaultConcurrency       = 8
+	DefaultQueueSize         = 64
+	DefaultCommitInterval    = time.Second
+	DefaultInitRetryInterval = 100 * time.Millisecond
+	DefaultMaxRetryInterval  = time.Second
+	DefaultMaxRetryCount     = int32(3)
+
+	commitTimeout = 5 * time.Second
+)
+
+// ErrNonRetryable 表示消息处理失败且重试没有意义 ( 如消息格式错误 )。
+// ConsumeFunc 返回的错误包含 ErrNonRetryable 时不会重试，设置了 FailurePolicy 时直接转发到死信 topic。
+var ErrNonRetryable = errors.New("non-retryable")
+
 type ConsumeFunc func(ctx context.Context, msg *xmq.Message) error
 
+// Consumer 是网关事件消费者。
+//
+// Consumer 会创建 members 个同一消费者组的成员，分区由消费者组协调分配，成员变化时自动重新平衡。
+// 每个分区的消息按 key 分配给 concurrency 个 worker 并发处理 ( 相同 key 的消息按顺序处理 )，
+// 只有 ConsumeFunc 处理完成的消息才会提交 offset，进程崩溃或分区重新分配后未提交的消息会重新消费。
+//
+// 消息设置了 xmq.HeaderRetryAt 时 ( 来自重试 topic )，会等到该时间之后再处理。
 type Consumer struct {
 	consumerFactory pkgconsumer.ConsumerFactory
 
-	topic      string
-	groupID    string
-	partitions int32
+	topic   string
+	groupID string
+	members int32
+
+	concurrency    int
+	queueSize      int
+	commitInterval time.Duration
+
+	initRetryInterval time.Duration
+	maxRetryInterval  time.Duration
+	maxRetryCount     int32
+
+	failurePolicy *FailurePolicy
+
+	mu        sync.Mutex
+	consumers []pkgconsumer.Consumer
+	wg        sync.WaitGroup
+	stopOnce  sync.Once
 
 	ctx        context.Context
 	cancelFunc context.CancelFunc
@@ -24,8 +67,8 @@ type Consumer struct {
 }
 
 func (c *Consumer) Start(ctx context.Context, consumeFunc ConsumeFunc) error {
-	for i := range c.partitions {
-		partition := i
+	for i := range c.members {
+		member := i
 
 		consumer, err := c.consumerFactory.NewConsumer(c.topic, c.groupID)
 		if err != nil {
@@ -33,77 +76,320 @@ func (c *Consumer) Start(ctx context.Context, consumeFunc ConsumeFunc) error {
 				"[synp-gateway-consumer] failed to create consumer",
 				zap.String("topic", c.topic),
 				zap.String("group_id", c.groupID),
-				zap.Int32("partition", partition),
+				zap.Int32("member", member),
 				zap.Error(err),
 			)
 			return err
 		}
 
+		c.mu.Lock()
+		c.consumers = append(c.consumers, consumer)
+		c.mu.Unlock()
+
 		msgChan, err := consumer.ConsumeChan(ctx)
 		if err != nil {
 			c.logger.Error(
 				"[synp-gateway-consumer] failed to get message channel from mq",
 				zap.String("topic", c.topic),
 				zap.String("group_id", c.groupID),
-				zap.Int32("partition", partition),
+				zap.Int32("member", member),
 				zap.Error(err),
 			)
 			return err
 		}
 
-		go c.consume(ctx, msgChan, consumeFunc)
+		c.wg.Add(1)
+		go c.consume(ctx, consumer, msgChan, consumeFunc)
 	}
 	return nil
 }
 
-func (c *Consumer) consume(ctx context.Context, msgChan <-chan *xmq.Message, consumeFunc ConsumeFunc) {
+// consume 将消费者组成员拉取到的消息分配给对应分区的 worker，并定时提交已处理完成的 offset。
+func (c *Consumer) consume(ctx context.Context, consumer pkgconsumer.Consumer, msgChan <-chan *xmq.Message, consumeFunc ConsumeFunc) {
+	defer c.wg.Done()
+
+	// 方法参数 ctx 或 Stop 都会结束消费。
+	workerCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stopAfter := context.AfterFunc(c.ctx, cancel)
+	defer stopAfter()
+
+	handle := func(ctx context.Context, msg *xmq.Message) bool {
+		return c.handle(ctx, msg, consumeFunc)
+	}
+
+	workers := make(map[int]*partitionWorker)
+	defer func() {
+		cancel()
+		for _, worker := range workers {
+			worker.stop()
+		}
+		// 退出前提交已经处理完成的消息。
+		c.commit(consumer, workers)
+	}()
+
+	ticker := time.NewTicker(c.commitInterval)
+	defer ticker.Stop()
+
 	for {
 		select {
-		case <-c.ctx.Done():
-			c.logger.Info("[synp-gateway-consumer] consumer context done")
-			return
-		case <-ctx.Done():
-			c.logger.Info("[synp-gateway-consumer] method param context done")
+		case <-workerCtx.Done():
+			c.logger.Info("[synp-gateway-consumer] consumer context done", zap.String("topic", c.topic))
 			return
+		case <-ticker.C:
+			c.commit(consumer, workers)
 		case msg, ok := <-msgChan:
 			if !ok {
 				return
 			}
 
-			err := consumeFunc(ctx, msg)
-			if err != nil {
-				c.logger.Error(
-					"[synp-gateway-consumer] failed to consume message",
-					zap.String("message", string(msg.Val)),
-					zap.Error(err),
+			worker, ok := workers[msg.Partition]
+			if ok && worker.rewound(msg) {
+				// offset 回退说明分区被重新分配过，旧 worker 中未提交的消息会重新拉取，
+				// 等待旧 worker 退出后重新创建，避免 pending 中残留的 offset 阻塞提交。
+				c.logger.Info(
+					"[synp-gateway-consumer] partition offset rewound, reset worker",
+					zap.String("topic", msg.Topic),
+					zap.Int("partition", msg.Partition),
+					zap.Int64("offset", msg.Offset),
 				)
+				worker.stop()
+				delete(workers, msg.Partition)
+				ok = false
+			}
+			if !ok {
+				worker = newPartitionWorker(msg.Topic, msg.Partition, c.concurrency, c.queueSize)
+				worker.run(workerCtx, handle)
+				workers[msg.Partition] = worker
 			}
+
+			if err := worker.dispatch(workerCtx, msg); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// handle 处理单条消息，失败时使用指数退避策略原地重试，重试达到上限后交给 FailurePolicy 处理。
+// 返回 false 表示因为消费者关闭而放弃处理，消息不会被提交。
+func (c *Consumer) handle(ctx context.Context, msg *xmq.Message, consumeFunc ConsumeFunc) bool {
+	if at := retryAt(msg); !at.IsZero() {
+		if delay := time.Until(at); delay > 0 {
+			select {
+			case <-ctx.Done():
+				return false
+			case <-time.After(delay):
+			}
+		}
+	}
+
+	// 这里可以忽略 error。
+	// 创建 Consumer 的时候就应该确保重试策略的参数正确。
+	retryStrategy, _ := retry.NewExponentialBackoffStrategy(
+		c.initRetryInterval, c.maxRetryInterval, c.maxRetryCount,
+	)
+
+	for {
+		err := consumeFunc(ctx, msg)
+		if err == nil {
 			c.logger.Debug(
 				"[synp-gateway-consumer] successfully consumed message",
 				zap.String("message", string(msg.Val)),
 			)
+			return true
+		}
+
+		if ctx.Err() != nil {
+			return false
+		}
+
+		duration, ok := retryStrategy.Next()
+		if errors.Is(err, ErrNonRetryable) || !ok {
+			return c.fail(ctx, msg, err)
+		}
+
+		c.logger.Warn(
+			"[synp-gateway-consumer] failed to consume message, retry later",
+			zap.String("topic", msg.Topic),
+			zap.Int("partition", msg.Partition),
+			zap.Int64("offset", msg.Offset),
+			zap.Duration("retry_after", duration),
+			zap.Error(err),
+		)
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(duration):
 		}
 	}
 }
 
+// fail 处理原地重试达到上限 ( 或不可重试 ) 的消息。
+// 没有设置 FailurePolicy 时跳过该消息，避免阻塞分区；
+// 否则将消息转发到重试 topic 或死信 topic，转发失败时持续重试直到成功或消费者关闭。
+func (c *Consumer) fail(ctx context.Context, msg *xmq.Message, cause error) bool {
+	if c.failurePolicy == nil {
+		c.logger.Error(
+			"[synp-gateway-consumer] failed to consume message",
+			zap.String("topic", msg.Topic),
+			zap.Int("partition", msg.Partition),
+			zap.Int64("offset", msg.Offset),
+			zap.String("message", string(msg.Val)),
+			zap.Error(cause),
+		)
+		return true
+	}
+
+	for {
+		forwardCtx, cancel := context.WithTimeout(ctx, commitTimeout)
+		err := c.failurePolicy.Forward(forwardCtx, msg, cause)
+		cancel()
+		if err == nil {
+			c.logger.Warn(
+				"[synp-gateway-consumer] failed to consume message, forwarded to retry or dead letter topic",
+				zap.String("topic", msg.Topic),
+				zap.Int("partition", msg.Partition),
+				zap.Int64("offset", msg.Offset),
+				zap.Error(cause),
+			)
+			return true
+		}
+
+		c.logger.Error(
+			"[synp-gateway-consumer] failed to forward failed message, retry later",
+			zap.String("topic", msg.Topic),
+			zap.Int("partition", msg.Partition),
+			zap.Int64("offset", msg.Offset),
+			zap.Error(err),
+		)
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(c.maxRetryInterval):
+		}
+	}
+}
+
+// commit 提交各个分区已处理完成的 offset。
+func (c *Consumer) commit(consumer pkgconsumer.Consumer, workers map[int]*partitionWorker) {
+	msgs := make([]*xmq.Message, 0, len(workers))
+	for partition, worker := range workers {
+		offset, ok := worker.tracker.committable()
+		if !ok {
+			continue
+		}
+		msgs = append(msgs, &xmq.Message{
+			Topic:     worker.topic,
+			Partition: partition,
+			Offset:    offset,
+		})
+	}
+	if len(msgs) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commitTimeout)
+	defer cancel()
+
+	if err := consumer.Commit(ctx, msgs...); err != nil {
+		// 提交失败 ( 如分区已经重新分配 ) 时消息会被重新消费。
+		c.logger.Warn(
+			"[synp-gateway-consumer] failed to commit offsets",
+			zap.String("topic", c.topic),
+			zap.String("group_id", c.groupID),
+			zap.Error(err),
+		)
+	}
+}
+
+// Stop 停止消费，等待 worker 退出并提交已处理完成的 offset 后关闭消费者。
 func (c *Consumer) Stop() error {
-	c.cancelFunc()
-	return nil
+	var err error
+	c.stopOnce.Do(func() {
+		c.cancelFunc()
+		c.wg.Wait()
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for _, consumer := range c.consumers {
+			err = multierr.Append(err, consumer.Close())
+		}
+	})
+	return err
+}
+
+// ConsumerWithConcurrency 设置每个分区并发处理消息的 worker 数量及每个 worker 的队列长度。
+func ConsumerWithConcurrency(concurrency, queueSize int) option.Opt[Consumer] {
+	return func(c *Consumer) {
+		if concurrency > 0 {
+			c.concurrency = concurrency
+		}
+		if queueSize > 0 {
+			c.queueSize = queueSize
+		}
+	}
+}
+
+// ConsumerWithCommitInterval 设置提交 offset 的间隔。
+func ConsumerWithCommitInterval(commitInterval time.Duration) option.Opt[Consumer] {
+	return func(c *Consumer) {
+		if commitInterval > 0 {
+			c.commitInterval = commitInterval
+		}
+	}
 }
 
-func NewConsumer(consumerFactory pkgconsumer.ConsumerFactory, topic, groupID string, partitions int32, logger *zap.Logger) *Consumer {
+// ConsumerWithRetry 设置消息处理失败时的重试策略，maxRetryCount 为 0 时表示不重试。
+func ConsumerWithRetry(initRetryInterval, maxRetryInterval time.Duration, maxRetryCount int32) option.Opt[Consumer] {
+	return func(c *Consumer) {
+		if initRetryInterval > 0 && maxRetryInterval >= initRetryInterval && maxRetryCount >= 0 {
+			c.initRetryInterval = initRetryInterval
+			c.maxRetryInterval = maxRetryInterval
+			c.maxRetryCount = maxRetryCount
+		}
+	}
+}
+
+// ConsumerWithFailurePolicy 设置原地重试达到上限后的处理策略 ( 重试 topic / 死信 topic )。
+func ConsumerWithFailurePolicy(policy *FailurePolicy) option.Opt[Consumer] {
+	return func(c *Consumer) {
+		c.failurePolicy = policy
+	}
+}
+
+// NewConsumer 创建网关事件消费者，members 为同一消费者组内的成员数量 ( 不超过 topic 分区数 )。
+func NewConsumer(
+	consumerFactory pkgconsumer.ConsumerFactory,
+	topic, groupID string,
+	members int32,
+	logger *zap.Logger,
+	opts ...option.Opt[Consumer],
+) *Consumer {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	return &Consumer{
+	c := &Consumer{
 		consumerFactory: consumerFactory,
 
-		topic:      topic,
-		groupID:    groupID,
-		partitions: partitions,
+		topic:   topic,
+		groupID: groupID,
+		members: max(members, 1),
+
+		concurrency:    DefaultConcurrency,
+		queueSize:      DefaultQueueSize,
+		commitInterval: DefaultCommitInterval,
+
+		initRetryInterval: DefaultInitRetryInterval,
+		maxRetryInterval:  DefaultMaxRetryInterval,
+		maxRetryCount:     DefaultMaxRetryCount,
 
 		ctx:        ctx,
 		cancelFunc: cancel,
 
 		logger: logger,
 	}
+
+	option.Apply(c, opts...)
+	return c
 }
@@ -0,0 +1,256 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jrmarcco/synp/internal/pkg/xmq"
+	pkgconsumer "github.com/jrmarcco/synp/internal/pkg/xmq/consumer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestOffsetTracker(t *testing.T) {
+	t.Parallel()
+
+	tracker := newOffsetTracker()
+	for offset := range int64(5) {
+		tracker.add(offset)
+	}
+
+	_, ok := tracker.committable()
+	assert.False(t, ok)
+
+	// offset 1 完成但 offset 0 未完成，不能提交。
+	tracker.markDone(1)
+	_, ok = tracker.committable()
+	assert.False(t, ok)
+
+	tracker.markDone(0)
+	tracker.markDone(3)
+	offset, ok := tracker.committable()
+	require.True(t, ok)
+	assert.Equal(t, int64(1), offset)
+
+	tracker.markDone(2)
+	offset, ok = tracker.committable()
+	require.True(t, ok)
+	assert.Equal(t, int64(3), offset)
+
+	_, ok = tracker.committable()
+	assert.False(t, ok)
+}
+
+func TestOffsetTracker_Duplicate(t *testing.T) {
+	t.Parallel()
+
+	// 分区重新分配后 offset 1、2 被重复拉取。
+	tracker := newOffsetTracker()
+	for _, offset := range []int64{0, 1, 2, 1, 2, 3} {
+		tracker.add(offset)
+	}
+
+	tracker.markDone(0)
+	tracker.markDone(1)
+	tracker.markDone(2)
+	offset, ok := tracker.committable()
+	require.True(t, ok)
+	assert.Equal(t, int64(2), offset)
+
+	// 重复的 offset 需要各自完成。
+	tracker.markDone(1)
+	tracker.markDone(3)
+	offset, ok = tracker.committable()
+	require.True(t, ok)
+	assert.Equal(t, int64(1), offset)
+
+	tracker.markDone(2)
+	offset, ok = tracker.committable()
+	require.True(t, ok)
+	assert.Equal(t, int64(3), offset)
+	assert.Empty(t, tracker.pending)
+	assert.Empty(t, tracker.done)
+}
+
+func TestConsumer_Rewound(t *testing.T) {
+	t.Parallel()
+
+	mq := newTestConsumer()
+	c := NewConsumer(
+		&testConsumerFactory{consumer: mq}, "test-topic", "test-group", 1, zap.NewNop(),
+		ConsumerWithConcurrency(2, 4),
+		ConsumerWithCommitInterval(10*time.Millisecond),
+	)
+
+	release := make(chan struct{})
+	err := c.Start(t.Context(), func(_ context.Context, msg *xmq.Message) error {
+		if msg.Offset == 1 {
+			<-release
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	for offset := range int64(3) {
+		mq.ch <- &xmq.Message{Topic: "test-topic", Partition: 0, Offset: offset}
+	}
+	assert.Eventually(t, func() bool {
+		return mq.committed(0) == 0
+	}, time.Second, 5*time.Millisecond)
+
+	// 分区重新分配后从 offset 1 重新拉取，重置 worker 后继续提交。
+	close(release)
+	for offset := int64(1); offset < 4; offset++ {
+		mq.ch <- &xmq.Message{Topic: "test-topic", Partition: 0, Offset: offset}
+	}
+	assert.Eventually(t, func() bool {
+		return mq.committed(0) == 3
+	}, time.Second, 5*time.Millisecond)
+
+	require.NoError(t, c.Stop())
+}
+
+func TestConsumer(t *testing.T) {
+	t.Parallel()
+
+	mq := newTestConsumer()
+	c := NewConsumer(
+		&testConsumerFactory{consumer: mq}, "test-topic", "test-group", 1, zap.NewNop(),
+		ConsumerWithConcurrency(2, 4),
+		ConsumerWithCommitInterval(10*time.Millisecond),
+		ConsumerWithRetry(time.Millisecond, time.Millisecond, 1),
+	)
+
+	slowRelease := make(chan struct{})
+	var mu sync.Mutex
+	consumed := make(map[string][]int64)
+	attempts := make(map[string]int)
+
+	err := c.Start(t.Context(), func(_ context.Context, msg *xmq.Message) error {
+		mu.Lock()
+		attempts[string(msg.Key)]++
+		cnt := attempts[string(msg.Key)]
+		mu.Unlock()
+
+		switch string(msg.Key) {
+		case "slow":
+			<-slowRelease
+		case "fail":
+			if cnt == 1 {
+				return errors.New("mock error")
+			}
+		}
+
+		mu.Lock()
+		consumed[string(msg.Key)] = append(consumed[string(msg.Key)], msg.Offset)
+		mu.Unlock()
+		return nil
+	})
+	require.NoError(t, err)
+
+	// 找到与 slow 不在同一个 worker 的 key。
+	worker := newPartitionWorker("test-topic", 0, 2, 1)
+	fastKey := "fast"
+	for i := 0; worker.queueIndex(&xmq.Message{Key: []byte(fastKey)}) == worker.queueIndex(&xmq.Message{Key: []byte("slow")}); i++ {
+		fastKey = "fast" + string(rune('a'+i))
+	}
+
+	mq.ch <- &xmq.Message{Topic: "test-topic", Partition: 0, Offset: 0, Key: []byte("slow")}
+	mq.ch <- &xmq.Message{Topic: "test-topic", Partition: 0, Offset: 1, Key: []byte(fastKey)}
+	mq.ch <- &xmq.Message{Topic: "test-topic", Partition: 0, Offset: 2, Key: []byte(fastKey)}
+	mq.ch <- &xmq.Message{Topic: "test-topic", Partition: 1, Offset: 0, Key: []byte("fail")}
+
+	// 慢用户不会阻塞同一分区其他用户的消息，失败的消息会被重试。
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(consumed[fastKey]) == 2 && len(consumed["fail"]) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	mu.Lock()
+	assert.Equal(t, []int64{1, 2}, consumed[fastKey])
+	assert.Equal(t, 2, attempts["fail"])
+	mu.Unlock()
+
+	// 慢用户的消息未处理完成，分区 0 不能提交 offset。
+	assert.Eventually(t, func() bool {
+		return mq.committed(1) == 0
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, int64(-1), mq.committed(0))
+
+	close(slowRelease)
+	assert.Eventually(t, func() bool {
+		return mq.committed(0) == 2
+	}, time.Second, 5*time.Millisecond)
+
+	require.NoError(t, c.Stop())
+	mq.mu.Lock()
+	assert.True(t, mq.closed)
+	mq.mu.Unlock()
+}
+
+type testConsumerFactory struct {
+	consumer *testConsumer
+}
+
+func (f *testConsumerFactory) NewConsumer(_, _ string) (pkgconsumer.Consumer, error) {
+	return f.consumer, nil
+}
+
+type testConsumer struct {
+	ch chan *xmq.Message
+
+	mu      sync.Mutex
+	offsets map[int]int64
+	closed  bool
+}
+
+func (c *testConsumer) Consume(ctx context.Context) (*xmq.Message, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case msg := <-c.ch:
+		return msg, nil
+	}
+}
+
+func (c *testConsumer) ConsumeChan(_ context.Context) (<-chan *xmq.Message, error) {
+	return c.ch, nil
+}
+
+func (c *testConsumer) Commit(_ context.Context, msgs ...*xmq.Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, msg := range msgs {
+		c.offsets[msg.Partition] = msg.Offset
+	}
+	return nil
+}
+
+func (c *testConsumer) committed(partition int) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	offset, ok := c.offsets[partition]
+	if !ok {
+		return -1
+	}
+	return offset
+}
+
+func (c *testConsumer) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+func newTestConsumer() *testConsumer {
+	return &testConsumer{
+		ch:      make(chan *xmq.Message, 16),
+		offsets: make(map[int]int64),
+	}
+}
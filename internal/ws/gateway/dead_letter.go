@@ -0,0 +1,201 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"strconv"
+	"time"
+
+	"github.com/jrmarcco/synp/internal/pkg/xmq"
+	pkgconsumer "github.com/jrmarcco/synp/internal/pkg/xmq/consumer"
+	"github.com/jrmarcco/synp/internal/pkg/xmq/produce"
+)
+
+const (
+	DefaultRetryDelay         = 5 * time.Second
+	DefaultMaxDelayedRetries  = 3
+	DefaultRedriveIdleTimeout = 5 * time.Second
+)
+
+// FailurePolicy 为消费失败消息的处理策略。
+//
+// 原地重试达到上限后，消息会被转发到重试 topic，延迟 retryDelay 后由重试 topic 的消费者重新处理；
+// 转发到重试 topic 的次数达到上限 ( 或错误不可重试 ) 后，消息会被转发到死信 topic。
+// 转发时保留消息原有的 key 和 header，并附加失败原因等 header ( 见 xmq.HeaderError 等 )。
+type FailurePolicy struct {
+	producer produce.Producer
+
+	retryTopic        string
+	deadLetterTopic   string
+	retryDelay        time.Duration
+	maxDelayedRetries int
+}
+
+// Forward 将消费失败的消息转发到重试 topic 或死信 topic。
+func (p *FailurePolicy) Forward(ctx context.Context, msg *xmq.Message, cause error) error {
+	return p.producer.Produce(ctx, p.next(msg, cause, time.Now()))
+}
+
+// next 生成转发到重试 topic 或死信 topic 的消息。
+func (p *FailurePolicy) next(msg *xmq.Message, cause error, now time.Time) *xmq.Message {
+	headers := make(xmq.Headers, len(msg.Headers)+6)
+	maps.Copy(headers, msg.Headers)
+
+	// 只记录第一次消费失败时的位置。
+	if _, ok := headers[xmq.HeaderOriginalTopic]; !ok {
+		headers[xmq.HeaderOriginalTopic] = msg.Topic
+		headers[xmq.HeaderOriginalPartition] = strconv.Itoa(msg.Partition)
+		headers[xmq.HeaderOriginalOffset] = strconv.FormatInt(msg.Offset, 10)
+	}
+	headers[xmq.HeaderError] = cause.Error()
+	headers[xmq.HeaderFailedAt] = strconv.FormatInt(now.UnixMilli(), 10)
+
+	retryCount, _ := strconv.Atoi(headers[xmq.HeaderRetryCount])
+
+	topic := p.deadLetterTopic
+	if p.retryTopic != "" && retryCount < p.maxDelayedRetries && !errors.Is(cause, ErrNonRetryable) {
+		topic = p.retryTopic
+		headers[xmq.HeaderRetryCount] = strconv.Itoa(retryCount + 1)
+		headers[xmq.HeaderRetryAt] = strconv.FormatInt(now.Add(p.retryDelay).UnixMilli(), 10)
+	} else {
+		delete(headers, xmq.HeaderRetryAt)
+	}
+
+	return &xmq.Message{
+		Headers: headers,
+		Topic:   topic,
+		Key:     msg.Key,
+		Val:     msg.Val,
+	}
+}
+
+// NewFailurePolicy 创建消费失败消息的处理策略。
+// retryTopic 为空时不进行延迟重试，失败的消息直接转发到死信 topic。
+func NewFailurePolicy(
+	producer produce.Producer,
+	retryTopic, deadLetterTopic string,
+	retryDelay time.Duration,
+	maxDelayedRetries int,
+) (*FailurePolicy, error) {
+	if deadLetterTopic == "" {
+		return nil, errors.New("dead letter topic is required")
+	}
+	if retryDelay <= 0 {
+		retryDelay = DefaultRetryDelay
+	}
+	if maxDelayedRetries < 0 {
+		maxDelayedRetries = DefaultMaxDelayedRetries
+	}
+
+	return &FailurePolicy{
+		producer:          producer,
+		retryTopic:        retryTopic,
+		deadLetterTopic:   deadLetterTopic,
+		retryDelay:        retryDelay,
+		maxDelayedRetries: maxDelayedRetries,
+	}, nil
+}
+
+// retryAt 返回消息最早的重新处理时间，没有设置时返回零值。
+func retryAt(msg *xmq.Message) time.Time {
+	val, ok := msg.Headers[xmq.HeaderRetryAt]
+	if !ok {
+		return time.Time{}
+	}
+
+	ms, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
+
+// DeadLetterRedriver 将死信 topic 中的消息重新发送到第一次消费失败时所在的 topic。
+// 使用独立的消费者组读取死信 topic，已经重新发送的消息会提交 offset，不会被重复发送。
+type DeadLetterRedriver struct {
+	consumerFactory pkgconsumer.ConsumerFactory
+	producer        produce.Producer
+
+	deadLetterTopic string
+	groupID         string
+	defaultTopic    string // 消息没有 xmq.HeaderOriginalTopic 时发送到的 topic
+}
+
+// Redrive 重新发送最多 limit 条死信消息，返回重新发送的消息数。
+// 等待 idleTimeout 时间仍然没有新的死信消息时结束。
+func (r *DeadLetterRedriver) Redrive(ctx context.Context, limit int, idleTimeout time.Duration) (int, error) {
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultRedriveIdleTimeout
+	}
+
+	consumer, err := r.consumerFactory.NewConsumer(r.deadLetterTopic, r.groupID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create dead letter consumer: %w", err)
+	}
+	defer func() { _ = consumer.Close() }()
+
+	cnt := 0
+	for cnt < limit {
+		consumeCtx, cancel := context.WithTimeout(ctx, idleTimeout)
+		msg, err := consumer.Consume(consumeCtx)
+		cancel()
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+				// 没有更多的死信消息。
+				return cnt, nil
+			}
+			return cnt, fmt.Errorf("failed to consume dead letter message: %w", err)
+		}
+
+		if err = r.producer.Produce(ctx, r.redriven(msg)); err != nil {
+			return cnt, fmt.Errorf("failed to redrive dead letter message: %w", err)
+		}
+		if err = consumer.Commit(ctx, msg); err != nil {
+			return cnt, fmt.Errorf("failed to commit dead letter message: %w", err)
+		}
+		cnt++
+	}
+	return cnt, nil
+}
+
+// redriven 生成重新发送的消息，清除重试次数以获得完整的重试机会。
+func (r *DeadLetterRedriver) redriven(msg *xmq.Message) *xmq.Message {
+	headers := maps.Clone(msg.Headers)
+	if headers == nil {
+		headers = xmq.Headers{}
+	}
+
+	topic := headers[xmq.HeaderOriginalTopic]
+	if topic == "" {
+		topic = r.defaultTopic
+	}
+
+	delete(headers, xmq.HeaderRetryCount)
+	delete(headers, xmq.HeaderRetryAt)
+	delete(headers, xmq.HeaderOriginalTopic)
+	delete(headers, xmq.HeaderOriginalPartition)
+	delete(headers, xmq.HeaderOriginalOffset)
+
+	return &xmq.Message{
+		Headers: headers,
+		Topic:   topic,
+		Key:     msg.Key,
+		Val:     msg.Val,
+	}
+}
+
+func NewDeadLetterRedriver(
+	consumerFactory pkgconsumer.ConsumerFactory,
+	producer produce.Producer,
+	deadLetterTopic, groupID, defaultTopic string,
+) *DeadLetterRedriver {
+	return &DeadLetterRedriver{
+		consumerFactory: consumerFactory,
+		producer:        producer,
+		deadLetterTopic: deadLetterTopic,
+		groupID:         groupID,
+		defaultTopic:    defaultTopic,
+	}
+}
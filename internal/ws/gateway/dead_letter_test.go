@@ -0,0 +1,145 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jrmarcco/synp/internal/pkg/xmq"
+	producemock "github.com/jrmarcco/synp/internal/pkg/xmq/produce/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func TestFailurePolicy_Next(t *testing.T) {
+	t.Parallel()
+
+	policy, err := NewFailurePolicy(producemock.NewMockProducer(gomock.NewController(t)), "retry-topic", "dlt-topic", time.Second, 2)
+	require.NoError(t, err)
+
+	now := time.UnixMilli(1_000_000)
+	msg := &xmq.Message{
+		Headers:   xmq.Headers{xmq.HeaderBID: "1"},
+		Topic:     "push-topic",
+		Partition: 3,
+		Offset:    42,
+		Key:       []byte("key"),
+		Val:       []byte("val"),
+	}
+
+	// 第一次失败：转发到重试 topic，记录原始位置。
+	next := policy.next(msg, errors.New("mock error"), now)
+	assert.Equal(t, "retry-topic", next.Topic)
+	assert.Equal(t, []byte("key"), next.Key)
+	assert.Equal(t, []byte("val"), next.Val)
+	assert.Equal(t, "1", next.Headers[xmq.HeaderBID])
+	assert.Equal(t, "push-topic", next.Headers[xmq.HeaderOriginalTopic])
+	assert.Equal(t, "3", next.Headers[xmq.HeaderOriginalPartition])
+	assert.Equal(t, "42", next.Headers[xmq.HeaderOriginalOffset])
+	assert.Equal(t, "mock error", next.Headers[xmq.HeaderError])
+	assert.Equal(t, "1", next.Headers[xmq.HeaderRetryCount])
+	assert.Equal(t, strconv.FormatInt(now.Add(time.Second).UnixMilli(), 10), next.Headers[xmq.HeaderRetryAt])
+	assert.Equal(t, now.Add(time.Second), retryAt(next))
+	// 原消息的 header 不会被修改。
+	assert.Len(t, msg.Headers, 1)
+
+	// 重试 topic 中再次失败：保留原始位置。
+	next.Topic, next.Partition, next.Offset = "retry-topic", 0, 7
+	next = policy.next(next, errors.New("mock error 2"), now)
+	assert.Equal(t, "retry-topic", next.Topic)
+	assert.Equal(t, "push-topic", next.Headers[xmq.HeaderOriginalTopic])
+	assert.Equal(t, "42", next.Headers[xmq.HeaderOriginalOffset])
+	assert.Equal(t, "2", next.Headers[xmq.HeaderRetryCount])
+
+	// 延迟重试达到上限：转发到死信 topic。
+	next = policy.next(next, errors.New("mock error 3"), now)
+	assert.Equal(t, "dlt-topic", next.Topic)
+	assert.Equal(t, "mock error 3", next.Headers[xmq.HeaderError])
+	assert.NotContains(t, next.Headers, xmq.HeaderRetryAt)
+
+	// 不可重试的错误直接转发到死信 topic。
+	next = policy.next(msg, fmt.Errorf("%w: bad json", ErrNonRetryable), now)
+	assert.Equal(t, "dlt-topic", next.Topic)
+}
+
+func TestConsumer_FailurePolicy(t *testing.T) {
+	t.Parallel()
+
+	producer, recorder := producemock.NewRecordingProducer(gomock.NewController(t))
+	policy, err := NewFailurePolicy(producer, "retry-topic", "dlt-topic", time.Second, 1)
+	require.NoError(t, err)
+
+	mq := newTestConsumer()
+	c := NewConsumer(
+		&testConsumerFactory{consumer: mq}, "push-topic", "test-group", 1, zap.NewNop(),
+		ConsumerWithRetry(time.Millisecond, time.Millisecond, 1),
+		ConsumerWithCommitInterval(10*time.Millisecond),
+		ConsumerWithFailurePolicy(policy),
+	)
+
+	err = c.Start(t.Context(), func(_ context.Context, _ *xmq.Message) error {
+		return errors.New("mock error")
+	})
+	require.NoError(t, err)
+
+	mq.ch <- &xmq.Message{Topic: "push-topic", Partition: 0, Offset: 0, Key: []byte("key")}
+
+	// 原地重试达到上限后转发到重试 topic 并提交 offset。
+	assert.Eventually(t, func() bool {
+		return mq.committed(0) == 0
+	}, time.Second, 5*time.Millisecond)
+
+	msgs := recorder.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "retry-topic", msgs[0].Topic)
+	assert.Equal(t, "mock error", msgs[0].Headers[xmq.HeaderError])
+
+	require.NoError(t, c.Stop())
+}
+
+func TestDeadLetterRedriver(t *testing.T) {
+	t.Parallel()
+
+	mq := newTestConsumer()
+	producer, recorder := producemock.NewRecordingProducer(gomock.NewController(t))
+	redriver := NewDeadLetterRedriver(&testConsumerFactory{consumer: mq}, producer, "dlt-topic", "redrive-group", "push-topic")
+
+	mq.ch <- &xmq.Message{
+		Headers: xmq.Headers{
+			xmq.HeaderBID:           "1",
+			xmq.HeaderError:         "mock error",
+			xmq.HeaderRetryCount:    "3",
+			xmq.HeaderOriginalTopic: "origin-topic",
+		},
+		Topic:  "dlt-topic",
+		Offset: 0,
+		Key:    []byte("key"),
+	}
+	mq.ch <- &xmq.Message{Topic: "dlt-topic", Offset: 1}
+	mq.ch <- &xmq.Message{Topic: "dlt-topic", Offset: 2}
+
+	cnt, err := redriver.Redrive(t.Context(), 2, 10*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, 2, cnt)
+	assert.Equal(t, int64(1), mq.committed(0))
+
+	msgs := recorder.Messages()
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "origin-topic", msgs[0].Topic)
+	assert.Equal(t, []byte("key"), msgs[0].Key)
+	assert.Equal(t, "1", msgs[0].Headers[xmq.HeaderBID])
+	assert.NotContains(t, msgs[0].Headers, xmq.HeaderRetryCount)
+	assert.NotContains(t, msgs[0].Headers, xmq.HeaderOriginalTopic)
+	// 没有原始 topic 时发送到默认 topic。
+	assert.Equal(t, "push-topic", msgs[1].Topic)
+
+	// 没有更多死信消息时等待 idleTimeout 后结束。
+	cnt, err = redriver.Redrive(t.Context(), 10, 10*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, 1, cnt)
+}
@@ -0,0 +1,155 @@
+package gateway
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+
+	"github.com/jrmarcco/synp/internal/pkg/xmq"
+)
+
+// offsetTracker 记录单个分区内消息的处理进度。
+// 同一分区的消息会并发处理，完成顺序与拉取顺序不一定一致，
+// 只有拉取顺序上连续完成的消息才能提交 offset，保证未处理完成的消息不会被提交。
+//
+// 分区重新分配后同一个 offset 可能被重复拉取，done 记录每个 offset 完成的次数，
+// 重复的 offset 各自完成后才会移出 pending，不会阻塞之后的提交。
+type offsetTracker struct {
+	mu sync.Mutex
+
+	pending []int64 // 按拉取顺序记录未提交的 offset
+	done    map[int64]int
+}
+
+func (t *offsetTracker) add(offset int64) {
+	t.mu.Lock()
+	t.pending = append(t.pending, offset)
+	t.mu.Unlock()
+}
+
+func (t *offsetTracker) markDone(offset int64) {
+	t.mu.Lock()
+	t.done[offset]++
+	t.mu.Unlock()
+}
+
+// committable 返回可以提交的最大 offset，并将其之前的记录移除。
+// 没有可以提交的 offset 时返回 false。
+func (t *offsetTracker) committable() (int64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	idx := 0
+	for idx < len(t.pending) {
+		offset := t.pending[idx]
+		if t.done[offset] == 0 {
+			break
+		}
+		if t.done[offset]--; t.done[offset] == 0 {
+			delete(t.done, offset)
+		}
+		idx++
+	}
+	if idx == 0 {
+		return 0, false
+	}
+
+	offset := t.pending[idx-1]
+	t.pending = t.pending[idx:]
+	return offset, true
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{
+		done: make(map[int64]int),
+	}
+}
+
+// partitionWorker 负责处理单个分区的消息。
+// 分区内的消息按 key 分配到固定数量的 worker 上，
+// 相同 key ( 同一用户 ) 的消息由同一个 worker 按顺序处理，某个用户处理缓慢时只会阻塞其所在的 worker。
+type partitionWorker struct {
+	topic     string
+	partition int
+
+	tracker *offsetTracker
+	queues  []chan *xmq.Message
+	last    int64 // 最近一次分配的 offset
+
+	wg sync.WaitGroup
+}
+
+// dispatch 将消息分配给对应的 worker，worker 队列已满时阻塞。
+func (w *partitionWorker) dispatch(ctx context.Context, msg *xmq.Message) error {
+	w.tracker.add(msg.Offset)
+	w.last = msg.Offset
+
+	select {
+	case w.queues[w.queueIndex(msg)] <- msg:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// rewound 判断分区的 offset 是否回退 ( 分区重新分配后从已提交的 offset 重新拉取 )。
+func (w *partitionWorker) rewound(msg *xmq.Message) bool {
+	return msg.Offset <= w.last
+}
+
+func (w *partitionWorker) queueIndex(msg *xmq.Message) int {
+	if len(msg.Key) == 0 {
+		// 没有 key 的消息不需要保证顺序，按 offset 分配。
+		return int(msg.Offset % int64(len(w.queues)))
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write(msg.Key)
+	return int(h.Sum32() % uint32(len(w.queues)))
+}
+
+// run 启动 worker，handle 返回 true 时表示消息处理完成 ( 可以提交 offset )。
+func (w *partitionWorker) run(ctx context.Context, handle func(ctx context.Context, msg *xmq.Message) bool) {
+	for _, queue := range w.queues {
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case msg, ok := <-queue:
+					if !ok {
+						return
+					}
+					if handle(ctx, msg) {
+						w.tracker.markDone(msg.Offset)
+					}
+				}
+			}
+		}()
+	}
+}
+
+// stop 关闭 worker 队列并等待 worker 退出。
+func (w *partitionWorker) stop() {
+	for _, queue := range w.queues {
+		close(queue)
+	}
+	w.wg.Wait()
+}
+
+func newPartitionWorker(topic string, partition, concurrency, queueSize int) *partitionWorker {
+	queues := make([]chan *xmq.Message, concurrency)
+	for i := range queues {
+		queues[i] = make(chan *xmq.Message, queueSize)
+	}
+
+	return &partitionWorker{
+		topic:     topic,
+		partition: partition,
+		tracker:   newOffsetTracker(),
+		queues:    queues,
+		last:      -1,
+	}
+}
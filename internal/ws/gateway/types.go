@@ -1,6 +1,9 @@
 package gateway
 
 const (
-	EventPushMessage = "push_message"
-	EventScaleUp     = "scale_up"
+	EventPushMessage      = "push_message"
+	EventPushMessageRetry = "push_message_retry" // 延迟重试的 push message
+	EventScaleUp          = "scale_up"
+	EventPresence         = "presence"
+	EventRPCReply         = "rpc_reply"
 )
@@ -1,8 +1,13 @@
 package ws
 
 import (
+	"context"
+	"fmt"
+	"os"
+
 	"github.com/jrmarcco/synp"
 	"github.com/jrmarcco/synp/internal/pkg/auth"
+	"github.com/jrmarcco/synp/internal/pkg/codec"
 	"github.com/jrmarcco/synp/internal/pkg/compression"
 	"github.com/redis/go-redis/v9"
 	"github.com/spf13/viper"
@@ -20,7 +25,13 @@ var WsUpgraderFxModule = fx.Module(
 	),
 )
 
-func newWsUpgrader(rdb redis.Cmdable, validator auth.Validator, logger *zap.Logger) (*Upgrader, error) {
+func newWsUpgrader(
+	rdb redis.Cmdable,
+	validator auth.Validator,
+	c codec.Codec,
+	lifecycle fx.Lifecycle,
+	logger *zap.Logger,
+) (*Upgrader, error) {
 	type config struct {
 		Enabled                 bool `mapstructure:"enabled"`
 		ServerMaxWindowBits     int  `mapstructure:"server_max_window_bits"`
@@ -28,6 +39,13 @@ func newWsUpgrader(rdb redis.Cmdable, validator auth.Validator, logger *zap.Logg
 		ClientMaxWindowBits     int  `mapstructure:"client_max_window_bits"`
 		ClientNoContextTakeover bool `mapstructure:"client_no_context_takeover"`
 		Level                   int  `mapstructure:"level"`
+		MinSize                 int  `mapstructure:"min_size"`
+
+		Zstd struct {
+			Enabled  bool   `mapstructure:"enabled"`
+			DictPath string `mapstructure:"dict_path"`
+			Level    int    `mapstructure:"level"`
+		} `mapstructure:"zstd"`
 	}
 
 	cfg := config{}
@@ -35,12 +53,44 @@ func newWsUpgrader(rdb redis.Cmdable, validator auth.Validator, logger *zap.Logg
 		return nil, err
 	}
 
-	return NewUpgrader(rdb, validator, compression.Config{
+	compressionConfig := compression.Config{
 		Enabled:                 cfg.Enabled,
 		ServerMaxWindowBits:     cfg.ServerMaxWindowBits,
 		ServerNoContextTakeover: cfg.ServerNoContextTakeover,
 		ClientMaxWindowBits:     cfg.ClientMaxWindowBits,
 		ClientNoContextTakeover: cfg.ClientNoContextTakeover,
 		Level:                   cfg.Level,
-	}, logger), nil
+		MinSize:                 cfg.MinSize,
+	}
+
+	if cfg.Enabled && cfg.Zstd.Enabled {
+		// 字典按 JSON 编码的消息训练，对 protobuf 编码的消息没有效果。
+		if _, ok := c.(*codec.JSONCodec); !ok {
+			return nil, fmt.Errorf("zstd compression requires json codec, got synp.codec.type: %s", c.Name())
+		}
+
+		dict, err := os.ReadFile(cfg.Zstd.DictPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read zstd dictionary: %w", err)
+		}
+
+		// 解压后的长度与前端消息的最大长度一致。
+		maxMessageSize := viper.GetInt64("synp.conn.manager.max_message_size")
+		zstdCodec, err := compression.NewZstd(dict, cfg.Zstd.Level, maxMessageSize)
+		if err != nil {
+			return nil, err
+		}
+		lifecycle.Append(fx.Hook{
+			OnStop: func(_ context.Context) error {
+				return zstdCodec.Close()
+			},
+		})
+		compressionConfig.Zstd = zstdCodec
+	}
+
+	trustProxyHeaders := viper.GetBool("synp.websocket.trust_proxy_headers")
+
+	return NewUpgrader(
+		rdb, validator, compressionConfig, logger, UpgraderWithTrustProxyHeaders(trustProxyHeaders),
+	), nil
 }
@@ -2,7 +2,12 @@ package ws
 
 import (
 	"github.com/jrmarcco/jit/bean/option"
+	"github.com/jrmarcco/synp/internal/pkg/codec"
 	"github.com/jrmarcco/synp/internal/pkg/limiter"
+	"github.com/jrmarcco/synp/internal/pkg/offline"
+	"github.com/jrmarcco/synp/internal/pkg/presence"
+	"github.com/jrmarcco/synp/internal/pkg/push"
+	"github.com/jrmarcco/synp/internal/pkg/rpc"
 )
 
 func SvrWithConnLimiter(connLimiter *limiter.TokenLimiter) option.Opt[Server] {
@@ -10,3 +15,35 @@ func SvrWithConnLimiter(connLimiter *limiter.TokenLimiter) option.Opt[Server] {
 		s.connLimiter = connLimiter
 	}
 }
+
+// SvrWithPushCodecs 设置解码后端推送消息使用的 Codec，按消息的 content-type header 选择。
+func SvrWithPushCodecs(pushCodecs *codec.Registry) option.Opt[Server] {
+	return func(s *Server) {
+		s.pushCodecs = pushCodecs
+	}
+}
+
+func SvrWithPresenceHub(presenceHub *presence.Hub) option.Opt[Server] {
+	return func(s *Server) {
+		s.presenceHub = presenceHub
+	}
+}
+
+func SvrWithOfflineStore(offlineStore offline.Store) option.Opt[Server] {
+	return func(s *Server) {
+		s.offlineStore = offlineStore
+	}
+}
+
+// SvrWithPusher 设置 Pusher，接收者在本节点没有连接时转发到其所在的节点，而不是直接保存为离线消息。
+func SvrWithPusher(pusher *push.Pusher) option.Opt[Server] {
+	return func(s *Server) {
+		s.pusher = pusher
+	}
+}
+
+func SvrWithRPCManager(rpcManager *rpc.Manager) option.Opt[Server] {
+	return func(s *Server) {
+		s.rpcManager = rpcManager
+	}
+}
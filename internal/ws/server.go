@@ -2,10 +2,10 @@ package ws
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net"
+	"strconv"
 	"sync/atomic"
 	"time"
 
@@ -13,7 +13,13 @@ import (
 	"github.com/jrmarcco/jit/bean/option"
 	"github.com/jrmarcco/synp"
 	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/jrmarcco/synp/internal/pkg/codec"
 	"github.com/jrmarcco/synp/internal/pkg/limiter"
+	"github.com/jrmarcco/synp/internal/pkg/message"
+	"github.com/jrmarcco/synp/internal/pkg/offline"
+	"github.com/jrmarcco/synp/internal/pkg/presence"
+	"github.com/jrmarcco/synp/internal/pkg/push"
+	"github.com/jrmarcco/synp/internal/pkg/rpc"
 	"github.com/jrmarcco/synp/internal/pkg/session"
 	"github.com/jrmarcco/synp/internal/pkg/xmq"
 	wsc "github.com/jrmarcco/synp/internal/ws/conn"
@@ -36,6 +42,13 @@ type Server struct {
 
 	consumers map[string]*gateway.Consumer
 
+	presenceHub  *presence.Hub
+	rpcManager   *rpc.Manager
+	offlineStore offline.Store
+	pusher       *push.Pusher // 投递给其他节点上的连接
+
+	pushCodecs *codec.Registry // 按 content-type 解码后端推送的消息
+
 	connLimiter *limiter.TokenLimiter
 	backoff     *backoff.ExponentialBackOff
 
@@ -63,7 +76,7 @@ func (s *Server) Start() error {
 	// 初始化网关业务消息消费者。
 	for key := range s.consumers {
 		switch key {
-		case gateway.EventPushMessage:
+		case gateway.EventPushMessage, gateway.EventPushMessageRetry:
 			consumer, ok := s.consumers[key]
 			if !ok {
 				s.logger.Warn("[synp-server] consumer not found", zap.String("event", key))
@@ -89,6 +102,40 @@ func (s *Server) Start() error {
 				)
 				return err
 			}
+		case gateway.EventPresence:
+			consumer, ok := s.consumers[key]
+			if !ok {
+				s.logger.Warn("[synp-server] consumer not found", zap.String("event", key))
+				continue
+			}
+			if s.presenceHub == nil {
+				s.logger.Warn("[synp-server] presence hub not set, skip presence consumer")
+				continue
+			}
+			if err := consumer.Start(s.ctx, s.consumePresence); err != nil {
+				s.logger.Error(
+					"[synp-server] failed to start presence consumer",
+					zap.Error(err),
+				)
+				return err
+			}
+		case gateway.EventRPCReply:
+			consumer, ok := s.consumers[key]
+			if !ok {
+				s.logger.Warn("[synp-server] consumer not found", zap.String("event", key))
+				continue
+			}
+			if s.rpcManager == nil {
+				s.logger.Warn("[synp-server] rpc manager not set, skip rpc reply consumer")
+				continue
+			}
+			if err := consumer.Start(s.ctx, s.consumeRPCReply); err != nil {
+				s.logger.Error(
+					"[synp-server] failed to start rpc reply consumer",
+					zap.Error(err),
+				)
+				return err
+			}
 		}
 	}
 
@@ -145,28 +192,67 @@ func (s *Server) acceptConn() {
 			continue
 		}
 
-		// 在追求极致性能的情况下，这里可以考虑使用连接池。
-		// 在绝大多数情况下，goroutine : connection = 1 : 1 即可。
+		// 默认 goroutine : connection = 1 : 1，
+		// 事件驱动模式下 handleConn 完成 upgrade 后即返回，由事件循环处理连接的收发。
 		go s.handleConn(conn)
 	}
 }
 
+// eventConn 为支持事件驱动模式的连接，见 wsc.Conn.Serve。
+type eventConn interface {
+	Serve(fn func(payload []byte), onClose func()) bool
+}
+
 // handleConn 处理 WebSocket 连接。
 func (s *Server) handleConn(conn net.Conn) {
-	// 归还令牌。
-	defer s.connLimiter.Release()
+	synpConn, ok := s.openConn(conn)
+	if !ok {
+		return
+	}
 
-	// 关闭连接。
-	defer func() {
-		err := conn.Close()
-		if err != nil && !errors.Is(err, net.ErrClosed) {
-			s.logger.Warn(
-				"[synp-server] failed to close connection",
-				zap.Error(err),
-			)
+	// 事件驱动模式下由事件循环读取消息，连接关闭后释放资源，不需要占用当前 goroutine。
+	if ec, ok := synpConn.(eventConn); ok && ec.Serve(
+		func(payload []byte) {
+			if errors.Is(s.receive(synpConn, payload), wsc.ErrConnClosed) {
+				_ = synpConn.Close()
+			}
+		},
+		func() {
+			s.closeConn(conn, synpConn)
+		},
+	) {
+		return
+	}
+	defer s.closeConn(conn, synpConn)
+
+	// 处理收发消息。
+	for {
+		select {
+		case message, ok := <-synpConn.Receive():
+			if !ok {
+				return
+			}
+			// 消息处理完成后放回缓冲区池，处理器需要保留的数据都已经解码复制。
+			err := s.receive(synpConn, message.Bytes())
+			message.Release()
+
+			// 如果连接已关闭，则直接返回 ( wsc => internal/ws/conn )。
+			if errors.Is(err, wsc.ErrConnClosed) {
+				return
+			}
+		case <-synpConn.Closed():
+			s.logger.Info("[synp-server] synp connection has been closed")
+			return
+		case <-s.ctx.Done():
+			s.logger.Info("[synp-server] server has been closed")
+			return
 		}
-	}()
+	}
+}
 
+// openConn 完成 upgrade 并创建连接，处理 on connect 事件。
+// 失败时释放连接占用的资源并返回 false。
+func (s *Server) openConn(conn net.Conn) (synp.Conn, bool) {
 	// 处理 upgrade 请求。
 	sess, compressionState, err := s.upgrader.Upgrade(conn)
 	if err != nil {
@@ -174,7 +260,8 @@ func (s *Server) handleConn(conn net.Conn) {
 			"[synp-server] failed to upgrade connection from HTTP to WebSocket",
 			zap.Error(err),
 		)
-		return
+		s.releaseConn(conn)
+		return nil, false
 	}
 
 	// 创建、管理连接。
@@ -185,22 +272,11 @@ func (s *Server) handleConn(conn net.Conn) {
 			"[synp-server] failed to create synp connection",
 			zap.Error(err),
 		)
-		return
+		s.releaseConn(conn)
+		return nil, false
 	}
 
-	user := synpConn.Session().User()
-	defer func() {
-		s.connManager.RemoveConn(user)
-		if err := synpConn.Close(); err != nil {
-			s.logger.Error(
-				"[synp-server] failed to close synp connection",
-				zap.String("conn_id", synpConn.ID()),
-				zap.Error(err),
-			)
-		}
-	}()
-
-	// 处理 on connect & on disconnect 事件。
+	// 处理 on connect 事件。
 	if err := s.connHandler.OnConnect(synpConn); err != nil {
 		s.logger.Error(
 			"[synp-server] failed to handle on connect lifecycle event",
@@ -210,63 +286,84 @@ func (s *Server) handleConn(conn net.Conn) {
 		// on connect 事件失败，直接返回。
 		// 注：
 		//  这里最好直接返回以防止后续的事件处理发生不可预料的错误。
-		return
+		s.removeConn(synpConn)
+		s.releaseConn(conn)
+		return nil, false
 	}
-	defer func() {
-		if err := s.connHandler.OnDisconnect(synpConn); err != nil {
-			s.logger.Error(
-				"[synp-server] failed to handle on disconnect lifecycle event",
-				zap.String("conn_id", synpConn.ID()),
-				zap.Error(err),
-			)
-		}
-	}()
+	return synpConn, true
+}
 
-	// 处理收发消息。
-	for {
-		select {
-		case message, ok := <-synpConn.Receive():
-			if !ok {
-				return
-			}
-			if err := s.connHandler.OnReceiveFromFrontend(synpConn, message); err != nil {
-				// 处理前端（业务客户端）发送的消息失败。
-				s.logger.Error(
-					"[synp-server] failed to handle on receive from frontend event",
-					zap.Error(err),
-				)
+// receive 处理前端（业务客户端）发送的消息，payload 只在处理期间有效。
+func (s *Server) receive(synpConn synp.Conn, payload []byte) error {
+	err := s.connHandler.OnReceiveFromFrontend(synpConn, payload)
+	if err != nil {
+		s.logger.Error(
+			"[synp-server] failed to handle on receive from frontend event",
+			zap.Error(err),
+		)
+	}
+	return err
+}
 
-				// 如果连接已关闭，则直接返回 ( wsc => internal/ws/conn )。
-				if errors.Is(err, wsc.ErrConnClosed) {
-					return
-				}
-			}
-		case <-synpConn.Closed():
-			s.logger.Info("[synp-server] synp connection has been closed")
-			return
-		case <-s.ctx.Done():
-			s.logger.Info("[synp-server] server has been closed")
-			return
-		}
+// closeConn 处理 on disconnect 事件，移除并关闭连接。
+func (s *Server) closeConn(conn net.Conn, synpConn synp.Conn) {
+	if err := s.connHandler.OnDisconnect(synpConn); err != nil {
+		s.logger.Error(
+			"[synp-server] failed to handle on disconnect lifecycle event",
+			zap.String("conn_id", synpConn.ID()),
+			zap.Error(err),
+		)
+	}
+
+	s.removeConn(synpConn)
+	s.releaseConn(conn)
+}
+
+func (s *Server) removeConn(synpConn synp.Conn) {
+	s.connManager.RemoveConn(synpConn.Session().User())
+	if err := synpConn.Close(); err != nil {
+		s.logger.Error(
+			"[synp-server] failed to close synp connection",
+			zap.String("conn_id", synpConn.ID()),
+			zap.Error(err),
+		)
+	}
+}
+
+// releaseConn 关闭底层连接并归还令牌。
+func (s *Server) releaseConn(conn net.Conn) {
+	defer s.connLimiter.Release()
+
+	err := conn.Close()
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		s.logger.Warn(
+			"[synp-server] failed to close connection",
+			zap.Error(err),
+		)
 	}
 }
 
 // consumePushMessage 消费 push message 事件。
-func (s *Server) consumePushMessage(_ context.Context, msg *xmq.Message) error {
-	pushMsg := &messagev1.PushMessage{}
-	// 后端 ( 业务服务端 ) 推送到消息队列的消息必须使用 json 格式，
-	// 所以直接使用 json 进行解码即可。
-	if err := json.Unmarshal(msg.Val, pushMsg); err != nil {
+func (s *Server) consumePushMessage(ctx context.Context, msg *xmq.Message) error {
+	pushMsg, err := s.decodePushMessage(msg)
+	if err != nil {
 		s.logger.Error(
-			"[synp-server] failed to unmarshal push message",
-			zap.String("message", string(msg.Val)),
+			"[synp-server] failed to decode push message",
+			zap.String("content_type", msg.Headers[xmq.HeaderContentType]),
+			zap.Int("size", len(msg.Val)),
 			zap.Error(err),
 		)
-		return err
+		// 消息格式错误，重试没有意义。
+		return fmt.Errorf("%w: %w", gateway.ErrNonRetryable, err)
 	}
 
 	conns, err := s.findConn(pushMsg)
 	if err != nil {
+		if errors.Is(err, ErrUnknownReceiver) {
+			// 消费者组由所有节点共享，接收者可能在其他节点上有连接。
+			return s.pushRemote(ctx, pushMsg)
+		}
+
 		s.logger.Error(
 			"[synp-server] failed to find connection for user",
 			zap.String("message", string(msg.Val)),
@@ -286,6 +383,41 @@ func (s *Server) consumePushMessage(_ context.Context, msg *xmq.Message) error {
 	return nil
 }
 
+// decodePushMessage 按消息的 content-type header 解码 push message。
+// 没有 content-type 时按 protojson 解码，
+// 优先级、合并 key 及过期时间由 x-synp-priority、x-synp-conflation-key 和 x-synp-expire-at header 指定。
+func (s *Server) decodePushMessage(msg *xmq.Message) (*messagev1.PushMessage, error) {
+	c, err := s.pushCodecs.Lookup(msg.Headers[xmq.HeaderContentType])
+	if err != nil {
+		return nil, err
+	}
+
+	pushMsg := &messagev1.PushMessage{}
+	if err = c.Unmarshal(msg.Val, pushMsg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal push message with %s codec: %w", c.Name(), err)
+	}
+
+	// header 指定的优先级、合并 key 及过期时间覆盖消息中的值。
+	if val, ok := msg.Headers[xmq.HeaderPriority]; ok {
+		priority, err := message.ParsePushPriority(val)
+		if err != nil {
+			return nil, err
+		}
+		message.SetPushPriority(pushMsg, priority)
+	}
+	if key, ok := msg.Headers[xmq.HeaderConflationKey]; ok {
+		message.SetConflationKey(pushMsg, key)
+	}
+	if val, ok := msg.Headers[xmq.HeaderExpireAt]; ok {
+		expireAt, err := strconv.ParseInt(val, 10, 64)
+		if err != nil || expireAt <= 0 {
+			return nil, fmt.Errorf("invalid %s header: %q", xmq.HeaderExpireAt, val)
+		}
+		message.SetExpireAt(pushMsg, time.UnixMilli(expireAt))
+	}
+	return pushMsg, nil
+}
+
 func (s *Server) findConn(pushMsg *messagev1.PushMessage) ([]synp.Conn, error) {
 	conns, ok := s.connManager.FindUserConn(session.User{
 		BID: pushMsg.GetBizId(),
@@ -297,6 +429,91 @@ func (s *Server) findConn(pushMsg *messagev1.PushMessage) ([]synp.Conn, error) {
 	return conns, nil
 }
 
+// pushRemote 处理接收者在本节点没有连接的消息。
+// 设置了 Pusher 时根据在线状态转发到接收者所在的节点，接收者不在任何节点在线时才保存为离线消息；
+// 否则直接保存为离线消息。
+func (s *Server) pushRemote(ctx context.Context, pushMsg *messagev1.PushMessage) error {
+	if s.pusher == nil {
+		return s.saveOffline(ctx, pushMsg)
+	}
+
+	if err := push.Validate(pushMsg); err != nil {
+		return fmt.Errorf("%w: %w", gateway.ErrNonRetryable, err)
+	}
+
+	res := s.pusher.Push(ctx, pushMsg, 0)
+	switch res.Status {
+	case push.StatusFailed:
+		s.logger.Error(
+			"[synp-server] failed to push message to remote receiver",
+			zap.String("message_id", pushMsg.GetMessageId()),
+			zap.Uint64("biz_id", pushMsg.GetBizId()),
+			zap.Uint64("receiver_id", pushMsg.GetReceiverId()),
+			zap.String("node_id", res.NodeID),
+			zap.String("error", res.Error),
+		)
+		return fmt.Errorf("failed to push message %s: %s", pushMsg.GetMessageId(), res.Error)
+	case push.StatusUnknownReceiver:
+		s.logger.Warn(
+			"[synp-server] receiver is offline and offline store not set, drop message",
+			zap.String("message_id", pushMsg.GetMessageId()),
+			zap.Uint64("biz_id", pushMsg.GetBizId()),
+			zap.Uint64("receiver_id", pushMsg.GetReceiverId()),
+		)
+	}
+	return nil
+}
+
+// saveOffline 保存接收者不在线的消息，接收者下次连接时重新推送。
+// 没有设置离线消息存储时丢弃消息。
+func (s *Server) saveOffline(ctx context.Context, pushMsg *messagev1.PushMessage) error {
+	if s.offlineStore == nil {
+		s.logger.Warn(
+			"[synp-server] receiver is offline and offline store not set, drop message",
+			zap.String("message_id", pushMsg.GetMessageId()),
+			zap.Uint64("biz_id", pushMsg.GetBizId()),
+			zap.Uint64("receiver_id", pushMsg.GetReceiverId()),
+		)
+		return nil
+	}
+
+	if err := s.offlineStore.Save(ctx, pushMsg); err != nil {
+		s.logger.Error(
+			"[synp-server] failed to save offline message",
+			zap.String("message_id", pushMsg.GetMessageId()),
+			zap.Error(err),
+		)
+		return err
+	}
+	return nil
+}
+
+// consumePresence 消费在线状态事件，推送给本节点的订阅者。
+func (s *Server) consumePresence(_ context.Context, msg *xmq.Message) error {
+	if err := s.presenceHub.Consume(msg.Val); err != nil {
+		s.logger.Error(
+			"[synp-server] failed to consume presence event",
+			zap.String("message", string(msg.Val)),
+			zap.Error(err),
+		)
+		return err
+	}
+	return nil
+}
+
+// consumeRPCReply 消费 RPC 响应，回复给本节点发起调用的连接。
+func (s *Server) consumeRPCReply(_ context.Context, msg *xmq.Message) error {
+	if err := s.rpcManager.Consume(msg.Val); err != nil {
+		s.logger.Error(
+			"[synp-server] failed to consume rpc reply",
+			zap.String("message", string(msg.Val)),
+			zap.Error(err),
+		)
+		return err
+	}
+	return nil
+}
+
 // consumeScaleUp 消费 scale up 事件。
 func (s *Server) consumeScaleUp(_ context.Context, _ *xmq.Message) error {
 	// TODO: not implemented
@@ -304,6 +521,13 @@ func (s *Server) consumeScaleUp(_ context.Context, _ *xmq.Message) error {
 }
 
 func (s *Server) Shutdown() error {
+	// 停止消费者，提交已处理完成的消息。
+	for key, consumer := range s.consumers {
+		if err := consumer.Stop(); err != nil {
+			s.logger.Warn("[synp-server] failed to stop consumer", zap.String("event", key), zap.Error(err))
+		}
+	}
+
 	// 关闭限流器。
 	if err := s.connLimiter.Close(); err != nil {
 		s.logger.Error("[synp-server] failed to close connection limiter", zap.Error(err))
@@ -346,6 +570,7 @@ func NewServer(
 		connManager: connManager,
 		connHandler: connHandler,
 
+		pushCodecs:  codec.NewDefaultRegistry(),                       // 默认支持 protojson、protobuf 及 CloudEvents。
 		connLimiter: limiter.NewTokenLimiter(limiter.DefaultConfig()), // 默认令牌桶限流器。
 		backoff:     backoff.NewExponentialBackOff(),                  // 默认退避策略。
 
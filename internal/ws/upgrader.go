@@ -11,6 +11,7 @@ import (
 	"github.com/gobwas/httphead"
 	"github.com/gobwas/ws"
 	"github.com/gobwas/ws/wsflate"
+	"github.com/jrmarcco/jit/bean/option"
 	"github.com/jrmarcco/synp"
 	"github.com/jrmarcco/synp/internal/pkg/auth"
 	"github.com/jrmarcco/synp/internal/pkg/compression"
@@ -34,6 +35,10 @@ type Upgrader struct {
 	validator         auth.Validator
 	compressionConfig compression.Config
 
+	// 是否信任代理设置的 X-Forwarded-For / X-Real-IP 头。
+	// 只有网关部署在可信的反向代理之后时才能开启，否则客户端可以伪造 IP。
+	trustProxyHeaders bool
+
 	logger *zap.Logger
 }
 
@@ -51,16 +56,32 @@ func (u *Upgrader) Upgrade(conn net.Conn) (session.Session, *compression.State,
 		u.logger.Info("[synp-upgrader] compression enabled", zap.Any("params", params))
 	}
 
+	// zstd 与 permessage-deflate 都使用 RSV1 标记压缩的消息，只能启用其中一个。
+	var zstdAccepted bool
+	zstdCodec := u.compressionConfig.Zstd
+
 	var user session.User
 	var sess session.Session
 	var autoClose bool
+	var forwardedFor, realIP string
 	upgrader := ws.Upgrader{
 		// 协商过程，这里主要是压缩相关的协商（是否启用以及压缩算法）。
+		// 客户端按偏好顺序提供扩展，先协商成功的扩展生效。
 		Negotiate: func(opt httphead.Option) (httphead.Option, error) {
-			if ext != nil {
-				return ext.Negotiate(opt)
+			if ext == nil || zstdAccepted {
+				return httphead.Option{}, nil
+			}
+			if _, accepted := ext.Accepted(); accepted {
+				return httphead.Option{}, nil
 			}
-			return httphead.Option{}, nil
+
+			if zstdCodec != nil {
+				if accept, ok := zstdCodec.Negotiate(opt); ok {
+					zstdAccepted = true
+					return accept, nil
+				}
+			}
+			return ext.Negotiate(opt)
 		},
 		OnRequest: func(uri []byte) error {
 			// 验证 token 并提取用户信息。
@@ -71,6 +92,13 @@ func (u *Upgrader) Upgrade(conn net.Conn) (session.Session, *compression.State,
 			return nil
 		},
 		OnHeader: func(key, value []byte) error {
+			switch {
+			case strings.EqualFold(string(key), "x-forwarded-for"):
+				forwardedFor = string(value)
+			case strings.EqualFold(string(key), "x-real-ip"):
+				realIP = string(value)
+			}
+
 			// 解析 auto close 参数。
 			if strings.EqualFold(string(key), "x-auto-close") {
 				autoClose = string(value) == "true"
@@ -86,6 +114,7 @@ func (u *Upgrader) Upgrade(conn net.Conn) (session.Session, *compression.State,
 		OnBeforeUpgrade: func() (header ws.HandshakeHeader, err error) {
 			// 设置 auto close 参数。
 			user.AutoClose = autoClose
+			user.ClientIP = u.clientIP(conn, forwardedFor, realIP)
 
 			// 初始化 session。
 			sessionBuilder := sr.NewSessionBuilder(u.rdb)
@@ -105,6 +134,8 @@ func (u *Upgrader) Upgrade(conn net.Conn) (session.Session, *compression.State,
 
 	state := compression.State{
 		Enabled: false,
+		Level:   u.compressionConfig.Level,
+		MinSize: u.compressionConfig.MinSize,
 	}
 
 	if _, err := upgrader.Upgrade(conn); err != nil {
@@ -112,6 +143,16 @@ func (u *Upgrader) Upgrade(conn net.Conn) (session.Session, *compression.State,
 	}
 
 	// 检查协商压缩的结果。
+	if zstdAccepted {
+		state.Enabled = true
+		state.Zstd = zstdCodec
+
+		u.logger.Info(
+			"[synp-upgrader] successfully negotiated zstd compression",
+			zap.Uint32("dict_id", zstdCodec.DictID()),
+		)
+		return sess, &state, nil
+	}
 	if ext != nil {
 		if params, accepted := ext.Accepted(); accepted {
 			state.Enabled = true
@@ -131,6 +172,28 @@ func (u *Upgrader) Upgrade(conn net.Conn) (session.Session, *compression.State,
 	return sess, &state, nil
 }
 
+// clientIP 获取客户端 IP。
+// 信任代理头时优先使用 X-Forwarded-For 中的第一个地址，其次是 X-Real-IP，最后是连接的远端地址。
+func (u *Upgrader) clientIP(conn net.Conn, forwardedFor, realIP string) string {
+	if u.trustProxyHeaders {
+		if first, _, _ := strings.Cut(forwardedFor, ","); strings.TrimSpace(first) != "" {
+			return strings.TrimSpace(first)
+		}
+		if realIP = strings.TrimSpace(realIP); realIP != "" {
+			return realIP
+		}
+	}
+
+	if conn.RemoteAddr() == nil {
+		return ""
+	}
+	addr := conn.RemoteAddr().String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
 // extractToken 从 URI 中提取 token。
 func (u *Upgrader) extractToken(uri []byte) (string, error) {
 	parsedURL, err := url.Parse(string(uri))
@@ -193,11 +256,27 @@ func (u *Upgrader) extractUserInfo(uri []byte) (session.User, error) {
 	return user, nil
 }
 
-func NewUpgrader(rdb redis.Cmdable, validator auth.Validator, compressionConfig compression.Config, logger *zap.Logger) *Upgrader {
-	return &Upgrader{
+// UpgraderWithTrustProxyHeaders 设置是否信任代理设置的客户端 IP 头。
+func UpgraderWithTrustProxyHeaders(trust bool) option.Opt[Upgrader] {
+	return func(u *Upgrader) {
+		u.trustProxyHeaders = trust
+	}
+}
+
+func NewUpgrader(
+	rdb redis.Cmdable,
+	validator auth.Validator,
+	compressionConfig compression.Config,
+	logger *zap.Logger,
+	opts ...option.Opt[Upgrader],
+) *Upgrader {
+	u := &Upgrader{
 		rdb:               rdb,
 		validator:         validator,
 		compressionConfig: compressionConfig,
 		logger:            logger,
 	}
+
+	option.Apply(u, opts...)
+	return u
 }
@@ -0,0 +1,18 @@
+package synpmock
+
+import (
+	"github.com/jrmarcco/synp/internal/pkg/session"
+	sessionmock "github.com/jrmarcco/synp/internal/pkg/session/mock"
+	"go.uber.org/mock/gomock"
+)
+
+// NewUserConn 创建 user 的 MockConn，ID 和 Session 可以调用任意次，其他方法由测试按需设置预期。
+func NewUserConn(ctrl *gomock.Controller, user session.User) *MockConn {
+	sess := sessionmock.NewMockSession(ctrl)
+	sess.EXPECT().User().Return(user).AnyTimes()
+
+	conn := NewMockConn(ctrl)
+	conn.EXPECT().ID().Return(user.ConnID()).AnyTimes()
+	conn.EXPECT().Session().Return(sess).AnyTimes()
+	return conn
+}
@@ -0,0 +1,1018 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: types.go
+//
+// Generated by this command:
+//
+//	mockgen -source=types.go -destination=mock/synp.mock.go -package=synpmock -typed
+//
+
+// Package synpmock is a generated GoMock package.
+package synpmock
+
+import (
+	context "context"
+	net "net"
+	reflect "reflect"
+
+	synp "github.com/jrmarcco/synp"
+	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	bufpool "github.com/jrmarcco/synp/internal/pkg/bufpool"
+	compression "github.com/jrmarcco/synp/internal/pkg/compression"
+	session "github.com/jrmarcco/synp/internal/pkg/session"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockServer is a mock of Server interface.
+type MockServer struct {
+	ctrl     *gomock.Controller
+	recorder *MockServerMockRecorder
+	isgomock struct{}
+}
+
+// MockServerMockRecorder is the mock recorder for MockServer.
+type MockServerMockRecorder struct {
+	mock *MockServer
+}
+
+// NewMockServer creates a new mock instance.
+func NewMockServer(ctrl *gomock.Controller) *MockServer {
+	mock := &MockServer{ctrl: ctrl}
+	mock.recorder = &MockServerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockServer) EXPECT() *MockServerMockRecorder {
+	return m.recorder
+}
+
+// GracefulShutdown mocks base method.
+func (m *MockServer) GracefulShutdown() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GracefulShutdown")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// GracefulShutdown indicates an expected call of GracefulShutdown.
+func (mr *MockServerMockRecorder) GracefulShutdown() *MockServerGracefulShutdownCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GracefulShutdown", reflect.TypeOf((*MockServer)(nil).GracefulShutdown))
+	return &MockServerGracefulShutdownCall{Call: call}
+}
+
+// MockServerGracefulShutdownCall wrap *gomock.Call
+type MockServerGracefulShutdownCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockServerGracefulShutdownCall) Return(arg0 error) *MockServerGracefulShutdownCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockServerGracefulShutdownCall) Do(f func() error) *MockServerGracefulShutdownCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockServerGracefulShutdownCall) DoAndReturn(f func() error) *MockServerGracefulShutdownCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// Shutdown mocks base method.
+func (m *MockServer) Shutdown() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Shutdown")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Shutdown indicates an expected call of Shutdown.
+func (mr *MockServerMockRecorder) Shutdown() *MockServerShutdownCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Shutdown", reflect.TypeOf((*MockServer)(nil).Shutdown))
+	return &MockServerShutdownCall{Call: call}
+}
+
+// MockServerShutdownCall wrap *gomock.Call
+type MockServerShutdownCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockServerShutdownCall) Return(arg0 error) *MockServerShutdownCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockServerShutdownCall) Do(f func() error) *MockServerShutdownCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockServerShutdownCall) DoAndReturn(f func() error) *MockServerShutdownCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// Start mocks base method.
+func (m *MockServer) Start() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Start")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Start indicates an expected call of Start.
+func (mr *MockServerMockRecorder) Start() *MockServerStartCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Start", reflect.TypeOf((*MockServer)(nil).Start))
+	return &MockServerStartCall{Call: call}
+}
+
+// MockServerStartCall wrap *gomock.Call
+type MockServerStartCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockServerStartCall) Return(arg0 error) *MockServerStartCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockServerStartCall) Do(f func() error) *MockServerStartCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockServerStartCall) DoAndReturn(f func() error) *MockServerStartCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// MockUpgrader is a mock of Upgrader interface.
+type MockUpgrader struct {
+	ctrl     *gomock.Controller
+	recorder *MockUpgraderMockRecorder
+	isgomock struct{}
+}
+
+// MockUpgraderMockRecorder is the mock recorder for MockUpgrader.
+type MockUpgraderMockRecorder struct {
+	mock *MockUpgrader
+}
+
+// NewMockUpgrader creates a new mock instance.
+func NewMockUpgrader(ctrl *gomock.Controller) *MockUpgrader {
+	mock := &MockUpgrader{ctrl: ctrl}
+	mock.recorder = &MockUpgraderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUpgrader) EXPECT() *MockUpgraderMockRecorder {
+	return m.recorder
+}
+
+// Name mocks base method.
+func (m *MockUpgrader) Name() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Name")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// Name indicates an expected call of Name.
+func (mr *MockUpgraderMockRecorder) Name() *MockUpgraderNameCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Name", reflect.TypeOf((*MockUpgrader)(nil).Name))
+	return &MockUpgraderNameCall{Call: call}
+}
+
+// MockUpgraderNameCall wrap *gomock.Call
+type MockUpgraderNameCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockUpgraderNameCall) Return(arg0 string) *MockUpgraderNameCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockUpgraderNameCall) Do(f func() string) *MockUpgraderNameCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockUpgraderNameCall) DoAndReturn(f func() string) *MockUpgraderNameCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// Upgrade mocks base method.
+func (m *MockUpgrader) Upgrade(conn net.Conn) (session.Session, *compression.State, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Upgrade", conn)
+	ret0, _ := ret[0].(session.Session)
+	ret1, _ := ret[1].(*compression.State)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Upgrade indicates an expected call of Upgrade.
+func (mr *MockUpgraderMockRecorder) Upgrade(conn any) *MockUpgraderUpgradeCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Upgrade", reflect.TypeOf((*MockUpgrader)(nil).Upgrade), conn)
+	return &MockUpgraderUpgradeCall{Call: call}
+}
+
+// MockUpgraderUpgradeCall wrap *gomock.Call
+type MockUpgraderUpgradeCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockUpgraderUpgradeCall) Return(arg0 session.Session, arg1 *compression.State, arg2 error) *MockUpgraderUpgradeCall {
+	c.Call = c.Call.Return(arg0, arg1, arg2)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockUpgraderUpgradeCall) Do(f func(net.Conn) (session.Session, *compression.State, error)) *MockUpgraderUpgradeCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockUpgraderUpgradeCall) DoAndReturn(f func(net.Conn) (session.Session, *compression.State, error)) *MockUpgraderUpgradeCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// MockConn is a mock of Conn interface.
+type MockConn struct {
+	ctrl     *gomock.Controller
+	recorder *MockConnMockRecorder
+	isgomock struct{}
+}
+
+// MockConnMockRecorder is the mock recorder for MockConn.
+type MockConnMockRecorder struct {
+	mock *MockConn
+}
+
+// NewMockConn creates a new mock instance.
+func NewMockConn(ctrl *gomock.Controller) *MockConn {
+	mock := &MockConn{ctrl: ctrl}
+	mock.recorder = &MockConnMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockConn) EXPECT() *MockConnMockRecorder {
+	return m.recorder
+}
+
+// Close mocks base method.
+func (m *MockConn) Close() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Close")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockConnMockRecorder) Close() *MockConnCloseCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockConn)(nil).Close))
+	return &MockConnCloseCall{Call: call}
+}
+
+// MockConnCloseCall wrap *gomock.Call
+type MockConnCloseCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockConnCloseCall) Return(arg0 error) *MockConnCloseCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockConnCloseCall) Do(f func() error) *MockConnCloseCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockConnCloseCall) DoAndReturn(f func() error) *MockConnCloseCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// Closed mocks base method.
+func (m *MockConn) Closed() <-chan struct{} {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Closed")
+	ret0, _ := ret[0].(<-chan struct{})
+	return ret0
+}
+
+// Closed indicates an expected call of Closed.
+func (mr *MockConnMockRecorder) Closed() *MockConnClosedCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Closed", reflect.TypeOf((*MockConn)(nil).Closed))
+	return &MockConnClosedCall{Call: call}
+}
+
+// MockConnClosedCall wrap *gomock.Call
+type MockConnClosedCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockConnClosedCall) Return(arg0 <-chan struct{}) *MockConnClosedCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockConnClosedCall) Do(f func() <-chan struct{}) *MockConnClosedCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockConnClosedCall) DoAndReturn(f func() <-chan struct{}) *MockConnClosedCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// ID mocks base method.
+func (m *MockConn) ID() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ID")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// ID indicates an expected call of ID.
+func (mr *MockConnMockRecorder) ID() *MockConnIDCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ID", reflect.TypeOf((*MockConn)(nil).ID))
+	return &MockConnIDCall{Call: call}
+}
+
+// MockConnIDCall wrap *gomock.Call
+type MockConnIDCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockConnIDCall) Return(arg0 string) *MockConnIDCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockConnIDCall) Do(f func() string) *MockConnIDCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockConnIDCall) DoAndReturn(f func() string) *MockConnIDCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// Receive mocks base method.
+func (m *MockConn) Receive() <-chan *bufpool.Buffer {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Receive")
+	ret0, _ := ret[0].(<-chan *bufpool.Buffer)
+	return ret0
+}
+
+// Receive indicates an expected call of Receive.
+func (mr *MockConnMockRecorder) Receive() *MockConnReceiveCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Receive", reflect.TypeOf((*MockConn)(nil).Receive))
+	return &MockConnReceiveCall{Call: call}
+}
+
+// MockConnReceiveCall wrap *gomock.Call
+type MockConnReceiveCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockConnReceiveCall) Return(arg0 <-chan *bufpool.Buffer) *MockConnReceiveCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockConnReceiveCall) Do(f func() <-chan *bufpool.Buffer) *MockConnReceiveCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockConnReceiveCall) DoAndReturn(f func() <-chan *bufpool.Buffer) *MockConnReceiveCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// Send mocks base method.
+func (m *MockConn) Send(payload []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Send", payload)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Send indicates an expected call of Send.
+func (mr *MockConnMockRecorder) Send(payload any) *MockConnSendCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*MockConn)(nil).Send), payload)
+	return &MockConnSendCall{Call: call}
+}
+
+// MockConnSendCall wrap *gomock.Call
+type MockConnSendCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockConnSendCall) Return(arg0 error) *MockConnSendCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockConnSendCall) Do(f func([]byte) error) *MockConnSendCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockConnSendCall) DoAndReturn(f func([]byte) error) *MockConnSendCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// SendFrame mocks base method.
+func (m *MockConn) SendFrame(frame synp.Frame) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendFrame", frame)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendFrame indicates an expected call of SendFrame.
+func (mr *MockConnMockRecorder) SendFrame(frame any) *MockConnSendFrameCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendFrame", reflect.TypeOf((*MockConn)(nil).SendFrame), frame)
+	return &MockConnSendFrameCall{Call: call}
+}
+
+// MockConnSendFrameCall wrap *gomock.Call
+type MockConnSendFrameCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockConnSendFrameCall) Return(arg0 error) *MockConnSendFrameCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockConnSendFrameCall) Do(f func(synp.Frame) error) *MockConnSendFrameCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockConnSendFrameCall) DoAndReturn(f func(synp.Frame) error) *MockConnSendFrameCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// Session mocks base method.
+func (m *MockConn) Session() session.Session {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Session")
+	ret0, _ := ret[0].(session.Session)
+	return ret0
+}
+
+// Session indicates an expected call of Session.
+func (mr *MockConnMockRecorder) Session() *MockConnSessionCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Session", reflect.TypeOf((*MockConn)(nil).Session))
+	return &MockConnSessionCall{Call: call}
+}
+
+// MockConnSessionCall wrap *gomock.Call
+type MockConnSessionCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockConnSessionCall) Return(arg0 session.Session) *MockConnSessionCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockConnSessionCall) Do(f func() session.Session) *MockConnSessionCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockConnSessionCall) DoAndReturn(f func() session.Session) *MockConnSessionCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// UpdateActivityTime mocks base method.
+func (m *MockConn) UpdateActivityTime() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UpdateActivityTime")
+}
+
+// UpdateActivityTime indicates an expected call of UpdateActivityTime.
+func (mr *MockConnMockRecorder) UpdateActivityTime() *MockConnUpdateActivityTimeCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateActivityTime", reflect.TypeOf((*MockConn)(nil).UpdateActivityTime))
+	return &MockConnUpdateActivityTimeCall{Call: call}
+}
+
+// MockConnUpdateActivityTimeCall wrap *gomock.Call
+type MockConnUpdateActivityTimeCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockConnUpdateActivityTimeCall) Return() *MockConnUpdateActivityTimeCall {
+	c.Call = c.Call.Return()
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockConnUpdateActivityTimeCall) Do(f func()) *MockConnUpdateActivityTimeCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockConnUpdateActivityTimeCall) DoAndReturn(f func()) *MockConnUpdateActivityTimeCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// MockConnManager is a mock of ConnManager interface.
+type MockConnManager struct {
+	ctrl     *gomock.Controller
+	recorder *MockConnManagerMockRecorder
+	isgomock struct{}
+}
+
+// MockConnManagerMockRecorder is the mock recorder for MockConnManager.
+type MockConnManagerMockRecorder struct {
+	mock *MockConnManager
+}
+
+// NewMockConnManager creates a new mock instance.
+func NewMockConnManager(ctrl *gomock.Controller) *MockConnManager {
+	mock := &MockConnManager{ctrl: ctrl}
+	mock.recorder = &MockConnManagerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockConnManager) EXPECT() *MockConnManagerMockRecorder {
+	return m.recorder
+}
+
+// FindConn mocks base method.
+func (m *MockConnManager) FindConn(user session.User) (synp.Conn, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindConn", user)
+	ret0, _ := ret[0].(synp.Conn)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// FindConn indicates an expected call of FindConn.
+func (mr *MockConnManagerMockRecorder) FindConn(user any) *MockConnManagerFindConnCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindConn", reflect.TypeOf((*MockConnManager)(nil).FindConn), user)
+	return &MockConnManagerFindConnCall{Call: call}
+}
+
+// MockConnManagerFindConnCall wrap *gomock.Call
+type MockConnManagerFindConnCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockConnManagerFindConnCall) Return(arg0 synp.Conn, arg1 bool) *MockConnManagerFindConnCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockConnManagerFindConnCall) Do(f func(session.User) (synp.Conn, bool)) *MockConnManagerFindConnCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockConnManagerFindConnCall) DoAndReturn(f func(session.User) (synp.Conn, bool)) *MockConnManagerFindConnCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// FindUserConn mocks base method.
+func (m *MockConnManager) FindUserConn(user session.User) ([]synp.Conn, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindUserConn", user)
+	ret0, _ := ret[0].([]synp.Conn)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// FindUserConn indicates an expected call of FindUserConn.
+func (mr *MockConnManagerMockRecorder) FindUserConn(user any) *MockConnManagerFindUserConnCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUserConn", reflect.TypeOf((*MockConnManager)(nil).FindUserConn), user)
+	return &MockConnManagerFindUserConnCall{Call: call}
+}
+
+// MockConnManagerFindUserConnCall wrap *gomock.Call
+type MockConnManagerFindUserConnCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockConnManagerFindUserConnCall) Return(arg0 []synp.Conn, arg1 bool) *MockConnManagerFindUserConnCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockConnManagerFindUserConnCall) Do(f func(session.User) ([]synp.Conn, bool)) *MockConnManagerFindUserConnCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockConnManagerFindUserConnCall) DoAndReturn(f func(session.User) ([]synp.Conn, bool)) *MockConnManagerFindUserConnCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// NewConn mocks base method.
+func (m *MockConnManager) NewConn(ctx context.Context, netConn net.Conn, sess session.Session, compressionState *compression.State) (synp.Conn, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NewConn", ctx, netConn, sess, compressionState)
+	ret0, _ := ret[0].(synp.Conn)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// NewConn indicates an expected call of NewConn.
+func (mr *MockConnManagerMockRecorder) NewConn(ctx, netConn, sess, compressionState any) *MockConnManagerNewConnCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewConn", reflect.TypeOf((*MockConnManager)(nil).NewConn), ctx, netConn, sess, compressionState)
+	return &MockConnManagerNewConnCall{Call: call}
+}
+
+// MockConnManagerNewConnCall wrap *gomock.Call
+type MockConnManagerNewConnCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockConnManagerNewConnCall) Return(arg0 synp.Conn, arg1 error) *MockConnManagerNewConnCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockConnManagerNewConnCall) Do(f func(context.Context, net.Conn, session.Session, *compression.State) (synp.Conn, error)) *MockConnManagerNewConnCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockConnManagerNewConnCall) DoAndReturn(f func(context.Context, net.Conn, session.Session, *compression.State) (synp.Conn, error)) *MockConnManagerNewConnCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// RangeConn mocks base method.
+func (m *MockConnManager) RangeConn(fn func(synp.Conn) bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RangeConn", fn)
+}
+
+// RangeConn indicates an expected call of RangeConn.
+func (mr *MockConnManagerMockRecorder) RangeConn(fn any) *MockConnManagerRangeConnCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RangeConn", reflect.TypeOf((*MockConnManager)(nil).RangeConn), fn)
+	return &MockConnManagerRangeConnCall{Call: call}
+}
+
+// MockConnManagerRangeConnCall wrap *gomock.Call
+type MockConnManagerRangeConnCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockConnManagerRangeConnCall) Return() *MockConnManagerRangeConnCall {
+	c.Call = c.Call.Return()
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockConnManagerRangeConnCall) Do(f func(func(synp.Conn) bool)) *MockConnManagerRangeConnCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockConnManagerRangeConnCall) DoAndReturn(f func(func(synp.Conn) bool)) *MockConnManagerRangeConnCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// RemoveConn mocks base method.
+func (m *MockConnManager) RemoveConn(user session.User) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveConn", user)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// RemoveConn indicates an expected call of RemoveConn.
+func (mr *MockConnManagerMockRecorder) RemoveConn(user any) *MockConnManagerRemoveConnCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveConn", reflect.TypeOf((*MockConnManager)(nil).RemoveConn), user)
+	return &MockConnManagerRemoveConnCall{Call: call}
+}
+
+// MockConnManagerRemoveConnCall wrap *gomock.Call
+type MockConnManagerRemoveConnCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockConnManagerRemoveConnCall) Return(arg0 bool) *MockConnManagerRemoveConnCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockConnManagerRemoveConnCall) Do(f func(session.User) bool) *MockConnManagerRemoveConnCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockConnManagerRemoveConnCall) DoAndReturn(f func(session.User) bool) *MockConnManagerRemoveConnCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// RemoveUserConn mocks base method.
+func (m *MockConnManager) RemoveUserConn(user session.User) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveUserConn", user)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// RemoveUserConn indicates an expected call of RemoveUserConn.
+func (mr *MockConnManagerMockRecorder) RemoveUserConn(user any) *MockConnManagerRemoveUserConnCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveUserConn", reflect.TypeOf((*MockConnManager)(nil).RemoveUserConn), user)
+	return &MockConnManagerRemoveUserConnCall{Call: call}
+}
+
+// MockConnManagerRemoveUserConnCall wrap *gomock.Call
+type MockConnManagerRemoveUserConnCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockConnManagerRemoveUserConnCall) Return(arg0 bool) *MockConnManagerRemoveUserConnCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockConnManagerRemoveUserConnCall) Do(f func(session.User) bool) *MockConnManagerRemoveUserConnCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockConnManagerRemoveUserConnCall) DoAndReturn(f func(session.User) bool) *MockConnManagerRemoveUserConnCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// MockHandler is a mock of Handler interface.
+type MockHandler struct {
+	ctrl     *gomock.Controller
+	recorder *MockHandlerMockRecorder
+	isgomock struct{}
+}
+
+// MockHandlerMockRecorder is the mock recorder for MockHandler.
+type MockHandlerMockRecorder struct {
+	mock *MockHandler
+}
+
+// NewMockHandler creates a new mock instance.
+func NewMockHandler(ctrl *gomock.Controller) *MockHandler {
+	mock := &MockHandler{ctrl: ctrl}
+	mock.recorder = &MockHandlerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockHandler) EXPECT() *MockHandlerMockRecorder {
+	return m.recorder
+}
+
+// OnConnect mocks base method.
+func (m *MockHandler) OnConnect(conn synp.Conn) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OnConnect", conn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// OnConnect indicates an expected call of OnConnect.
+func (mr *MockHandlerMockRecorder) OnConnect(conn any) *MockHandlerOnConnectCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnConnect", reflect.TypeOf((*MockHandler)(nil).OnConnect), conn)
+	return &MockHandlerOnConnectCall{Call: call}
+}
+
+// MockHandlerOnConnectCall wrap *gomock.Call
+type MockHandlerOnConnectCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockHandlerOnConnectCall) Return(arg0 error) *MockHandlerOnConnectCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockHandlerOnConnectCall) Do(f func(synp.Conn) error) *MockHandlerOnConnectCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockHandlerOnConnectCall) DoAndReturn(f func(synp.Conn) error) *MockHandlerOnConnectCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// OnDisconnect mocks base method.
+func (m *MockHandler) OnDisconnect(conn synp.Conn) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OnDisconnect", conn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// OnDisconnect indicates an expected call of OnDisconnect.
+func (mr *MockHandlerMockRecorder) OnDisconnect(conn any) *MockHandlerOnDisconnectCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnDisconnect", reflect.TypeOf((*MockHandler)(nil).OnDisconnect), conn)
+	return &MockHandlerOnDisconnectCall{Call: call}
+}
+
+// MockHandlerOnDisconnectCall wrap *gomock.Call
+type MockHandlerOnDisconnectCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockHandlerOnDisconnectCall) Return(arg0 error) *MockHandlerOnDisconnectCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockHandlerOnDisconnectCall) Do(f func(synp.Conn) error) *MockHandlerOnDisconnectCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockHandlerOnDisconnectCall) DoAndReturn(f func(synp.Conn) error) *MockHandlerOnDisconnectCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// OnReceiveFromBackend mocks base method.
+func (m *MockHandler) OnReceiveFromBackend(conns []synp.Conn, pushMsg *messagev1.PushMessage) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OnReceiveFromBackend", conns, pushMsg)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// OnReceiveFromBackend indicates an expected call of OnReceiveFromBackend.
+func (mr *MockHandlerMockRecorder) OnReceiveFromBackend(conns, pushMsg any) *MockHandlerOnReceiveFromBackendCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnReceiveFromBackend", reflect.TypeOf((*MockHandler)(nil).OnReceiveFromBackend), conns, pushMsg)
+	return &MockHandlerOnReceiveFromBackendCall{Call: call}
+}
+
+// MockHandlerOnReceiveFromBackendCall wrap *gomock.Call
+type MockHandlerOnReceiveFromBackendCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockHandlerOnReceiveFromBackendCall) Return(arg0 error) *MockHandlerOnReceiveFromBackendCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockHandlerOnReceiveFromBackendCall) Do(f func([]synp.Conn, *messagev1.PushMessage) error) *MockHandlerOnReceiveFromBackendCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockHandlerOnReceiveFromBackendCall) DoAndReturn(f func([]synp.Conn, *messagev1.PushMessage) error) *MockHandlerOnReceiveFromBackendCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// OnReceiveFromFrontend mocks base method.
+func (m *MockHandler) OnReceiveFromFrontend(conn synp.Conn, payload []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OnReceiveFromFrontend", conn, payload)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// OnReceiveFromFrontend indicates an expected call of OnReceiveFromFrontend.
+func (mr *MockHandlerMockRecorder) OnReceiveFromFrontend(conn, payload any) *MockHandlerOnReceiveFromFrontendCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnReceiveFromFrontend", reflect.TypeOf((*MockHandler)(nil).OnReceiveFromFrontend), conn, payload)
+	return &MockHandlerOnReceiveFromFrontendCall{Call: call}
+}
+
+// MockHandlerOnReceiveFromFrontendCall wrap *gomock.Call
+type MockHandlerOnReceiveFromFrontendCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *MockHandlerOnReceiveFromFrontendCall) Return(arg0 error) *MockHandlerOnReceiveFromFrontendCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *MockHandlerOnReceiveFromFrontendCall) Do(f func(synp.Conn, []byte) error) *MockHandlerOnReceiveFromFrontendCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *MockHandlerOnReceiveFromFrontendCall) DoAndReturn(f func(synp.Conn, []byte) error) *MockHandlerOnReceiveFromFrontendCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
@@ -34,6 +34,10 @@ echo "🚀 install & update golangci-lint ..."
 go install github.com/golangci/golangci-lint/cmd/golangci-lint@latest
 echo "✅ done"
 
+echo "🚀 install & update mockgen ..."
+go install go.uber.org/mock/mockgen@latest
+echo "✅ done"
+
 echo "🚀 install & update goimports ..."
 go install golang.org/x/tools/cmd/goimports@latest
 echo "✅ done"
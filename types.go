@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"net"
+	"time"
 
 	messagev1 "github.com/jrmarcco/synp-api/api/go/message/v1"
+	"github.com/jrmarcco/synp/internal/pkg/bufpool"
 	"github.com/jrmarcco/synp/internal/pkg/compression"
 	"github.com/jrmarcco/synp/internal/pkg/session"
 	"go.uber.org/multierr"
@@ -27,13 +29,85 @@ type Upgrader interface {
 	Upgrade(conn net.Conn) (session.Session, *compression.State, error)
 }
 
+// Priority 为下行消息的发送优先级，取值越大越优先，零值为 PriorityNormal。
+//
+// 每个优先级在连接上有独立的发送队列，控制帧总是最先发送，
+// 其余优先级按权重轮流发送，避免大量普通消息阻塞心跳、ack 等延迟敏感的消息。
+type Priority uint8
+
+const (
+	PriorityNormal  Priority = iota // 普通业务消息
+	PriorityHigh                    // 高优先级业务消息 ( 如紧急通知、RPC 响应 )
+	PriorityAck                     // ack 消息
+	PriorityControl                 // 控制帧 ( 心跳、重定向、限流通知等 )
+
+	// PriorityCount 为优先级的数量。
+	PriorityCount = int(PriorityControl) + 1
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityControl:
+		return "control"
+	case PriorityAck:
+		return "ack"
+	case PriorityHigh:
+		return "high"
+	case PriorityNormal:
+		return "normal"
+	default:
+		return "unknown"
+	}
+}
+
+// Frame 为发送给前端 ( 业务客户端 ) 的一条消息。
+type Frame struct {
+	Payload  []byte   // 编码后的消息
+	Priority Priority // 发送优先级
+
+	// Buffer 为池化的编码结果 ( 可以在多个连接间共享 )，不为空时代替 Payload 发送。
+	// SendFrame 接管调用方持有的一次引用，发送完成或丢弃后由连接释放。
+	Buffer *bufpool.Buffer
+	// Suffix 在消息之后追加发送，共享编码结果时用于追加连接相关的字段 ( 如序号 )。
+	Suffix []byte
+
+	// Key 用于发送队列溢出时合并消息 ( 通常为 message id )，为空时不合并。
+	Key string
+	// ConflationKey 为合并 key，发送队列中有相同合并 key 的旧消息时直接替换，为空时不替换。
+	ConflationKey string
+	// ExpireAt 为过期时间，发送时已经过期的消息会被丢弃，零值表示永不过期。
+	ExpireAt time.Time
+	// Message 为编码前的消息，发送队列已满时可能被保存为离线消息，为空时不保存。
+	Message *messagev1.Message
+}
+
+// Bytes 返回编码后的消息 ( 不包括 Suffix )。
+func (f *Frame) Bytes() []byte {
+	if f.Buffer != nil {
+		return f.Buffer.Bytes()
+	}
+	return f.Payload
+}
+
+// Release 释放 Buffer 的引用，没有使用 Buffer 时不做任何处理。
+func (f *Frame) Release() {
+	if f.Buffer != nil {
+		f.Buffer.Release()
+		f.Buffer = nil
+	}
+}
+
 // Conn 是用户连接的抽象，封装了底层的网络连接 ( 如 WebSocket、TCP 连接 ) 。
 type Conn interface {
 	ID() string
 	Session() session.Session
 
+	// Send 以 PriorityNormal 发送消息。
 	Send(payload []byte) error
-	Receive() <-chan []byte
+	// SendFrame 将消息放入对应优先级的发送队列，队列已满时按连接的溢出策略处理。
+	SendFrame(frame Frame) error
+	// Receive 返回前端消息的 channel，消息处理完成后需要调用 Buffer.Release。
+	Receive() <-chan *bufpool.Buffer
 
 	UpdateActivityTime()
 
@@ -50,6 +124,9 @@ type ConnManager interface {
 
 	FindConn(user session.User) (Conn, bool)
 	FindUserConn(user session.User) ([]Conn, bool)
+
+	// RangeConn 遍历本节点上的所有连接，fn 返回 false 时停止遍历。
+	RangeConn(fn func(conn Conn) bool)
 }
 
 // Handler 是连接生命周期相关事件的回调接口。
@@ -58,6 +135,7 @@ type Handler interface {
 	OnDisconnect(conn Conn) error
 
 	// OnReceiveFromFrontend 收到后端（业务服务端）消息的回调。
+	// payload 只在回调返回前有效 ( 随后放回缓冲区池 )，需要保留时应复制。
 	OnReceiveFromFrontend(conn Conn, payload []byte) error
 
 	// OnReceiveFromBackend 收到前端（业务客户端）消息的回调，通常用于发送消息到后端。